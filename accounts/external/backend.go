@@ -26,6 +26,7 @@ import (
 	"github.com/matthieu/go-ethereum/common"
 	"github.com/matthieu/go-ethereum/common/hexutil"
 	"github.com/matthieu/go-ethereum/core/types"
+	"github.com/matthieu/go-ethereum/crypto"
 	"github.com/matthieu/go-ethereum/event"
 	"github.com/matthieu/go-ethereum/log"
 	"github.com/matthieu/go-ethereum/rpc"
@@ -166,6 +167,9 @@ func (api *ExternalSigner) SignData(account accounts.Account, mimeType string, d
 		hexutil.Encode(data)); err != nil {
 		return nil, err
 	}
+	if len(res) != crypto.SignatureLength {
+		return nil, fmt.Errorf("invalid signature length %d", len(res))
+	}
 	// If V is on 27/28-form, convert to 0/1 for Clique
 	if mimeType == accounts.MimetypeClique && (res[64] == 27 || res[64] == 28) {
 		res[64] -= 27 // Transform V from 27/28 to 0/1 for Clique use
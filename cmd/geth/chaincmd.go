@@ -94,6 +94,7 @@ The dumpgenesis command dumps the genesis block configuration in JSON format to
 			utils.MetricsInfluxDBPasswordFlag,
 			utils.MetricsInfluxDBTagsFlag,
 			utils.TxLookupLimitFlag,
+			utils.InternalTxsFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -0,0 +1,129 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/matthieu/go-ethereum/cmd/utils"
+	"github.com/matthieu/go-ethereum/consensus/clique"
+	"github.com/matthieu/go-ethereum/core"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	cliqueFlags = []cli.Flag{
+		utils.DataDirFlag,
+		utils.AncientFlag,
+		utils.CacheFlag,
+		utils.RinkebyFlag,
+		utils.GoerliFlag,
+	}
+
+	cliqueCommand = cli.Command{
+		Name:     "clique",
+		Usage:    "A set of commands reporting on the clique proof-of-authority chain",
+		Category: "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:      "votes",
+				Usage:     "Print the signer authorization votes cast in a block range",
+				ArgsUsage: "[<from> [<to>]]",
+				Action:    utils.MigrateFlags(cliqueVotes),
+				Category:  "BLOCKCHAIN COMMANDS",
+				Flags:     cliqueFlags,
+				Description: `
+geth clique votes [<from> [<to>]]
+prints every authorize and deauthorize vote cast within the given block range,
+along with the block its proposal passed in. The range defaults to the entire
+chain. This is the offline equivalent of clique_getVoteHistory.
+`,
+			},
+			{
+				Name:      "stats",
+				Usage:     "Print the sealing statistics of the signers in a block range",
+				ArgsUsage: "[<from> [<to>]]",
+				Action:    utils.MigrateFlags(cliqueStats),
+				Category:  "BLOCKCHAIN COMMANDS",
+				Flags:     cliqueFlags,
+				Description: `
+geth clique stats [<from> [<to>]]
+prints the number of blocks each signer sealed in-turn and out-of-turn, and the
+number of in-turn blocks it missed within the given block range. The range
+defaults to the entire chain. This is the offline equivalent of
+clique_getSignerStats.
+`,
+			},
+		},
+	}
+)
+
+// cliqueVotes prints the vote history of a clique chain.
+func cliqueVotes(ctx *cli.Context) error {
+	return cliqueReport(ctx, func(engine *clique.Clique, chain *core.BlockChain, from, to uint64) (interface{}, error) {
+		return engine.VoteHistory(chain, from, to)
+	})
+}
+
+// cliqueStats prints the signer statistics of a clique chain.
+func cliqueStats(ctx *cli.Context) error {
+	return cliqueReport(ctx, func(engine *clique.Clique, chain *core.BlockChain, from, to uint64) (interface{}, error) {
+		return engine.SignerStats(chain, from, to)
+	})
+}
+
+// cliqueReport opens the chain in the configured datadir, parses the optional
+// block range from the arguments and prints the generated report as JSON.
+func cliqueReport(ctx *cli.Context, report func(engine *clique.Clique, chain *core.BlockChain, from, to uint64) (interface{}, error)) error {
+	if ctx.NArg() > 2 {
+		return errors.New("too many arguments")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, chainDb := utils.MakeChain(ctx, stack, true)
+	defer chainDb.Close()
+	defer chain.Stop()
+
+	engine, ok := chain.Engine().(*clique.Clique)
+	if !ok {
+		return errors.New("chain is not using clique consensus")
+	}
+	from, to := uint64(0), chain.CurrentHeader().Number.Uint64()
+	for i, arg := range ctx.Args() {
+		number, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid block number %q: %v", arg, err)
+		}
+		if i == 0 {
+			from = number
+		} else {
+			to = number
+		}
+	}
+	result, err := report(engine, chain, from, to)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
@@ -81,6 +81,9 @@ var (
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
+		utils.TxPoolPoolJournalFlag,
+		utils.TxPoolPoolRejournalFlag,
+		utils.TxPoolPoolJournalLimitFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
@@ -88,11 +91,18 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolAllowSendersFlag,
+		utils.TxPoolDenyAddressesFlag,
+		utils.TxPoolDenySelectorsFlag,
+		utils.TxPoolSenderRateLimitFlag,
+		utils.TxPoolSenderRatePeriodFlag,
 		utils.SyncModeFlag,
 		utils.ExitWhenSyncedFlag,
 		utils.GCModeFlag,
 		utils.SnapshotFlag,
 		utils.TxLookupLimitFlag,
+		utils.InternalTxsFlag,
+		utils.InternalTxIndexFlag,
 		utils.LightServeFlag,
 		utils.LegacyLightServFlag,
 		utils.LightIngressFlag,
@@ -129,6 +139,10 @@ var (
 		utils.LegacyMinerExtraDataFlag,
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerNoVerfiyFlag,
+		utils.MinerSignTimeoutFlag,
+		utils.MinerBuilderFlag,
+		utils.MinerReservedSendersFlag,
+		utils.MinerReservedGasFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
@@ -154,6 +168,7 @@ var (
 		utils.LegacyGpoPercentileFlag,
 		utils.EWASMInterpreterFlag,
 		utils.EVMInterpreterFlag,
+		utils.VMListenersFlag,
 		configFileFlag,
 	}
 
@@ -184,11 +199,29 @@ var (
 		utils.LegacyWSApiFlag,
 		utils.WSAllowedOriginsFlag,
 		utils.LegacyWSAllowedOriginsFlag,
+		utils.AuthEnabledFlag,
+		utils.AuthListenAddrFlag,
+		utils.AuthPortFlag,
+		utils.AuthVirtualHostsFlag,
+		utils.AuthApiFlag,
+		utils.JWTSecretFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 		utils.InsecureUnlockAllowedFlag,
 		utils.RPCGlobalGasCap,
+		utils.RPCBundleTimeoutFlag,
 		utils.RPCGlobalTxFeeCap,
+		utils.RPCBatchLimitFlag,
+		utils.RPCResponseLimitFlag,
+		utils.RPCConcurrencyLimitFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCRateBurstFlag,
+		utils.RPCStreamLimitFlag,
+		utils.RPCLogFileFlag,
+		utils.RPCLogMaxSizeFlag,
+		utils.RPCLogMaxFilesFlag,
+		utils.RPCLogSampleFlag,
+		utils.RPCLogSlowFlag,
 	}
 
 	whisperFlags = []cli.Flag{
@@ -229,6 +262,8 @@ func init() {
 		dumpCommand,
 		dumpGenesisCommand,
 		inspectCommand,
+		// See cliquecmd.go:
+		cliqueCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
@@ -243,6 +278,8 @@ func init() {
 		licenseCommand,
 		// See config.go
 		dumpConfigCommand,
+		// See snapshot.go
+		snapshotCommand,
 		// See retesteth.go
 		retestethCommand,
 		// See cmd/utils/flags_legacy.go
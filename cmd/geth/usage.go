@@ -48,6 +48,8 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.ExitWhenSyncedFlag,
 			utils.GCModeFlag,
 			utils.TxLookupLimitFlag,
+			utils.InternalTxsFlag,
+			utils.InternalTxIndexFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightKDFFlag,
@@ -94,6 +96,9 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.TxPoolNoLocalsFlag,
 			utils.TxPoolJournalFlag,
 			utils.TxPoolRejournalFlag,
+			utils.TxPoolPoolJournalFlag,
+			utils.TxPoolPoolRejournalFlag,
+			utils.TxPoolPoolJournalLimitFlag,
 			utils.TxPoolPriceLimitFlag,
 			utils.TxPoolPriceBumpFlag,
 			utils.TxPoolAccountSlotsFlag,
@@ -101,6 +106,11 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
 			utils.TxPoolLifetimeFlag,
+			utils.TxPoolAllowSendersFlag,
+			utils.TxPoolDenyAddressesFlag,
+			utils.TxPoolDenySelectorsFlag,
+			utils.TxPoolSenderRateLimitFlag,
+			utils.TxPoolSenderRatePeriodFlag,
 		},
 	},
 	{
@@ -139,13 +149,31 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.AuthEnabledFlag,
+			utils.AuthListenAddrFlag,
+			utils.AuthPortFlag,
+			utils.AuthVirtualHostsFlag,
+			utils.AuthApiFlag,
+			utils.JWTSecretFlag,
 			utils.GraphQLEnabledFlag,
 			utils.GraphQLListenAddrFlag,
 			utils.GraphQLPortFlag,
 			utils.GraphQLCORSDomainFlag,
 			utils.GraphQLVirtualHostsFlag,
 			utils.RPCGlobalGasCap,
+			utils.RPCBundleTimeoutFlag,
 			utils.RPCGlobalTxFeeCap,
+			utils.RPCBatchLimitFlag,
+			utils.RPCResponseLimitFlag,
+			utils.RPCConcurrencyLimitFlag,
+			utils.RPCRateLimitFlag,
+			utils.RPCRateBurstFlag,
+			utils.RPCStreamLimitFlag,
+			utils.RPCLogFileFlag,
+			utils.RPCLogMaxSizeFlag,
+			utils.RPCLogMaxFilesFlag,
+			utils.RPCLogSampleFlag,
+			utils.RPCLogSlowFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
@@ -182,6 +210,10 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.MinerExtraDataFlag,
 			utils.MinerRecommitIntervalFlag,
 			utils.MinerNoVerfiyFlag,
+			utils.MinerSignTimeoutFlag,
+			utils.MinerBuilderFlag,
+			utils.MinerReservedSendersFlag,
+			utils.MinerReservedGasFlag,
 		},
 	},
 	{
@@ -197,6 +229,7 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.VMEnableDebugFlag,
 			utils.EVMInterpreterFlag,
 			utils.EWASMInterpreterFlag,
+			utils.VMListenersFlag,
 		},
 	},
 	{
@@ -36,6 +36,7 @@ import (
 	"github.com/matthieu/go-ethereum/accounts/keystore"
 	"github.com/matthieu/go-ethereum/common"
 	"github.com/matthieu/go-ethereum/common/fdlimit"
+	"github.com/matthieu/go-ethereum/common/hexutil"
 	"github.com/matthieu/go-ethereum/consensus"
 	"github.com/matthieu/go-ethereum/consensus/clique"
 	"github.com/matthieu/go-ethereum/consensus/ethash"
@@ -189,7 +190,7 @@ var (
 	defaultSyncMode = eth.DefaultConfig.SyncMode
 	SyncModeFlag    = TextMarshalerFlag{
 		Name:  "syncmode",
-		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
+		Usage: `Blockchain sync mode ("fast", "full", "snap" or "light")`,
 		Value: &defaultSyncMode,
 	}
 	GCModeFlag = cli.StringFlag{
@@ -206,6 +207,14 @@ var (
 		Usage: "Number of recent blocks to maintain transactions index by-hash for (default = index all blocks)",
 		Value: 0,
 	}
+	InternalTxsFlag = cli.BoolFlag{
+		Name:  "internaltxs",
+		Usage: "Persist the internal transactions captured during block import",
+	}
+	InternalTxIndexFlag = cli.BoolFlag{
+		Name:  "internaltxindex",
+		Usage: "Build the internal transaction address index in the background",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -310,6 +319,21 @@ var (
 		Usage: "Time interval to regenerate the local transaction journal",
 		Value: core.DefaultTxPoolConfig.Rejournal,
 	}
+	TxPoolPoolJournalFlag = cli.StringFlag{
+		Name:  "txpool.pooljournal",
+		Usage: "Disk journal for all pooled transactions to survive node restarts (disabled if empty)",
+		Value: core.DefaultTxPoolConfig.PoolJournal,
+	}
+	TxPoolPoolRejournalFlag = cli.DurationFlag{
+		Name:  "txpool.poolrejournal",
+		Usage: "Time interval to regenerate the full pool transaction journal",
+		Value: core.DefaultTxPoolConfig.PoolRejournal,
+	}
+	TxPoolPoolJournalLimitFlag = cli.Uint64Flag{
+		Name:  "txpool.pooljournallimit",
+		Usage: "Maximum number of transactions retained in the full pool journal",
+		Value: core.DefaultTxPoolConfig.PoolJournalLimit,
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -345,6 +369,28 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: eth.DefaultConfig.TxPool.Lifetime,
 	}
+	TxPoolAllowSendersFlag = cli.StringFlag{
+		Name:  "txpool.allowsenders",
+		Usage: "Comma separated accounts allowed to submit transactions (all if empty)",
+	}
+	TxPoolDenyAddressesFlag = cli.StringFlag{
+		Name:  "txpool.denyaddresses",
+		Usage: "Comma separated accounts whose transactions are rejected, as sender or recipient",
+	}
+	TxPoolDenySelectorsFlag = cli.StringFlag{
+		Name:  "txpool.denyselectors",
+		Usage: "Comma separated 4-byte method selectors whose contract calls are rejected",
+	}
+	TxPoolSenderRateLimitFlag = cli.Uint64Flag{
+		Name:  "txpool.senderratelimit",
+		Usage: "Maximum number of transactions admitted per sender within the rate period (0 = unlimited)",
+		Value: eth.DefaultConfig.TxPool.SenderRateLimit,
+	}
+	TxPoolSenderRatePeriodFlag = cli.DurationFlag{
+		Name:  "txpool.senderrateperiod",
+		Usage: "Time window of the per sender transaction rate limit",
+		Value: eth.DefaultConfig.TxPool.SenderRatePeriod,
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
@@ -422,6 +468,24 @@ var (
 		Name:  "miner.noverify",
 		Usage: "Disable remote sealing verification",
 	}
+	MinerSignTimeoutFlag = cli.DurationFlag{
+		Name:  "miner.signtimeout",
+		Usage: "Maximum time to wait for the clique signer to seal a block",
+		Value: eth.DefaultConfig.Miner.SignTimeout,
+	}
+	MinerBuilderFlag = cli.StringFlag{
+		Name:  "miner.builder",
+		Usage: `Block building strategy ("price" orders by gas price, "fifo" by arrival in the pool)`,
+		Value: miner.PriceNonceStrategy,
+	}
+	MinerReservedSendersFlag = cli.StringFlag{
+		Name:  "miner.reservedsenders",
+		Usage: "Comma separated system accounts allowed to use the reserved block gas",
+	}
+	MinerReservedGasFlag = cli.Uint64Flag{
+		Name:  "miner.reservedgas",
+		Usage: "Block gas withheld from all but the reserved system senders",
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -451,11 +515,63 @@ var (
 		Usage: "Sets a cap on gas that can be used in eth_call/estimateGas (0=infinite)",
 		Value: eth.DefaultConfig.RPCGasCap,
 	}
+	RPCBundleTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.bundletimeout",
+		Usage: "Sets a timeout used for eth_callBundle simulations (0=infinite)",
+		Value: eth.DefaultConfig.RPCBundleTimeout,
+	}
 	RPCGlobalTxFeeCap = cli.Float64Flag{
 		Name:  "rpc.txfeecap",
 		Usage: "Sets a cap on transaction fee (in ether) that can be sent via the RPC APIs (0 = no cap)",
 		Value: eth.DefaultConfig.RPCTxFeeCap,
 	}
+	RPCBatchLimitFlag = cli.IntFlag{
+		Name:  "rpc.batchlimit",
+		Usage: "Maximum number of requests in an RPC batch (0 = no limit)",
+	}
+	RPCResponseLimitFlag = cli.IntFlag{
+		Name:  "rpc.responselimit",
+		Usage: "Maximum size in bytes of the response to an RPC request or batch (0 = no limit)",
+	}
+	RPCConcurrencyLimitFlag = cli.StringFlag{
+		Name:  "rpc.concurrency",
+		Usage: "Comma separated list of method=limit pairs capping concurrent RPC executions (e.g. eth_getLogs=4)",
+	}
+	RPCRateLimitFlag = cli.Float64Flag{
+		Name:  "rpc.ratelimit",
+		Usage: "Number of RPC requests per second allowed per client (0 = no limit)",
+	}
+	RPCRateBurstFlag = cli.IntFlag{
+		Name:  "rpc.rateburst",
+		Usage: "Number of RPC requests a client may issue at once (0 = rate limit)",
+	}
+	RPCStreamLimitFlag = cli.IntFlag{
+		Name:  "rpc.streamlimit",
+		Usage: "Maximum number of concurrent RPC subscriptions over HTTP event streams per client (0 = no limit)",
+	}
+	RPCLogFileFlag = cli.StringFlag{
+		Name:  "rpc.log",
+		Usage: "File to log the served RPC calls to as JSON lines (relative to the datadir)",
+	}
+	RPCLogMaxSizeFlag = cli.IntFlag{
+		Name:  "rpc.log.maxsize",
+		Usage: "Size in megabytes at which the RPC call log is rotated (0 = no rotation)",
+		Value: int(node.DefaultConfig.RPCRequestLog.MaxSize / 1024 / 1024),
+	}
+	RPCLogMaxFilesFlag = cli.IntFlag{
+		Name:  "rpc.log.maxfiles",
+		Usage: "Number of rotated RPC call log files to retain",
+		Value: node.DefaultConfig.RPCRequestLog.MaxFiles,
+	}
+	RPCLogSampleFlag = cli.Float64Flag{
+		Name:  "rpc.log.sample",
+		Usage: "Fraction of the RPC calls to log (0-1)",
+		Value: node.DefaultConfig.RPCRequestLog.SampleRate,
+	}
+	RPCLogSlowFlag = cli.DurationFlag{
+		Name:  "rpc.log.slow",
+		Usage: "Duration beyond which RPC calls are always logged, regardless of sampling (0 = disabled)",
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
@@ -531,6 +647,35 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	AuthEnabledFlag = cli.BoolFlag{
+		Name:  "authrpc",
+		Usage: "Enable the JWT authenticated HTTP and WS-RPC server",
+	}
+	AuthListenAddrFlag = cli.StringFlag{
+		Name:  "authrpc.addr",
+		Usage: "Authenticated RPC server listening interface",
+		Value: node.DefaultAuthHost,
+	}
+	AuthPortFlag = cli.IntFlag{
+		Name:  "authrpc.port",
+		Usage: "Authenticated RPC server listening port",
+		Value: node.DefaultAuthPort,
+	}
+	AuthVirtualHostsFlag = cli.StringFlag{
+		Name:  "authrpc.vhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept authenticated requests (server enforced). Accepts '*' wildcard.",
+		Value: strings.Join(node.DefaultConfig.AuthVirtualHosts, ","),
+	}
+	AuthApiFlag = cli.StringFlag{
+		Name:  "authrpc.api",
+		Usage: "API's offered over the authenticated RPC interface to tokens without a role claim",
+		Value: "",
+	}
+	JWTSecretFlag = cli.StringFlag{
+		Name:  "authrpc.jwtsecret",
+		Usage: "Path to the hex encoded secret verifying the authenticated RPC tokens (generated if missing)",
+		Value: "",
+	}
 	GraphQLEnabledFlag = cli.BoolFlag{
 		Name:  "graphql",
 		Usage: "Enable the GraphQL server",
@@ -719,6 +864,11 @@ var (
 		Usage: "External EVM configuration (default = built-in interpreter)",
 		Value: "",
 	}
+	VMListenersFlag = cli.StringFlag{
+		Name:  "vm.listeners",
+		Usage: "Comma separated internal transaction listeners to attach to the EVM (" + strings.Join(core.InternalTxListenerNames(), ", ") + ")",
+		Value: strings.Join(core.DefaultInternalTxListeners, ","),
+	}
 )
 
 // MakeDataDir retrieves the currently requested data directory, terminating
@@ -994,6 +1144,89 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setAuth creates the authenticated RPC listener interface string from the set
+// command line flags, returning empty if the authenticated endpoint is disabled.
+func setAuth(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalBool(AuthEnabledFlag.Name) && cfg.AuthHost == "" {
+		cfg.AuthHost = "127.0.0.1"
+		if ctx.GlobalIsSet(AuthListenAddrFlag.Name) {
+			cfg.AuthHost = ctx.GlobalString(AuthListenAddrFlag.Name)
+		}
+	}
+	if ctx.GlobalIsSet(AuthPortFlag.Name) {
+		cfg.AuthPort = ctx.GlobalInt(AuthPortFlag.Name)
+	}
+	if ctx.GlobalIsSet(AuthVirtualHostsFlag.Name) {
+		cfg.AuthVirtualHosts = splitAndTrim(ctx.GlobalString(AuthVirtualHostsFlag.Name))
+	}
+	if ctx.GlobalIsSet(AuthApiFlag.Name) {
+		cfg.AuthModules = splitAndTrim(ctx.GlobalString(AuthApiFlag.Name))
+	}
+	if ctx.GlobalIsSet(JWTSecretFlag.Name) {
+		cfg.JWTSecret = ctx.GlobalString(JWTSecretFlag.Name)
+	}
+}
+
+// setRPCLimits applies the RPC resource limits from the set command line flags.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCBatchLimitFlag.Name) {
+		cfg.RPCLimits.BatchItems = ctx.GlobalInt(RPCBatchLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCResponseLimitFlag.Name) {
+		cfg.RPCLimits.ResponseBytes = ctx.GlobalInt(RPCResponseLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCConcurrencyLimitFlag.Name) {
+		cfg.RPCLimits.MethodConcurrency = make(map[string]int)
+		for _, entry := range splitAndTrim(ctx.GlobalString(RPCConcurrencyLimitFlag.Name)) {
+			parts := strings.Split(entry, "=")
+			if len(parts) != 2 {
+				Fatalf("Invalid RPC concurrency limit %q, want method=limit", entry)
+			}
+			limit, err := strconv.Atoi(parts[1])
+			if err != nil {
+				Fatalf("Invalid RPC concurrency limit %q: %v", entry, err)
+			}
+			if limit < 0 {
+				Fatalf("Invalid RPC concurrency limit %q, want non-negative limit", entry)
+			}
+			cfg.RPCLimits.MethodConcurrency[parts[0]] = limit
+		}
+	}
+	if ctx.GlobalIsSet(RPCRateLimitFlag.Name) {
+		cfg.RPCLimits.RequestRate = ctx.GlobalFloat64(RPCRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateBurstFlag.Name) {
+		cfg.RPCLimits.RequestBurst = ctx.GlobalInt(RPCRateBurstFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCStreamLimitFlag.Name) {
+		cfg.RPCLimits.EventStreams = ctx.GlobalInt(RPCStreamLimitFlag.Name)
+	}
+}
+
+// setRPCRequestLog applies the RPC call log configuration from the set command
+// line flags.
+func setRPCRequestLog(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCLogFileFlag.Name) {
+		cfg.RPCRequestLog.Path = ctx.GlobalString(RPCLogFileFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCLogMaxSizeFlag.Name) {
+		cfg.RPCRequestLog.MaxSize = int64(ctx.GlobalInt(RPCLogMaxSizeFlag.Name)) * 1024 * 1024
+	}
+	if ctx.GlobalIsSet(RPCLogMaxFilesFlag.Name) {
+		cfg.RPCRequestLog.MaxFiles = ctx.GlobalInt(RPCLogMaxFilesFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCLogSampleFlag.Name) {
+		sample := ctx.GlobalFloat64(RPCLogSampleFlag.Name)
+		if sample < 0 || sample > 1 {
+			Fatalf("Invalid RPC log sample rate %v, want 0-1", sample)
+		}
+		cfg.RPCRequestLog.SampleRate = sample
+	}
+	if ctx.GlobalIsSet(RPCLogSlowFlag.Name) {
+		cfg.RPCRequestLog.SlowThreshold = ctx.GlobalDuration(RPCLogSlowFlag.Name)
+	}
+}
+
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an empty string if IPC was explicitly disabled, or the set path.
 func setIPC(ctx *cli.Context, cfg *node.Config) {
@@ -1212,6 +1445,9 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setHTTP(ctx, cfg)
 	setGraphQL(ctx, cfg)
 	setWS(ctx, cfg)
+	setAuth(ctx, cfg)
+	setRPCLimits(ctx, cfg)
+	setRPCRequestLog(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 	setDataDir(ctx, cfg)
 	setSmartCard(ctx, cfg)
@@ -1322,6 +1558,15 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolPoolJournalFlag.Name) {
+		cfg.PoolJournal = ctx.GlobalString(TxPoolPoolJournalFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolPoolRejournalFlag.Name) {
+		cfg.PoolRejournal = ctx.GlobalDuration(TxPoolPoolRejournalFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolPoolJournalLimitFlag.Name) {
+		cfg.PoolJournalLimit = ctx.GlobalUint64(TxPoolPoolJournalLimitFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1343,6 +1588,39 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolAllowSendersFlag.Name) {
+		cfg.AllowSenders = parseAddresses(ctx, TxPoolAllowSendersFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolDenyAddressesFlag.Name) {
+		cfg.DenyAddresses = parseAddresses(ctx, TxPoolDenyAddressesFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolDenySelectorsFlag.Name) {
+		for _, selector := range splitAndTrim(ctx.GlobalString(TxPoolDenySelectorsFlag.Name)) {
+			blob, err := hexutil.Decode(selector)
+			if err != nil || len(blob) != 4 {
+				Fatalf("Invalid method selector in --%s: %s", TxPoolDenySelectorsFlag.Name, selector)
+			}
+			cfg.DenySelectors = append(cfg.DenySelectors, blob)
+		}
+	}
+	if ctx.GlobalIsSet(TxPoolSenderRateLimitFlag.Name) {
+		cfg.SenderRateLimit = ctx.GlobalUint64(TxPoolSenderRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolSenderRatePeriodFlag.Name) {
+		cfg.SenderRatePeriod = ctx.GlobalDuration(TxPoolSenderRatePeriodFlag.Name)
+	}
+}
+
+// parseAddresses parses the comma separated list of accounts in the given flag.
+func parseAddresses(ctx *cli.Context, name string) []common.Address {
+	var addrs []common.Address
+	for _, account := range splitAndTrim(ctx.GlobalString(name)) {
+		if !common.IsHexAddress(account) {
+			Fatalf("Invalid account in --%s: %s", name, account)
+		}
+		addrs = append(addrs, common.HexToAddress(account))
+	}
+	return addrs
 }
 
 func setEthash(ctx *cli.Context, cfg *eth.Config) {
@@ -1406,6 +1684,23 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.GlobalIsSet(MinerNoVerfiyFlag.Name) {
 		cfg.Noverify = ctx.GlobalBool(MinerNoVerfiyFlag.Name)
 	}
+	if ctx.GlobalIsSet(MinerSignTimeoutFlag.Name) {
+		cfg.SignTimeout = ctx.GlobalDuration(MinerSignTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerBuilderFlag.Name) {
+		switch builder := ctx.GlobalString(MinerBuilderFlag.Name); builder {
+		case miner.PriceNonceStrategy, miner.FIFOStrategy:
+			cfg.Builder = builder
+		default:
+			Fatalf("Invalid block building strategy in --%s: %s", MinerBuilderFlag.Name, builder)
+		}
+	}
+	if ctx.GlobalIsSet(MinerReservedSendersFlag.Name) {
+		cfg.ReservedSenders = parseAddresses(ctx, MinerReservedSendersFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerReservedGasFlag.Name) {
+		cfg.ReservedGas = ctx.GlobalUint64(MinerReservedGasFlag.Name)
+	}
 }
 
 func setWhitelist(ctx *cli.Context, cfg *eth.Config) {
@@ -1534,6 +1829,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.GlobalIsSet(InternalTxsFlag.Name) {
+		cfg.InternalTxs = ctx.GlobalBool(InternalTxsFlag.Name)
+	}
+	if ctx.GlobalIsSet(InternalTxIndexFlag.Name) {
+		cfg.InternalTxIndex = ctx.GlobalBool(InternalTxIndexFlag.Name)
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
@@ -1561,6 +1862,24 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(EVMInterpreterFlag.Name) {
 		cfg.EVMInterpreter = ctx.GlobalString(EVMInterpreterFlag.Name)
 	}
+	if ctx.GlobalIsSet(VMListenersFlag.Name) {
+		cfg.InternalTxListeners = splitAndTrim(ctx.GlobalString(VMListenersFlag.Name))
+	}
+	if _, err := core.InternalTxListenerFactories(cfg.InternalTxListeners); err != nil {
+		Fatalf("Invalid --%s: %v", VMListenersFlag.Name, err)
+	}
+	if cfg.InternalTxListeners != nil {
+		watched := false
+		for _, name := range cfg.InternalTxListeners {
+			watched = watched || name == "watcher"
+		}
+		if !watched {
+			if cfg.InternalTxs {
+				Fatalf("--%s requires the watcher in --%s", InternalTxsFlag.Name, VMListenersFlag.Name)
+			}
+			log.Warn("Internal transaction watcher disabled, internal transactions will not be captured", "listeners", cfg.InternalTxListeners)
+		}
+	}
 	if ctx.GlobalIsSet(RPCGlobalGasCap.Name) {
 		cfg.RPCGasCap = ctx.GlobalUint64(RPCGlobalGasCap.Name)
 	}
@@ -1569,6 +1888,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	} else {
 		log.Info("Global gas cap disabled")
 	}
+	if ctx.GlobalIsSet(RPCBundleTimeoutFlag.Name) {
+		cfg.RPCBundleTimeout = ctx.GlobalDuration(RPCBundleTimeoutFlag.Name)
+	}
 	if ctx.GlobalIsSet(RPCGlobalTxFeeCap.Name) {
 		cfg.RPCTxFeeCap = ctx.GlobalFloat64(RPCGlobalTxFeeCap.Name)
 	}
@@ -1864,6 +2186,7 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readOnly bool) (chain *core.B
 		TrieDirtyDisabled:   ctx.GlobalString(GCModeFlag.Name) == "archive",
 		TrieTimeLimit:       eth.DefaultConfig.TrieTimeout,
 		SnapshotLimit:       eth.DefaultConfig.SnapshotCache,
+		InternalTxs:         ctx.GlobalBool(InternalTxsFlag.Name),
 	}
 	if !ctx.GlobalIsSet(SnapshotFlag.Name) {
 		cache.SnapshotLimit = 0 // Disabled
@@ -1875,6 +2198,11 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readOnly bool) (chain *core.B
 		cache.TrieDirtyLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
 	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
+	if ctx.GlobalIsSet(VMListenersFlag.Name) {
+		if vmcfg.Listeners, err = core.InternalTxListenerFactories(splitAndTrim(ctx.GlobalString(VMListenersFlag.Name))); err != nil {
+			Fatalf("Invalid --%s: %v", VMListenersFlag.Name, err)
+		}
+	}
 	var limit *uint64
 	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) && !readOnly {
 		l := ctx.GlobalUint64(TxLookupLimitFlag.Name)
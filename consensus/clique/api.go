@@ -25,6 +25,10 @@ import (
 	"github.com/matthieu/go-ethereum/rpc"
 )
 
+// maxHistoryRange is the maximum number of blocks the vote history and the signer
+// statistics can be reconstructed for in a single request.
+const maxHistoryRange = 10000
+
 // API is a user facing RPC API to allow controlling the signer and voting
 // mechanisms of the proof-of-authority scheme.
 type API struct {
@@ -120,6 +124,58 @@ func (api *API) Discard(address common.Address) {
 	delete(api.clique.proposals, address)
 }
 
+// GetVoteHistory retrieves the authorization votes cast within the given block
+// range, along with the block their proposal passed in. The range defaults to
+// the most recent blocks, and may span at most maxHistoryRange blocks.
+func (api *API) GetVoteHistory(from, to *rpc.BlockNumber) ([]*VoteRecord, error) {
+	start, end, err := api.blockRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return api.clique.VoteHistory(api.chain, start, end)
+}
+
+// GetSignerStats retrieves the number of blocks sealed in-turn and out-of-turn
+// and the number of missed turns of each signer within the given block range.
+// The range defaults to the most recent blocks, and may span at most
+// maxHistoryRange blocks.
+func (api *API) GetSignerStats(from, to *rpc.BlockNumber) ([]*SignerStats, error) {
+	start, end, err := api.blockRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return api.clique.SignerStats(api.chain, start, end)
+}
+
+// blockRange resolves the boundaries of an optional block range, defaulting to
+// the last maxHistoryRange blocks up to the current one. Ranges spanning more
+// blocks are rejected.
+func (api *API) blockRange(from, to *rpc.BlockNumber) (uint64, uint64, error) {
+	resolve := func(number *rpc.BlockNumber, fallback uint64) uint64 {
+		switch {
+		case number == nil:
+			return fallback
+		case *number == rpc.LatestBlockNumber || *number == rpc.PendingBlockNumber:
+			return api.chain.CurrentHeader().Number.Uint64()
+		case *number == rpc.EarliestBlockNumber:
+			return 0
+		default:
+			return uint64(number.Int64())
+		}
+	}
+	end := resolve(to, api.chain.CurrentHeader().Number.Uint64())
+
+	var start uint64
+	if end >= maxHistoryRange {
+		start = end - maxHistoryRange + 1
+	}
+	start = resolve(from, start)
+	if start <= end && end-start >= maxHistoryRange {
+		return 0, 0, fmt.Errorf("block range %d-%d exceeds the maximum of %d blocks", start, end, maxHistoryRange)
+	}
+	return start, end, nil
+}
+
 type status struct {
 	InturnPercent float64                `json:"inturnPercent"`
 	SigningStatus map[common.Address]int `json:"sealerActivity"`
@@ -20,6 +20,7 @@ package clique
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"math/big"
 	"math/rand"
@@ -27,6 +28,7 @@ import (
 	"time"
 
 	"github.com/matthieu/go-ethereum/accounts"
+	"github.com/matthieu/go-ethereum/accounts/usbwallet"
 	"github.com/matthieu/go-ethereum/common"
 	"github.com/matthieu/go-ethereum/common/hexutil"
 	"github.com/matthieu/go-ethereum/consensus"
@@ -35,6 +37,7 @@ import (
 	"github.com/matthieu/go-ethereum/core/types"
 	"github.com/matthieu/go-ethereum/crypto"
 	"github.com/matthieu/go-ethereum/ethdb"
+	"github.com/matthieu/go-ethereum/event"
 	"github.com/matthieu/go-ethereum/log"
 	"github.com/matthieu/go-ethereum/params"
 	"github.com/matthieu/go-ethereum/rlp"
@@ -49,6 +52,8 @@ const (
 	inmemorySignatures = 4096 // Number of recent block signatures to keep in memory
 
 	wiggleTime = 500 * time.Millisecond // Random delay (per signer) to allow concurrent signers
+
+	signTimeout = 10 * time.Second // Default maximum time to wait for the signer to seal a block
 )
 
 // Clique proof-of-authority protocol constants.
@@ -135,8 +140,30 @@ var (
 	// errRecentlySigned is returned if a header is signed by an authorized entity
 	// that already signed a header recently, thus is temporarily not allowed to.
 	errRecentlySigned = errors.New("recently signed")
+
+	// errSignTimeout is returned if the signer didn't seal a block in time.
+	errSignTimeout = errors.New("signing timed out")
+
+	// errSignAborted is returned if sealing was terminated while waiting for the
+	// signer to seal a block.
+	errSignAborted = errors.New("signing aborted")
+
+	// errSignerMismatch is returned if the signer sealed a block with a different
+	// account than the one it was requested to.
+	errSignerMismatch = errors.New("signature by wrong account")
+
+	// errUnsupportedWallet is returned if a signer is authorized with a wallet
+	// backend unable to sign clique headers.
+	errUnsupportedWallet = errors.New("wallet cannot sign clique headers")
 )
 
+// unsupportedWallets are the URL schemes of the wallet backends unable to sign
+// clique headers. USB hardware wallets only sign transactions and text messages.
+var unsupportedWallets = map[string]bool{
+	usbwallet.LedgerScheme: true,
+	usbwallet.TrezorScheme: true,
+}
+
 // SignerFn is a signer callback function to request a header to be signed by a
 // backing account.
 type SignerFn func(accounts.Account, string, []byte) ([]byte, error)
@@ -181,6 +208,10 @@ type Clique struct {
 	signFn SignerFn       // Signer function to authorize hashes with
 	lock   sync.RWMutex   // Protects the signer fields
 
+	signTimeout time.Duration // Maximum time to wait for the signer function
+
+	sealFailureFeed event.Feed // Feed of the blocks failed to be signed in the background
+
 	// The fields below are for testing only
 	fakeDiff bool // Skip difficulty verifications
 }
@@ -198,11 +229,12 @@ func New(config *params.CliqueConfig, db ethdb.Database) *Clique {
 	signatures, _ := lru.NewARC(inmemorySignatures)
 
 	return &Clique{
-		config:     &conf,
-		db:         db,
-		recents:    recents,
-		signatures: signatures,
-		proposals:  make(map[common.Address]bool),
+		config:      &conf,
+		db:          db,
+		recents:     recents,
+		signatures:  signatures,
+		proposals:   make(map[common.Address]bool),
+		signTimeout: signTimeout,
 	}
 }
 
@@ -570,7 +602,8 @@ func (c *Clique) FinalizeAndAssemble(chain consensus.ChainReader, header *types.
 }
 
 // Authorize injects a private key into the consensus engine to mint new blocks
-// with.
+// with. The signer function may be backed by an external signer, in which case
+// the key never needs to be unlocked within the process.
 func (c *Clique) Authorize(signer common.Address, signFn SignerFn) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
@@ -579,8 +612,36 @@ func (c *Clique) Authorize(signer common.Address, signFn SignerFn) {
 	c.signFn = signFn
 }
 
+// AuthorizeWallet authorizes an account of a wallet to mint new blocks with,
+// rejecting the wallet backends unable to sign clique headers.
+func (c *Clique) AuthorizeWallet(signer common.Address, wallet accounts.Wallet) error {
+	if scheme := wallet.URL().Scheme; unsupportedWallets[scheme] {
+		return fmt.Errorf("%w: %s", errUnsupportedWallet, scheme)
+	}
+	c.Authorize(signer, wallet.SignData)
+	return nil
+}
+
+// SetSignTimeout sets the maximum time to wait for the signer to seal a block,
+// restoring the default if zero.
+func (c *Clique) SetSignTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = signTimeout
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.signTimeout = timeout
+}
+
+// SubscribeSealFailures implements consensus.SealFailureReporter, registering a
+// subscription for the blocks failed to be signed after Seal returned.
+func (c *Clique) SubscribeSealFailures(ch chan<- consensus.SealFailure) event.Subscription {
+	return c.sealFailureFeed.Subscribe(ch)
+}
+
 // Seal implements consensus.Engine, attempting to create a sealed block using
-// the local signing credentials.
+// the authorized signing credentials, which may be held by an external signer.
 func (c *Clique) Seal(chain consensus.ChainReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
 	header := block.Header()
 
@@ -596,7 +657,7 @@ func (c *Clique) Seal(chain consensus.ChainReader, block *types.Block, results c
 	}
 	// Don't hold the signer fields for the entire sealing procedure
 	c.lock.RLock()
-	signer, signFn := c.signer, c.signFn
+	signer, signFn, timeout := c.signer, c.signFn, c.signTimeout
 	c.lock.RUnlock()
 
 	// Bail out if we're unauthorized to sign a block
@@ -626,19 +687,27 @@ func (c *Clique) Seal(chain consensus.ChainReader, block *types.Block, results c
 
 		log.Trace("Out-of-turn signing requested", "wiggle", common.PrettyDuration(wiggle))
 	}
-	// Sign all the things!
-	sighash, err := signFn(accounts.Account{Address: signer}, accounts.MimetypeClique, CliqueRLP(header))
-	if err != nil {
-		return err
-	}
-	copy(header.Extra[len(header.Extra)-extraSeal:], sighash)
-	// Wait until sealing is terminated or delay timeout.
-	log.Trace("Waiting for slot to sign and propagate", "delay", common.PrettyDuration(delay))
+	// Sign all the things! The signer may be an external one (e.g. clef) needing
+	// arbitrary time to respond, so sign in the background to not stall the miner.
+	deadline := time.Now().Add(delay)
 	go func() {
+		sighash, err := c.signHeader(signFn, signer, header, timeout, stop)
+		if err != nil {
+			if err != errSignAborted {
+				sealhash := SealHash(header)
+				log.Warn("Failed to sign block", "number", number, "sealhash", sealhash, "err", err)
+				c.sealFailureFeed.Send(consensus.SealFailure{Number: number, SealHash: sealhash, Err: err})
+			}
+			return
+		}
+		copy(header.Extra[len(header.Extra)-extraSeal:], sighash)
+
+		// Wait until sealing is terminated or delay timeout.
+		log.Trace("Waiting for slot to sign and propagate", "delay", common.PrettyDuration(time.Until(deadline)))
 		select {
 		case <-stop:
 			return
-		case <-time.After(delay):
+		case <-time.After(time.Until(deadline)):
 		}
 
 		select {
@@ -651,6 +720,52 @@ func (c *Clique) Seal(chain consensus.ChainReader, block *types.Block, results c
 	return nil
 }
 
+// signHeader requests the signer function to seal the given header, returning the
+// signature once it's verified to originate from the expected account. Signing is
+// abandoned if sealing is terminated or the signer doesn't respond in time, though
+// the request itself cannot be cancelled.
+func (c *Clique) signHeader(signFn SignerFn, signer common.Address, header *types.Header, timeout time.Duration, stop <-chan struct{}) ([]byte, error) {
+	type signature struct {
+		sig []byte
+		err error
+	}
+	var (
+		sealhash = SealHash(header)
+		data     = CliqueRLP(header)
+		done     = make(chan signature, 1) // Buffered to not leak the signing goroutine
+	)
+	go func() {
+		sig, err := signFn(accounts.Account{Address: signer}, accounts.MimetypeClique, data)
+		done <- signature{sig, err}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			return nil, res.err
+		}
+		if len(res.sig) != extraSeal {
+			return nil, errMissingSignature
+		}
+		pubkey, err := crypto.Ecrecover(sealhash.Bytes(), res.sig)
+		if err != nil {
+			return nil, err
+		}
+		if common.BytesToAddress(crypto.Keccak256(pubkey[1:])[12:]) != signer {
+			return nil, errSignerMismatch
+		}
+		return res.sig, nil
+
+	case <-stop:
+		return nil, errSignAborted
+
+	case <-timer.C:
+		return nil, errSignTimeout
+	}
+}
+
 // CalcDifficulty is the difficulty adjustment algorithm. It returns the difficulty
 // that a new block should have based on the previous blocks in the chain and the
 // current signer.
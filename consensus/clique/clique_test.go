@@ -17,10 +17,17 @@
 package clique
 
 import (
+	"crypto/ecdsa"
+	"errors"
 	"math/big"
 	"testing"
+	"time"
 
+	"github.com/matthieu/go-ethereum/accounts"
+	"github.com/matthieu/go-ethereum/accounts/keystore"
+	"github.com/matthieu/go-ethereum/accounts/usbwallet"
 	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/consensus"
 	"github.com/matthieu/go-ethereum/core"
 	"github.com/matthieu/go-ethereum/core/rawdb"
 	"github.com/matthieu/go-ethereum/core/types"
@@ -111,3 +118,146 @@ func TestReimportMirroredState(t *testing.T) {
 		t.Fatalf("chain head mismatch: have %d, want %d", head, 3)
 	}
 }
+
+// Tests that blocks are sealed in the background, so that slow (e.g. external)
+// signers don't stall the miner, and that bad or late signatures are discarded
+// and reported as seal failures.
+func TestSealSignerFn(t *testing.T) {
+	var (
+		db       = rawdb.NewMemoryDatabase()
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr     = crypto.PubkeyToAddress(key.PublicKey)
+		other, _ = crypto.GenerateKey()
+		config   = *params.AllCliqueProtocolChanges
+		release  = make(chan struct{})
+		rejected = errors.New("rejected")
+	)
+	defer close(release)
+
+	config.Clique = &params.CliqueConfig{Period: 1, Epoch: 30000}
+	engine := New(config.Clique, db)
+	engine.SetSignTimeout(100 * time.Millisecond)
+
+	failures := make(chan consensus.SealFailure, 1)
+	sub := engine.SubscribeSealFailures(failures)
+	defer sub.Unsubscribe()
+
+	genspec := &core.Genesis{ExtraData: make([]byte, extraVanity+common.AddressLength+extraSeal)}
+	copy(genspec.ExtraData[extraVanity:], addr[:])
+	genesis := genspec.MustCommit(db)
+
+	chain, _ := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	signWith := func(key *ecdsa.PrivateKey) SignerFn {
+		return func(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+			if mimeType != accounts.MimetypeClique {
+				t.Errorf("mime type mismatch: have %s, want %s", mimeType, accounts.MimetypeClique)
+			}
+			return crypto.Sign(crypto.Keccak256(data), key)
+		}
+	}
+	tests := []struct {
+		signFn  SignerFn
+		abort   bool
+		sealed  bool
+		failure error
+	}{
+		// Valid signature, block sealed
+		{signFn: signWith(key), sealed: true},
+		// Signature by a different account, block dropped
+		{signFn: signWith(other), failure: errSignerMismatch},
+		// Signer failure, block dropped
+		{signFn: func(accounts.Account, string, []byte) ([]byte, error) { return nil, rejected }, failure: rejected},
+		// Signer not responding in time, block dropped
+		{signFn: func(accounts.Account, string, []byte) ([]byte, error) { <-release; return nil, nil }, failure: errSignTimeout},
+		// Sealing aborted while waiting for the signer, block dropped
+		{signFn: func(accounts.Account, string, []byte) ([]byte, error) { <-release; return nil, nil }, abort: true},
+	}
+	for i, tt := range tests {
+		engine.Authorize(addr, tt.signFn)
+
+		header := &types.Header{
+			ParentHash: genesis.Hash(),
+			Number:     big.NewInt(1),
+			Time:       uint64(time.Now().Unix()),
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		var (
+			results = make(chan *types.Block, 1)
+			stop    = make(chan struct{})
+		)
+		if tt.abort {
+			close(stop)
+		}
+		start := time.Now()
+		if err := engine.Seal(chain, types.NewBlockWithHeader(header), results, stop); err != nil {
+			t.Fatalf("test %d: failed to seal block: %v", i, err)
+		}
+		if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+			t.Errorf("test %d: sealing blocked for %v", i, elapsed)
+		}
+		select {
+		case block := <-results:
+			if !tt.sealed {
+				t.Errorf("test %d: unexpected sealed block", i)
+			} else if author, err := engine.Author(block.Header()); err != nil || author != addr {
+				t.Errorf("test %d: author mismatch: have %x, want %x, err %v", i, author, addr, err)
+			}
+		case failure := <-failures:
+			if failure.Err != tt.failure {
+				t.Errorf("test %d: failure mismatch: have %v, want %v", i, failure.Err, tt.failure)
+			}
+			if failure.SealHash != SealHash(header) {
+				t.Errorf("test %d: failure sealhash mismatch: have %x, want %x", i, failure.SealHash, SealHash(header))
+			}
+		case <-time.After(2 * engine.signTimeout):
+			if tt.sealed {
+				t.Errorf("test %d: block not sealed", i)
+			}
+			if tt.failure != nil {
+				t.Errorf("test %d: failure not reported", i)
+			}
+		}
+		if !tt.abort {
+			close(stop)
+		}
+	}
+}
+
+// testWallet is a wallet of the given backend, only implementing the methods
+// needed to authorize a clique signer.
+type testWallet struct {
+	accounts.Wallet
+	scheme string
+}
+
+func (w *testWallet) URL() accounts.URL {
+	return accounts.URL{Scheme: w.scheme, Path: "test"}
+}
+
+func (w *testWallet) SignData(accounts.Account, string, []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// Tests that wallets unable to sign clique headers are rejected when authorized.
+func TestAuthorizeWallet(t *testing.T) {
+	engine := New(params.AllCliqueProtocolChanges.Clique, rawdb.NewMemoryDatabase())
+	addr := common.HexToAddress("0x1000")
+
+	for _, scheme := range []string{usbwallet.LedgerScheme, usbwallet.TrezorScheme} {
+		if err := engine.AuthorizeWallet(addr, &testWallet{scheme: scheme}); !errors.Is(err, errUnsupportedWallet) {
+			t.Errorf("%s: error mismatch: have %v, want %v", scheme, err, errUnsupportedWallet)
+		}
+		if engine.signer != (common.Address{}) || engine.signFn != nil {
+			t.Fatalf("%s: unsupported wallet authorized", scheme)
+		}
+	}
+	if err := engine.AuthorizeWallet(addr, &testWallet{scheme: keystore.KeyStoreScheme}); err != nil {
+		t.Fatalf("failed to authorize keystore wallet: %v", err)
+	}
+	if engine.signer != addr || engine.signFn == nil {
+		t.Fatalf("keystore wallet not authorized")
+	}
+}
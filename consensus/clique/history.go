@@ -0,0 +1,156 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/consensus"
+	"github.com/matthieu/go-ethereum/core/types"
+)
+
+// VoteRecord is a single authorization vote cast in the header chain.
+type VoteRecord struct {
+	Block     uint64         `json:"block"`            // Block number the vote was cast in
+	Hash      common.Hash    `json:"hash"`             // Block hash the vote was cast in
+	Signer    common.Address `json:"signer"`           // Authorized signer that cast the vote
+	Address   common.Address `json:"address"`          // Account being voted on
+	Authorize bool           `json:"authorize"`        // Whether to authorize or deauthorize the account
+	Passed    *uint64        `json:"passed,omitempty"` // Block the proposal passed in, nil if it didn't (yet)
+}
+
+// SignerStats is the sealing activity of a single signer within a block range.
+type SignerStats struct {
+	Signer    common.Address `json:"signer"`
+	InTurn    uint64         `json:"inTurn"`    // Number of blocks sealed while in-turn
+	OutOfTurn uint64         `json:"outOfTurn"` // Number of blocks sealed while out-of-turn
+	Missed    uint64         `json:"missed"`    // Number of in-turn blocks sealed by someone else
+}
+
+// VoteHistory reconstructs all the authorization votes cast within the given
+// (inclusive) block range, along with the block their proposal passed in.
+func (c *Clique) VoteHistory(chain consensus.ChainReader, from, to uint64) ([]*VoteRecord, error) {
+	votes := []*VoteRecord{}
+	err := c.replay(chain, from, to, func(parent, snap *Snapshot, header *types.Header, signer common.Address) {
+		number := header.Number.Uint64()
+		if header.Coinbase != (common.Address{}) && number%c.config.Epoch != 0 {
+			votes = append(votes, &VoteRecord{
+				Block:     number,
+				Hash:      header.Hash(),
+				Signer:    signer,
+				Address:   header.Coinbase,
+				Authorize: bytes.Equal(header.Nonce[:], nonceAuthVote),
+			})
+		}
+		// If the signer set changed, the proposal on the coinbase passed. Mark all
+		// the votes counted towards it since the last checkpoint.
+		_, before := parent.Signers[header.Coinbase]
+		_, after := snap.Signers[header.Coinbase]
+		if before == after {
+			return
+		}
+		checkpoint := number - number%c.config.Epoch
+		for _, vote := range votes {
+			if vote.Address == header.Coinbase && vote.Authorize == after && vote.Passed == nil && vote.Block >= checkpoint {
+				passed := number
+				vote.Passed = &passed
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
+
+// SignerStats counts the blocks each signer sealed in-turn and out-of-turn and
+// the in-turn blocks it missed within the given (inclusive) block range. All
+// signers authorized at any point of the range are reported, ordered by address.
+func (c *Clique) SignerStats(chain consensus.ChainReader, from, to uint64) ([]*SignerStats, error) {
+	stats := make(map[common.Address]*SignerStats)
+	get := func(signer common.Address) *SignerStats {
+		if _, ok := stats[signer]; !ok {
+			stats[signer] = &SignerStats{Signer: signer}
+		}
+		return stats[signer]
+	}
+	err := c.replay(chain, from, to, func(parent, snap *Snapshot, header *types.Header, signer common.Address) {
+		signers := parent.signers()
+		for _, addr := range signers {
+			get(addr)
+		}
+		inturn := signers[header.Number.Uint64()%uint64(len(signers))]
+		if signer == inturn {
+			get(signer).InTurn++
+		} else {
+			get(signer).OutOfTurn++
+			get(inturn).Missed++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*SignerStats, 0, len(stats))
+	for _, stat := range stats {
+		result = append(result, stat)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return bytes.Compare(result[i].Signer[:], result[j].Signer[:]) < 0
+	})
+	return result, nil
+}
+
+// replay iterates over the headers of the given (inclusive) block range, applying
+// them one by one onto the snapshot of their parent and invoking the callback with
+// the snapshots before and after each header, along with the header's signer.
+func (c *Clique) replay(chain consensus.ChainReader, from, to uint64, fn func(parent, snap *Snapshot, header *types.Header, signer common.Address)) error {
+	// The genesis block is not sealed, start from its child
+	if from == 0 {
+		from = 1
+	}
+	if from > to {
+		return fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	header := chain.GetHeaderByNumber(from - 1)
+	if header == nil {
+		return errUnknownBlock
+	}
+	snap, err := c.snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return err
+	}
+	for number := from; number <= to; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			return errUnknownBlock
+		}
+		signer, err := ecrecover(header, c.signatures)
+		if err != nil {
+			return err
+		}
+		next, err := snap.apply([]*types.Header{header})
+		if err != nil {
+			return err
+		}
+		fn(snap, next, header, signer)
+		snap = next
+	}
+	return nil
+}
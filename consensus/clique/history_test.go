@@ -0,0 +1,157 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/core"
+	"github.com/matthieu/go-ethereum/core/rawdb"
+	"github.com/matthieu/go-ethereum/core/types"
+	"github.com/matthieu/go-ethereum/core/vm"
+	"github.com/matthieu/go-ethereum/params"
+	"github.com/matthieu/go-ethereum/rpc"
+)
+
+// Tests that the vote history and the signer statistics are correctly
+// reconstructed from the header chain.
+func TestHistory(t *testing.T) {
+	accounts := newTesterAccountPool()
+
+	// Blocks sealed by the given signers, casting a vote on the voted account
+	votes := []testerVote{
+		{signer: "A", voted: "C", auth: true},
+		{signer: "B", voted: "C", auth: true},
+		{signer: "A"},
+		{signer: "C", voted: "B", auth: false},
+	}
+	// Create a pristine blockchain with the initial signers injected
+	signers := []common.Address{accounts.address("A"), accounts.address("B")}
+	sort.Sort(signersAscending(signers))
+
+	genesis := &core.Genesis{
+		ExtraData: make([]byte, extraVanity+common.AddressLength*len(signers)+extraSeal),
+	}
+	for j, signer := range signers {
+		copy(genesis.ExtraData[extraVanity+j*common.AddressLength:], signer[:])
+	}
+	db := rawdb.NewMemoryDatabase()
+	genesis.Commit(db)
+
+	config := *params.TestChainConfig
+	config.Clique = &params.CliqueConfig{Period: 1, Epoch: 30000}
+	engine := New(config.Clique, db)
+	engine.fakeDiff = true
+
+	blocks, _ := core.GenerateChain(&config, genesis.ToBlock(db), engine, db, len(votes), func(j int, gen *core.BlockGen) {
+		gen.SetCoinbase(accounts.address(votes[j].voted))
+		if votes[j].auth {
+			var nonce types.BlockNonce
+			copy(nonce[:], nonceAuthVote)
+			gen.SetNonce(nonce)
+		}
+	})
+	for j, block := range blocks {
+		header := block.Header()
+		if j > 0 {
+			header.ParentHash = blocks[j-1].Hash()
+		}
+		header.Extra = make([]byte, extraVanity+extraSeal)
+		header.Difficulty = diffInTurn
+
+		accounts.sign(header, votes[j].signer)
+		blocks[j] = block.WithSeal(header)
+	}
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create test chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import test chain: %v", err)
+	}
+	// Check the votes and whether they passed
+	history, err := engine.VoteHistory(chain, 0, uint64(len(blocks)))
+	if err != nil {
+		t.Fatalf("failed to retrieve vote history: %v", err)
+	}
+	passed := uint64(2)
+	want := []*VoteRecord{
+		{Block: 1, Signer: accounts.address("A"), Address: accounts.address("C"), Authorize: true, Passed: &passed},
+		{Block: 2, Signer: accounts.address("B"), Address: accounts.address("C"), Authorize: true, Passed: &passed},
+		{Block: 4, Signer: accounts.address("C"), Address: accounts.address("B"), Authorize: false},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("vote count mismatch: have %d, want %d", len(history), len(want))
+	}
+	for i, vote := range history {
+		if vote.Block != want[i].Block || vote.Signer != want[i].Signer || vote.Address != want[i].Address || vote.Authorize != want[i].Authorize {
+			t.Errorf("vote %d mismatch: have %+v, want %+v", i, vote, want[i])
+		}
+		if (vote.Passed == nil) != (want[i].Passed == nil) || (vote.Passed != nil && *vote.Passed != *want[i].Passed) {
+			t.Errorf("vote %d passed mismatch: have %v, want %v", i, vote.Passed, want[i].Passed)
+		}
+	}
+	// Check the sealing statistics against the expected turns
+	stats, err := engine.SignerStats(chain, 1, uint64(len(blocks)))
+	if err != nil {
+		t.Fatalf("failed to retrieve signer stats: %v", err)
+	}
+	expect := make(map[common.Address]*SignerStats)
+	for _, name := range []string{"A", "B", "C"} {
+		expect[accounts.address(name)] = &SignerStats{Signer: accounts.address(name)}
+	}
+	turns := signers
+	for j, vote := range votes {
+		sealer, inturn := accounts.address(vote.signer), turns[uint64(j+1)%uint64(len(turns))]
+		if sealer == inturn {
+			expect[sealer].InTurn++
+		} else {
+			expect[sealer].OutOfTurn++
+			expect[inturn].Missed++
+		}
+		if j == 1 { // C got authorized by the second block
+			turns = append([]common.Address{accounts.address("C")}, turns...)
+			sort.Sort(signersAscending(turns))
+		}
+	}
+	if len(stats) != len(expect) {
+		t.Fatalf("signer count mismatch: have %d, want %d", len(stats), len(expect))
+	}
+	for i, stat := range stats {
+		if i > 0 && bytes.Compare(stats[i-1].Signer[:], stat.Signer[:]) >= 0 {
+			t.Errorf("signer %d out of order", i)
+		}
+		if *stat != *expect[stat.Signer] {
+			t.Errorf("signer %x stats mismatch: have %+v, want %+v", stat.Signer, stat, expect[stat.Signer])
+		}
+	}
+	// Check that the API defaults to the most recent blocks and limits the range
+	api := &API{chain: chain, clique: engine}
+	if history, err := api.GetVoteHistory(nil, nil); err != nil || len(history) != len(want) {
+		t.Errorf("default range vote history mismatch: have %d votes, err %v, want %d votes", len(history), err, len(want))
+	}
+	from, to := rpc.BlockNumber(0), rpc.BlockNumber(maxHistoryRange)
+	if _, err := api.GetVoteHistory(&from, &to); err == nil {
+		t.Errorf("vote history range above the maximum accepted")
+	}
+	if _, err := api.GetSignerStats(&from, &to); err == nil {
+		t.Errorf("signer stats range above the maximum accepted")
+	}
+}
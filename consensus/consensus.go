@@ -23,6 +23,7 @@ import (
 	"github.com/matthieu/go-ethereum/common"
 	"github.com/matthieu/go-ethereum/core/state"
 	"github.com/matthieu/go-ethereum/core/types"
+	"github.com/matthieu/go-ethereum/event"
 	"github.com/matthieu/go-ethereum/params"
 	"github.com/matthieu/go-ethereum/rpc"
 )
@@ -123,3 +124,22 @@ type PoW interface {
 	// Hashrate returns the current mining hashrate of a PoW consensus engine.
 	Hashrate() float64
 }
+
+// SealFailure is a sealing attempt that failed after Seal returned, such as a
+// signer not responding in time.
+type SealFailure struct {
+	Number   uint64      // Number of the block failed to be sealed
+	SealHash common.Hash // Hash of the block prior to it being sealed
+	Err      error       // Reason of the failure
+}
+
+// SealFailureReporter is a consensus engine sealing blocks in the background,
+// reporting the attempts that fail after Seal returned.
+type SealFailureReporter interface {
+	Engine
+
+	// SubscribeSealFailures registers a subscription for the failed sealing
+	// attempts. The channel must be read from, as failures are delivered
+	// synchronously.
+	SubscribeSealFailures(ch chan<- SealFailure) event.Subscription
+}
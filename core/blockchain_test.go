@@ -1120,6 +1120,133 @@ func checkLogEvents(t *testing.T, logsCh <-chan []*types.Log, rmLogsCh <-chan Re
 	}
 }
 
+// Tests that the internal transactions of canonical blocks are announced, and
+// announced again as removed when their block is reorged out.
+func TestInternalTxsEvents(t *testing.T) {
+	var (
+		key1, _       = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr1         = crypto.PubkeyToAddress(key1.PublicKey)
+		recipient     = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+		db            = rawdb.NewMemoryDatabase()
+		gspec         = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{addr1: {Balance: big.NewInt(10000000000000)}}}
+		genesis       = gspec.MustCommit(db)
+		signer        = types.NewEIP155Signer(gspec.Config.ChainID)
+		engine        = ethash.NewFaker()
+		blockchain, _ = NewBlockChain(db, nil, gspec.Config, engine, vm.Config{}, nil, nil)
+	)
+	defer blockchain.Stop()
+
+	itxsCh := make(chan InternalTxsEvent, 10)
+	blockchain.SubscribeInternalTxsEvent(itxsCh)
+
+	// Deploy a contract whose constructor forwards 5 wei to the recipient:
+	// CALL(GAS, recipient, 5, 0, 0, 0, 0)
+	code := append([]byte{0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x60, 0x05, 0x73}, recipient.Bytes()...)
+	code = append(code, 0x5a, 0xf1, 0x00)
+
+	chain, _ := GenerateChain(params.TestChainConfig, genesis, engine, db, 2, func(i int, gen *BlockGen) {
+		if i == 1 {
+			tx, err := types.SignTx(types.NewContractCreation(gen.TxNonce(addr1), big.NewInt(10), 1000000, new(big.Int), code), signer, key1)
+			if err != nil {
+				t.Fatalf("failed to create tx: %v", err)
+			}
+			gen.AddTx(tx)
+		}
+	})
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if len(itxsCh) != 1 {
+		t.Fatalf("wrong number of internal transaction events: got %d, want 1", len(itxsCh))
+	}
+	ev := <-itxsCh
+	if ev.Block.Hash() != chain[1].Hash() || ev.Removed {
+		t.Fatalf("wrong internal transaction event: block %x, removed %v", ev.Block.Hash(), ev.Removed)
+	}
+	if len(ev.Internals) != 1 || len(ev.Internals[0]) != 1 {
+		t.Fatalf("wrong internal transactions announced: %v", ev.Internals)
+	}
+	if itx := ev.Internals[0][0]; *itx.To() != recipient || itx.Value().Int64() != 5 || itx.Rejected {
+		t.Fatalf("wrong internal transaction announced: to %x, value %v, rejected %v", *itx.To(), itx.Value(), itx.Rejected)
+	}
+	// Reorg the block out with a longer, empty chain
+	forkChain, _ := GenerateChain(params.TestChainConfig, genesis, engine, db, 3, func(i int, gen *BlockGen) {})
+	if _, err := blockchain.InsertChain(forkChain); err != nil {
+		t.Fatalf("failed to insert forked chain: %v", err)
+	}
+	if len(itxsCh) != 1 {
+		t.Fatalf("wrong number of internal transaction events after reorg: got %d, want 1", len(itxsCh))
+	}
+	if ev := <-itxsCh; ev.Block.Hash() != chain[1].Hash() || !ev.Removed {
+		t.Fatalf("wrong internal transaction event after reorg: block %x, removed %v", ev.Block.Hash(), ev.Removed)
+	}
+}
+
+// Tests that the internal transaction address index entries of blocks are
+// deleted when the blocks are reorged out or rewound.
+func TestInternalTxAddrEntriesReorg(t *testing.T) {
+	var (
+		key1, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr1     = crypto.PubkeyToAddress(key1.PublicKey)
+		recipient = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+		gspec     = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{addr1: {Balance: big.NewInt(10000000000000)}}}
+		signer    = types.NewEIP155Signer(gspec.Config.ChainID)
+		engine    = ethash.NewFaker()
+	)
+	// Deploy a contract whose constructor forwards 5 wei to the recipient
+	code := append([]byte{0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x60, 0x05, 0x73}, recipient.Bytes()...)
+	code = append(code, 0x5a, 0xf1, 0x00)
+
+	// countEntries returns the number of index entries of the recipient, including
+	// those of non-canonical blocks.
+	countEntries := func(db ethdb.Database) int {
+		it := db.NewIterator(append([]byte("X"), recipient.Bytes()...), nil)
+		defer it.Release()
+
+		var count int
+		for it.Next() {
+			count++
+		}
+		return count
+	}
+	for _, rewind := range []bool{false, true} {
+		db := rawdb.NewMemoryDatabase()
+		genesis := gspec.MustCommit(db)
+		cacheConfig := &CacheConfig{TrieCleanLimit: 256, TrieDirtyLimit: 256, TrieTimeLimit: 5 * time.Minute, InternalTxs: true}
+		blockchain, _ := NewBlockChain(db, cacheConfig, gspec.Config, engine, vm.Config{}, nil, nil)
+
+		chain, _ := GenerateChain(params.TestChainConfig, genesis, engine, db, 2, func(i int, gen *BlockGen) {
+			if i == 1 {
+				tx, _ := types.SignTx(types.NewContractCreation(gen.TxNonce(addr1), big.NewInt(10), 1000000, new(big.Int), code), signer, key1)
+				gen.AddTx(tx)
+			}
+		})
+		if _, err := blockchain.InsertChain(chain); err != nil {
+			t.Fatalf("failed to insert chain: %v", err)
+		}
+		// Index the block the way the internal transaction indexer does
+		internals, _ := rawdb.ReadInternalTxs(db, chain[1].Hash(), 2)
+		rawdb.WriteInternalTxAddrEntries(db, chain[1].Hash(), 2, internals)
+		if n := countEntries(db); n != 1 {
+			t.Fatalf("rewind %v: wrong number of entries: have %d, want 1", rewind, n)
+		}
+		if rewind {
+			if err := blockchain.SetHead(1); err != nil {
+				t.Fatalf("failed to rewind chain: %v", err)
+			}
+		} else {
+			forkChain, _ := GenerateChain(params.TestChainConfig, genesis, engine, db, 3, func(i int, gen *BlockGen) {})
+			if _, err := blockchain.InsertChain(forkChain); err != nil {
+				t.Fatalf("failed to insert forked chain: %v", err)
+			}
+		}
+		if n := countEntries(db); n != 0 {
+			t.Errorf("rewind %v: stale entries left: have %d, want 0", rewind, n)
+		}
+		blockchain.Stop()
+	}
+}
+
 func TestReorgSideEvent(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
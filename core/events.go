@@ -30,6 +30,14 @@ type NewMinedBlockEvent struct{ Block *types.Block }
 // RemovedLogsEvent is posted when a reorg happens
 type RemovedLogsEvent struct{ Logs []*types.Log }
 
+// InternalTxsEvent is posted when the internal transactions of a block enter the
+// canonical chain, or leave it because of a reorg.
+type InternalTxsEvent struct {
+	Block     *types.Block
+	Internals []types.InternalTransactions // Internal transactions of each transaction in the block
+	Removed   bool                         // Whether the block was rolled back by a reorg
+}
+
 type ChainEvent struct {
 	Block *types.Block
 	Hash  common.Hash
@@ -1,8 +1,10 @@
 package core
 
 import (
+	"fmt"
 	"math/big"
 
+	"github.com/matthieu/go-ethereum/accounts/abi"
 	"github.com/matthieu/go-ethereum/common"
 	"github.com/matthieu/go-ethereum/core/types"
 	"github.com/matthieu/go-ethereum/core/vm"
@@ -12,6 +14,7 @@ import (
 
 type InternalTxWatcher struct {
 	internals types.InternalTransactions
+	frames    []int // Indexes of the internal transactions opening the live call frames
 }
 
 func NewInternalTxWatcher() *InternalTxWatcher {
@@ -31,15 +34,21 @@ func (self *InternalTxWatcher) InternalTransactions() types.InternalTransactions
 	return self.internals
 }
 
+// Finalise links the internal transactions to their parent transaction.
+func (self *InternalTxWatcher) Finalise(tx *types.Transaction, receipt *types.Receipt) {
+	self.SetParentHash(tx.Hash())
+}
+
 // Public API: For interfacing with EVM
 func (self *InternalTxWatcher) RegisterCall(nonce uint64, gasPrice *big.Int, gas uint64, srcAddr, dstAddr common.Address, value *big.Int, data []byte, depth uint64) {
+	self.enter()
 	self.internals = append(self.internals,
 		types.NewInternalTransaction(nonce, gasPrice, gas,
 			srcAddr, dstAddr, value, data, depth, self.index(), "call"))
 }
 
 func (self *InternalTxWatcher) RegisterStaticCall(nonce uint64, gasPrice *big.Int, gas uint64, srcAddr, dstAddr common.Address, data []byte, depth uint64) {
-
+	self.enter()
 	self.internals = append(self.internals,
 		types.NewInternalTransaction(nonce, gasPrice, gas,
 			srcAddr, dstAddr, big.NewInt(0), data, depth, self.index(),
@@ -47,6 +56,7 @@ func (self *InternalTxWatcher) RegisterStaticCall(nonce uint64, gasPrice *big.In
 }
 
 func (self *InternalTxWatcher) RegisterCallCode(nonce uint64, gasPrice *big.Int, gas uint64, contractAddr common.Address, value *big.Int, data []byte, depth uint64) {
+	self.enter()
 	self.internals = append(self.internals,
 		types.NewInternalTransaction(nonce, gasPrice, gas,
 			contractAddr, contractAddr, value, data, depth, self.index(),
@@ -54,6 +64,7 @@ func (self *InternalTxWatcher) RegisterCallCode(nonce uint64, gasPrice *big.Int,
 }
 
 func (self *InternalTxWatcher) RegisterCreate(nonce uint64, gasPrice *big.Int, gas uint64, srcAddr, newContractAddr common.Address, value *big.Int, data []byte, depth uint64) {
+	self.enter()
 	self.internals = append(self.internals,
 		types.NewInternalTransaction(nonce, gasPrice, gas,
 			srcAddr, newContractAddr, value, data, depth, self.index(),
@@ -61,6 +72,7 @@ func (self *InternalTxWatcher) RegisterCreate(nonce uint64, gasPrice *big.Int, g
 }
 
 func (self *InternalTxWatcher) RegisterDelegateCall(nonce uint64, gasPrice *big.Int, gas uint64, callerAddr common.Address, value *big.Int, data []byte, depth uint64) {
+	self.enter()
 	self.internals = append(self.internals,
 		types.NewInternalTransaction(nonce, gasPrice, gas,
 			callerAddr, callerAddr, value, data, depth, self.index(), "call"))
@@ -74,7 +86,48 @@ func (self *InternalTxWatcher) RegisterSuicide(nonce uint64, gasPrice *big.Int,
 			depth, self.index(), "suicide"))
 }
 
+// RegisterExit closes the innermost live call frame. If the frame failed, the
+// internal transaction opening it and all the ones nested within are rejected,
+// as their effects were reverted. A failing outermost frame (depth 0) rejects
+// every internal transaction of the transaction.
+func (self *InternalTxWatcher) RegisterExit(depth uint64, gasUsed uint64, ret []byte, err error) {
+	start := 0
+	if depth > 0 {
+		if len(self.frames) == 0 {
+			return
+		}
+		start = self.frames[len(self.frames)-1]
+		self.frames = self.frames[:len(self.frames)-1]
+
+		self.internals[start].GasUsed = gasUsed
+	}
+	if err == nil {
+		return
+	}
+	for _, itx := range self.internals[start:] {
+		itx.Reject()
+	}
+	if depth > 0 {
+		self.internals[start].Error = exitError(ret, err)
+	}
+}
+
 // Utilities
+func (self *InternalTxWatcher) enter() {
+	self.frames = append(self.frames, len(self.internals))
+}
+
+// exitError converts a frame error into its textual form, decoding the revert
+// reason if one was returned.
+func exitError(ret []byte, err error) string {
+	if err == vm.ErrExecutionReverted {
+		if reason, errUnpack := abi.UnpackRevert(ret); errUnpack == nil {
+			return fmt.Sprintf("%v: %v", err, reason)
+		}
+	}
+	return err.Error()
+}
+
 func (self *InternalTxWatcher) index() uint64 {
 	return uint64(len(self.internals))
 }
@@ -0,0 +1,79 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/common/hexutil"
+	"github.com/matthieu/go-ethereum/core/vm"
+)
+
+// Tests that the internal transactions of failed frames, and only those, are
+// rejected when the frames exit.
+func TestInternalTxWatcherRejectsFailedFrames(t *testing.T) {
+	var (
+		a = common.HexToAddress("0xa")
+		b = common.HexToAddress("0xb")
+		c = common.HexToAddress("0xc")
+		d = common.HexToAddress("0xd")
+	)
+	// Revert data of `revert("denied")`
+	reason := hexutil.MustDecode("0x08c379a00000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000000664656e6965640000000000000000000000000000000000000000000000000000")
+
+	w := NewInternalTxWatcher()
+
+	// a -> b succeeds, b -> c reverts after calling c -> d, a -> d runs out of gas
+	w.RegisterCall(0, big.NewInt(1), 1000, a, b, big.NewInt(1), nil, 1)
+	w.RegisterExit(1, 100, nil, nil)
+	w.RegisterCall(0, big.NewInt(1), 1000, b, c, big.NewInt(1), nil, 1)
+	w.RegisterCall(0, big.NewInt(1), 500, c, d, big.NewInt(1), nil, 2)
+	w.RegisterExit(2, 50, nil, nil)
+	w.RegisterExit(1, 200, reason, vm.ErrExecutionReverted)
+	w.RegisterStaticCall(0, big.NewInt(1), 1000, a, d, nil, 1)
+	w.RegisterExit(1, 1000, nil, vm.ErrOutOfGas)
+	w.RegisterExit(0, 2000, nil, nil)
+
+	tests := []struct {
+		rejected bool
+		gasUsed  uint64
+		err      string
+	}{
+		{false, 100, ""},
+		{true, 200, "execution reverted: denied"},
+		{true, 50, ""},
+		{true, 1000, "out of gas"},
+	}
+	internals := w.InternalTransactions()
+	if len(internals) != len(tests) {
+		t.Fatalf("internal transaction count mismatch: have %d, want %d", len(internals), len(tests))
+	}
+	for i, tt := range tests {
+		if itx := internals[i]; itx.Rejected != tt.rejected || itx.GasUsed != tt.gasUsed || itx.Error != tt.err {
+			t.Errorf("internal %d: outcome mismatch: have %v/%d/%q, want %v/%d/%q", i, itx.Rejected, itx.GasUsed, itx.Error, tt.rejected, tt.gasUsed, tt.err)
+		}
+	}
+	// A failing outermost frame rejects everything
+	w.RegisterExit(0, 2000, nil, vm.ErrOutOfGas)
+	for i, itx := range w.InternalTransactions() {
+		if !itx.Rejected {
+			t.Errorf("internal %d: not rejected after top level failure", i)
+		}
+	}
+}
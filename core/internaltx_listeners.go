@@ -0,0 +1,154 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/core/types"
+	"github.com/matthieu/go-ethereum/core/vm"
+	"github.com/matthieu/go-ethereum/metrics"
+)
+
+// DefaultInternalTxListeners are the listeners attached to the EVM when none
+// are configured explicitly.
+var DefaultInternalTxListeners = []string{"watcher"}
+
+var (
+	internalTxListenerLock sync.RWMutex
+
+	// internalTxListenerFactories contains the listeners selectable by name.
+	internalTxListenerFactories = map[string]vm.InternalTxListenerFactory{
+		"watcher": func() vm.InternalTxListener { return NewInternalTxWatcher() },
+		"metrics": func() vm.InternalTxListener { return internalTxMetrics },
+	}
+)
+
+// TxFinaliser is implemented by the internal transaction listeners wanting to be
+// notified once the transaction they observed has been applied.
+type TxFinaliser interface {
+	Finalise(tx *types.Transaction, receipt *types.Receipt)
+}
+
+// RegisterInternalTxListener makes an internal transaction listener selectable
+// by name. Listeners should be registered before the node is configured, as the
+// configured names are resolved once at startup. An error is returned if the
+// name is already taken.
+func RegisterInternalTxListener(name string, factory vm.InternalTxListenerFactory) error {
+	internalTxListenerLock.Lock()
+	defer internalTxListenerLock.Unlock()
+
+	if _, ok := internalTxListenerFactories[name]; ok {
+		return fmt.Errorf("internal transaction listener %q already registered", name)
+	}
+	internalTxListenerFactories[name] = factory
+	return nil
+}
+
+// InternalTxListenerNames returns the sorted names of the selectable listeners.
+func InternalTxListenerNames() []string {
+	internalTxListenerLock.RLock()
+	defer internalTxListenerLock.RUnlock()
+
+	return internalTxListenerNames()
+}
+
+// internalTxListenerNames returns the sorted names of the selectable listeners.
+// It must be called with the lock held.
+func internalTxListenerNames() []string {
+	names := make([]string, 0, len(internalTxListenerFactories))
+	for name := range internalTxListenerFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// InternalTxListenerFactories resolves the given listener names into the
+// factories to configure the EVM with.
+func InternalTxListenerFactories(names []string) ([]vm.InternalTxListenerFactory, error) {
+	internalTxListenerLock.RLock()
+	defer internalTxListenerLock.RUnlock()
+
+	factories := make([]vm.InternalTxListenerFactory, 0, len(names))
+	for _, name := range names {
+		factory, ok := internalTxListenerFactories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown internal transaction listener %q, available: %v", name, internalTxListenerNames())
+		}
+		factories = append(factories, factory)
+	}
+	return factories, nil
+}
+
+// internalTxMetrics is the single, stateless, metrics listener shared by all
+// the EVMs it is attached to.
+var internalTxMetrics = &internalTxMetricsListener{
+	calls:    metrics.NewRegisteredCounter("chain/internaltx/calls", nil),
+	creates:  metrics.NewRegisteredCounter("chain/internaltx/creates", nil),
+	suicides: metrics.NewRegisteredCounter("chain/internaltx/suicides", nil),
+	failures: metrics.NewRegisteredCounter("chain/internaltx/failures", nil),
+	gas:      metrics.NewRegisteredCounter("chain/internaltx/gas", nil),
+}
+
+// internalTxMetricsListener counts the internal transactions executed by the
+// EVMs it is attached to.
+type internalTxMetricsListener struct {
+	calls    metrics.Counter // Message calls of any kind
+	creates  metrics.Counter // Contract creations
+	suicides metrics.Counter // Self-destructs
+	failures metrics.Counter // Internal frames which failed or reverted
+	gas      metrics.Counter // Gas used by the internal frames
+}
+
+func (m *internalTxMetricsListener) RegisterCall(nonce uint64, gasPrice *big.Int, gas uint64, srcAddr, dstAddr common.Address, value *big.Int, data []byte, depth uint64) {
+	m.calls.Inc(1)
+}
+
+func (m *internalTxMetricsListener) RegisterStaticCall(nonce uint64, gasPrice *big.Int, gas uint64, srcAddr, dstAddr common.Address, data []byte, depth uint64) {
+	m.calls.Inc(1)
+}
+
+func (m *internalTxMetricsListener) RegisterCallCode(nonce uint64, gasPrice *big.Int, gas uint64, contractAddr common.Address, value *big.Int, data []byte, depth uint64) {
+	m.calls.Inc(1)
+}
+
+func (m *internalTxMetricsListener) RegisterCreate(nonce uint64, gasPrice *big.Int, gas uint64, srcAddr, newContractAddr common.Address, value *big.Int, data []byte, depth uint64) {
+	m.creates.Inc(1)
+}
+
+func (m *internalTxMetricsListener) RegisterDelegateCall(nonce uint64, gasPrice *big.Int, gas uint64, callerAddr common.Address, value *big.Int, data []byte, depth uint64) {
+	m.calls.Inc(1)
+}
+
+func (m *internalTxMetricsListener) RegisterSuicide(nonce uint64, gasPrice *big.Int, gas uint64, contractAddr, creatorAddr common.Address, remainingValue *big.Int, depth uint64) {
+	m.suicides.Inc(1)
+}
+
+func (m *internalTxMetricsListener) RegisterExit(depth uint64, gasUsed uint64, ret []byte, err error) {
+	if depth == 0 {
+		return
+	}
+	m.gas.Inc(int64(gasUsed))
+	if err != nil {
+		m.failures.Inc(1)
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import "testing"
+
+// Tests that listeners are resolved by name, and unknown ones rejected.
+func TestInternalTxListenerFactories(t *testing.T) {
+	factories, err := InternalTxListenerFactories([]string{"watcher", "metrics"})
+	if err != nil {
+		t.Fatalf("failed to resolve listeners: %v", err)
+	}
+	if _, ok := factories[0]().(*InternalTxWatcher); !ok {
+		t.Errorf("watcher factory type mismatch: have %T", factories[0]())
+	}
+	if factories[1]() != internalTxMetrics {
+		t.Errorf("metrics factory mismatch: have %T", factories[1]())
+	}
+	if _, err := InternalTxListenerFactories([]string{"watcher", "nonexistent"}); err == nil {
+		t.Errorf("unknown listener resolved")
+	}
+	if err := RegisterInternalTxListener("watcher", factories[0]); err == nil {
+		t.Errorf("duplicate listener registered")
+	}
+}
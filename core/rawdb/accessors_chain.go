@@ -604,6 +604,109 @@ func DeleteReceipts(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	}
 }
 
+// HasInternalTxs verifies the existence of the internal transactions captured
+// while importing a block.
+func HasInternalTxs(db ethdb.Reader, hash common.Hash, number uint64) bool {
+	return len(ReadInternalTxsRLP(db, hash, number)) > 0
+}
+
+// ReadInternalTxsRLP retrieves the internal transactions belonging to a block in
+// RLP encoding. Blocks imported without internal transaction recording have an
+// empty entry in the ancient store, which is reported as missing.
+func ReadInternalTxsRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
+	// First try to look up the data in ancient database. Extra hash
+	// comparison is necessary since ancient database only maintains
+	// the canonical data.
+	data, _ := db.Ancient(freezerInternalsTable, number)
+	if len(data) > 0 {
+		h, _ := db.Ancient(freezerHashTable, number)
+		if common.BytesToHash(h) == hash {
+			return data
+		}
+	}
+	// Then try to look up the data in leveldb.
+	data, _ = db.Get(blockInternalsKey(number, hash))
+	if len(data) > 0 {
+		return data
+	}
+	// In the background freezer is moving data from leveldb to flatten files.
+	// So during the first check for ancient db, the data is not yet in there,
+	// but when we reach into leveldb, the data was already moved. That would
+	// result in a not found error.
+	data, _ = db.Ancient(freezerInternalsTable, number)
+	if len(data) > 0 {
+		h, _ := db.Ancient(freezerHashTable, number)
+		if common.BytesToHash(h) == hash {
+			return data
+		}
+	}
+	return nil // Can't find the data anywhere.
+}
+
+// ReadInternalTxs retrieves the internal transactions and the EVM error strings
+// of all the transactions belonging to a block. If the block was imported without
+// recording them, nil is returned for both.
+func ReadInternalTxs(db ethdb.Reader, hash common.Hash, number uint64) ([]types.InternalTransactions, []string) {
+	data := ReadInternalTxsRLP(db, hash, number)
+	if len(data) == 0 {
+		return nil, nil
+	}
+	stored := []*types.TxInternalsForStorage{}
+	if err := rlp.DecodeBytes(data, &stored); err != nil {
+		log.Error("Invalid internal transaction array RLP", "hash", hash, "err", err)
+		return nil, nil
+	}
+	var (
+		internals = make([]types.InternalTransactions, len(stored))
+		vmerrs    = make([]string, len(stored))
+	)
+	for i, entry := range stored {
+		internals[i] = make(types.InternalTransactions, len(entry.Internals))
+		for j, itx := range entry.Internals {
+			internals[i][j] = (*types.InternalTransaction)(itx)
+		}
+		vmerrs[i] = entry.VMErr
+	}
+	return internals, vmerrs
+}
+
+// encodeInternalTxs converts the internal transactions and EVM errors of a block
+// into their storage form and serializes them.
+func encodeInternalTxs(internals []types.InternalTransactions, vmerrs []string) []byte {
+	stored := make([]*types.TxInternalsForStorage, len(internals))
+	for i, txInternals := range internals {
+		stored[i] = &types.TxInternalsForStorage{
+			Internals: make([]*types.InternalTransactionForStorage, len(txInternals)),
+		}
+		if i < len(vmerrs) {
+			stored[i].VMErr = vmerrs[i]
+		}
+		for j, itx := range txInternals {
+			stored[i].Internals[j] = (*types.InternalTransactionForStorage)(itx)
+		}
+	}
+	bytes, err := rlp.EncodeToBytes(stored)
+	if err != nil {
+		log.Crit("Failed to encode block internal transactions", "err", err)
+	}
+	return bytes
+}
+
+// WriteInternalTxs stores the internal transactions and the EVM error strings
+// of all the transactions belonging to a block.
+func WriteInternalTxs(db ethdb.KeyValueWriter, hash common.Hash, number uint64, internals []types.InternalTransactions, vmerrs []string) {
+	if err := db.Put(blockInternalsKey(number, hash), encodeInternalTxs(internals, vmerrs)); err != nil {
+		log.Crit("Failed to store block internal transactions", "err", err)
+	}
+}
+
+// DeleteInternalTxs removes all internal transaction data associated with a block hash.
+func DeleteInternalTxs(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(blockInternalsKey(number, hash)); err != nil {
+		log.Crit("Failed to delete block internal transactions", "err", err)
+	}
+}
+
 // ReadBlock retrieves an entire block corresponding to the hash, assembling it
 // back from the stored header and body. If either the header or body could not
 // be retrieved nil is returned.
@@ -629,7 +732,9 @@ func WriteBlock(db ethdb.KeyValueWriter, block *types.Block) {
 }
 
 // WriteAncientBlock writes entire block data into ancient store and returns the total written size.
-func WriteAncientBlock(db ethdb.AncientWriter, block *types.Block, receipts types.Receipts, td *big.Int) int {
+// The internal transactions are passed in their raw storage encoding and may be
+// empty if they were not recorded for the block.
+func WriteAncientBlock(db ethdb.AncientWriter, block *types.Block, receipts types.Receipts, internals rlp.RawValue, td *big.Int) int {
 	// Encode all block components to RLP format.
 	headerBlob, err := rlp.EncodeToBytes(block.Header())
 	if err != nil {
@@ -652,16 +757,17 @@ func WriteAncientBlock(db ethdb.AncientWriter, block *types.Block, receipts type
 		log.Crit("Failed to RLP encode block total difficulty", "err", err)
 	}
 	// Write all blob to flatten files.
-	err = db.AppendAncient(block.NumberU64(), block.Hash().Bytes(), headerBlob, bodyBlob, receiptBlob, tdBlob)
+	err = db.AppendAncient(block.NumberU64(), block.Hash().Bytes(), headerBlob, bodyBlob, receiptBlob, internals, tdBlob)
 	if err != nil {
 		log.Crit("Failed to write block data to ancient store", "err", err)
 	}
-	return len(headerBlob) + len(bodyBlob) + len(receiptBlob) + len(tdBlob) + common.HashLength
+	return len(headerBlob) + len(bodyBlob) + len(receiptBlob) + len(internals) + len(tdBlob) + common.HashLength
 }
 
 // DeleteBlock removes all block data associated with a hash.
 func DeleteBlock(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	DeleteReceipts(db, hash, number)
+	DeleteInternalTxs(db, hash, number)
 	DeleteHeader(db, hash, number)
 	DeleteBody(db, hash, number)
 	DeleteTd(db, hash, number)
@@ -671,6 +777,7 @@ func DeleteBlock(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 // the hash to number mapping.
 func DeleteBlockWithoutNumber(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	DeleteReceipts(db, hash, number)
+	DeleteInternalTxs(db, hash, number)
 	deleteHeaderWithoutNumber(db, hash, number)
 	DeleteBody(db, hash, number)
 	DeleteTd(db, hash, number)
@@ -362,6 +362,57 @@ func checkReceiptsRLP(have, want types.Receipts) error {
 	return nil
 }
 
+func TestBlockInternalTxsStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	// Create the internal transactions of two transactions, the second failing
+	itx1 := types.NewInternalTransaction(1, big.NewInt(1), 21000, common.HexToAddress("0x1"), common.HexToAddress("0x2"), big.NewInt(10), nil, 1, 0, "call")
+	itx2 := types.NewInternalTransaction(2, big.NewInt(1), 21000, common.HexToAddress("0x2"), common.HexToAddress("0x3"), big.NewInt(5), []byte{0x01}, 2, 1, "create")
+	itx2.Reject()
+	itx2.GasUsed, itx2.Error = 1234, "execution reverted: denied"
+	itx3 := types.NewInternalTransaction(3, big.NewInt(1), 21000, common.HexToAddress("0x3"), common.HexToAddress("0x4"), big.NewInt(0), nil, 1, 0, "staticcall")
+
+	internals := []types.InternalTransactions{{itx1, itx2}, {itx3}}
+	vmerrs := []string{"", "execution reverted"}
+
+	// Check that no internal transaction entries are in a pristine database
+	hash := common.BytesToHash([]byte{0x03, 0x14})
+	if HasInternalTxs(db, hash, 0) {
+		t.Fatalf("non existent internal transactions reported")
+	}
+	if itxs, errs := ReadInternalTxs(db, hash, 0); itxs != nil || errs != nil {
+		t.Fatalf("non existent internal transactions returned: %v %v", itxs, errs)
+	}
+	// Insert the internal transactions into the database and check presence
+	WriteInternalTxs(db, hash, 0, internals, vmerrs)
+
+	itxs, errs := ReadInternalTxs(db, hash, 0)
+	if len(itxs) != len(internals) {
+		t.Fatalf("internal transaction count mismatch: have %d, want %d", len(itxs), len(internals))
+	}
+	for i := range internals {
+		if errs[i] != vmerrs[i] {
+			t.Errorf("tx %d: vm error mismatch: have %q, want %q", i, errs[i], vmerrs[i])
+		}
+		if len(itxs[i]) != len(internals[i]) {
+			t.Fatalf("tx %d: internal transaction count mismatch: have %d, want %d", i, len(itxs[i]), len(internals[i]))
+		}
+		for j := range internals[i] {
+			if have, want := itxs[i][j].Hash(), internals[i][j].Hash(); have != want {
+				t.Errorf("tx %d, internal %d: hash mismatch: have %x, want %x", i, j, have, want)
+			}
+			if have, want := itxs[i][j], internals[i][j]; have.Rejected != want.Rejected || have.GasUsed != want.GasUsed || have.Error != want.Error {
+				t.Errorf("tx %d, internal %d: outcome mismatch: have %v/%d/%q, want %v/%d/%q", i, j, have.Rejected, have.GasUsed, have.Error, want.Rejected, want.GasUsed, want.Error)
+			}
+		}
+	}
+	// Delete the internal transactions and check purge
+	DeleteInternalTxs(db, hash, 0)
+	if HasInternalTxs(db, hash, 0) {
+		t.Fatalf("deleted internal transactions reported")
+	}
+}
+
 func TestAncientStorage(t *testing.T) {
 	// Freezer style fast import the chain.
 	frdir, err := ioutil.TempDir("", "")
@@ -396,8 +447,11 @@ func TestAncientStorage(t *testing.T) {
 	if blob := ReadTdRLP(db, hash, number); len(blob) > 0 {
 		t.Fatalf("non existent td returned")
 	}
+	if blob := ReadInternalTxsRLP(db, hash, number); len(blob) > 0 {
+		t.Fatalf("non existent internal transactions returned")
+	}
 	// Write and verify the header in the database
-	WriteAncientBlock(db, block, nil, big.NewInt(100))
+	WriteAncientBlock(db, block, nil, nil, big.NewInt(100))
 	if blob := ReadHeaderRLP(db, hash, number); len(blob) == 0 {
 		t.Fatalf("no header returned")
 	}
@@ -410,6 +464,9 @@ func TestAncientStorage(t *testing.T) {
 	if blob := ReadTdRLP(db, hash, number); len(blob) == 0 {
 		t.Fatalf("no td returned")
 	}
+	if blob := ReadInternalTxsRLP(db, hash, number); len(blob) > 0 {
+		t.Fatalf("unrecorded internal transactions returned")
+	}
 	// Use a fake hash for data retrieval, nothing should be returned.
 	fakeHash := common.BytesToHash([]byte{0x01, 0x02, 0x03})
 	if blob := ReadHeaderRLP(db, fakeHash, number); len(blob) != 0 {
@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/matthieu/go-ethereum/common"
@@ -173,3 +174,83 @@ func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// InternalTxAddrEntry is a positional metadata to help looking up the internal
+// transactions touching a given address.
+type InternalTxAddrEntry struct {
+	BlockHash   common.Hash
+	BlockNumber uint64
+	TxIndex     uint32
+	Index       uint32
+}
+
+// internalTxAddrs returns the addresses an internal transaction is indexed by,
+// its sender and recipient.
+func internalTxAddrs(itx *types.InternalTransaction) []common.Address {
+	addrs := []common.Address{*itx.Sender}
+	if to := itx.To(); to != nil && *to != *itx.Sender {
+		addrs = append(addrs, *to)
+	}
+	return addrs
+}
+
+// WriteInternalTxAddrEntries stores an address lookup entry for the sender and
+// the recipient of every internal transaction of a block.
+func WriteInternalTxAddrEntries(db ethdb.KeyValueWriter, hash common.Hash, number uint64, internals []types.InternalTransactions) {
+	for i, txInternals := range internals {
+		for _, itx := range txInternals {
+			for _, addr := range internalTxAddrs(itx) {
+				if err := db.Put(internalTxAddrKey(addr, number, uint32(i), uint32(itx.Index)), hash.Bytes()); err != nil {
+					log.Crit("Failed to store internal transaction address entry", "err", err)
+				}
+			}
+		}
+	}
+}
+
+// DeleteInternalTxAddrEntries removes the address lookup entries of the internal
+// transactions of a block.
+func DeleteInternalTxAddrEntries(db ethdb.KeyValueWriter, number uint64, internals []types.InternalTransactions) {
+	for i, txInternals := range internals {
+		for _, itx := range txInternals {
+			for _, addr := range internalTxAddrs(itx) {
+				if err := db.Delete(internalTxAddrKey(addr, number, uint32(i), uint32(itx.Index))); err != nil {
+					log.Crit("Failed to delete internal transaction address entry", "err", err)
+				}
+			}
+		}
+	}
+}
+
+// ReadInternalTxAddrEntries retrieves at most limit address lookup entries of
+// the internal transactions touching the given address, starting at the given
+// block number, transaction index and internal transaction index. Entries are
+// returned in chain order. Entries pointing to blocks that are not canonical
+// anymore are skipped.
+func ReadInternalTxAddrEntries(db ethdb.Database, address common.Address, number uint64, txIndex, index uint32, limit int) []InternalTxAddrEntry {
+	var (
+		prefix = append(append([]byte{}, internalTxAddrPrefix...), address.Bytes()...)
+		start  = internalTxAddrKey(address, number, txIndex, index)[len(prefix):]
+	)
+	it := db.NewIterator(prefix, start)
+	defer it.Release()
+
+	var entries []InternalTxAddrEntry
+	for it.Next() && len(entries) < limit {
+		key := it.Key()
+		if len(key) != len(prefix)+16 || len(it.Value()) != common.HashLength {
+			continue
+		}
+		entry := InternalTxAddrEntry{
+			BlockHash:   common.BytesToHash(it.Value()),
+			BlockNumber: binary.BigEndian.Uint64(key[len(prefix):]),
+			TxIndex:     binary.BigEndian.Uint32(key[len(prefix)+8:]),
+			Index:       binary.BigEndian.Uint32(key[len(prefix)+12:]),
+		}
+		if ReadCanonicalHash(db, entry.BlockNumber) != entry.BlockHash {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -151,3 +151,63 @@ func TestDeleteBloomBits(t *testing.T) {
 	check(1, 1, params.MainnetGenesisHash, true)
 	check(1, 1, params.RinkebyGenesisHash, true)
 }
+
+// Tests that the internal transaction address index can be stored and paged
+// through, skipping entries of non-canonical blocks.
+func TestInternalTxAddrEntries(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var (
+		alice = common.HexToAddress("0x1")
+		bob   = common.HexToAddress("0x2")
+		carol = common.HexToAddress("0x3")
+
+		canon = []common.Hash{{0x01}, {0x02}}
+		side  = common.Hash{0xff}
+	)
+	internals := []types.InternalTransactions{
+		{
+			types.NewInternalTransaction(0, nil, 0, alice, bob, big.NewInt(1), nil, 1, 0, "call"),
+			types.NewInternalTransaction(0, nil, 0, bob, carol, big.NewInt(1), nil, 2, 1, "call"),
+		},
+		{
+			types.NewInternalTransaction(0, nil, 0, carol, alice, big.NewInt(1), nil, 1, 0, "call"),
+		},
+	}
+	// Index a side block first, the canonical one reindexed after a reorg should
+	// replace its entries
+	WriteInternalTxAddrEntries(db, side, 1, internals)
+	for i, hash := range canon {
+		WriteCanonicalHash(db, hash, uint64(i))
+		WriteInternalTxAddrEntries(db, hash, uint64(i), internals)
+	}
+
+	entries := ReadInternalTxAddrEntries(db, alice, 0, 0, 0, 10)
+	if len(entries) != 4 {
+		t.Fatalf("entry count mismatch: have %d, want %d", len(entries), 4)
+	}
+	for _, entry := range entries {
+		if entry.BlockHash != canon[entry.BlockNumber] {
+			t.Errorf("non canonical entry returned: %v", entry)
+		}
+	}
+	// Page from a position within the first block and from an exact position
+	want := InternalTxAddrEntry{BlockHash: canon[1], BlockNumber: 1, TxIndex: 0, Index: 0}
+	if entries := ReadInternalTxAddrEntries(db, alice, 0, 0, 1, 3); len(entries) != 3 || entries[1] != want {
+		t.Fatalf("paged entry mismatch: have %v, want %v", entries, want)
+	}
+	if entries := ReadInternalTxAddrEntries(db, alice, 1, 0, 0, 1); len(entries) != 1 || entries[0] != want {
+		t.Fatalf("paged entry mismatch: have %v, want %v", entries, want)
+	}
+	if entries := ReadInternalTxAddrEntries(db, alice, 2, 0, 0, 10); len(entries) != 0 {
+		t.Fatalf("entries returned past the last block: %v", entries)
+	}
+	if entries := ReadInternalTxAddrEntries(db, common.HexToAddress("0x4"), 0, 0, 0, 10); len(entries) != 0 {
+		t.Fatalf("entries returned for unknown address: %v", entries)
+	}
+	// Reorg the second block out and ensure its stale entries are skipped
+	WriteCanonicalHash(db, side, 1)
+	if entries := ReadInternalTxAddrEntries(db, alice, 0, 0, 0, 10); len(entries) != 2 {
+		t.Fatalf("entry count mismatch after reorg: have %d, want %d", len(entries), 2)
+	}
+}
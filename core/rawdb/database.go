@@ -79,7 +79,7 @@ func (db *nofreezedb) AncientSize(kind string) (uint64, error) {
 }
 
 // AppendAncient returns an error as we don't have a backing chain freezer.
-func (db *nofreezedb) AppendAncient(number uint64, hash, header, body, receipts, td []byte) error {
+func (db *nofreezedb) AppendAncient(number uint64, hash, header, body, receipts, internals, td []byte) error {
 	return errNotSupported
 }
 
@@ -237,6 +237,7 @@ func InspectDatabase(db ethdb.Database) error {
 		headerSize      common.StorageSize
 		bodySize        common.StorageSize
 		receiptSize     common.StorageSize
+		internalsSize   common.StorageSize
 		tdSize          common.StorageSize
 		numHashPairing  common.StorageSize
 		hashNumPairing  common.StorageSize
@@ -246,12 +247,14 @@ func InspectDatabase(db ethdb.Database) error {
 		storageSnapSize common.StorageSize
 		preimageSize    common.StorageSize
 		bloomBitsSize   common.StorageSize
+		itxAddrSize     common.StorageSize
 		cliqueSnapsSize common.StorageSize
 
 		// Ancient store statistics
 		ancientHeaders  common.StorageSize
 		ancientBodies   common.StorageSize
 		ancientReceipts common.StorageSize
+		ancientInternal common.StorageSize
 		ancientHashes   common.StorageSize
 		ancientTds      common.StorageSize
 
@@ -283,6 +286,8 @@ func InspectDatabase(db ethdb.Database) error {
 			bodySize += size
 		case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == (len(blockReceiptsPrefix)+8+common.HashLength):
 			receiptSize += size
+		case bytes.HasPrefix(key, blockInternalsPrefix) && len(key) == (len(blockInternalsPrefix)+8+common.HashLength):
+			internalsSize += size
 		case bytes.HasPrefix(key, txLookupPrefix) && len(key) == (len(txLookupPrefix)+common.HashLength):
 			txlookupSize += size
 		case bytes.HasPrefix(key, SnapshotAccountPrefix) && len(key) == (len(SnapshotAccountPrefix)+common.HashLength):
@@ -293,6 +298,8 @@ func InspectDatabase(db ethdb.Database) error {
 			preimageSize += size
 		case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == (len(bloomBitsPrefix)+10+common.HashLength):
 			bloomBitsSize += size
+		case bytes.HasPrefix(key, internalTxAddrPrefix) && len(key) == (len(internalTxAddrPrefix)+common.AddressLength+16):
+			itxAddrSize += size
 		case bytes.HasPrefix(key, []byte("clique-")) && len(key) == 7+common.HashLength:
 			cliqueSnapsSize += size
 		case bytes.HasPrefix(key, []byte("cht-")) && len(key) == 4+common.HashLength:
@@ -321,8 +328,8 @@ func InspectDatabase(db ethdb.Database) error {
 		}
 	}
 	// Inspect append-only file store then.
-	ancients := []*common.StorageSize{&ancientHeaders, &ancientBodies, &ancientReceipts, &ancientHashes, &ancientTds}
-	for i, category := range []string{freezerHeaderTable, freezerBodiesTable, freezerReceiptTable, freezerHashTable, freezerDifficultyTable} {
+	ancients := []*common.StorageSize{&ancientHeaders, &ancientBodies, &ancientReceipts, &ancientInternal, &ancientHashes, &ancientTds}
+	for i, category := range []string{freezerHeaderTable, freezerBodiesTable, freezerReceiptTable, freezerInternalsTable, freezerHashTable, freezerDifficultyTable} {
 		if size, err := db.AncientSize(category); err == nil {
 			*ancients[i] += common.StorageSize(size)
 			total += common.StorageSize(size)
@@ -333,11 +340,13 @@ func InspectDatabase(db ethdb.Database) error {
 		{"Key-Value store", "Headers", headerSize.String()},
 		{"Key-Value store", "Bodies", bodySize.String()},
 		{"Key-Value store", "Receipts", receiptSize.String()},
+		{"Key-Value store", "Internal transactions", internalsSize.String()},
 		{"Key-Value store", "Difficulties", tdSize.String()},
 		{"Key-Value store", "Block number->hash", numHashPairing.String()},
 		{"Key-Value store", "Block hash->number", hashNumPairing.String()},
 		{"Key-Value store", "Transaction index", txlookupSize.String()},
 		{"Key-Value store", "Bloombit index", bloomBitsSize.String()},
+		{"Key-Value store", "Internal transaction index", itxAddrSize.String()},
 		{"Key-Value store", "Trie nodes", trieSize.String()},
 		{"Key-Value store", "Trie preimages", preimageSize.String()},
 		{"Key-Value store", "Account snapshot", accountSnapSize.String()},
@@ -347,6 +356,7 @@ func InspectDatabase(db ethdb.Database) error {
 		{"Ancient store", "Headers", ancientHeaders.String()},
 		{"Ancient store", "Bodies", ancientBodies.String()},
 		{"Ancient store", "Receipts", ancientReceipts.String()},
+		{"Ancient store", "Internal transactions", ancientInternal.String()},
 		{"Ancient store", "Difficulties", ancientTds.String()},
 		{"Ancient store", "Block number->hash", ancientHashes.String()},
 		{"Light client", "CHT trie nodes", chtTrieNodes.String()},
@@ -115,6 +115,13 @@ func newFreezer(datadir string, namespace string) (*freezer, error) {
 		}
 		freezer.tables[name] = table
 	}
+	if err := freezer.backfill(); err != nil {
+		for _, table := range freezer.tables {
+			table.Close()
+		}
+		lock.Release()
+		return nil, err
+	}
 	if err := freezer.repair(); err != nil {
 		for _, table := range freezer.tables {
 			table.Close()
@@ -180,7 +187,7 @@ func (f *freezer) AncientSize(kind string) (uint64, error) {
 // Notably, this function is lock free but kind of thread-safe. All out-of-order
 // injection will be rejected. But if two injections with same number happen at
 // the same time, we can get into the trouble.
-func (f *freezer) AppendAncient(number uint64, hash, header, body, receipts, td []byte) (err error) {
+func (f *freezer) AppendAncient(number uint64, hash, header, body, receipts, internals, td []byte) (err error) {
 	// Ensure the binary blobs we are appending is continuous with freezer.
 	if atomic.LoadUint64(&f.frozen) != number {
 		return errOutOrderInsertion
@@ -213,6 +220,10 @@ func (f *freezer) AppendAncient(number uint64, hash, header, body, receipts, td
 		log.Error("Failed to append ancient receipts", "number", f.frozen, "hash", hash, "err", err)
 		return err
 	}
+	if err := f.tables[freezerInternalsTable].Append(f.frozen, internals); err != nil {
+		log.Error("Failed to append ancient internal transactions", "number", f.frozen, "hash", hash, "err", err)
+		return err
+	}
 	if err := f.tables[freezerDifficultyTable].Append(f.frozen, td); err != nil {
 		log.Error("Failed to append ancient difficulty", "number", f.frozen, "hash", hash, "err", err)
 		return err
@@ -340,9 +351,13 @@ func (f *freezer) freeze(db ethdb.KeyValueStore) {
 				log.Error("Total difficulty missing, can't freeze", "number", f.frozen, "hash", hash)
 				break
 			}
+			// Internal transactions are only recorded if enabled, freeze an empty
+			// entry otherwise to keep the tables aligned.
+			internals := ReadInternalTxsRLP(nfdb, hash, f.frozen)
+
 			log.Trace("Deep froze ancient block", "number", f.frozen, "hash", hash)
 			// Inject all the components into the relevant data tables
-			if err := f.AppendAncient(f.frozen, hash[:], header, body, receipts, td); err != nil {
+			if err := f.AppendAncient(f.frozen, hash[:], header, body, receipts, internals, td); err != nil {
 				break
 			}
 			ancients = append(ancients, hash)
@@ -392,6 +407,33 @@ func (f *freezer) freeze(db ethdb.KeyValueStore) {
 	}
 }
 
+// backfill fills the internal transactions table with empty entries if it was
+// created on top of an already populated freezer. Without this, repair would
+// truncate every other table down to the new, empty one.
+func (f *freezer) backfill() error {
+	internals := f.tables[freezerInternalsTable]
+	if atomic.LoadUint64(&internals.items) != 0 {
+		return nil
+	}
+	max := uint64(0)
+	for name, table := range f.tables {
+		if name == freezerInternalsTable {
+			continue
+		}
+		if items := atomic.LoadUint64(&table.items); items > max {
+			max = items
+		}
+	}
+	if max == 0 {
+		return nil
+	}
+	log.Info("Backfilling ancient internal transactions table", "items", max)
+	if err := internals.appendRepeated(0, nil, max); err != nil {
+		return err
+	}
+	return internals.Sync()
+}
+
 // repair truncates all data tables to the same length.
 func (f *freezer) repair() error {
 	min := uint64(math.MaxUint64)
@@ -17,6 +17,7 @@
 package rawdb
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -55,6 +56,9 @@ type indexEntry struct {
 
 const indexEntrySize = 6
 
+// freezerAppendBatch is the maximum number of items appendRepeated writes at once.
+const freezerAppendBatch = 1 << 20
+
 // unmarshallBinary deserializes binary b into the rawIndex entry.
 func (i *indexEntry) unmarshalBinary(b []byte) error {
 	i.filenum = uint32(binary.BigEndian.Uint16(b[:2]))
@@ -516,6 +520,80 @@ func (t *freezerTable) Append(item uint64, blob []byte) error {
 	return nil
 }
 
+// appendRepeated appends the same blob as the next count items. As opposed to
+// calling Append repeatedly, the data and index entries are written in large
+// chunks, which makes it suitable for filling a table with placeholder items.
+func (t *freezerTable) appendRepeated(item uint64, blob []byte, count uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// Ensure the table is still accessible
+	if t.index == nil || t.head == nil {
+		return errClosed
+	}
+	// Ensure only the next items can be written, nothing else
+	if atomic.LoadUint64(&t.items) != item {
+		return fmt.Errorf("appending unexpected item: want %d, have %d", t.items, item)
+	}
+	if !t.noCompression {
+		blob = snappy.Encode(nil, blob)
+	}
+	bLen := uint64(len(blob))
+	for count > 0 {
+		// Determine how many items still fit into the head file, and open a
+		// new one if none does
+		batch := count
+		if bLen > 0 {
+			if fit := (uint64(t.maxFileSize) - uint64(atomic.LoadUint32(&t.headBytes))) / bLen; fit < batch {
+				batch = fit
+			}
+		}
+		if batch > freezerAppendBatch {
+			batch = freezerAppendBatch
+		}
+		if batch == 0 && atomic.LoadUint32(&t.headBytes) == 0 {
+			batch = 1 // Oversized blob, store it alone like Append does
+		}
+		if batch == 0 {
+			nextID := atomic.LoadUint32(&t.headId) + 1
+			newHead, err := t.openFile(nextID, openFreezerFileTruncated)
+			if err != nil {
+				return err
+			}
+			t.releaseFile(t.headId)
+			t.openFile(t.headId, openFreezerFileForReadOnly)
+
+			t.head = newHead
+			atomic.StoreUint32(&t.headBytes, 0)
+			atomic.StoreUint32(&t.headId, nextID)
+			continue
+		}
+		// Write the data and index entries of the whole batch at once
+		var (
+			data  = bytes.Repeat(blob, int(batch))
+			index = make([]byte, 0, batch*indexEntrySize)
+			entry = indexEntry{filenum: atomic.LoadUint32(&t.headId), offset: atomic.LoadUint32(&t.headBytes)}
+		)
+		for i := uint64(0); i < batch; i++ {
+			entry.offset += uint32(bLen)
+			index = append(index, entry.marshallBinary()...)
+		}
+		if _, err := t.head.Write(data); err != nil {
+			return err
+		}
+		if _, err := t.index.Write(index); err != nil {
+			return err
+		}
+		atomic.StoreUint32(&t.headBytes, entry.offset)
+		t.writeMeter.Mark(int64(len(data) + len(index)))
+		t.sizeGauge.Inc(int64(len(data) + len(index)))
+
+		atomic.AddUint64(&t.items, batch)
+		count -= batch
+	}
+	return nil
+}
+
 // getBounds returns the indexes for the item
 // returns start, end, filenumber and error
 func (t *freezerTable) getBounds(item uint64) (uint32, uint32, uint32, error) {
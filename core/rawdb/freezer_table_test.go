@@ -84,6 +84,57 @@ func TestFreezerBasics(t *testing.T) {
 	}
 }
 
+// TestFreezerAppendRepeated tests that appending a repeated item in batches
+// produces the same table as appending the items one by one, across multiple
+// data files.
+func TestFreezerAppendRepeated(t *testing.T) {
+	t.Parallel()
+	for _, noCompression := range []bool{true, false} {
+		rm, wm, sg := metrics.NewMeter(), metrics.NewMeter(), metrics.NewGauge()
+		fname := fmt.Sprintf("repeated-%d", rand.Uint64())
+		f, err := newCustomTable(os.TempDir(), fname, rm, wm, sg, 50, noCompression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Write 15 bytes once, then 255 times in a batch, results in 86 files
+		if err := f.Append(0, getChunk(15, 0xFF)); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.appendRepeated(1, getChunk(15, 0xEE), 255); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Append(256, getChunk(15, 0xDD)); err != nil {
+			t.Fatal(err)
+		}
+		if f.items != 257 {
+			t.Fatalf("items mismatch: have %d, want %d", f.items, 257)
+		}
+		// Reopen the table to check the index is consistent with the data
+		f.Close()
+		f, err = newCustomTable(os.TempDir(), fname, rm, wm, sg, 50, noCompression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for y := 0; y < 257; y++ {
+			exp := getChunk(15, 0xEE)
+			switch y {
+			case 0:
+				exp = getChunk(15, 0xFF)
+			case 256:
+				exp = getChunk(15, 0xDD)
+			}
+			got, err := f.Retrieve(uint64(y))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, exp) {
+				t.Fatalf("test %d, got \n%x != \n%x", y, got, exp)
+			}
+		}
+		f.Close()
+	}
+}
+
 // TestFreezerBasicsClosing tests same as TestFreezerBasics, but also closes and reopens the freezer between
 // every operation
 func TestFreezerBasicsClosing(t *testing.T) {
@@ -59,11 +59,13 @@ var (
 	headerHashSuffix   = []byte("n") // headerPrefix + num (uint64 big endian) + headerHashSuffix -> hash
 	headerNumberPrefix = []byte("H") // headerNumberPrefix + hash -> num (uint64 big endian)
 
-	blockBodyPrefix     = []byte("b") // blockBodyPrefix + num (uint64 big endian) + hash -> block body
-	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
+	blockBodyPrefix      = []byte("b") // blockBodyPrefix + num (uint64 big endian) + hash -> block body
+	blockReceiptsPrefix  = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
+	blockInternalsPrefix = []byte("x") // blockInternalsPrefix + num (uint64 big endian) + hash -> block internal transactions
 
 	txLookupPrefix        = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix       = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	internalTxAddrPrefix  = []byte("X") // internalTxAddrPrefix + address + num (uint64 big endian) + tx index (uint32 big endian) + internal index (uint32 big endian) -> hash
 	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
 
@@ -71,7 +73,8 @@ var (
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
-	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	BloomBitsIndexPrefix  = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	InternalTxIndexPrefix = []byte("iX") // InternalTxIndexPrefix is the data table of the internal transaction indexer to track its progress
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -92,6 +95,9 @@ const (
 
 	// freezerDifficultyTable indicates the name of the freezer total difficulty table.
 	freezerDifficultyTable = "diffs"
+
+	// freezerInternalsTable indicates the name of the freezer internal transactions table.
+	freezerInternalsTable = "internals"
 )
 
 // freezerNoSnappy configures whether compression is disabled for the ancient-tables.
@@ -102,6 +108,7 @@ var freezerNoSnappy = map[string]bool{
 	freezerBodiesTable:     false,
 	freezerReceiptTable:    false,
 	freezerDifficultyTable: true,
+	freezerInternalsTable:  false,
 }
 
 // LegacyTxLookupEntry is the legacy TxLookupEntry definition with some unnecessary
@@ -154,6 +161,11 @@ func blockReceiptsKey(number uint64, hash common.Hash) []byte {
 	return append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
 
+// blockInternalsKey = blockInternalsPrefix + num (uint64 big endian) + hash
+func blockInternalsKey(number uint64, hash common.Hash) []byte {
+	return append(append(blockInternalsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
 // txLookupKey = txLookupPrefix + hash
 func txLookupKey(hash common.Hash) []byte {
 	return append(txLookupPrefix, hash.Bytes()...)
@@ -184,6 +196,17 @@ func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	return key
 }
 
+// internalTxAddrKey = internalTxAddrPrefix + address + num (uint64 big endian) + tx index (uint32 big endian) + internal index (uint32 big endian)
+func internalTxAddrKey(address common.Address, number uint64, txIndex uint32, index uint32) []byte {
+	key := append(append(internalTxAddrPrefix, address.Bytes()...), make([]byte, 16)...)
+
+	binary.BigEndian.PutUint64(key[len(internalTxAddrPrefix)+common.AddressLength:], number)
+	binary.BigEndian.PutUint32(key[len(internalTxAddrPrefix)+common.AddressLength+8:], txIndex)
+	binary.BigEndian.PutUint32(key[len(internalTxAddrPrefix)+common.AddressLength+12:], index)
+
+	return key
+}
+
 // preimageKey = preimagePrefix + hash
 func preimageKey(hash common.Hash) []byte {
 	return append(preimagePrefix, hash.Bytes()...)
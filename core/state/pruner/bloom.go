@@ -0,0 +1,113 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/log"
+	"github.com/steakknife/bloomfilter"
+)
+
+// stateBloomHasher is a wrapper around a byte blob to satisfy the interface API
+// requirements of the bloom library used. It's used to convert a trie hash or
+// contract code hash into a 64 bit mini hash.
+type stateBloomHasher []byte
+
+func (f stateBloomHasher) Write(p []byte) (n int, err error) { panic("not implemented") }
+func (f stateBloomHasher) Sum(b []byte) []byte               { panic("not implemented") }
+func (f stateBloomHasher) Reset()                            { panic("not implemented") }
+func (f stateBloomHasher) BlockSize() int                    { panic("not implemented") }
+func (f stateBloomHasher) Size() int                         { return 8 }
+func (f stateBloomHasher) Sum64() uint64                     { return binary.BigEndian.Uint64(f) }
+
+// stateBloom is a bloom filter used during the state pruning to separate the
+// live state entries (trie nodes and contract codes) from the stale ones. It is
+// persisted to disk once fully built, so that an interrupted pruning can be
+// resumed with the exact same filter.
+//
+// False positives only mean that a few stale entries survive the pruning, which
+// is harmless. There are no false negatives, so live entries are never deleted.
+type stateBloom struct {
+	bloom *bloomfilter.Filter
+}
+
+// newStateBloomWithSize creates a brand new state bloom of the given size (in
+// megabytes). The bloom is hard coded to use 4 filters, which gives a false
+// positive rate of around 0.05% for 600M entries in a 2GB filter.
+func newStateBloomWithSize(size uint64) (*stateBloom, error) {
+	bloom, err := bloomfilter.New(size*1024*1024*8, 4)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Initialized state bloom", "size", common.StorageSize(float64(bloom.M()/8)))
+	return &stateBloom{bloom: bloom}, nil
+}
+
+// newStateBloomFromDisk loads the state bloom persisted by a previous pruning.
+func newStateBloomFromDisk(filename string) (*stateBloom, error) {
+	bloom, _, err := bloomfilter.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &stateBloom{bloom: bloom}, nil
+}
+
+// Commit flushes the bloom filter to disk, first writing it to a temporary file
+// and atomically moving it into place once synced, so that only a complete
+// filter is ever found under the final name.
+func (bloom *stateBloom) Commit(filename, tempname string) error {
+	if _, err := bloom.bloom.WriteFile(tempname); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(tempname, os.O_RDWR, 0666)
+	if err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	f.Close()
+
+	return os.Rename(tempname, filename)
+}
+
+// Put implements the KeyValueWriter interface, marking the given trie node or
+// contract code hash as live. The value is ignored.
+func (bloom *stateBloom) Put(key []byte, value []byte) error {
+	if len(key) != common.HashLength {
+		return errors.New("invalid state entry key")
+	}
+	bloom.bloom.Add(stateBloomHasher(key))
+	return nil
+}
+
+// Delete implements the KeyValueWriter interface. Entries cannot be removed from
+// a bloom filter, so it always fails.
+func (bloom *stateBloom) Delete(key []byte) error {
+	return errors.New("not supported")
+}
+
+// Contain returns whether the given trie node or contract code hash may be
+// live. False positives are possible, false negatives are not.
+func (bloom *stateBloom) Contain(key []byte) bool {
+	return bloom.bloom.Contains(stateBloomHasher(key))
+}
@@ -0,0 +1,481 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package pruner implements offline pruning of the stale state data.
+package pruner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/core/rawdb"
+	"github.com/matthieu/go-ethereum/core/state"
+	"github.com/matthieu/go-ethereum/core/state/snapshot"
+	"github.com/matthieu/go-ethereum/core/types"
+	"github.com/matthieu/go-ethereum/crypto"
+	"github.com/matthieu/go-ethereum/ethdb"
+	"github.com/matthieu/go-ethereum/log"
+	"github.com/matthieu/go-ethereum/rlp"
+	"github.com/matthieu/go-ethereum/trie"
+)
+
+const (
+	// stateBloomFilePrefix is the filename prefix of the state bloom filter.
+	stateBloomFilePrefix = "statebloom"
+
+	// stateBloomFileSuffix is the filename suffix of the state bloom filter.
+	stateBloomFileSuffix = "bf.gz"
+
+	// stateBloomFileTempSuffix is the filename suffix of the state bloom filter
+	// while it's being written out, to detect write aborts.
+	stateBloomFileTempSuffix = ".tmp"
+
+	// rangeCompactionThreshold is the minimal number of deleted entries to
+	// trigger a range compaction of the database. It's a quite arbitrary
+	// number, meant to skip the lengthy compaction if barely anything changed.
+	rangeCompactionThreshold = 100000
+
+	// pruneLayers is the number of diff layers kept above the pruning target
+	// by default, matching the number of recent states retained in memory.
+	pruneLayers = 128
+
+	// minBloomSize is the minimal size of the state bloom filter in megabytes.
+	minBloomSize = 256
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	// emptyCode is the known hash of the empty EVM bytecode.
+	emptyCode = crypto.Keccak256(nil)
+)
+
+// Pruner is an offline tool to prune the stale state with the help of the
+// snapshot. It marks every trie node and contract code reachable from the target
+// state into a bloom filter, using the snapshot to find the storage tries and
+// codes, then iterates the database and deletes all the other state entries
+// which belong to neither the target state, nor the genesis state.
+//
+// It can take several hours(around 2 hours for mainnet) to finish the whole
+// pruning work. It's recommended to run this offline tool periodically in
+// order to release the disk usage and improve the disk read performance to
+// some extent.
+type Pruner struct {
+	db         ethdb.Database
+	stateBloom *stateBloom
+	datadir    string
+	headHeader *types.Header
+	snaptree   *snapshot.Tree
+}
+
+// NewPruner creates the pruner instance. It refuses to operate unless the state
+// snapshot is fully generated and matches the state of the given head.
+func NewPruner(db ethdb.Database, headHeader *types.Header, datadir string, bloomSize uint64) (*Pruner, error) {
+	snaptree, err := snapshot.Load(db, trie.NewDatabase(db), 256, headHeader.Root)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot not usable for pruning: %v", err)
+	}
+	// Sanitize the bloom filter size if it's too small.
+	if bloomSize < minBloomSize {
+		log.Warn("Sanitizing bloomfilter size", "provided(MB)", bloomSize, "updated(MB)", minBloomSize)
+		bloomSize = minBloomSize
+	}
+	stateBloom, err := newStateBloomWithSize(bloomSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Pruner{
+		db:         db,
+		stateBloom: stateBloom,
+		datadir:    datadir,
+		headHeader: headHeader,
+		snaptree:   snaptree,
+	}, nil
+}
+
+// Prune deletes all the historical state entries which are not reachable from
+// the specified target state root, nor from the genesis state. If the root is
+// not specified, the state of the bottom-most diff layer is picked, i.e. the
+// 128th most recent one. Any pruning interrupted before is resumed instead.
+func (p *Pruner) Prune(root common.Hash) error {
+	// If the state bloom filter is already committed previously, reuse it for
+	// pruning instead of generating a new one. It's mandatory because a part of
+	// the state may already have been deleted and can't be regenerated.
+	bloomPath, err := findBloomFilter(p.datadir)
+	if err != nil {
+		return err
+	}
+	if bloomPath != "" {
+		return RecoverPruning(p.datadir, p.db)
+	}
+	// Pick the target state root to prune against. It must be covered by the
+	// snapshot, and its trie must be complete on disk for the regeneration.
+	if root == (common.Hash{}) {
+		layers := p.snaptree.Snapshots(p.headHeader.Root, pruneLayers, true)
+		if len(layers) != pruneLayers {
+			return fmt.Errorf("snapshot not old enough yet: need %d more blocks", pruneLayers-len(layers))
+		}
+		root = layers[len(layers)-1].Root()
+		log.Info("Selecting bottom-most difflayer as the pruning target", "root", root, "height", p.headHeader.Number.Uint64()-pruneLayers+1)
+	} else {
+		log.Info("Selecting user-specified state as the pruning target", "root", root)
+	}
+	if p.snaptree.Snapshot(root) == nil {
+		return fmt.Errorf("snapshot missing for target state %x", root)
+	}
+	if ok, _ := p.db.Has(root.Bytes()); !ok {
+		return fmt.Errorf("associated state[%x] is not present", root)
+	}
+	// All the state roots of the layers above the target are deleted, so that
+	// nothing mistakes their dangling tries for complete states afterwards.
+	middleRoots := findMiddleRoots(p.snaptree, p.headHeader.Root, root)
+
+	// Traverse the target state, mark all the trie nodes and contract codes into
+	// the bloom filter. The genesis state is always retained too.
+	start := time.Now()
+	if err := markSnapshotState(p.db, p.snaptree, root, p.stateBloom); err != nil {
+		return err
+	}
+	if err := markGenesisState(p.db, p.stateBloom); err != nil {
+		return err
+	}
+	// Persist the bloom filter before touching the database, so that the pruning
+	// can be resumed with the very same filter after a crash.
+	filterName := bloomFilterName(p.datadir, root)
+
+	log.Info("Writing state bloom to disk", "name", filterName)
+	if err := p.stateBloom.Commit(filterName, filterName+stateBloomFileTempSuffix); err != nil {
+		return err
+	}
+	log.Info("State bloom filter committed", "name", filterName, "elapsed", common.PrettyDuration(time.Since(start)))
+	return prune(p.snaptree, root, p.db, p.stateBloom, filterName, middleRoots, start)
+}
+
+// RecoverPruning resumes the pruning procedure interrupted by a crash or an exit,
+// if any. It's called on every startup to ensure no half-pruned database is ever
+// used, as it would miss state entries without knowing about it.
+func RecoverPruning(datadir string, db ethdb.Database) error {
+	bloomPath, err := findBloomFilter(datadir)
+	if err != nil {
+		return err
+	}
+	if bloomPath == "" {
+		return nil // nothing to recover
+	}
+	headBlock := readHeadBlock(db)
+	if headBlock == nil {
+		return errors.New("failed to load head block")
+	}
+	stateBloomRoot, err := bloomFilterRoot(bloomPath)
+	if err != nil {
+		return err
+	}
+	// The snapshot is only flattened to the target state at the very end of the
+	// pruning, so it's either still at the head or already at the target.
+	triedb := trie.NewDatabase(db)
+	snaptree, err := snapshot.Load(db, triedb, 256, headBlock.Root())
+	if err != nil {
+		if snaptree, err = snapshot.Load(db, triedb, 256, stateBloomRoot); err != nil {
+			return fmt.Errorf("snapshot not usable for pruning recovery: %v", err)
+		}
+	}
+	stateBloom, err := newStateBloomFromDisk(bloomPath)
+	if err != nil {
+		return err
+	}
+	log.Info("Loaded state bloom filter", "path", bloomPath)
+
+	middleRoots := findMiddleRoots(snaptree, headBlock.Root(), stateBloomRoot)
+	return prune(snaptree, stateBloomRoot, db, stateBloom, bloomPath, middleRoots, time.Now())
+}
+
+// prune deletes all the state entries of the database which are not marked in
+// the state bloom, flattens the snapshot onto the target state and finally
+// removes the bloom filter, marking the pruning as complete.
+func prune(snaptree *snapshot.Tree, root common.Hash, maindb ethdb.Database, stateBloom *stateBloom, bloomPath string, middleRoots map[common.Hash]struct{}, start time.Time) error {
+	// Delete all stale trie nodes and contract codes, both of which are stored
+	// under their plain 32 byte hash. Resuming the deletion of a crashed run is
+	// fine, as the bloom filter is the same.
+	var (
+		count  int
+		size   common.StorageSize
+		pstart = time.Now()
+		logged = time.Now()
+		batch  = maindb.NewBatch()
+		iter   = maindb.NewIterator(nil, nil)
+	)
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != common.HashLength {
+			continue
+		}
+		if _, ok := middleRoots[common.BytesToHash(key)]; !ok && stateBloom.Contain(key) {
+			continue
+		}
+		count++
+		size += common.StorageSize(len(key) + len(iter.Value()))
+		batch.Delete(key)
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				iter.Release()
+				return err
+			}
+			batch.Reset()
+
+			// Recreate the iterator after every batch write to release the
+			// database snapshot it holds on to, copying the key as it's reused.
+			iter.Release()
+			iter = maindb.NewIterator(nil, common.CopyBytes(key))
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Pruning state data", "nodes", count, "size", size, "elapsed", common.PrettyDuration(time.Since(pstart)))
+			logged = time.Now()
+		}
+	}
+	err := iter.Error()
+	iter.Release()
+	if err != nil {
+		return err
+	}
+	if batch.ValueSize() > 0 {
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Reset()
+	}
+	log.Info("Pruned state data", "nodes", count, "size", size, "elapsed", common.PrettyDuration(time.Since(pstart)))
+
+	// The state of the layers above the target is gone, flatten the snapshot
+	// onto the target so that it matches the state still present.
+	if len(snaptree.Snapshots(root, 1, true)) > 0 {
+		if err := snaptree.Cap(root, 0); err != nil {
+			return err
+		}
+	}
+	if _, err := snaptree.Journal(root); err != nil {
+		return err
+	}
+	// Delete the state bloom, marking the entire pruning as complete. Any crash
+	// from here on doesn't require any recovery.
+	if err := os.RemoveAll(bloomPath); err != nil {
+		return err
+	}
+	// Compact the whole database if enough entries were deleted, to reclaim the
+	// disk space right away. The database is split into chunks to report some
+	// progress, as the compaction can take a long time.
+	if count >= rangeCompactionThreshold {
+		cstart := time.Now()
+		for b := 0x00; b <= 0xf0; b += 0x10 {
+			var (
+				start = []byte{byte(b)}
+				end   = []byte{byte(b + 0x10)}
+			)
+			if b == 0xf0 {
+				end = nil
+			}
+			log.Info("Compacting database", "range", fmt.Sprintf("%#x-%#x", start, end), "elapsed", common.PrettyDuration(time.Since(cstart)))
+			if err := maindb.Compact(start, end); err != nil {
+				log.Error("Database compaction failed", "error", err)
+				return err
+			}
+		}
+		log.Info("Database compaction finished", "elapsed", common.PrettyDuration(time.Since(cstart)))
+	}
+	log.Info("State pruning successful", "pruned", size, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// findMiddleRoots collects the state roots of all the snapshot layers between
+// the head and the pruning target, excluding the target itself.
+func findMiddleRoots(snaptree *snapshot.Tree, head common.Hash, root common.Hash) map[common.Hash]struct{} {
+	middleRoots := make(map[common.Hash]struct{})
+	for _, layer := range snaptree.Snapshots(head, pruneLayers+1, true) {
+		if layer.Root() == root {
+			break
+		}
+		middleRoots[layer.Root()] = struct{}{}
+	}
+	return middleRoots
+}
+
+// markSnapshotState marks all the trie nodes and contract codes of the given
+// state into the bloom filter. The account trie is traversed directly, whereas
+// the storage tries and contract codes are found via the snapshot accounts,
+// visiting each distinct storage trie only once.
+func markSnapshotState(db ethdb.Database, snaptree *snapshot.Tree, root common.Hash, bloom *stateBloom) error {
+	var (
+		start  = time.Now()
+		logged = time.Now()
+		triedb = trie.NewDatabase(db)
+	)
+	if err := markTrie(triedb, root, bloom); err != nil {
+		return err
+	}
+	it, err := snaptree.AccountIterator(root, common.Hash{})
+	if err != nil {
+		return err
+	}
+	defer it.Release()
+
+	var (
+		accounts uint64
+		visited  = make(map[common.Hash]struct{})
+	)
+	for it.Next() {
+		account, err := snapshot.FullAccount(it.Account())
+		if err != nil {
+			return err
+		}
+		if storageRoot := common.BytesToHash(account.Root); storageRoot != emptyRoot {
+			if _, ok := visited[storageRoot]; !ok {
+				if err := markTrie(triedb, storageRoot, bloom); err != nil {
+					return err
+				}
+				visited[storageRoot] = struct{}{}
+			}
+		}
+		if !bytes.Equal(account.CodeHash, emptyCode) {
+			bloom.Put(account.CodeHash, nil)
+		}
+		accounts++
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Marking state entries", "accounts", accounts, "at", it.Hash(), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	log.Info("Marked state entries", "accounts", accounts, "storages", len(visited), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// markGenesisState marks all the trie nodes and contract codes of the genesis
+// state into the bloom filter. The genesis state has no snapshot, so it's found
+// by decoding the accounts of its trie.
+func markGenesisState(db ethdb.Database, bloom *stateBloom) error {
+	genesisHash := rawdb.ReadCanonicalHash(db, 0)
+	if genesisHash == (common.Hash{}) {
+		return errors.New("missing genesis hash")
+	}
+	genesis := rawdb.ReadBlock(db, genesisHash, 0)
+	if genesis == nil {
+		return errors.New("missing genesis block")
+	}
+	triedb := trie.NewDatabase(db)
+	t, err := trie.NewSecure(genesis.Root(), triedb)
+	if err != nil {
+		return err
+	}
+	accIter := t.NodeIterator(nil)
+	for accIter.Next(true) {
+		if hash := accIter.Hash(); hash != (common.Hash{}) {
+			bloom.Put(hash.Bytes(), nil)
+		}
+		if !accIter.Leaf() {
+			continue
+		}
+		var acc state.Account
+		if err := rlp.DecodeBytes(accIter.LeafBlob(), &acc); err != nil {
+			return err
+		}
+		if acc.Root != emptyRoot {
+			if err := markTrie(triedb, acc.Root, bloom); err != nil {
+				return err
+			}
+		}
+		if !bytes.Equal(acc.CodeHash, emptyCode) {
+			bloom.Put(acc.CodeHash, nil)
+		}
+	}
+	return accIter.Error()
+}
+
+// markTrie marks all the nodes of the trie with the given root into the bloom
+// filter. Embedded nodes have no hash of their own and are skipped.
+func markTrie(triedb *trie.Database, root common.Hash, bloom *stateBloom) error {
+	t, err := trie.New(root, triedb)
+	if err != nil {
+		return err
+	}
+	it := t.NodeIterator(nil)
+	for it.Next(true) {
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			bloom.Put(hash.Bytes(), nil)
+		}
+	}
+	return it.Error()
+}
+
+// readHeadBlock retrieves the current head block from the database.
+func readHeadBlock(db ethdb.Reader) *types.Block {
+	hash := rawdb.ReadHeadBlockHash(db)
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	number := rawdb.ReadHeaderNumber(db, hash)
+	if number == nil {
+		return nil
+	}
+	return rawdb.ReadBlock(db, hash, *number)
+}
+
+// bloomFilterName returns the filename of the state bloom filter built for
+// pruning against the given state root.
+func bloomFilterName(datadir string, hash common.Hash) string {
+	return filepath.Join(datadir, fmt.Sprintf("%s.%s.%s", stateBloomFilePrefix, hash.Hex(), stateBloomFileSuffix))
+}
+
+// bloomFilterRoot extracts the target state root from the state bloom filename.
+func bloomFilterRoot(path string) (common.Hash, error) {
+	name := filepath.Base(path)
+	parts := strings.Split(name, ".")
+	if len(parts) != 4 || parts[0] != stateBloomFilePrefix || !strings.HasPrefix(parts[1], "0x") || len(parts[1]) != 2+2*common.HashLength {
+		return common.Hash{}, fmt.Errorf("invalid state bloom filename %q", name)
+	}
+	return common.HexToHash(parts[1]), nil
+}
+
+// findBloomFilter looks for the state bloom filter committed by a previous
+// pruning in the data directory, returning an empty path if there's none.
+func findBloomFilter(datadir string) (string, error) {
+	if datadir == "" {
+		return "", nil
+	}
+	files, err := ioutil.ReadDir(datadir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasPrefix(name, stateBloomFilePrefix) && strings.HasSuffix(name, stateBloomFileSuffix) {
+			return filepath.Join(datadir, name), nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/core/rawdb"
+	"github.com/matthieu/go-ethereum/core/state"
+	"github.com/matthieu/go-ethereum/core/state/snapshot"
+	"github.com/matthieu/go-ethereum/core/types"
+	"github.com/matthieu/go-ethereum/ethdb"
+	"github.com/matthieu/go-ethereum/trie"
+)
+
+// commitState applies the given modifications on top of the parent state and
+// flushes the resulting tries to disk.
+func commitState(t *testing.T, sdb state.Database, parent common.Hash, modify func(*state.StateDB)) common.Hash {
+	statedb, err := state.New(parent, sdb, nil)
+	if err != nil {
+		t.Fatalf("failed to open state %x: %v", parent, err)
+	}
+	modify(statedb)
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := sdb.TrieDB().Commit(root, false, nil); err != nil {
+		t.Fatalf("failed to flush state: %v", err)
+	}
+	return root
+}
+
+// trieComplete checks whether all the nodes of the given trie are present.
+func trieComplete(db ethdb.Database, root common.Hash) bool {
+	tr, err := trie.New(root, trie.NewDatabase(db))
+	if err != nil {
+		return false
+	}
+	it := tr.NodeIterator(nil)
+	for it.Next(true) {
+	}
+	return it.Error() == nil
+}
+
+// Tests that pruning deletes the state entries only reachable from stale states,
+// retaining the entire target state along with the genesis one.
+func TestPrune(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "pruner-")
+	if err != nil {
+		t.Fatalf("failed to create temporary datadir: %v", err)
+	}
+	defer os.RemoveAll(datadir)
+
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		sdb   = state.NewDatabase(db)
+		alice = common.HexToAddress("0xa11ce")
+		bob   = common.HexToAddress("0xb0b")
+		code  = []byte{0x60, 0x00, 0x60, 0x00, 0xf3}
+	)
+	stale := commitState(t, sdb, common.Hash{}, func(statedb *state.StateDB) {
+		statedb.SetBalance(alice, big.NewInt(1))
+		for i := int64(0); i < 16; i++ {
+			statedb.SetState(bob, common.BigToHash(big.NewInt(i)), common.BigToHash(big.NewInt(i+1)))
+		}
+	})
+	root := commitState(t, sdb, stale, func(statedb *state.StateDB) {
+		statedb.SetBalance(alice, big.NewInt(2))
+		statedb.SetCode(bob, code)
+		for i := int64(0); i < 16; i++ {
+			statedb.SetState(bob, common.BigToHash(big.NewInt(i)), common.BigToHash(big.NewInt(i+2)))
+		}
+	})
+	genesis := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0), Root: emptyRoot})
+	rawdb.WriteBlock(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+
+	head := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), Root: root, ParentHash: genesis.Hash()})
+	rawdb.WriteBlock(db, head)
+	rawdb.WriteCanonicalHash(db, head.Hash(), 1)
+	rawdb.WriteHeadBlockHash(db, head.Hash())
+
+	// Pruning must be refused without a fully generated snapshot
+	if _, err := NewPruner(db, head.Header(), datadir, minBloomSize); err == nil {
+		t.Fatalf("pruner created without snapshot")
+	}
+	snaptree := snapshot.New(db, trie.NewDatabase(db), 16, root, false)
+	if _, err := snaptree.Journal(root); err != nil {
+		t.Fatalf("failed to journal snapshot: %v", err)
+	}
+	pruner, err := NewPruner(db, head.Header(), datadir, minBloomSize)
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	if err := pruner.Prune(root); err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	if trieComplete(db, stale) {
+		t.Errorf("stale state retained")
+	}
+	if !trieComplete(db, root) {
+		t.Fatalf("target state pruned")
+	}
+	statedb, err := state.New(root, state.NewDatabase(db), nil)
+	if err != nil {
+		t.Fatalf("failed to open pruned state: %v", err)
+	}
+	if balance := statedb.GetBalance(alice); balance.Cmp(big.NewInt(2)) != 0 {
+		t.Errorf("balance mismatch: have %v, want 2", balance)
+	}
+	if have := statedb.GetCode(bob); string(have) != string(code) {
+		t.Errorf("code mismatch: have %x, want %x", have, code)
+	}
+	for i := int64(0); i < 16; i++ {
+		want := common.BigToHash(big.NewInt(i + 2))
+		if have := statedb.GetState(bob, common.BigToHash(big.NewInt(i))); have != want {
+			t.Errorf("slot %d mismatch: have %x, want %x", i, have, want)
+		}
+	}
+	// The bloom filter must be removed to mark the pruning complete
+	if path, _ := findBloomFilter(datadir); path != "" {
+		t.Errorf("state bloom retained after pruning: %s", path)
+	}
+}
+
+// Tests that an interrupted pruning is resumed from the persisted bloom filter,
+// and that nothing is done if there's no bloom filter.
+func TestRecoverPruning(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "pruner-")
+	if err != nil {
+		t.Fatalf("failed to create temporary datadir: %v", err)
+	}
+	defer os.RemoveAll(datadir)
+
+	var (
+		db  = rawdb.NewMemoryDatabase()
+		sdb = state.NewDatabase(db)
+	)
+	stale := commitState(t, sdb, common.Hash{}, func(statedb *state.StateDB) {
+		statedb.SetBalance(common.HexToAddress("0xa11ce"), big.NewInt(1))
+	})
+	root := commitState(t, sdb, stale, func(statedb *state.StateDB) {
+		statedb.SetBalance(common.HexToAddress("0xb0b"), big.NewInt(1))
+	})
+	head := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), Root: root})
+	rawdb.WriteBlock(db, head)
+	rawdb.WriteHeadBlockHash(db, head.Hash())
+
+	snaptree := snapshot.New(db, trie.NewDatabase(db), 16, root, false)
+	if _, err := snaptree.Journal(root); err != nil {
+		t.Fatalf("failed to journal snapshot: %v", err)
+	}
+	if err := RecoverPruning(datadir, db); err != nil {
+		t.Fatalf("failed to skip recovery: %v", err)
+	}
+	if !trieComplete(db, stale) {
+		t.Fatalf("state pruned without interrupted pruning")
+	}
+	// Simulate a crash right after the bloom filter was committed
+	bloom, err := newStateBloomWithSize(1)
+	if err != nil {
+		t.Fatalf("failed to create state bloom: %v", err)
+	}
+	if err := markTrie(trie.NewDatabase(db), root, bloom); err != nil {
+		t.Fatalf("failed to mark state: %v", err)
+	}
+	name := bloomFilterName(datadir, root)
+	if err := bloom.Commit(name, name+stateBloomFileTempSuffix); err != nil {
+		t.Fatalf("failed to commit state bloom: %v", err)
+	}
+	if have, err := bloomFilterRoot(filepath.Base(name)); err != nil || have != root {
+		t.Fatalf("bloom filter root mismatch: have %x, want %x, err %v", have, root, err)
+	}
+	if err := RecoverPruning(datadir, db); err != nil {
+		t.Fatalf("failed to recover pruning: %v", err)
+	}
+	if trieComplete(db, stale) {
+		t.Errorf("stale state retained")
+	}
+	if !trieComplete(db, root) {
+		t.Errorf("target state pruned")
+	}
+	if path, _ := findBloomFilter(datadir); path != "" {
+		t.Errorf("state bloom retained after recovery: %s", path)
+	}
+}
@@ -193,6 +193,30 @@ func New(diskdb ethdb.KeyValueStore, triedb *trie.Database, cache int, root comm
 	return snap
 }
 
+// Load opens a previously persisted snapshot for offline use. Contrary to New,
+// it never regenerates the snapshot nor resumes its generation, failing instead
+// if it is missing, not fully generated or doesn't match the expected head.
+func Load(diskdb ethdb.KeyValueStore, triedb *trie.Database, cache int, root common.Hash) (*Tree, error) {
+	head, generator, err := loadLayers(diskdb, triedb, cache, root)
+	if err != nil {
+		return nil, err
+	}
+	if !generator.Done {
+		return nil, errors.New("snapshot is not fully generated")
+	}
+	snap := &Tree{
+		diskdb: diskdb,
+		triedb: triedb,
+		cache:  cache,
+		layers: make(map[common.Hash]snapshot),
+	}
+	for head != nil {
+		snap.layers[head.Root()] = head
+		head = head.Parent()
+	}
+	return snap, nil
+}
+
 // waitBuild blocks until the snapshot finishes rebuilding. This method is meant
 // to  be used by tests to ensure we're testing what we believe we are.
 func (t *Tree) waitBuild() {
@@ -223,6 +247,23 @@ func (t *Tree) Snapshot(blockRoot common.Hash) Snapshot {
 	return t.layers[blockRoot]
 }
 
+// Snapshots returns up to limit layers of the snapshot tree, starting from the
+// one belonging to the given block root and descending towards the disk layer.
+// The disk layer is only included if nodisk is not set.
+func (t *Tree) Snapshots(blockRoot common.Hash, limit int, nodisk bool) []Snapshot {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	var ret []Snapshot
+	for layer := t.layers[blockRoot]; layer != nil && len(ret) < limit; layer = layer.Parent() {
+		if _, ok := layer.(*diskLayer); ok && nodisk {
+			break
+		}
+		ret = append(ret, layer)
+	}
+	return ret
+}
+
 // Update adds a new snapshot into the tree, if that can be linked to an existing
 // old parent. It is disallowed to insert a disk layer (the origin of all).
 func (t *Tree) Update(blockRoot common.Hash, parentRoot common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) error {
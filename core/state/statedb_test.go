@@ -727,3 +727,34 @@ func TestMissingTrieNodes(t *testing.T) {
 		t.Fatalf("expected error, got root :%x", root)
 	}
 }
+
+// Tests that access list additions are journalled and rolled back on revert.
+func TestStateDBAccessListRevert(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	var (
+		addr  = common.HexToAddress("0xaa")
+		other = common.HexToAddress("0xbb")
+		slot  = common.HexToHash("0x01")
+	)
+	state.AddAddressToAccessList(addr)
+
+	id := state.Snapshot()
+	state.AddSlotToAccessList(addr, slot)
+	state.AddSlotToAccessList(other, slot)
+
+	if addrOk, slotOk := state.SlotInAccessList(other, slot); !addrOk || !slotOk {
+		t.Fatalf("slot missing from access list: address %v, slot %v", addrOk, slotOk)
+	}
+	state.RevertToSnapshot(id)
+
+	if !state.AddressInAccessList(addr) {
+		t.Errorf("address added before snapshot was reverted")
+	}
+	if _, slotOk := state.SlotInAccessList(addr, slot); slotOk {
+		t.Errorf("slot added after snapshot survived revert")
+	}
+	if state.AddressInAccessList(other) {
+		t.Errorf("address added after snapshot survived revert")
+	}
+}
@@ -104,14 +104,27 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 	// Create a new environment which holds all relevant information
 	// about the transaction and calling mechanisms.
 	vmenv := vm.NewEVM(context, statedb, config, cfg)
+	if len(cfg.Listeners) == 0 {
+		vmenv.AddListener(NewInternalTxWatcher())
+	}
+	receipt, result, internals, err := ApplyTransactionWithEVM(msg, config, gp, statedb, header, tx, usedGas, vmenv)
+	if err != nil {
+		return nil, 0, nil, "", err
+	}
+	if result.Err != nil {
+		return receipt, receipt.GasUsed, internals, result.Err.Error(), nil
+	}
+	return receipt, receipt.GasUsed, internals, "", nil
+}
 
-	itx := NewInternalTxWatcher()
-	vmenv.AddListener(itx)
-
+// ApplyTransactionWithEVM applies a transaction to the given state database
+// using a preconfigured EVM environment. Beside the receipt and the internal
+// transactions, it returns the raw execution result of the message.
+func ApplyTransactionWithEVM(msg types.Message, config *params.ChainConfig, gp *GasPool, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, vmenv *vm.EVM) (*types.Receipt, *ExecutionResult, types.InternalTransactions, error) {
 	// Apply the transaction to the current state (included in the env)
 	result, err := ApplyMessage(vmenv, msg, gp)
 	if err != nil {
-		return nil, 0, nil, "", err
+		return nil, nil, nil, err
 	}
 	// Update the state with pending changes
 	var root []byte
@@ -125,6 +138,7 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 	// Create a new receipt for the transaction, storing the intermediate root and gas used by the tx
 	// based on the eip phase, we're passing whether the root touch-delete accounts.
 	receipt := types.NewReceipt(root, result.Failed(), *usedGas)
+	receipt.Type = tx.Type()
 	receipt.TxHash = tx.Hash()
 	receipt.GasUsed = result.UsedGas
 	// if the transaction created a contract, store the creation address in the receipt.
@@ -138,10 +152,15 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 	receipt.BlockNumber = header.Number
 	receipt.TransactionIndex = uint(statedb.TxIndex())
 
-	itx.SetParentHash(tx.Hash())
-
-	if result.Err != nil {
-		return receipt, receipt.GasUsed, itx.InternalTransactions(), result.Err.Error(), err
+	// Let the listeners wrap up, collecting the internal transactions if watched
+	var internals types.InternalTransactions
+	for _, listener := range vmenv.Listeners() {
+		if finaliser, ok := listener.(TxFinaliser); ok {
+			finaliser.Finalise(tx, receipt)
+		}
+		if watcher, ok := listener.(*InternalTxWatcher); ok {
+			internals = watcher.InternalTransactions()
+		}
 	}
-	return receipt, receipt.GasUsed, itx.InternalTransactions(), "", err
+	return receipt, result, internals, nil
 }
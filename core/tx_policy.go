@@ -0,0 +1,240 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/common/hexutil"
+	"github.com/matthieu/go-ethereum/common/mclock"
+	"github.com/matthieu/go-ethereum/core/types"
+)
+
+// txRejectionLimit is the maximum number of policy rejections retained by the
+// transaction pool for later inspection.
+const txRejectionLimit = 1024
+
+// TxPoolPolicy is a pluggable admission and eviction policy of the transaction
+// pool, consulted on top of the built in validation rules.
+//
+// Policies are invoked with the pool lock held, so they must be fast and must
+// not call back into the pool.
+type TxPoolPolicy interface {
+	// Name returns a short identifier of the policy, reported with rejections.
+	Name() string
+
+	// Admit is called when a transaction passing the basic validation rules is
+	// about to be added to the pool. A non-nil error rejects the transaction.
+	//
+	// The recovered flag is set for transactions re-added after a chain reorg or
+	// loaded from a journal, which were already admitted once before.
+	Admit(tx *types.Transaction, from common.Address, local, recovered bool) error
+
+	// Promote is called when a queued transaction is about to be moved into the
+	// pending set. A non-nil error evicts the transaction from the pool.
+	Promote(tx *types.Transaction, from common.Address) error
+}
+
+// TxPolicyError is returned if a transaction was refused by one of the pool
+// policies.
+type TxPolicyError struct {
+	Policy string // Name of the policy rejecting the transaction
+	Reason string // Reason given by the policy for the rejection
+}
+
+// Error implements error, formatting the policy and its reason.
+func (e *TxPolicyError) Error() string {
+	return fmt.Sprintf("rejected by %s policy: %s", e.Policy, e.Reason)
+}
+
+// TxRejection is a record of a transaction refused or evicted by one of the
+// transaction pool policies.
+type TxRejection struct {
+	Hash   common.Hash    `json:"hash"`
+	From   common.Address `json:"from"`
+	Policy string         `json:"policy"`
+	Reason string         `json:"reason"`
+	Time   time.Time      `json:"time"`
+}
+
+// addressPolicy is a transaction pool policy filtering transactions based on
+// the sender and recipient addresses.
+type addressPolicy struct {
+	allow map[common.Address]struct{}
+	deny  map[common.Address]struct{}
+}
+
+// NewAddressPolicy creates a pool policy rejecting all transactions sent from or
+// to any of the denied addresses. If the allow list is non-empty, transactions
+// from senders not contained within are rejected too.
+func NewAddressPolicy(allow, deny []common.Address) TxPoolPolicy {
+	policy := &addressPolicy{
+		allow: make(map[common.Address]struct{}),
+		deny:  make(map[common.Address]struct{}),
+	}
+	for _, addr := range allow {
+		policy.allow[addr] = struct{}{}
+	}
+	for _, addr := range deny {
+		policy.deny[addr] = struct{}{}
+	}
+	return policy
+}
+
+// Name implements TxPoolPolicy, returning the policy identifier.
+func (p *addressPolicy) Name() string { return "address" }
+
+// Admit implements TxPoolPolicy, checking the sender and recipient of the
+// transaction against the allow and deny lists.
+func (p *addressPolicy) Admit(tx *types.Transaction, from common.Address, local, recovered bool) error {
+	return p.check(tx, from)
+}
+
+// Promote implements TxPoolPolicy, checking the sender and recipient of the
+// transaction against the allow and deny lists.
+func (p *addressPolicy) Promote(tx *types.Transaction, from common.Address) error {
+	return p.check(tx, from)
+}
+
+// check verifies the addresses of a transaction against the allow and deny lists.
+func (p *addressPolicy) check(tx *types.Transaction, from common.Address) error {
+	if _, ok := p.deny[from]; ok {
+		return fmt.Errorf("sender %x denied", from)
+	}
+	if to := tx.To(); to != nil {
+		if _, ok := p.deny[*to]; ok {
+			return fmt.Errorf("recipient %x denied", *to)
+		}
+	}
+	if len(p.allow) > 0 {
+		if _, ok := p.allow[from]; !ok {
+			return fmt.Errorf("sender %x not allowed", from)
+		}
+	}
+	return nil
+}
+
+// senderRatePolicy is a transaction pool policy limiting the number of
+// transactions each sender can submit within a time window.
+type senderRatePolicy struct {
+	limit  int
+	period time.Duration
+	clock  mclock.Clock
+
+	admits  map[common.Address][]mclock.AbsTime // Admission times within the window
+	cleaned mclock.AbsTime                      // Last time stale senders were dropped
+	lock    sync.Mutex
+}
+
+// NewSenderRatePolicy creates a pool policy rejecting transactions from senders
+// which already had limit transactions admitted within the last period.
+func NewSenderRatePolicy(limit int, period time.Duration) TxPoolPolicy {
+	return &senderRatePolicy{
+		limit:  limit,
+		period: period,
+		clock:  mclock.System{},
+		admits: make(map[common.Address][]mclock.AbsTime),
+	}
+}
+
+// Name implements TxPoolPolicy, returning the policy identifier.
+func (p *senderRatePolicy) Name() string { return "ratelimit" }
+
+// Admit implements TxPoolPolicy, rejecting the transaction if its sender ran out
+// of allowance in the current window. Recovered transactions were already charged
+// on their original admission, so they are let through without using allowance.
+func (p *senderRatePolicy) Admit(tx *types.Transaction, from common.Address, local, recovered bool) error {
+	if recovered {
+		return nil
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// Drop all the admissions which fell out of the window
+	var (
+		now    = p.clock.Now()
+		admits = p.admits[from]
+	)
+	for len(admits) > 0 && now.Sub(admits[0]) >= p.period {
+		admits = admits[1:]
+	}
+	if len(admits) >= p.limit {
+		p.admits[from] = admits
+		return fmt.Errorf("sender %x exceeded %d transactions per %v", from, p.limit, p.period)
+	}
+	p.admits[from] = append(admits, now)
+
+	// Periodically clean up senders without recent admissions
+	if now.Sub(p.cleaned) >= p.period {
+		for addr, times := range p.admits {
+			if now.Sub(times[len(times)-1]) >= p.period {
+				delete(p.admits, addr)
+			}
+		}
+		p.cleaned = now
+	}
+	return nil
+}
+
+// Promote implements TxPoolPolicy. Rate limits only apply to admissions.
+func (p *senderRatePolicy) Promote(tx *types.Transaction, from common.Address) error {
+	return nil
+}
+
+// selectorPolicy is a transaction pool policy filtering contract calls based on
+// the 4-byte method selector.
+type selectorPolicy struct {
+	deny map[[4]byte]struct{}
+}
+
+// NewSelectorPolicy creates a pool policy rejecting all contract calls invoking
+// any of the denied 4-byte method selectors.
+func NewSelectorPolicy(deny [][4]byte) TxPoolPolicy {
+	policy := &selectorPolicy{
+		deny: make(map[[4]byte]struct{}),
+	}
+	for _, selector := range deny {
+		policy.deny[selector] = struct{}{}
+	}
+	return policy
+}
+
+// Name implements TxPoolPolicy, returning the policy identifier.
+func (p *selectorPolicy) Name() string { return "selector" }
+
+// Admit implements TxPoolPolicy, rejecting calls to denied method selectors.
+func (p *selectorPolicy) Admit(tx *types.Transaction, from common.Address, local, recovered bool) error {
+	data := tx.Data()
+	if tx.To() == nil || len(data) < 4 {
+		return nil
+	}
+	var selector [4]byte
+	copy(selector[:], data)
+	if _, ok := p.deny[selector]; ok {
+		return fmt.Errorf("method %s denied", hexutil.Encode(selector[:]))
+	}
+	return nil
+}
+
+// Promote implements TxPoolPolicy. The transaction payload is immutable, so
+// there is nothing to recheck after admission.
+func (p *selectorPolicy) Promote(tx *types.Transaction, from common.Address) error {
+	return nil
+}
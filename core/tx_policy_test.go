@@ -0,0 +1,242 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/common/hexutil"
+	"github.com/matthieu/go-ethereum/common/mclock"
+	"github.com/matthieu/go-ethereum/core/rawdb"
+	"github.com/matthieu/go-ethereum/core/state"
+	"github.com/matthieu/go-ethereum/core/types"
+	"github.com/matthieu/go-ethereum/crypto"
+	"github.com/matthieu/go-ethereum/event"
+	"github.com/matthieu/go-ethereum/params"
+)
+
+// nonceTestPolicy is a pool policy refusing the promotion of a single nonce.
+type nonceTestPolicy struct {
+	nonce uint64
+}
+
+func (p *nonceTestPolicy) Name() string { return "nonce" }
+
+func (p *nonceTestPolicy) Admit(tx *types.Transaction, from common.Address, local, recovered bool) error {
+	return nil
+}
+
+func (p *nonceTestPolicy) Promote(tx *types.Transaction, from common.Address) error {
+	if tx.Nonce() == p.nonce {
+		return errors.New("nonce refused")
+	}
+	return nil
+}
+
+// setupPolicyTxPool creates a transaction pool running the given policies, along
+// with a funded test account.
+func setupPolicyTxPool(policies ...TxPoolPolicy) (*TxPool, *ecdsa.PrivateKey) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 10000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.Policies = policies
+
+	key, _ := crypto.GenerateKey()
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	return pool, key
+}
+
+// Tests that transactions refused by a pool policy are rejected with a policy
+// error and recorded for later inspection.
+func TestTransactionPolicyAdmission(t *testing.T) {
+	t.Parallel()
+
+	denied := common.HexToAddress("0xdead")
+	pool, key := setupPolicyTxPool(
+		NewAddressPolicy(nil, []common.Address{denied}),
+		NewSelectorPolicy([][4]byte{{0xa9, 0x05, 0x9c, 0xbb}}),
+	)
+	defer pool.Stop()
+
+	signer := types.HomesteadSigner{}
+	toDenied, _ := types.SignTx(types.NewTransaction(0, denied, big.NewInt(1), 100000, big.NewInt(1), nil), signer, key)
+	transfer, _ := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(0), 100000, big.NewInt(1), common.FromHex("0xa9059cbb00")), signer, key)
+	allowed, _ := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(0), 100000, big.NewInt(1), common.FromHex("0x095ea7b300")), signer, key)
+
+	for i, tx := range []*types.Transaction{toDenied, transfer} {
+		err := pool.addRemoteSync(tx)
+		if perr, ok := err.(*TxPolicyError); !ok {
+			t.Fatalf("tx %d: error mismatch: have %v, want policy error", i, err)
+		} else if want := []string{"address", "selector"}[i]; perr.Policy != want {
+			t.Errorf("tx %d: policy mismatch: have %s, want %s", i, perr.Policy, want)
+		}
+	}
+	if err := pool.addRemoteSync(allowed); err != nil {
+		t.Fatalf("failed to add allowed transaction: %v", err)
+	}
+	rejections := pool.Rejections()
+	if len(rejections) != 2 {
+		t.Fatalf("rejection count mismatch: have %d, want %d", len(rejections), 2)
+	}
+	if rejections[0].Hash != toDenied.Hash() || rejections[1].Hash != transfer.Hash() {
+		t.Errorf("rejected hashes mismatch: have %x, %x", rejections[0].Hash, rejections[1].Hash)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the pool policies are only charged for transactions the pool itself
+// would accept, and not for transactions recovered after a reorg.
+func TestTransactionPolicyAdmissionCharging(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPolicyTxPool(NewSenderRatePolicy(2, time.Hour))
+	defer pool.Stop()
+
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(2), key)); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	// An underpriced replacement should be refused without using up allowance
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(1), key)); err != ErrReplaceUnderpriced {
+		t.Fatalf("underpriced replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	}
+	last := pricedTransaction(1, 100000, big.NewInt(2), key)
+	if err := pool.addRemoteSync(last); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	// Reinjecting a dropped transaction should pass even with the allowance used up
+	pool.mu.Lock()
+	pool.removeTx(last.Hash(), true)
+	errs, _ := pool.addTxsLocked([]*types.Transaction{last}, false, true)
+	pool.mu.Unlock()
+
+	if errs[0] != nil {
+		t.Fatalf("failed to reinject transaction: %v", errs[0])
+	}
+	if err := pool.addRemoteSync(pricedTransaction(2, 100000, big.NewInt(2), key)); err == nil {
+		t.Fatalf("rate limited transaction admitted")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that queued transactions refused promotion by a pool policy are evicted,
+// and the subsequent ones are kept in the queue.
+func TestTransactionPolicyEviction(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPolicyTxPool(&nonceTestPolicy{nonce: 1})
+	defer pool.Stop()
+
+	account := crypto.PubkeyToAddress(key.PublicKey)
+	errs := pool.AddRemotesSync([]*types.Transaction{
+		transaction(0, 100000, key),
+		transaction(1, 100000, key),
+		transaction(2, 100000, key),
+	})
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("tx %d: failed to add transaction: %v", i, err)
+		}
+	}
+	if pending := pool.pending[account].Len(); pending != 1 {
+		t.Errorf("pending transactions mismatch: have %d, want %d", pending, 1)
+	}
+	if queued := pool.queue[account].Len(); queued != 1 {
+		t.Errorf("queued transactions mismatch: have %d, want %d", queued, 1)
+	}
+	if count := pool.all.Count(); count != 2 {
+		t.Errorf("total transactions mismatch: have %d, want %d", count, 2)
+	}
+	if rejections := pool.Rejections(); len(rejections) != 1 || rejections[0].Policy != "nonce" {
+		t.Errorf("rejections mismatch: have %v", rejections)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the sender rate limiting policy only admits the configured number
+// of transactions within its time window.
+func TestSenderRatePolicy(t *testing.T) {
+	var (
+		clock  = new(mclock.Simulated)
+		policy = NewSenderRatePolicy(2, time.Minute).(*senderRatePolicy)
+		from   = common.Address{1}
+		tx     = types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)
+	)
+	policy.clock = clock
+
+	for i := 0; i < 2; i++ {
+		if err := policy.Admit(tx, from, false, false); err != nil {
+			t.Fatalf("tx %d: failed to admit transaction: %v", i, err)
+		}
+	}
+	if err := policy.Admit(tx, from, false, false); err == nil {
+		t.Fatalf("rate limited transaction admitted")
+	}
+	if err := policy.Admit(tx, common.Address{2}, false, false); err != nil {
+		t.Fatalf("failed to admit transaction from other sender: %v", err)
+	}
+	clock.Run(time.Minute)
+	if err := policy.Admit(tx, from, false, false); err != nil {
+		t.Fatalf("failed to admit transaction after window: %v", err)
+	}
+}
+
+// Tests that the built in policies enabled in the pool configuration are created
+// on sanitization, ahead of the custom ones.
+func TestTxPoolConfigPolicies(t *testing.T) {
+	config := TxPoolConfig{
+		DenyAddresses:   []common.Address{{0xde, 0xad}},
+		DenySelectors:   []hexutil.Bytes{{0xa9, 0x05, 0x9c, 0xbb}, {0x01}},
+		SenderRateLimit: 10,
+		Policies:        []TxPoolPolicy{&nonceTestPolicy{}},
+	}
+	conf := config.sanitize()
+
+	var names []string
+	for _, policy := range conf.Policies {
+		names = append(names, policy.Name())
+	}
+	if want := []string{"address", "selector", "ratelimit", "nonce"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("policies mismatch: have %v, want %v", names, want)
+	}
+	if len(config.Policies) != 1 {
+		t.Errorf("configured policies modified: have %d, want %d", len(config.Policies), 1)
+	}
+	if selectors := conf.Policies[1].(*selectorPolicy).deny; len(selectors) != 1 {
+		t.Errorf("selector count mismatch: have %d, want %d", len(selectors), 1)
+	}
+	if period := conf.Policies[2].(*senderRatePolicy).period; period != DefaultTxPoolConfig.SenderRatePeriod {
+		t.Errorf("rate period mismatch: have %v, want %v", period, DefaultTxPoolConfig.SenderRatePeriod)
+	}
+	if policies := new(TxPoolConfig).sanitize().Policies; len(policies) != 0 {
+		t.Errorf("policies enabled by default: have %d", len(policies))
+	}
+}
@@ -17,6 +17,7 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"math"
 	"math/big"
@@ -25,6 +26,7 @@ import (
 	"time"
 
 	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/common/hexutil"
 	"github.com/matthieu/go-ethereum/common/prque"
 	"github.com/matthieu/go-ethereum/core/state"
 	"github.com/matthieu/go-ethereum/core/types"
@@ -99,12 +101,14 @@ var (
 	queuedRateLimitMeter = metrics.NewRegisteredMeter("txpool/queued/ratelimit", nil) // Dropped due to rate limiting
 	queuedNofundsMeter   = metrics.NewRegisteredMeter("txpool/queued/nofunds", nil)   // Dropped due to out-of-funds
 	queuedEvictionMeter  = metrics.NewRegisteredMeter("txpool/queued/eviction", nil)  // Dropped due to lifetime
+	queuedRejectMeter    = metrics.NewRegisteredMeter("txpool/queued/reject", nil)    // Dropped due to pool policies
 
 	// General tx metrics
 	knownTxMeter       = metrics.NewRegisteredMeter("txpool/known", nil)
 	validTxMeter       = metrics.NewRegisteredMeter("txpool/valid", nil)
 	invalidTxMeter     = metrics.NewRegisteredMeter("txpool/invalid", nil)
 	underpricedTxMeter = metrics.NewRegisteredMeter("txpool/underpriced", nil)
+	rejectedTxMeter    = metrics.NewRegisteredMeter("txpool/rejected", nil)
 
 	pendingGauge = metrics.NewRegisteredGauge("txpool/pending", nil)
 	queuedGauge  = metrics.NewRegisteredGauge("txpool/queued", nil)
@@ -139,6 +143,10 @@ type TxPoolConfig struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	PoolJournal      string        // Journal of all pooled transactions to survive node restarts
+	PoolRejournal    time.Duration // Time interval to regenerate the full pool journal
+	PoolJournalLimit uint64        // Maximum number of transactions retained in the full pool journal
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -148,6 +156,14 @@ type TxPoolConfig struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	AllowSenders     []common.Address // Senders allowed to submit transactions, all if empty
+	DenyAddresses    []common.Address // Accounts whose transactions are rejected, as sender or recipient
+	DenySelectors    []hexutil.Bytes  // 4-byte method selectors whose contract calls are rejected
+	SenderRateLimit  uint64           // Maximum number of transactions admitted per sender within the rate period (0 = unlimited)
+	SenderRatePeriod time.Duration    // Time window of the per sender rate limit
+
+	Policies []TxPoolPolicy `toml:"-"` // Custom admission and eviction policies consulted after the built in ones
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -156,6 +172,9 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	Journal:   "transactions.rlp",
 	Rejournal: time.Hour,
 
+	PoolRejournal:    5 * time.Minute,
+	PoolJournalLimit: 4096,
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -165,6 +184,8 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	SenderRatePeriod: time.Minute,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -175,6 +196,14 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
 		conf.Rejournal = time.Second
 	}
+	if conf.PoolRejournal < time.Second {
+		log.Warn("Sanitizing invalid txpool pool journal time", "provided", conf.PoolRejournal, "updated", time.Second)
+		conf.PoolRejournal = time.Second
+	}
+	if conf.PoolJournalLimit < 1 {
+		log.Warn("Sanitizing invalid txpool pool journal limit", "provided", conf.PoolJournalLimit, "updated", DefaultTxPoolConfig.PoolJournalLimit)
+		conf.PoolJournalLimit = DefaultTxPoolConfig.PoolJournalLimit
+	}
 	if conf.PriceLimit < 1 {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultTxPoolConfig.PriceLimit)
 		conf.PriceLimit = DefaultTxPoolConfig.PriceLimit
@@ -203,6 +232,33 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultTxPoolConfig.Lifetime)
 		conf.Lifetime = DefaultTxPoolConfig.Lifetime
 	}
+	if conf.SenderRateLimit > 0 && conf.SenderRatePeriod < time.Second {
+		log.Warn("Sanitizing invalid txpool sender rate period", "provided", conf.SenderRatePeriod, "updated", DefaultTxPoolConfig.SenderRatePeriod)
+		conf.SenderRatePeriod = DefaultTxPoolConfig.SenderRatePeriod
+	}
+	// Assemble the built in policies enabled by the configuration, followed by the
+	// custom ones, without touching the caller's policy list
+	var policies []TxPoolPolicy
+	if len(conf.AllowSenders) > 0 || len(conf.DenyAddresses) > 0 {
+		policies = append(policies, NewAddressPolicy(conf.AllowSenders, conf.DenyAddresses))
+	}
+	if len(conf.DenySelectors) > 0 {
+		selectors := make([][4]byte, 0, len(conf.DenySelectors))
+		for _, selector := range conf.DenySelectors {
+			if len(selector) != 4 {
+				log.Warn("Ignoring invalid txpool method selector", "selector", selector)
+				continue
+			}
+			var sel [4]byte
+			copy(sel[:], selector)
+			selectors = append(selectors, sel)
+		}
+		policies = append(policies, NewSelectorPolicy(selectors))
+	}
+	if conf.SenderRateLimit > 0 {
+		policies = append(policies, NewSenderRatePolicy(int(conf.SenderRateLimit), conf.SenderRatePeriod))
+	}
+	conf.Policies = append(policies, conf.Policies...)
 	return conf
 }
 
@@ -224,13 +280,15 @@ type TxPool struct {
 	mu          sync.RWMutex
 
 	istanbul bool // Fork indicator whether we are in the istanbul stage.
+	eip2718  bool // Fork indicator whether we are using EIP-2718 type transactions.
 
 	currentState  *state.StateDB // Current state in the blockchain head
 	pendingNonces *txNoncer      // Pending state tracking virtual nonces
 	currentMaxGas uint64         // Current gas limit for transaction caps
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *txJournal  // Journal of local transaction to back up to disk
+	locals      *accountSet // Set of local transaction to exempt from eviction rules
+	journal     *txJournal  // Journal of local transaction to back up to disk
+	poolJournal *txJournal  // Journal of all pooled transactions to back up to disk
 
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
@@ -238,6 +296,8 @@ type TxPool struct {
 	all     *txLookup                    // All transactions to allow lookups
 	priced  *txPricedList                // All transactions sorted by price
 
+	rejections []*TxRejection // Most recent transactions refused by the pool policies
+
 	chainHeadCh     chan ChainHeadEvent
 	chainHeadSub    event.Subscription
 	reqResetCh      chan *txpoolResetRequest
@@ -263,7 +323,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		config:          config,
 		chainconfig:     chainconfig,
 		chain:           chain,
-		signer:          types.NewEIP155Signer(chainconfig.ChainID),
+		signer:          types.LatestSignerForChainID(chainconfig.ChainID),
 		pending:         make(map[common.Address]*txList),
 		queue:           make(map[common.Address]*txList),
 		beats:           make(map[common.Address]time.Time),
@@ -290,15 +350,25 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 
 	// If local transactions and journaling is enabled, load from disk
 	if !config.NoLocals && config.Journal != "" {
-		pool.journal = newTxJournal(config.Journal)
+		pool.journal = newTxJournal(config.Journal, "local")
 
-		if err := pool.journal.load(pool.AddLocals); err != nil {
+		if err := pool.journal.load(pool.addJournaled(true)); err != nil {
 			log.Warn("Failed to load transaction journal", "err", err)
 		}
 		if err := pool.journal.rotate(pool.local()); err != nil {
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If full pool journaling is enabled, load from disk, revalidating everything
+	// against the current head as any remote transaction
+	if config.PoolJournal != "" {
+		pool.poolJournal = newTxJournal(config.PoolJournal, "pool")
+
+		if err := pool.poolJournal.load(pool.addJournaled(false)); err != nil {
+			log.Warn("Failed to load pool transaction journal", "err", err)
+		}
+		pool.rotatePoolJournal()
+	}
 
 	// Subscribe events from blockchain and start the main event loop.
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
@@ -320,12 +390,14 @@ func (pool *TxPool) loop() {
 		report  = time.NewTicker(statsReportInterval)
 		evict   = time.NewTicker(evictionInterval)
 		journal = time.NewTicker(pool.config.Rejournal)
+		persist = time.NewTicker(pool.config.PoolRejournal)
 		// Track the previous head headers for transaction reorgs
 		head = pool.chain.CurrentBlock()
 	)
 	defer report.Stop()
 	defer evict.Stop()
 	defer journal.Stop()
+	defer persist.Stop()
 
 	for {
 		select {
@@ -381,6 +453,12 @@ func (pool *TxPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+
+		// Handle full pool transaction journal rotation
+		case <-persist.C:
+			if pool.poolJournal != nil {
+				pool.rotatePoolJournal()
+			}
 		}
 	}
 }
@@ -397,6 +475,10 @@ func (pool *TxPool) Stop() {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	if pool.poolJournal != nil {
+		pool.rotatePoolJournal()
+		pool.poolJournal.close()
+	}
 	log.Info("Transaction pool stopped")
 }
 
@@ -514,9 +596,58 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+// persistable retrieves the transactions to back up into the full pool journal,
+// grouped by origin account and sorted by nonce. Executable transactions are
+// preferred over queued ones if the configured limit is reached, after which the
+// accounts are taken in address order to keep the retained set deterministic.
+func (pool *TxPool) persistable() map[common.Address]types.Transactions {
+	var (
+		txs   = make(map[common.Address]types.Transactions)
+		count uint64
+	)
+	for _, lists := range []map[common.Address]*txList{pool.pending, pool.queue} {
+		addrs := make([]common.Address, 0, len(lists))
+		for addr := range lists {
+			addrs = append(addrs, addr)
+		}
+		sort.Slice(addrs, func(i, j int) bool {
+			return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+		})
+		for _, addr := range addrs {
+			flat := lists[addr].Flatten()
+			if left := pool.config.PoolJournalLimit - count; uint64(len(flat)) > left {
+				flat = flat[:left]
+			}
+			txs[addr] = append(txs[addr], flat...)
+
+			if count += uint64(len(flat)); count >= pool.config.PoolJournalLimit {
+				return txs
+			}
+		}
+	}
+	return txs
+}
+
+// rotatePoolJournal regenerates the full pool journal from a snapshot of the
+// current pool contents. The snapshot is taken under the pool lock, but the
+// disk write is done without holding it.
+func (pool *TxPool) rotatePoolJournal() {
+	pool.mu.RLock()
+	txs := pool.persistable()
+	pool.mu.RUnlock()
+
+	if err := pool.poolJournal.rotate(txs); err != nil {
+		log.Warn("Failed to rotate pool tx journal", "err", err)
+	}
+}
+
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
+	// Reject typed transactions before the EIP-2718 fork activates
+	if !pool.eip2718 && tx.Type() != types.LegacyTxType {
+		return ErrTxTypeNotSupported
+	}
 	// Reject transactions over defined size to prevent DOS attacks
 	if uint64(tx.Size()) > txMaxSize {
 		return ErrOversizedData
@@ -550,7 +681,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		return ErrInsufficientFunds
 	}
 	// Ensure the transaction has more gas than the basic tx fee.
-	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, true, pool.istanbul)
+	intrGas, err := IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, true, pool.istanbul)
 	if err != nil {
 		return err
 	}
@@ -567,7 +698,10 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 // If a newly added transaction is marked as local, its sending account will be
 // whitelisted, preventing any associated transaction from being dropped out of the pool
 // due to pricing constraints.
-func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err error) {
+//
+// Recovered transactions (reinjected after a reorg or loaded from a journal) are
+// reported as such to the pool policies, as they were already admitted before.
+func (pool *TxPool) add(tx *types.Transaction, local, recovered bool) (replaced bool, err error) {
 	// If the transaction is already known, discard it
 	hash := tx.Hash()
 	if pool.all.Get(hash) != nil {
@@ -581,15 +715,34 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		invalidTxMeter.Mark(1)
 		return false, err
 	}
-	// If the transaction pool is full, discard underpriced transactions
-	if uint64(pool.all.Count()) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
-		// If the new transaction is underpriced, don't accept it
-		if !local && pool.priced.Underpriced(tx, pool.locals) {
-			log.Trace("Discarding underpriced transaction", "hash", hash, "price", tx.GasPrice())
-			underpricedTxMeter.Mark(1)
-			return false, ErrUnderpriced
-		}
-		// New transaction is better than our worse ones, make room for it
+	// If the transaction pool is full and the new transaction is underpriced, discard it
+	full := uint64(pool.all.Count()) >= pool.config.GlobalSlots+pool.config.GlobalQueue
+	if full && !local && pool.priced.Underpriced(tx, pool.locals) {
+		log.Trace("Discarding underpriced transaction", "hash", hash, "price", tx.GasPrice())
+		underpricedTxMeter.Mark(1)
+		return false, ErrUnderpriced
+	}
+	// If the transaction would replace an existing one without the required
+	// price bump, discard it
+	from, _ := types.Sender(pool.signer, tx) // already validated
+	if list := pool.pending[from]; list != nil && !list.Replaceable(tx, pool.config.PriceBump) {
+		pendingDiscardMeter.Mark(1)
+		return false, ErrReplaceUnderpriced
+	}
+	if list := pool.queue[from]; list != nil && !list.Replaceable(tx, pool.config.PriceBump) {
+		queuedDiscardMeter.Mark(1)
+		return false, ErrReplaceUnderpriced
+	}
+	// If the transaction is refused by any of the pool policies, discard it. This
+	// is done after the pool's own checks to avoid charging policies for transactions
+	// the pool would discard anyway.
+	if err := pool.admitTx(tx, from, local, recovered); err != nil {
+		log.Trace("Discarding policy rejected transaction", "hash", hash, "err", err)
+		rejectedTxMeter.Mark(1)
+		return false, err
+	}
+	// If the transaction pool is full, make room for the new transaction
+	if full {
 		drop := pool.priced.Discard(pool.all.Slots()-int(pool.config.GlobalSlots+pool.config.GlobalQueue)+numSlots(tx), pool.locals)
 		for _, tx := range drop {
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
@@ -598,7 +751,6 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		}
 	}
 	// Try to replace an existing transaction in the pending pool
-	from, _ := types.Sender(pool.signer, tx) // already validated
 	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
 		// Nonce already pending, check if required price bump is met
 		inserted, old := list.Add(tx, pool.config.PriceBump)
@@ -643,6 +795,63 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 	return replaced, nil
 }
 
+// admitTx consults the pool policies whether the transaction should be accepted,
+// recording any rejection.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) admitTx(tx *types.Transaction, from common.Address, local, recovered bool) error {
+	for _, policy := range pool.config.Policies {
+		if err := policy.Admit(tx, from, local, recovered); err != nil {
+			return pool.rejectTx(tx, from, policy, err)
+		}
+	}
+	return nil
+}
+
+// promotableTx consults the pool policies whether the queued transaction should
+// be moved into the pending set, recording any rejection.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) promotableTx(addr common.Address, tx *types.Transaction) error {
+	for _, policy := range pool.config.Policies {
+		if err := policy.Promote(tx, addr); err != nil {
+			return pool.rejectTx(tx, addr, policy, err)
+		}
+	}
+	return nil
+}
+
+// rejectTx records a policy rejection and returns the error to report back to
+// the transaction's submitter.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) rejectTx(tx *types.Transaction, from common.Address, policy TxPoolPolicy, err error) error {
+	rejection := &TxRejection{
+		Hash:   tx.Hash(),
+		From:   from,
+		Policy: policy.Name(),
+		Reason: err.Error(),
+		Time:   time.Now(),
+	}
+	if len(pool.rejections) >= txRejectionLimit {
+		pool.rejections = append(pool.rejections[:0], pool.rejections[1:]...)
+	}
+	pool.rejections = append(pool.rejections, rejection)
+
+	return &TxPolicyError{Policy: rejection.Policy, Reason: rejection.Reason}
+}
+
+// Rejections returns the most recent transactions refused or evicted by the pool
+// policies, oldest first.
+func (pool *TxPool) Rejections() []*TxRejection {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	rejections := make([]*TxRejection, len(pool.rejections))
+	copy(rejections, pool.rejections)
+	return rejections
+}
+
 // enqueueTx inserts a new transaction into the non-executable transaction queue.
 //
 // Note, this method assumes the pool lock is held!
@@ -737,7 +946,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 // This method is used to add transactions from the RPC API and performs synchronous pool
 // reorganization and event propagation.
 func (pool *TxPool) AddLocals(txs []*types.Transaction) []error {
-	return pool.addTxs(txs, !pool.config.NoLocals, true)
+	return pool.addTxs(txs, !pool.config.NoLocals, true, false)
 }
 
 // AddLocal enqueues a single local transaction into the pool if it is valid. This is
@@ -753,12 +962,12 @@ func (pool *TxPool) AddLocal(tx *types.Transaction) error {
 // This method is used to add transactions from the p2p network and does not wait for pool
 // reorganization and internal event propagation.
 func (pool *TxPool) AddRemotes(txs []*types.Transaction) []error {
-	return pool.addTxs(txs, false, false)
+	return pool.addTxs(txs, false, false, false)
 }
 
 // This is like AddRemotes, but waits for pool reorganization. Tests use this method.
 func (pool *TxPool) AddRemotesSync(txs []*types.Transaction) []error {
-	return pool.addTxs(txs, false, true)
+	return pool.addTxs(txs, false, true, false)
 }
 
 // This is like AddRemotes with a single transaction, but waits for pool reorganization. Tests use this method.
@@ -776,8 +985,17 @@ func (pool *TxPool) AddRemote(tx *types.Transaction) error {
 	return errs[0]
 }
 
+// addJournaled enqueues a batch of transactions loaded from a journal, waiting
+// for pool reorganization. The transactions are reported as recovered to the pool
+// policies.
+func (pool *TxPool) addJournaled(local bool) func([]*types.Transaction) []error {
+	return func(txs []*types.Transaction) []error {
+		return pool.addTxs(txs, local, true, true)
+	}
+}
+
 // addTxs attempts to queue a batch of transactions if they are valid.
-func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
+func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync, recovered bool) []error {
 	// Filter out known ones without obtaining the pool lock or recovering signatures
 	var (
 		errs = make([]error, len(txs))
@@ -808,7 +1026,7 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 
 	// Process all the new transaction and merge any errors into the original slice
 	pool.mu.Lock()
-	newErrs, dirtyAddrs := pool.addTxsLocked(news, local)
+	newErrs, dirtyAddrs := pool.addTxsLocked(news, local, recovered)
 	pool.mu.Unlock()
 
 	var nilSlot = 0
@@ -828,11 +1046,11 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 
 // addTxsLocked attempts to queue a batch of transactions if they are valid.
 // The transaction pool lock must be held.
-func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local bool) ([]error, *accountSet) {
+func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local, recovered bool) ([]error, *accountSet) {
 	dirty := newAccountSet(pool.signer)
 	errs := make([]error, len(txs))
 	for i, tx := range txs {
-		replaced, err := pool.add(tx, local)
+		replaced, err := pool.add(tx, local, recovered)
 		errs[i] = err
 		if err == nil && !replaced {
 			dirty.addTx(tx)
@@ -870,6 +1088,12 @@ func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
 	return pool.all.Get(hash)
 }
 
+// Arrival returns the sequence number of a transaction in the order transactions
+// entered the pool, and whether the transaction is contained in the pool.
+func (pool *TxPool) Arrival(hash common.Hash) (uint64, bool) {
+	return pool.all.Arrival(hash)
+}
+
 // Has returns an indicator whether txpool has a transaction cached with the
 // given hash.
 func (pool *TxPool) Has(hash common.Hash) bool {
@@ -1171,11 +1395,12 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	senderCacher.recover(pool.signer, reinject)
-	pool.addTxsLocked(reinject, false)
+	pool.addTxsLocked(reinject, false, true)
 
 	// Update all fork indicator by next pending block number.
 	next := new(big.Int).Add(newHead.Number, big.NewInt(1))
 	pool.istanbul = pool.chainconfig.IsIstanbul(next)
+	pool.eip2718 = pool.chainconfig.IsBerlin(next)
 }
 
 // promoteExecutables moves transactions that have become processable from the
@@ -1207,8 +1432,27 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 		log.Trace("Removed unpayable queued transactions", "count", len(drops))
 		queuedNofundsMeter.Mark(int64(len(drops)))
 
-		// Gather all executable transactions and promote them
+		// Gather all executable transactions and evict any refused by the pool policies.
+		// Subsequent transactions are not executable any more, keep them queued.
 		readies := list.Ready(pool.pendingNonces.get(addr))
+
+		var rejects types.Transactions
+		for i, tx := range readies {
+			if err := pool.promotableTx(addr, tx); err != nil {
+				hash := tx.Hash()
+				pool.all.Remove(hash)
+				log.Trace("Removed policy rejected queued transaction", "hash", hash, "err", err)
+
+				for _, tx := range readies[i+1:] {
+					list.Add(tx, pool.config.PriceBump)
+				}
+				rejects, readies = types.Transactions{tx}, readies[:i]
+				break
+			}
+		}
+		queuedRejectMeter.Mark(int64(len(rejects)))
+
+		// Promote all the remaining executable transactions
 		for _, tx := range readies {
 			hash := tx.Hash()
 			if pool.promoteTx(addr, hash, tx) {
@@ -1230,10 +1474,10 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 			queuedRateLimitMeter.Mark(int64(len(caps)))
 		}
 		// Mark all the items dropped as removed
-		pool.priced.Removed(len(forwards) + len(drops) + len(caps))
-		queuedGauge.Dec(int64(len(forwards) + len(drops) + len(caps)))
+		pool.priced.Removed(len(forwards) + len(drops) + len(rejects) + len(caps))
+		queuedGauge.Dec(int64(len(forwards) + len(drops) + len(rejects) + len(caps)))
 		if pool.locals.contains(addr) {
-			localGauge.Dec(int64(len(forwards) + len(drops) + len(caps)))
+			localGauge.Dec(int64(len(forwards) + len(drops) + len(rejects) + len(caps)))
 		}
 		// Delete the entire queue entry if it became empty.
 		if list.Empty() {
@@ -1523,15 +1767,18 @@ func (as *accountSet) merge(other *accountSet) {
 // peeking into the pool in TxPool.Get without having to acquire the widely scoped
 // TxPool.mu mutex.
 type txLookup struct {
-	all   map[common.Hash]*types.Transaction
-	slots int
-	lock  sync.RWMutex
+	all      map[common.Hash]*types.Transaction
+	arrivals map[common.Hash]uint64 // Sequence numbers of the transactions in the order they were added
+	next     uint64                 // Sequence number of the next transaction added
+	slots    int
+	lock     sync.RWMutex
 }
 
 // newTxLookup returns a new txLookup structure.
 func newTxLookup() *txLookup {
 	return &txLookup{
-		all: make(map[common.Hash]*types.Transaction),
+		all:      make(map[common.Hash]*types.Transaction),
+		arrivals: make(map[common.Hash]uint64),
 	}
 }
 
@@ -1563,6 +1810,16 @@ func (t *txLookup) Count() int {
 	return len(t.all)
 }
 
+// Arrival returns the sequence number of a transaction in the order transactions
+// were added to the lookup, and whether it exists in the lookup.
+func (t *txLookup) Arrival(hash common.Hash) (uint64, bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	seq, ok := t.arrivals[hash]
+	return seq, ok
+}
+
 // Slots returns the current number of slots used in the lookup.
 func (t *txLookup) Slots() int {
 	t.lock.RLock()
@@ -1580,6 +1837,8 @@ func (t *txLookup) Add(tx *types.Transaction) {
 	slotsGauge.Update(int64(t.slots))
 
 	t.all[tx.Hash()] = tx
+	t.arrivals[tx.Hash()] = t.next
+	t.next++
 }
 
 // Remove removes a transaction from the lookup.
@@ -1591,6 +1850,7 @@ func (t *txLookup) Remove(hash common.Hash) {
 	slotsGauge.Update(int64(t.slots))
 
 	delete(t.all, hash)
+	delete(t.arrivals, hash)
 }
 
 // numSlots calculates the number of slots needed for a single transaction.
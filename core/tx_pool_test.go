@@ -17,6 +17,7 @@
 package core
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"errors"
 	"fmt"
@@ -24,6 +25,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"sort"
 	"testing"
 	"time"
 
@@ -356,14 +358,14 @@ func TestTransactionChainFork(t *testing.T) {
 	resetState()
 
 	tx := transaction(0, 100000, key)
-	if _, err := pool.add(tx, false); err != nil {
+	if _, err := pool.add(tx, false, false); err != nil {
 		t.Error("didn't expect error", err)
 	}
 	pool.removeTx(tx.Hash(), true)
 
 	// reset the pool's internal state
 	resetState()
-	if _, err := pool.add(tx, false); err != nil {
+	if _, err := pool.add(tx, false, false); err != nil {
 		t.Error("didn't expect error", err)
 	}
 }
@@ -390,10 +392,10 @@ func TestTransactionDoubleNonce(t *testing.T) {
 	tx3, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(100), 1000000, big.NewInt(1), nil), signer, key)
 
 	// Add the first two transaction, ensure higher priced stays only
-	if replace, err := pool.add(tx1, false); err != nil || replace {
+	if replace, err := pool.add(tx1, false, false); err != nil || replace {
 		t.Errorf("first transaction insert failed (%v) or reported replacement (%v)", err, replace)
 	}
-	if replace, err := pool.add(tx2, false); err != nil || !replace {
+	if replace, err := pool.add(tx2, false, false); err != nil || !replace {
 		t.Errorf("second transaction insert failed (%v) or not reported replacement (%v)", err, replace)
 	}
 	<-pool.requestPromoteExecutables(newAccountSet(signer, addr))
@@ -405,7 +407,7 @@ func TestTransactionDoubleNonce(t *testing.T) {
 	}
 
 	// Add the third transaction and ensure it's not saved (smaller price)
-	pool.add(tx3, false)
+	pool.add(tx3, false, false)
 	<-pool.requestPromoteExecutables(newAccountSet(signer, addr))
 	if pool.pending[addr].Len() != 1 {
 		t.Error("expected 1 pending transactions, got", pool.pending[addr].Len())
@@ -428,7 +430,7 @@ func TestTransactionMissingNonce(t *testing.T) {
 	addr := crypto.PubkeyToAddress(key.PublicKey)
 	pool.currentState.AddBalance(addr, big.NewInt(100000000000000))
 	tx := transaction(1, 100000, key)
-	if _, err := pool.add(tx, false); err != nil {
+	if _, err := pool.add(tx, false, false); err != nil {
 		t.Error("didn't expect error", err)
 	}
 	if len(pool.pending) != 0 {
@@ -1848,6 +1850,117 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+// Tests that remote transactions are persisted into the full pool journal and
+// revalidated against the current head when reloaded.
+func TestTransactionPoolJournaling(t *testing.T) {
+	t.Parallel()
+
+	// Create a temporary file for the journal
+	file, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary journal: %v", err)
+	}
+	journal := file.Name()
+	defer os.Remove(journal)
+
+	// Clean up the temporary file, we only need the path for now
+	file.Close()
+	os.Remove(journal)
+
+	// Create the original pool to inject remote transactions into the journal
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.PoolJournal = journal
+	config.PoolJournalLimit = 4
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	key, _ := crypto.GenerateKey()
+	account := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(account, big.NewInt(1000000000))
+
+	// Add a few executable and a queued transaction
+	for _, nonce := range []uint64{0, 1, 2, 5} {
+		if err := pool.addRemoteSync(transaction(nonce, 100000, key)); err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", nonce, err)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 3 || queued != 1 {
+		t.Fatalf("pool content mismatch: have %d/%d pending/queued, want %d/%d", pending, queued, 3, 1)
+	}
+	// Terminate the old pool, bump the nonce, create a new pool and ensure only the
+	// transactions still valid on top of the new head survive
+	pool.Stop()
+	statedb.SetNonce(account, 1)
+	blockchain = &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	if pending, queued := pool.Stats(); pending != 2 || queued != 1 {
+		t.Fatalf("reloaded pool content mismatch: have %d/%d pending/queued, want %d/%d", pending, queued, 2, 1)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Ensure the journal is capped, preferring executable transactions
+	pool.mu.Lock()
+	pool.config.PoolJournalLimit = 2
+	persisted := pool.persistable()
+	pool.mu.Unlock()
+
+	if txs := persisted[account]; len(txs) != 2 || txs[0].Nonce() != 1 || txs[1].Nonce() != 2 {
+		t.Errorf("persisted transactions mismatch: have %v", txs)
+	}
+}
+
+// Tests that the capped full pool journal retains the same transactions on every
+// rotation, taking the accounts in address order.
+func TestTransactionPoolJournalLimitOrder(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	// Fill the pool with a few executable transactions from multiple accounts
+	addrs := make([]common.Address, 8)
+	for i := range addrs {
+		key, _ := crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(key.PublicKey)
+		pool.currentState.AddBalance(addrs[i], big.NewInt(1000000000))
+
+		for nonce := uint64(0); nonce < 2; nonce++ {
+			if err := pool.addRemoteSync(transaction(nonce, 100000, key)); err != nil {
+				t.Fatalf("account %d: failed to add transaction %d: %v", i, nonce, err)
+			}
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	// Cap the journal mid account and ensure the lowest accounts are retained
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.config.PoolJournalLimit = 5
+	for i := 0; i < 10; i++ {
+		persisted := pool.persistable()
+		if len(persisted) != 3 {
+			t.Fatalf("attempt %d: persisted account count mismatch: have %d, want %d", i, len(persisted), 3)
+		}
+		for j, want := range []int{2, 2, 1} {
+			if txs := persisted[addrs[j]]; len(txs) != want {
+				t.Fatalf("attempt %d: account %d transaction count mismatch: have %d, want %d", i, j, len(txs), want)
+			}
+		}
+	}
+}
+
 // TestTransactionStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestTransactionStatusCheck(t *testing.T) {
@@ -1,9 +1,11 @@
 package types
 
 import (
+	"io"
 	"math/big"
 
 	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/rlp"
 )
 
 type InternalTransaction struct {
@@ -15,6 +17,8 @@ type InternalTransaction struct {
 	Index      uint64
 	Note       string
 	Rejected   bool
+	GasUsed    uint64 // Gas used by the call frame opened by the internal transaction
+	Error      string // Error the call frame failed with, including the revert reason
 }
 
 type InternalTransactions []*InternalTransaction
@@ -26,7 +30,7 @@ func NewInternalTransaction(accountNonce uint64, price *big.Int,
 
 	tx := NewTransaction(accountNonce, recipient, amount, gasLimit, price, payload)
 	var h common.Hash
-	return &InternalTransaction{tx, &sender, h, depth, index, note, false}
+	return &InternalTransaction{tx, &sender, h, depth, index, note, false, 0, ""}
 }
 
 func (self *InternalTransaction) Reject() {
@@ -54,3 +58,72 @@ func (tx *InternalTransaction) Hash() common.Hash {
 	}
 	return rlpHash(data)
 }
+
+// storedInternalTxRLP is the storage encoding of an internal transaction.
+type storedInternalTxRLP struct {
+	AccountNonce uint64
+	Price        *big.Int
+	GasLimit     uint64
+	Sender       common.Address
+	Recipient    common.Address
+	Amount       *big.Int
+	Payload      []byte
+	ParentHash   common.Hash
+	Depth        uint64
+	Index        uint64
+	Note         string
+	Rejected     bool
+	GasUsed      uint64
+	Error        string
+}
+
+// InternalTransactionForStorage is a wrapper around an InternalTransaction that
+// flattens all its fields into an RLP stream for database storage.
+type InternalTransactionForStorage InternalTransaction
+
+// EncodeRLP implements rlp.Encoder, and flattens all content fields of an
+// internal transaction into an RLP stream.
+func (tx *InternalTransactionForStorage) EncodeRLP(w io.Writer) error {
+	return rlp.Encode(w, &storedInternalTxRLP{
+		AccountNonce: tx.Dat.AccountNonce,
+		Price:        tx.Dat.Price,
+		GasLimit:     tx.Dat.GasLimit,
+		Sender:       *tx.Sender,
+		Recipient:    *tx.Dat.Recipient,
+		Amount:       tx.Dat.Amount,
+		Payload:      tx.Dat.Payload,
+		ParentHash:   tx.ParentHash,
+		Depth:        tx.Depth,
+		Index:        tx.Index,
+		Note:         tx.Note,
+		Rejected:     tx.Rejected,
+		GasUsed:      tx.GasUsed,
+		Error:        tx.Error,
+	})
+}
+
+// DecodeRLP implements rlp.Decoder, and loads all the fields of an internal
+// transaction from an RLP stream.
+func (tx *InternalTransactionForStorage) DecodeRLP(s *rlp.Stream) error {
+	var stored storedInternalTxRLP
+	if err := s.Decode(&stored); err != nil {
+		return err
+	}
+	itx := NewInternalTransaction(stored.AccountNonce, stored.Price, stored.GasLimit,
+		stored.Sender, stored.Recipient, stored.Amount, stored.Payload,
+		stored.Depth, stored.Index, stored.Note)
+	itx.ParentHash = stored.ParentHash
+	itx.Rejected = stored.Rejected
+	itx.GasUsed = stored.GasUsed
+	itx.Error = stored.Error
+
+	*tx = InternalTransactionForStorage(*itx)
+	return nil
+}
+
+// TxInternalsForStorage bundles the internal transactions captured during the
+// execution of a single transaction with the error the EVM returned, if any.
+type TxInternalsForStorage struct {
+	VMErr     string
+	Internals []*InternalTransactionForStorage
+}
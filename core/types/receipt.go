@@ -38,6 +38,10 @@ var (
 	receiptStatusSuccessfulRLP = []byte{0x01}
 )
 
+// errEmptyTypedReceipt is returned when decoding a typed receipt envelope which
+// is missing its content.
+var errEmptyTypedReceipt = errors.New("empty typed receipt bytes")
+
 const (
 	// ReceiptStatusFailed is the status code of a transaction if execution failed.
 	ReceiptStatusFailed = uint64(0)
@@ -49,6 +53,7 @@ const (
 // Receipt represents the results of a transaction.
 type Receipt struct {
 	// Consensus fields: These fields are defined by the Yellow Paper
+	Type              uint8  `json:"type,omitempty"`
 	PostState         []byte `json:"root"`
 	Status            uint64 `json:"status"`
 	CumulativeGasUsed uint64 `json:"cumulativeGasUsed" gencodec:"required"`
@@ -69,6 +74,7 @@ type Receipt struct {
 }
 
 type receiptMarshaling struct {
+	Type              hexutil.Uint64
 	PostState         hexutil.Bytes
 	Status            hexutil.Uint64
 	CumulativeGasUsed hexutil.Uint64
@@ -126,22 +132,100 @@ func NewReceipt(root []byte, failed bool, cumulativeGasUsed uint64) *Receipt {
 
 // EncodeRLP implements rlp.Encoder, and flattens the consensus fields of a receipt
 // into an RLP stream. If no post state is present, byzantium fork is assumed.
+// Receipts of typed transactions are wrapped into an RLP string containing their
+// EIP-2718 envelope.
 func (r *Receipt) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, &receiptRLP{r.statusEncoding(), r.CumulativeGasUsed, r.Bloom, r.Logs})
+	data := &receiptRLP{r.statusEncoding(), r.CumulativeGasUsed, r.Bloom, r.Logs}
+	if r.Type == LegacyTxType {
+		return rlp.Encode(w, data)
+	}
+	blob, err := r.encodeTyped(data)
+	if err != nil {
+		return err
+	}
+	return rlp.Encode(w, blob)
+}
+
+// MarshalBinary returns the consensus encoding of the receipt. For legacy
+// receipts this is the RLP encoding, for typed ones the EIP-2718 envelope.
+func (r *Receipt) MarshalBinary() ([]byte, error) {
+	data := &receiptRLP{r.statusEncoding(), r.CumulativeGasUsed, r.Bloom, r.Logs}
+	if r.Type == LegacyTxType {
+		return rlp.EncodeToBytes(data)
+	}
+	return r.encodeTyped(data)
+}
+
+// encodeTyped assembles the EIP-2718 envelope of a typed receipt.
+func (r *Receipt) encodeTyped(data *receiptRLP) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(r.Type)
+	if err := rlp.Encode(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
 // DecodeRLP implements rlp.Decoder, and loads the consensus fields of a receipt
 // from an RLP stream.
 func (r *Receipt) DecodeRLP(s *rlp.Stream) error {
-	var dec receiptRLP
-	if err := s.Decode(&dec); err != nil {
+	kind, _, err := s.Kind()
+	switch {
+	case err != nil:
 		return err
+
+	case kind == rlp.List:
+		var dec receiptRLP
+		if err := s.Decode(&dec); err != nil {
+			return err
+		}
+		r.Type = LegacyTxType
+		return r.setFromRLP(dec)
+
+	default:
+		blob, err := s.Bytes()
+		if err != nil {
+			return err
+		}
+		return r.decodeTyped(blob)
+	}
+}
+
+// UnmarshalBinary decodes the consensus encoding of receipts. It supports both
+// legacy RLP receipts and EIP-2718 typed receipts.
+func (r *Receipt) UnmarshalBinary(b []byte) error {
+	if len(b) > 0 && b[0] > 0x7f {
+		// It's a legacy receipt
+		var dec receiptRLP
+		if err := rlp.DecodeBytes(b, &dec); err != nil {
+			return err
+		}
+		r.Type = LegacyTxType
+		return r.setFromRLP(dec)
 	}
-	if err := r.setStatus(dec.PostStateOrStatus); err != nil {
+	return r.decodeTyped(b)
+}
+
+// decodeTyped decodes a typed receipt envelope.
+func (r *Receipt) decodeTyped(b []byte) error {
+	if len(b) == 0 {
+		return errEmptyTypedReceipt
+	}
+	if b[0] != AccessListTxType {
+		return ErrTxTypeNotSupported
+	}
+	var dec receiptRLP
+	if err := rlp.DecodeBytes(b[1:], &dec); err != nil {
 		return err
 	}
+	r.Type = b[0]
+	return r.setFromRLP(dec)
+}
+
+// setFromRLP sets the consensus fields of the receipt from their decoded form.
+func (r *Receipt) setFromRLP(dec receiptRLP) error {
 	r.CumulativeGasUsed, r.Bloom, r.Logs = dec.CumulativeGasUsed, dec.Bloom, dec.Logs
-	return nil
+	return r.setStatus(dec.PostStateOrStatus)
 }
 
 func (r *Receipt) setStatus(postStateOrStatus []byte) error {
@@ -283,9 +367,10 @@ type Receipts []*Receipt
 // Len returns the number of receipts in this list.
 func (r Receipts) Len() int { return len(r) }
 
-// GetRlp returns the RLP encoding of one receipt from the list.
+// GetRlp returns the consensus encoding of one receipt from the list (the
+// EIP-2718 envelope for typed receipts).
 func (r Receipts) GetRlp(i int) []byte {
-	bytes, err := rlp.EncodeToBytes(r[i])
+	bytes, err := r[i].MarshalBinary()
 	if err != nil {
 		panic(err)
 	}
@@ -302,7 +387,8 @@ func (r Receipts) DeriveFields(config *params.ChainConfig, hash common.Hash, num
 		return errors.New("transaction and receipt count mismatch")
 	}
 	for i := 0; i < len(r); i++ {
-		// The transaction hash can be retrieved from the transaction itself
+		// The transaction type and hash can be retrieved from the transaction itself
+		r[i].Type = txs[i].Type()
 		r[i].TxHash = txs[i].Hash()
 
 		// block location fields
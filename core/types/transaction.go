@@ -17,6 +17,7 @@
 package types
 
 import (
+	"bytes"
 	"container/heap"
 	"errors"
 	"io"
@@ -32,7 +33,17 @@ import (
 //go:generate gencodec -type txdata -field-override txdataMarshaling -out gen_tx_json.go
 
 var (
-	ErrInvalidSig = errors.New("invalid transaction v, r, s values")
+	ErrInvalidSig          = errors.New("invalid transaction v, r, s values")
+	ErrTxTypeNotSupported  = errors.New("transaction type not supported")
+	errEmptyTypedTx        = errors.New("empty typed transaction bytes")
+	errMissingTypedChainID = errors.New("missing chain id in typed transaction")
+	errInvalidTypedYParity = errors.New("invalid y parity in typed transaction")
+)
+
+// Transaction types.
+const (
+	LegacyTxType     = 0x00
+	AccessListTxType = 0x01
 )
 
 type Transaction struct {
@@ -56,11 +67,19 @@ type TxData struct {
 	R *big.Int `json:"r" gencodec:"required"`
 	S *big.Int `json:"s" gencodec:"required"`
 
+	// EIP-2718 typed transaction fields. These are not part of the legacy RLP
+	// encoding, typed transactions being encoded via their own envelope.
+	Type       uint8       `json:"type"                 rlp:"-"`
+	ChainID    *big.Int    `json:"chainId,omitempty"    rlp:"-"`
+	AccessList *AccessList `json:"accessList,omitempty" rlp:"-"`
+
 	// This is only used when marshaling to JSON.
 	Hash *common.Hash `json:"hash" rlp:"-"`
 }
 
 type txdataMarshaling struct {
+	Type         hexutil.Uint64
+	ChainID      *hexutil.Big
 	AccountNonce hexutil.Uint64
 	Price        *hexutil.Big
 	GasLimit     hexutil.Uint64
@@ -103,16 +122,57 @@ func newTransaction(nonce uint64, to *common.Address, amount *big.Int, gasLimit
 	return &Transaction{Dat: d}
 }
 
+// NewAccessListTransaction creates an unsigned EIP-2930 transaction carrying an
+// access list. A nil recipient means contract creation.
+func NewAccessListTransaction(chainID *big.Int, nonce uint64, to *common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, accessList AccessList) *Transaction {
+	tx := newTransaction(nonce, to, amount, gasLimit, gasPrice, data)
+
+	list := make(AccessList, len(accessList))
+	for i, tuple := range accessList {
+		list[i] = AccessTuple{
+			Address:     tuple.Address,
+			StorageKeys: append([]common.Hash{}, tuple.StorageKeys...),
+		}
+	}
+	tx.Dat.Type = AccessListTxType
+	tx.Dat.ChainID = new(big.Int)
+	if chainID != nil {
+		tx.Dat.ChainID.Set(chainID)
+	}
+	tx.Dat.AccessList = &list
+	return tx
+}
+
+// Type returns the EIP-2718 type of the transaction.
+func (tx *Transaction) Type() uint8 {
+	return tx.Dat.Type
+}
+
 // ChainId returns which chain id this transaction was signed for (if at all)
 func (tx *Transaction) ChainId() *big.Int {
+	if tx.Dat.Type != LegacyTxType {
+		return new(big.Int).Set(tx.Dat.ChainID)
+	}
 	return deriveChainId(tx.Dat.V)
 }
 
 // Protected returns whether the transaction is protected from replay protection.
+// Typed transactions always include the chain id, so they are always protected.
 func (tx *Transaction) Protected() bool {
+	if tx.Dat.Type != LegacyTxType {
+		return true
+	}
 	return isProtectedV(tx.Dat.V)
 }
 
+// AccessList returns the access list of the transaction, nil for legacy ones.
+func (tx *Transaction) AccessList() AccessList {
+	if tx.Dat.AccessList == nil {
+		return nil
+	}
+	return *tx.Dat.AccessList
+}
+
 func isProtectedV(V *big.Int) bool {
 	if V.BitLen() <= 8 {
 		v := V.Uint64()
@@ -122,20 +182,137 @@ func isProtectedV(V *big.Int) bool {
 	return true
 }
 
-// EncodeRLP implements rlp.Encoder
+// EncodeRLP implements rlp.Encoder. Legacy transactions are encoded as a plain
+// RLP list, whereas typed ones are wrapped into an RLP string containing their
+// EIP-2718 envelope.
 func (tx *Transaction) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, &tx.Dat)
+	if tx.Dat.Type == LegacyTxType {
+		return rlp.Encode(w, &tx.Dat)
+	}
+	blob, err := tx.encodeTyped()
+	if err != nil {
+		return err
+	}
+	return rlp.Encode(w, blob)
 }
 
 // DecodeRLP implements rlp.Decoder
 func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
-	_, size, _ := s.Kind()
-	err := s.Decode(&tx.Dat)
-	if err == nil {
-		tx.size.Store(common.StorageSize(rlp.ListSize(size)))
+	kind, size, err := s.Kind()
+	switch {
+	case err != nil:
+		return err
+
+	case kind == rlp.List:
+		var dec TxData
+		if err := s.Decode(&dec); err != nil {
+			return err
+		}
+		tx.setDecoded(dec, common.StorageSize(rlp.ListSize(size)))
+		return nil
+
+	default:
+		blob, err := s.Bytes()
+		if err != nil {
+			return err
+		}
+		dec, err := decodeTyped(blob)
+		if err != nil {
+			return err
+		}
+		tx.setDecoded(dec, common.StorageSize(len(blob)))
+		return nil
 	}
+}
 
-	return err
+// MarshalBinary returns the canonical encoding of the transaction. For legacy
+// transactions this is the RLP encoding, for typed ones the EIP-2718 envelope.
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	if tx.Dat.Type == LegacyTxType {
+		return rlp.EncodeToBytes(&tx.Dat)
+	}
+	return tx.encodeTyped()
+}
+
+// UnmarshalBinary decodes the canonical encoding of transactions. It supports
+// both legacy RLP transactions and EIP-2718 typed transactions.
+func (tx *Transaction) UnmarshalBinary(b []byte) error {
+	if len(b) > 0 && b[0] > 0x7f {
+		// It's a legacy transaction
+		var dec TxData
+		if err := rlp.DecodeBytes(b, &dec); err != nil {
+			return err
+		}
+		tx.setDecoded(dec, common.StorageSize(len(b)))
+		return nil
+	}
+	// It's an EIP-2718 typed transaction envelope
+	dec, err := decodeTyped(b)
+	if err != nil {
+		return err
+	}
+	tx.setDecoded(dec, common.StorageSize(len(b)))
+	return nil
+}
+
+// setDecoded sets the inner transaction data and size after decoding.
+func (tx *Transaction) setDecoded(dec TxData, size common.StorageSize) {
+	*tx = Transaction{Dat: dec}
+	tx.size.Store(size)
+}
+
+// encodeTyped assembles the EIP-2718 envelope of a typed transaction, that is
+// the type byte followed by the RLP encoding of the type specific payload.
+func (tx *Transaction) encodeTyped() ([]byte, error) {
+	if tx.Dat.Type != AccessListTxType {
+		return nil, ErrTxTypeNotSupported
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(tx.Dat.Type)
+	if err := rlp.Encode(&buf, &accessListTxRLP{
+		ChainID:      tx.Dat.ChainID,
+		AccountNonce: tx.Dat.AccountNonce,
+		Price:        tx.Dat.Price,
+		GasLimit:     tx.Dat.GasLimit,
+		Recipient:    tx.Dat.Recipient,
+		Amount:       tx.Dat.Amount,
+		Payload:      tx.Dat.Payload,
+		AccessList:   tx.AccessList(),
+		V:            tx.Dat.V,
+		R:            tx.Dat.R,
+		S:            tx.Dat.S,
+	}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeTyped decodes an EIP-2718 typed transaction envelope.
+func decodeTyped(b []byte) (TxData, error) {
+	if len(b) == 0 {
+		return TxData{}, errEmptyTypedTx
+	}
+	if b[0] != AccessListTxType {
+		return TxData{}, ErrTxTypeNotSupported
+	}
+	var dec accessListTxRLP
+	if err := rlp.DecodeBytes(b[1:], &dec); err != nil {
+		return TxData{}, err
+	}
+	return TxData{
+		Type:         b[0],
+		ChainID:      dec.ChainID,
+		AccountNonce: dec.AccountNonce,
+		Price:        dec.Price,
+		GasLimit:     dec.GasLimit,
+		Recipient:    dec.Recipient,
+		Amount:       dec.Amount,
+		Payload:      dec.Payload,
+		AccessList:   &dec.AccessList,
+		V:            dec.V,
+		R:            dec.R,
+		S:            dec.S,
+	}, nil
 }
 
 // MarshalJSON encodes the web3 RPC transaction format.
@@ -153,10 +330,30 @@ func (tx *Transaction) UnmarshalJSON(input []byte) error {
 		return err
 	}
 
+	switch dec.Type {
+	case LegacyTxType:
+		dec.ChainID, dec.AccessList = nil, nil
+
+	case AccessListTxType:
+		if dec.ChainID == nil {
+			return errMissingTypedChainID
+		}
+		if dec.AccessList == nil {
+			dec.AccessList = new(AccessList)
+		}
+
+	default:
+		return ErrTxTypeNotSupported
+	}
 	withSignature := dec.V.Sign() != 0 || dec.R.Sign() != 0 || dec.S.Sign() != 0
 	if withSignature {
 		var V byte
-		if isProtectedV(dec.V) {
+		if dec.Type != LegacyTxType {
+			if dec.V.BitLen() > 1 {
+				return errInvalidTypedYParity
+			}
+			V = byte(dec.V.Uint64())
+		} else if isProtectedV(dec.V) {
 			chainID := deriveChainId(dec.V).Uint64()
 			V = byte(dec.V.Uint64() - 35 - 2*chainID)
 		} else {
@@ -194,25 +391,36 @@ func (tx *Transaction) To() *common.Address {
 	return &to
 }
 
-// Hash hashes the RLP encoding of tx.
+// Hash hashes the RLP encoding of tx, or the EIP-2718 envelope of typed ones.
 // It uniquely identifies the transaction.
 func (tx *Transaction) Hash() common.Hash {
 	if hash := tx.hash.Load(); hash != nil {
 		return hash.(common.Hash)
 	}
-	v := rlpHash(tx)
+	var v common.Hash
+	if tx.Dat.Type == LegacyTxType {
+		v = rlpHash(tx)
+	} else {
+		blob, _ := tx.encodeTyped()
+		v = crypto.Keccak256Hash(blob)
+	}
 	tx.hash.Store(v)
 	return v
 }
 
-// Size returns the true RLP encoded storage size of the transaction, either by
+// Size returns the true encoded storage size of the transaction, either by
 // encoding and returning it, or returning a previsouly cached value.
 func (tx *Transaction) Size() common.StorageSize {
 	if size := tx.size.Load(); size != nil {
 		return size.(common.StorageSize)
 	}
 	c := writeCounter(0)
-	rlp.Encode(&c, &tx.Dat)
+	if tx.Dat.Type == LegacyTxType {
+		rlp.Encode(&c, &tx.Dat)
+	} else {
+		blob, _ := tx.encodeTyped()
+		c = writeCounter(len(blob))
+	}
 	tx.size.Store(common.StorageSize(c))
 	return common.StorageSize(c)
 }
@@ -230,6 +438,7 @@ func (tx *Transaction) AsMessage(s Signer) (Message, error) {
 		to:         tx.Dat.Recipient,
 		amount:     tx.Dat.Amount,
 		data:       tx.Dat.Payload,
+		accessList: tx.AccessList(),
 		checkNonce: true,
 	}
 
@@ -247,6 +456,14 @@ func (tx *Transaction) WithSignature(signer Signer, sig []byte) (*Transaction, e
 	}
 	cpy := &Transaction{Dat: tx.Dat}
 	cpy.Dat.R, cpy.Dat.S, cpy.Dat.V = r, s, v
+
+	// Typed transactions created without a chain ID are signed for the chain of
+	// the signer, so the signed copy needs to carry it too.
+	if tx.Dat.Type == AccessListTxType && (tx.Dat.ChainID == nil || tx.Dat.ChainID.Sign() == 0) {
+		if eip2930, ok := signer.(EIP2930Signer); ok {
+			cpy.Dat.ChainID = new(big.Int).Set(eip2930.chainId)
+		}
+	}
 	return cpy, nil
 }
 
@@ -272,9 +489,10 @@ func (s Transactions) Len() int { return len(s) }
 // Swap swaps the i'th and the j'th element in s.
 func (s Transactions) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 
-// GetRlp implements Rlpable and returns the i'th element of s in rlp.
+// GetRlp implements Rlpable and returns the i'th element of s in its canonical
+// encoding (the EIP-2718 envelope for typed transactions).
 func (s Transactions) GetRlp(i int) []byte {
-	enc, _ := rlp.EncodeToBytes(s[i])
+	enc, _ := s[i].MarshalBinary()
 	return enc
 }
 
@@ -398,10 +616,11 @@ type Message struct {
 	gasLimit   uint64
 	gasPrice   *big.Int
 	data       []byte
+	accessList AccessList
 	checkNonce bool
 }
 
-func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, checkNonce bool) Message {
+func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, accessList AccessList, checkNonce bool) Message {
 	return Message{
 		from:       from,
 		to:         to,
@@ -410,15 +629,17 @@ func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *b
 		gasLimit:   gasLimit,
 		gasPrice:   gasPrice,
 		data:       data,
+		accessList: accessList,
 		checkNonce: checkNonce,
 	}
 }
 
-func (m Message) From() common.Address { return m.from }
-func (m Message) To() *common.Address  { return m.to }
-func (m Message) GasPrice() *big.Int   { return m.gasPrice }
-func (m Message) Value() *big.Int      { return m.amount }
-func (m Message) Gas() uint64          { return m.gasLimit }
-func (m Message) Nonce() uint64        { return m.nonce }
-func (m Message) Data() []byte         { return m.data }
-func (m Message) CheckNonce() bool     { return m.checkNonce }
+func (m Message) From() common.Address   { return m.from }
+func (m Message) To() *common.Address    { return m.to }
+func (m Message) GasPrice() *big.Int     { return m.gasPrice }
+func (m Message) Value() *big.Int        { return m.amount }
+func (m Message) Gas() uint64            { return m.gasLimit }
+func (m Message) Nonce() uint64          { return m.nonce }
+func (m Message) Data() []byte           { return m.data }
+func (m Message) AccessList() AccessList { return m.accessList }
+func (m Message) CheckNonce() bool       { return m.checkNonce }
@@ -42,6 +42,8 @@ type sigCache struct {
 func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
 	var signer Signer
 	switch {
+	case config.IsBerlin(blockNumber):
+		signer = NewEIP2930Signer(config.ChainID)
 	case config.IsEIP155(blockNumber):
 		signer = NewEIP155Signer(config.ChainID)
 	case config.IsHomestead(blockNumber):
@@ -52,6 +54,17 @@ func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
 	return signer
 }
 
+// LatestSignerForChainID returns the 'most permissive' Signer available for the
+// given chain id, accepting all transaction types known to this package. It is
+// meant for code that signs transactions without knowing the current block
+// number. If chainID is nil, only unprotected Homestead transactions are supported.
+func LatestSignerForChainID(chainID *big.Int) Signer {
+	if chainID == nil {
+		return HomesteadSigner{}
+	}
+	return NewEIP2930Signer(chainID)
+}
+
 // SignTx signs the transaction using the given signer and private key
 func SignTx(tx *Transaction, s Signer, prv *ecdsa.PrivateKey) (*Transaction, error) {
 	h := s.Hash(tx)
@@ -102,6 +115,78 @@ type Signer interface {
 	Equal(Signer) bool
 }
 
+// EIP2930Signer implements Signer using the EIP-2930 rules, accepting access
+// list transactions on top of the legacy ones handled by the EIP-155 rules.
+type EIP2930Signer struct{ EIP155Signer }
+
+// NewEIP2930Signer returns a signer accepting EIP-2930 access list transactions,
+// EIP-155 replay protected transactions and legacy Homestead transactions.
+func NewEIP2930Signer(chainId *big.Int) EIP2930Signer {
+	return EIP2930Signer{NewEIP155Signer(chainId)}
+}
+
+func (s EIP2930Signer) Equal(s2 Signer) bool {
+	eip2930, ok := s2.(EIP2930Signer)
+	return ok && eip2930.chainId.Cmp(s.chainId) == 0
+}
+
+func (s EIP2930Signer) Sender(tx *Transaction) (common.Address, error) {
+	switch tx.Type() {
+	case LegacyTxType:
+		return s.EIP155Signer.Sender(tx)
+	case AccessListTxType:
+		// Typed transactions carry the raw y parity (0 or 1) instead of V
+		if tx.ChainId().Cmp(s.chainId) != 0 {
+			return common.Address{}, ErrInvalidChainId
+		}
+		V := new(big.Int).Add(tx.Dat.V, big.NewInt(27))
+		return recoverPlain(s.Hash(tx), tx.Dat.R, tx.Dat.S, V, true)
+	default:
+		return common.Address{}, ErrTxTypeNotSupported
+	}
+}
+
+// SignatureValues returns signature values. This signature
+// needs to be in the [R || S || V] format where V is 0 or 1.
+func (s EIP2930Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	switch tx.Type() {
+	case LegacyTxType:
+		return s.EIP155Signer.SignatureValues(tx, sig)
+	case AccessListTxType:
+		// Check that chain ID of tx matches the signer. We also accept ID zero
+		// here, because it indicates that the chain ID was not specified in the tx.
+		if tx.Dat.ChainID.Sign() != 0 && tx.Dat.ChainID.Cmp(s.chainId) != 0 {
+			return nil, nil, nil, ErrInvalidChainId
+		}
+		R, S, V = decodeSignature(sig)
+		return R, S, V, nil
+	default:
+		return nil, nil, nil, ErrTxTypeNotSupported
+	}
+}
+
+// Hash returns the hash to be signed by the sender.
+// It does not uniquely identify the transaction.
+func (s EIP2930Signer) Hash(tx *Transaction) common.Hash {
+	switch tx.Type() {
+	case AccessListTxType:
+		return prefixedRlpHash(tx.Type(), []interface{}{
+			s.chainId,
+			tx.Dat.AccountNonce,
+			tx.Dat.Price,
+			tx.Dat.GasLimit,
+			tx.Dat.Recipient,
+			tx.Dat.Amount,
+			tx.Dat.Payload,
+			tx.AccessList(),
+		})
+	default:
+		// Legacy transactions and unknown types (which will fail to be signed
+		// or recovered anyway) are hashed via the EIP-155 rules
+		return s.EIP155Signer.Hash(tx)
+	}
+}
+
 // EIP155Transaction implements Signer using the EIP155 rules.
 type EIP155Signer struct {
 	chainId, chainIdMul *big.Int
@@ -125,6 +210,9 @@ func (s EIP155Signer) Equal(s2 Signer) bool {
 var big8 = big.NewInt(8)
 
 func (s EIP155Signer) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
 	if !tx.Protected() {
 		return HomesteadSigner{}.Sender(tx)
 	}
@@ -139,6 +227,9 @@ func (s EIP155Signer) Sender(tx *Transaction) (common.Address, error) {
 // SignatureValues returns signature values. This signature
 // needs to be in the [R || S || V] format where V is 0 or 1.
 func (s EIP155Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	if tx.Type() != LegacyTxType {
+		return nil, nil, nil, ErrTxTypeNotSupported
+	}
 	R, S, V, err = HomesteadSigner{}.SignatureValues(tx, sig)
 	if err != nil {
 		return nil, nil, nil, err
@@ -180,6 +271,9 @@ func (hs HomesteadSigner) SignatureValues(tx *Transaction, sig []byte) (r, s, v
 }
 
 func (hs HomesteadSigner) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
 	return recoverPlain(hs.Hash(tx), tx.Dat.R, tx.Dat.S, tx.Dat.V, true)
 }
 
@@ -193,13 +287,24 @@ func (s FrontierSigner) Equal(s2 Signer) bool {
 // SignatureValues returns signature values. This signature
 // needs to be in the [R || S || V] format where V is 0 or 1.
 func (fs FrontierSigner) SignatureValues(tx *Transaction, sig []byte) (r, s, v *big.Int, err error) {
+	if tx.Type() != LegacyTxType {
+		return nil, nil, nil, ErrTxTypeNotSupported
+	}
+	r, s, v = decodeSignature(sig)
+	v.Add(v, big.NewInt(27))
+	return r, s, v, nil
+}
+
+// decodeSignature splits a [R || S || V] signature into its raw values, with V
+// being the recovery id (0 or 1).
+func decodeSignature(sig []byte) (r, s, v *big.Int) {
 	if len(sig) != crypto.SignatureLength {
 		panic(fmt.Sprintf("wrong size for signature: got %d, want %d", len(sig), crypto.SignatureLength))
 	}
 	r = new(big.Int).SetBytes(sig[:32])
 	s = new(big.Int).SetBytes(sig[32:64])
-	v = new(big.Int).SetBytes([]byte{sig[64] + 27})
-	return r, s, v, nil
+	v = new(big.Int).SetBytes([]byte{sig[64]})
+	return r, s, v
 }
 
 // Hash returns the hash to be signed by the sender.
@@ -216,6 +321,9 @@ func (fs FrontierSigner) Hash(tx *Transaction) common.Hash {
 }
 
 func (fs FrontierSigner) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
 	return recoverPlain(fs.Hash(tx), tx.Dat.R, tx.Dat.S, tx.Dat.V, false)
 }
 
@@ -76,6 +76,27 @@ func TestEIP155ChainId(t *testing.T) {
 	}
 }
 
+func TestEIP2930SigningWithoutChainId(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	signer := NewEIP2930Signer(big.NewInt(18))
+	tx, err := SignTx(NewAccessListTransaction(nil, 0, &addr, new(big.Int), 0, new(big.Int), nil, nil), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.ChainId().Cmp(signer.chainId) != 0 {
+		t.Error("expected chainId to be", signer.chainId, "got", tx.ChainId())
+	}
+	from, err := Sender(signer, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from != addr {
+		t.Errorf("exected from and address to be equal. Got %x want %x", from, addr)
+	}
+}
+
 func TestEIP155SigningVitalik(t *testing.T) {
 	// Test vectors come from http://vitalik.ca/files/eip155_testvec.txt
 	for i, test := range []struct {
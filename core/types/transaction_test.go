@@ -219,3 +219,90 @@ func TestTransactionJSON(t *testing.T) {
 		}
 	}
 }
+
+// Tests that EIP-2930 access list transactions survive the binary, RLP and JSON
+// encodings, and that their hash is taken over the typed envelope.
+func TestAccessListTransactionEncoding(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	var (
+		to     = common.HexToAddress("0x095e7baea6a6c7c4c2dfeb977efac326af552d87")
+		list   = AccessList{{Address: to, StorageKeys: []common.Hash{{0x01}, {0x02}}}}
+		signer = NewEIP2930Signer(big.NewInt(1))
+	)
+	tx, err := SignTx(NewAccessListTransaction(big.NewInt(1), 3, &to, big.NewInt(10), 25000, big.NewInt(1), common.FromHex("5544"), list), signer, key)
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	if tx.Type() != AccessListTxType {
+		t.Fatalf("type mismatch: have %d, want %d", tx.Type(), AccessListTxType)
+	}
+	blob, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to encode transaction: %v", err)
+	}
+	if blob[0] != AccessListTxType {
+		t.Fatalf("envelope type mismatch: have %d, want %d", blob[0], AccessListTxType)
+	}
+	if hash := crypto.Keccak256Hash(blob); tx.Hash() != hash {
+		t.Fatalf("hash mismatch: have %x, want %x", tx.Hash(), hash)
+	}
+	// Decode the binary form and ensure all fields are retained
+	parsed := new(Transaction)
+	if err := parsed.UnmarshalBinary(blob); err != nil {
+		t.Fatalf("failed to decode binary transaction: %v", err)
+	}
+	if parsed.Hash() != tx.Hash() {
+		t.Errorf("binary hash mismatch: have %x, want %x", parsed.Hash(), tx.Hash())
+	}
+	if parsed.ChainId().Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("chain id mismatch: have %d, want 1", parsed.ChainId())
+	}
+	if len(parsed.AccessList()) != 1 || parsed.AccessList().StorageKeys() != 2 {
+		t.Errorf("access list mismatch: have %v, want %v", parsed.AccessList(), list)
+	}
+	if from, err := Sender(signer, parsed); err != nil || from != addr {
+		t.Errorf("sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+	// Embed the transaction into an RLP list, as done within blocks
+	enc, err := rlp.EncodeToBytes(Transactions{tx})
+	if err != nil {
+		t.Fatalf("failed to RLP encode transaction: %v", err)
+	}
+	var txs Transactions
+	if err := rlp.DecodeBytes(enc, &txs); err != nil {
+		t.Fatalf("failed to RLP decode transaction: %v", err)
+	}
+	if len(txs) != 1 || txs[0].Hash() != tx.Hash() {
+		t.Errorf("RLP hash mismatch: have %v, want %x", txs, tx.Hash())
+	}
+	// Round trip the transaction through JSON
+	data, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	var jsonTx *Transaction
+	if err := json.Unmarshal(data, &jsonTx); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	if jsonTx.Hash() != tx.Hash() {
+		t.Errorf("JSON hash mismatch: have %x, want %x", jsonTx.Hash(), tx.Hash())
+	}
+}
+
+// Tests that signers predating EIP-2930 reject typed transactions.
+func TestLegacySignerRejectsTypedTransaction(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	to := common.HexToAddress("0x095e7baea6a6c7c4c2dfeb977efac326af552d87")
+
+	tx, err := SignTx(NewAccessListTransaction(big.NewInt(1), 0, &to, big.NewInt(0), 21000, big.NewInt(1), nil, nil), NewEIP2930Signer(big.NewInt(1)), key)
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	for _, signer := range []Signer{NewEIP155Signer(big.NewInt(1)), HomesteadSigner{}, FrontierSigner{}} {
+		if _, err := Sender(signer, tx); err != ErrTxTypeNotSupported {
+			t.Errorf("%T: error mismatch: have %v, want %v", signer, err, ErrTxTypeNotSupported)
+		}
+	}
+}
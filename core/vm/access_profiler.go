@@ -0,0 +1,202 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/crypto"
+)
+
+// StateAccess is a single account or storage slot touched during execution,
+// along with the details of the first operation accessing it.
+type StateAccess struct {
+	Address common.Address `json:"address"`
+	Slot    *common.Hash   `json:"slot,omitempty"` // Storage key, nil for account accesses
+	Op      string         `json:"op"`             // Opcode of the first access
+	Gas     uint64         `json:"gas"`            // Gas charged by the first access
+}
+
+// ContractAccessCount is the number of state accesses made by a single contract
+// while executing its code.
+type ContractAccessCount struct {
+	StorageReads  uint64 `json:"storageReads"`  // Number of SLOAD operations
+	StorageWrites uint64 `json:"storageWrites"` // Number of SSTORE operations
+	AccountReads  uint64 `json:"accountReads"`  // Number of account reads, including its own balance
+	AccountWrites uint64 `json:"accountWrites"` // Number of value transfers, creations and self-destructs
+}
+
+// AccessProfile is the set of state accessed during execution, split into
+// reads and writes and ordered by first access.
+type AccessProfile struct {
+	Reads     []*StateAccess                          `json:"reads"`
+	Writes    []*StateAccess                          `json:"writes"`
+	Contracts map[common.Address]*ContractAccessCount `json:"contracts"`
+}
+
+// accessKey identifies an account (zero slot, account flag set) or a storage
+// slot within an account.
+type accessKey struct {
+	addr    common.Address
+	slot    common.Hash
+	account bool
+}
+
+// AccessProfiler is an EVM tracer which records every account and storage slot
+// touched by the executed code and implements Tracer.
+//
+// The gas reported for call operations excludes the gas forwarded to the callee,
+// leaving only the access, value transfer and memory expansion costs.
+type AccessProfiler struct {
+	profile AccessProfile
+
+	reads  map[accessKey]struct{}
+	writes map[accessKey]struct{}
+}
+
+// NewAccessProfiler returns a new state access profiler.
+func NewAccessProfiler() *AccessProfiler {
+	return &AccessProfiler{
+		profile: AccessProfile{
+			Reads:     []*StateAccess{},
+			Writes:    []*StateAccess{},
+			Contracts: make(map[common.Address]*ContractAccessCount),
+		},
+		reads:  make(map[accessKey]struct{}),
+		writes: make(map[accessKey]struct{}),
+	}
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (p *AccessProfiler) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureState implements the Tracer interface, recording the state accessed by
+// the operation about to be executed.
+func (p *AccessProfiler) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, rStack *ReturnStack, rData []byte, contract *Contract, depth int, err error) error {
+	// Operations failing before execution never access any state
+	if err != nil {
+		return nil
+	}
+	switch op {
+	case SLOAD:
+		slot := common.Hash(stack.Back(0).Bytes32())
+		p.record(p.reads, &p.profile.Reads, accessKey{addr: contract.Address(), slot: slot}, op, cost)
+		p.counts(contract.Address()).StorageReads++
+
+	case SSTORE:
+		slot := common.Hash(stack.Back(0).Bytes32())
+		p.record(p.writes, &p.profile.Writes, accessKey{addr: contract.Address(), slot: slot}, op, cost)
+		p.counts(contract.Address()).StorageWrites++
+
+	case BALANCE, EXTCODESIZE, EXTCODECOPY, EXTCODEHASH:
+		addr := common.Address(stack.Back(0).Bytes20())
+		p.record(p.reads, &p.profile.Reads, accessKey{addr: addr, account: true}, op, cost)
+		p.counts(contract.Address()).AccountReads++
+
+	case SELFBALANCE:
+		p.record(p.reads, &p.profile.Reads, accessKey{addr: contract.Address(), account: true}, op, cost)
+		p.counts(contract.Address()).AccountReads++
+
+	case SELFDESTRUCT:
+		// The balance is moved to the beneficiary and the contract itself is destroyed
+		beneficiary := common.Address(stack.Back(0).Bytes20())
+		p.record(p.writes, &p.profile.Writes, accessKey{addr: beneficiary, account: true}, op, cost)
+		p.record(p.writes, &p.profile.Writes, accessKey{addr: contract.Address(), account: true}, op, cost)
+		p.counts(contract.Address()).AccountWrites++
+
+	case CREATE, CREATE2:
+		// The created address is derived the same way the EVM does, before the
+		// creator's nonce is bumped by the operation
+		var addr common.Address
+		if op == CREATE {
+			addr = crypto.CreateAddress(contract.Address(), env.StateDB.GetNonce(contract.Address()))
+		} else {
+			var (
+				offset, size = stack.Back(1), stack.Back(2)
+				salt         = stack.Back(3).Bytes32()
+				code         = memory.GetPtr(int64(offset.Uint64()), int64(size.Uint64()))
+			)
+			addr = crypto.CreateAddress2(contract.Address(), salt, crypto.Keccak256(code))
+		}
+		p.record(p.writes, &p.profile.Writes, accessKey{addr: addr, account: true}, op, cost)
+		p.counts(contract.Address()).AccountWrites++
+
+	case CALL, CALLCODE, DELEGATECALL, STATICCALL:
+		var (
+			addr = common.Address(stack.Back(1).Bytes20())
+			key  = accessKey{addr: addr, account: true}
+		)
+		// The dynamic cost of calls includes the gas handed to the callee
+		if cost >= env.callGasTemp {
+			cost -= env.callGasTemp
+		}
+		p.record(p.reads, &p.profile.Reads, key, op, cost)
+		p.counts(contract.Address()).AccountReads++
+
+		if (op == CALL || op == CALLCODE) && stack.Back(2).Sign() != 0 {
+			p.record(p.writes, &p.profile.Writes, key, op, cost)
+			p.counts(contract.Address()).AccountWrites++
+		}
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (p *AccessProfiler) CaptureFault(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, rStack *ReturnStack, contract *Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (p *AccessProfiler) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
+	return nil
+}
+
+// Profile returns the state accesses recorded by the profiler.
+func (p *AccessProfiler) Profile() *AccessProfile {
+	return &p.profile
+}
+
+// record appends a state access to the given list if it's the first one of its
+// kind for the given account or slot.
+func (p *AccessProfiler) record(seen map[accessKey]struct{}, list *[]*StateAccess, key accessKey, op OpCode, cost uint64) {
+	if _, ok := seen[key]; ok {
+		return
+	}
+	seen[key] = struct{}{}
+
+	access := &StateAccess{Address: key.addr, Op: op.String(), Gas: cost}
+	if !key.account {
+		slot := key.slot
+		access.Slot = &slot
+	}
+	*list = append(*list, access)
+}
+
+// counts retrieves the access counters of a contract, creating them if needed.
+func (p *AccessProfiler) counts(addr common.Address) *ContractAccessCount {
+	counts, ok := p.profile.Contracts[addr]
+	if !ok {
+		counts = new(ContractAccessCount)
+		p.profile.Contracts[addr] = counts
+	}
+	return counts
+}
@@ -0,0 +1,142 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/core/rawdb"
+	"github.com/matthieu/go-ethereum/core/state"
+	"github.com/matthieu/go-ethereum/crypto"
+	"github.com/matthieu/go-ethereum/params"
+)
+
+// Tests that the access profiler splits accesses into reads and writes, only
+// reports the first access of each item and counts every one of them.
+func TestAccessProfilerCapture(t *testing.T) {
+	var (
+		env      = NewEVM(Context{}, &dummyStatedb{}, params.TestChainConfig, Config{})
+		profiler = NewAccessProfiler()
+		mem      = NewMemory()
+		rstack   = newReturnStack()
+		contract = NewContract(&dummyContractRef{}, &dummyContractRef{}, new(big.Int), 0)
+		target   = common.HexToAddress("0xdeadbeef")
+	)
+	capture := func(op OpCode, cost uint64, items ...*uint256.Int) {
+		stack := newstack()
+		for i := len(items) - 1; i >= 0; i-- {
+			stack.push(items[i])
+		}
+		profiler.CaptureState(env, 0, op, 0, cost, mem, stack, rstack, nil, contract, 0, nil)
+	}
+	slot := uint256.NewInt().SetUint64(1)
+	capture(SLOAD, 2100, slot)
+	capture(SLOAD, 100, slot)
+	capture(SSTORE, 2900, slot, uint256.NewInt().SetUint64(2))
+	capture(BALANCE, 2600, new(uint256.Int).SetBytes(target.Bytes()))
+
+	// Calls should not report the gas forwarded to the callee
+	env.callGasTemp = 1000
+	capture(CALL, 2600+9000+1000, uint256.NewInt(), new(uint256.Int).SetBytes(target.Bytes()), uint256.NewInt().SetUint64(1))
+
+	profile := profiler.Profile()
+	if len(profile.Reads) != 2 {
+		t.Fatalf("read count mismatch: have %d, want %d", len(profile.Reads), 2)
+	}
+	if read := profile.Reads[0]; read.Slot == nil || *read.Slot != common.BigToHash(big.NewInt(1)) || read.Gas != 2100 {
+		t.Errorf("slot read mismatch: have %+v", read)
+	}
+	if read := profile.Reads[1]; read.Address != target || read.Slot != nil || read.Op != "BALANCE" {
+		t.Errorf("account read mismatch: have %+v", read)
+	}
+	if len(profile.Writes) != 2 {
+		t.Fatalf("write count mismatch: have %d, want %d", len(profile.Writes), 2)
+	}
+	if write := profile.Writes[1]; write.Address != target || write.Op != "CALL" || write.Gas != 2600+9000 {
+		t.Errorf("value transfer mismatch: have %+v", write)
+	}
+	counts := profile.Contracts[contract.Address()]
+	if counts == nil {
+		t.Fatalf("missing access counts for %x", contract.Address())
+	}
+	if *counts != (ContractAccessCount{StorageReads: 2, StorageWrites: 1, AccountReads: 2, AccountWrites: 1}) {
+		t.Errorf("access counts mismatch: have %+v", counts)
+	}
+}
+
+// Tests that the access profiler records the accounts touched by the operations
+// reading the contract's own balance, creating contracts and self-destructing.
+func TestAccessProfilerAccounts(t *testing.T) {
+	var (
+		statedb, _ = state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		env        = NewEVM(Context{}, statedb, params.TestChainConfig, Config{})
+		profiler   = NewAccessProfiler()
+		mem        = NewMemory()
+		rstack     = newReturnStack()
+		contract   = NewContract(&dummyContractRef{}, &dummyContractRef{}, new(big.Int), 0)
+		target     = common.HexToAddress("0xdeadbeef")
+		initcode   = []byte{byte(PUSH1), 0x00, byte(DUP1), byte(RETURN)}
+	)
+	statedb.SetNonce(contract.Address(), 5)
+	mem.Resize(32)
+	mem.Set(0, uint64(len(initcode)), initcode)
+
+	capture := func(op OpCode, cost uint64, items ...*uint256.Int) {
+		stack := newstack()
+		for i := len(items) - 1; i >= 0; i-- {
+			stack.push(items[i])
+		}
+		profiler.CaptureState(env, 0, op, 0, cost, mem, stack, rstack, nil, contract, 0, nil)
+	}
+	salt := uint256.NewInt().SetUint64(7)
+	capture(SELFBALANCE, 5)
+	capture(CREATE, 32000, uint256.NewInt(), uint256.NewInt(), uint256.NewInt().SetUint64(uint64(len(initcode))))
+	capture(CREATE2, 32006, uint256.NewInt(), uint256.NewInt(), uint256.NewInt().SetUint64(uint64(len(initcode))), salt)
+	capture(SELFDESTRUCT, 5000, new(uint256.Int).SetBytes(target.Bytes()))
+
+	profile := profiler.Profile()
+	if len(profile.Reads) != 1 {
+		t.Fatalf("read count mismatch: have %d, want %d", len(profile.Reads), 1)
+	}
+	if read := profile.Reads[0]; read.Address != contract.Address() || read.Slot != nil || read.Op != "SELFBALANCE" {
+		t.Errorf("balance read mismatch: have %+v", read)
+	}
+	want := []StateAccess{
+		{Address: crypto.CreateAddress(contract.Address(), 5), Op: "CREATE", Gas: 32000},
+		{Address: crypto.CreateAddress2(contract.Address(), salt.Bytes32(), crypto.Keccak256(initcode)), Op: "CREATE2", Gas: 32006},
+		{Address: target, Op: "SELFDESTRUCT", Gas: 5000},
+		{Address: contract.Address(), Op: "SELFDESTRUCT", Gas: 5000},
+	}
+	if len(profile.Writes) != len(want) {
+		t.Fatalf("write count mismatch: have %d, want %d", len(profile.Writes), len(want))
+	}
+	for i, write := range profile.Writes {
+		if *write != want[i] {
+			t.Errorf("write %d mismatch: have %+v, want %+v", i, write, want[i])
+		}
+	}
+	counts := profile.Contracts[contract.Address()]
+	if counts == nil {
+		t.Fatalf("missing access counts for %x", contract.Address())
+	}
+	if *counts != (ContractAccessCount{AccountReads: 1, AccountWrites: 3}) {
+		t.Errorf("access counts mismatch: have %+v", counts)
+	}
+}
@@ -17,10 +17,12 @@
 package vm
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/binary"
 	"errors"
 	"math/big"
+	"sort"
 
 	"github.com/matthieu/go-ethereum/common"
 	"github.com/matthieu/go-ethereum/common/math"
@@ -78,6 +80,36 @@ var PrecompiledContractsIstanbul = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{9}): &blake2F{},
 }
 
+// activePrecompiledContracts returns the set of precompiled contracts active
+// under the given chain rules.
+func activePrecompiledContracts(rules params.Rules) map[common.Address]PrecompiledContract {
+	switch {
+	case rules.IsYoloV1:
+		return PrecompiledContractsYoloV1
+	case rules.IsIstanbul:
+		return PrecompiledContractsIstanbul
+	case rules.IsByzantium:
+		return PrecompiledContractsByzantium
+	default:
+		return PrecompiledContractsHomestead
+	}
+}
+
+// ActivePrecompiles returns the addresses of the precompiled contracts active
+// under the given chain rules, sorted by address.
+func ActivePrecompiles(rules params.Rules) []common.Address {
+	contracts := activePrecompiledContracts(rules)
+
+	addrs := make([]common.Address, 0, len(contracts))
+	for addr := range contracts {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	return addrs
+}
+
 // PrecompiledContractsYoloV1 contains the default set of pre-compiled Ethereum
 // contracts used in the Yolo v1 test release.
 var PrecompiledContractsYoloV1 = map[common.Address]PrecompiledContract{
@@ -43,18 +43,7 @@ type (
 )
 
 func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool) {
-	var precompiles map[common.Address]PrecompiledContract
-	switch {
-	case evm.chainRules.IsYoloV1:
-		precompiles = PrecompiledContractsYoloV1
-	case evm.chainRules.IsIstanbul:
-		precompiles = PrecompiledContractsIstanbul
-	case evm.chainRules.IsByzantium:
-		precompiles = PrecompiledContractsByzantium
-	default:
-		precompiles = PrecompiledContractsHomestead
-	}
-	p, ok := precompiles[addr]
+	p, ok := activePrecompiledContracts(evm.chainRules)[addr]
 	return p, ok
 }
 
@@ -127,7 +116,7 @@ type EVM struct {
 	// used throughout the execution of the tx.
 	interpreters []Interpreter
 	interpreter  Interpreter
-	listener     InternalTxListener
+	listener     InternalTxListener // Attached listener, fanning out if there are several
 	// abort is used to abort the EVM calling operations
 	// NOTE: must be set atomically
 	abort int32
@@ -139,6 +128,11 @@ type EVM struct {
 
 // Attachment point for things that are interested in various
 // balance-impacting internal state changes ("transactions").
+//
+// Every frame announced through one of the call or create registrations is
+// closed by a matching RegisterExit once it returns, letting listeners discard
+// the effects of frames that reverted or failed. RegisterExit is also invoked
+// for the outermost frame (depth 0), which is never registered itself.
 type InternalTxListener interface {
 	RegisterCall(nonce uint64, gasPrice *big.Int, gas uint64, srcAddr, dstAddr common.Address, value *big.Int, data []byte, depth uint64)
 	RegisterStaticCall(nonce uint64, gasPrice *big.Int, gas uint64, srcAddr, dstAddr common.Address, data []byte, depth uint64)
@@ -146,6 +140,7 @@ type InternalTxListener interface {
 	RegisterCreate(nonce uint64, gasPrice *big.Int, gas uint64, srcAddr, newContractAddr common.Address, value *big.Int, data []byte, depth uint64)
 	RegisterDelegateCall(nonce uint64, gasPrice *big.Int, gas uint64, callerAddr common.Address, value *big.Int, data []byte, depth uint64)
 	RegisterSuicide(nonce uint64, gasPrice *big.Int, gas uint64, contractAddr, creatorAddr common.Address, remainingValue *big.Int, depth uint64)
+	RegisterExit(depth uint64, gasUsed uint64, ret []byte, err error)
 }
 
 // NewEVM returns a new EVM. The returned EVM is not thread safe and should
@@ -181,14 +176,35 @@ func NewEVM(ctx Context, statedb StateDB, chainConfig *params.ChainConfig, vmCon
 	evm.interpreters = append(evm.interpreters, NewEVMInterpreter(evm, vmConfig))
 	evm.interpreter = evm.interpreters[0]
 
+	for _, factory := range vmConfig.Listeners {
+		evm.AddListener(factory())
+	}
 	return evm
 }
 
-// Replaces the listener with a new one.
-// Trivial to extend this to support arbitrary numbers of listeners,
-// if desired
+// AddListener attaches an additional listener to the EVM. All the attached
+// listeners are notified, in the order they were added.
 func (evm *EVM) AddListener(txl InternalTxListener) {
-	evm.listener = txl
+	switch listener := evm.listener.(type) {
+	case nil:
+		evm.listener = txl
+	case internalTxListeners:
+		evm.listener = append(listener, txl)
+	default:
+		evm.listener = internalTxListeners{listener, txl}
+	}
+}
+
+// Listeners returns the listeners attached to the EVM.
+func (evm *EVM) Listeners() []InternalTxListener {
+	switch listener := evm.listener.(type) {
+	case nil:
+		return nil
+	case internalTxListeners:
+		return listener
+	default:
+		return []InternalTxListener{listener}
+	}
 }
 
 // Cancel cancels any running EVM operation. This may be called concurrently and
@@ -239,10 +255,15 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	}
 	evm.Transfer(evm.StateDB, caller.Address(), addr, value)
 
-	if evm.listener != nil && evm.depth > 0 {
-		evm.listener.RegisterCall(evm.StateDB.GetNonce(caller.Address()),
-			evm.Context.GasPrice, gas, caller.Address(), addr, value,
-			evm.StateDB.GetCode(addr), uint64(evm.depth))
+	if evm.listener != nil {
+		if evm.depth > 0 {
+			evm.listener.RegisterCall(evm.StateDB.GetNonce(caller.Address()),
+				evm.Context.GasPrice, gas, caller.Address(), addr, value,
+				evm.StateDB.GetCode(addr), uint64(evm.depth))
+		}
+		defer func(startGas uint64) { // Lazy evaluation of the parameters
+			evm.listener.RegisterExit(uint64(evm.depth), startGas-gas, ret, err)
+		}(gas)
 	}
 
 	// Capture the tracer start/end events in debug mode
@@ -325,6 +346,9 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 			evm.listener.RegisterCallCode(evm.StateDB.GetNonce(caller.Address()),
 				evm.Context.GasPrice, gas, caller.Address(), value,
 				evm.StateDB.GetCode(addr), uint64(evm.depth))
+			defer func(startGas uint64) {
+				evm.listener.RegisterExit(uint64(evm.depth), startGas-gas, ret, err)
+			}(gas)
 		}
 
 		ret, err = run(evm, contract, input, false)
@@ -367,6 +391,9 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 			evm.listener.RegisterDelegateCall(evm.StateDB.GetNonce(caller.Address()),
 				evm.Context.GasPrice, gas, caller.Address(), contract.Value(),
 				evm.StateDB.GetCode(addr), uint64(evm.depth))
+			defer func(startGas uint64) {
+				evm.listener.RegisterExit(uint64(evm.depth), startGas-gas, ret, err)
+			}(gas)
 		}
 
 		ret, err = run(evm, contract, input, false)
@@ -423,6 +450,9 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 				evm.StateDB.GetNonce(caller.Address()),
 				evm.Context.GasPrice, gas, caller.Address(), addr,
 				evm.StateDB.GetCode(addr), uint64(evm.depth))
+			defer func(startGas uint64) {
+				evm.listener.RegisterExit(uint64(evm.depth), startGas-gas, ret, err)
+			}(gas)
 		}
 
 		// When an error was returned by the EVM or when setting the creation code
@@ -466,6 +496,11 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	nonce := evm.StateDB.GetNonce(caller.Address())
 	evm.StateDB.SetNonce(caller.Address(), nonce+1)
 
+	// We add this to the access list _before_ taking a snapshot. Even if the creation fails,
+	// the access-list change should not be rolled back
+	if evm.chainRules.IsBerlin {
+		evm.StateDB.AddAddressToAccessList(address)
+	}
 	// Ensure there's no existing contract already at the designated address
 	contractHash := evm.StateDB.GetCodeHash(address)
 	if evm.StateDB.GetNonce(address) != 0 || (contractHash != (common.Hash{}) && contractHash != emptyCodeHash) {
@@ -488,6 +523,9 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	contract.SetCodeOptionalHash(&address, codeAndHash)
 
 	if evm.vmConfig.NoRecursion && evm.depth > 0 {
+		if evm.listener != nil {
+			evm.listener.RegisterExit(uint64(evm.depth), 0, nil, nil)
+		}
 		return nil, address, gas, nil
 	}
 
@@ -529,6 +567,9 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	if evm.vmConfig.Debug && evm.depth == 0 {
 		evm.vmConfig.Tracer.CaptureEnd(ret, gas-contract.Gas, time.Since(start), err)
 	}
+	if evm.listener != nil {
+		evm.listener.RegisterExit(uint64(evm.depth), gas-contract.Gas, ret, err)
+	}
 	return ret, address, contract.Gas, err
 
 }
@@ -38,6 +38,8 @@ type Config struct {
 	EVMInterpreter   string // External EVM interpreter options
 
 	ExtraEips []int // Additional EIPS that are to be enabled
+
+	Listeners []InternalTxListenerFactory // Internal transaction listeners attached to every new EVM
 }
 
 // Interpreter is used to run Ethereum based contracts and will utilise the
@@ -99,6 +101,10 @@ func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
 	if cfg.JumpTable[STOP] == nil {
 		var jt JumpTable
 		switch {
+		case evm.chainRules.IsYoloV1 && evm.chainRules.IsBerlin:
+			jt = yoloV1BerlinInstructionSet
+		case evm.chainRules.IsBerlin:
+			jt = berlinInstructionSet
 		case evm.chainRules.IsYoloV1:
 			jt = yoloV1InstructionSet
 		case evm.chainRules.IsIstanbul:
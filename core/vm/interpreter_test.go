@@ -0,0 +1,64 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/core/rawdb"
+	"github.com/matthieu/go-ethereum/core/state"
+	"github.com/matthieu/go-ethereum/params"
+)
+
+// Tests that chains running both YOLOv1 and Berlin execute subroutines, with the
+// storage accesses charged the EIP-2929 gas costs.
+func TestInterpreterYoloV1Berlin(t *testing.T) {
+	config := *params.AllEthashProtocolChanges
+	config.YoloV1Block = big.NewInt(0)
+	config.BerlinBlock = big.NewInt(0)
+
+	address := common.BytesToAddress([]byte("contract"))
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.CreateAccount(address)
+	statedb.SetCode(address, []byte{
+		byte(PUSH1), 0x04,
+		byte(JUMPSUB),
+		byte(STOP),
+		byte(BEGINSUB),
+		byte(PUSH1), 0x00,
+		byte(SLOAD),
+		byte(POP),
+		byte(RETURNSUB),
+	})
+	vmctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: big.NewInt(0),
+	}
+	vmenv := NewEVM(vmctx, statedb, &config, Config{})
+
+	_, gas, err := vmenv.Call(AccountRef(common.Address{}), address, nil, 100000, new(big.Int))
+	if err != nil {
+		t.Fatalf("subroutine call failed: %v", err)
+	}
+	// PUSH1, JUMPSUB (landing past BEGINSUB), PUSH1, cold SLOAD, POP, RETURNSUB
+	if used, want := 100000-gas, uint64(3+10+3+params.ColdSloadCostEIP2929+2+5); used != want {
+		t.Errorf("gas used mismatch: have %d, want %d", used, want)
+	}
+}
@@ -57,11 +57,33 @@ var (
 	constantinopleInstructionSet   = newConstantinopleInstructionSet()
 	istanbulInstructionSet         = newIstanbulInstructionSet()
 	yoloV1InstructionSet           = newYoloV1InstructionSet()
+	berlinInstructionSet           = newBerlinInstructionSet()
+	yoloV1BerlinInstructionSet     = newYoloV1BerlinInstructionSet()
 )
 
 // JumpTable contains the EVM opcodes supported at a given fork.
 type JumpTable [256]*operation
 
+// newBerlinInstructionSet returns the frontier, homestead, byzantium,
+// contantinople, istanbul and berlin instructions.
+func newBerlinInstructionSet() JumpTable {
+	instructionSet := newIstanbulInstructionSet()
+
+	enable2929(&instructionSet) // Access lists for trie accesses https://eips.ethereum.org/EIPS/eip-2929
+
+	return instructionSet
+}
+
+// newYoloV1BerlinInstructionSet returns the berlin instructions, extended with
+// the subroutines of YOLOv1 for chains running both forks.
+func newYoloV1BerlinInstructionSet() JumpTable {
+	instructionSet := newBerlinInstructionSet()
+
+	enable2315(&instructionSet) // Subroutines - https://eips.ethereum.org/EIPS/eip-2315
+
+	return instructionSet
+}
+
 func newYoloV1InstructionSet() JumpTable {
 	instructionSet := newIstanbulInstructionSet()
 
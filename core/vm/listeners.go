@@ -0,0 +1,73 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+
+	"github.com/matthieu/go-ethereum/common"
+)
+
+// InternalTxListenerFactory creates a listener to be attached to a new EVM.
+// Listeners are generally stateful, so a fresh one is created for every EVM.
+type InternalTxListenerFactory func() InternalTxListener
+
+// internalTxListeners fans the notifications of an EVM out to several
+// listeners, in the order they were attached.
+type internalTxListeners []InternalTxListener
+
+func (ls internalTxListeners) RegisterCall(nonce uint64, gasPrice *big.Int, gas uint64, srcAddr, dstAddr common.Address, value *big.Int, data []byte, depth uint64) {
+	for _, l := range ls {
+		l.RegisterCall(nonce, gasPrice, gas, srcAddr, dstAddr, value, data, depth)
+	}
+}
+
+func (ls internalTxListeners) RegisterStaticCall(nonce uint64, gasPrice *big.Int, gas uint64, srcAddr, dstAddr common.Address, data []byte, depth uint64) {
+	for _, l := range ls {
+		l.RegisterStaticCall(nonce, gasPrice, gas, srcAddr, dstAddr, data, depth)
+	}
+}
+
+func (ls internalTxListeners) RegisterCallCode(nonce uint64, gasPrice *big.Int, gas uint64, contractAddr common.Address, value *big.Int, data []byte, depth uint64) {
+	for _, l := range ls {
+		l.RegisterCallCode(nonce, gasPrice, gas, contractAddr, value, data, depth)
+	}
+}
+
+func (ls internalTxListeners) RegisterCreate(nonce uint64, gasPrice *big.Int, gas uint64, srcAddr, newContractAddr common.Address, value *big.Int, data []byte, depth uint64) {
+	for _, l := range ls {
+		l.RegisterCreate(nonce, gasPrice, gas, srcAddr, newContractAddr, value, data, depth)
+	}
+}
+
+func (ls internalTxListeners) RegisterDelegateCall(nonce uint64, gasPrice *big.Int, gas uint64, callerAddr common.Address, value *big.Int, data []byte, depth uint64) {
+	for _, l := range ls {
+		l.RegisterDelegateCall(nonce, gasPrice, gas, callerAddr, value, data, depth)
+	}
+}
+
+func (ls internalTxListeners) RegisterSuicide(nonce uint64, gasPrice *big.Int, gas uint64, contractAddr, creatorAddr common.Address, remainingValue *big.Int, depth uint64) {
+	for _, l := range ls {
+		l.RegisterSuicide(nonce, gasPrice, gas, contractAddr, creatorAddr, remainingValue, depth)
+	}
+}
+
+func (ls internalTxListeners) RegisterExit(depth uint64, gasUsed uint64, ret []byte, err error) {
+	for _, l := range ls {
+		l.RegisterExit(depth, gasUsed, ret, err)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"math/big"
+	"time"
 
 	"github.com/matthieu/go-ethereum/accounts"
 	"github.com/matthieu/go-ethereum/common"
@@ -173,6 +174,29 @@ func (b *EthAPIBackend) GetReceipts(ctx context.Context, hash common.Hash) (type
 	return b.eth.blockchain.GetReceiptsByHash(hash), nil
 }
 
+func (b *EthAPIBackend) GetInternalTxs(ctx context.Context, hash common.Hash) ([]types.InternalTransactions, error) {
+	number := rawdb.ReadHeaderNumber(b.eth.chainDb, hash)
+	if number == nil {
+		return nil, nil
+	}
+	if internals, _ := rawdb.ReadInternalTxs(b.eth.chainDb, hash, *number); internals != nil {
+		return internals, nil
+	}
+	block := b.eth.blockchain.GetBlock(hash, *number)
+	if block == nil {
+		return nil, nil
+	}
+	internals, _, err := b.eth.internalTxsAtBlock(block, defaultTraceReexec)
+	return internals, err
+}
+
+func (b *EthAPIBackend) GetInternalTxAddrEntries(ctx context.Context, address common.Address, number uint64, txIndex, index uint32, limit int) ([]rawdb.InternalTxAddrEntry, error) {
+	if b.eth.internalTxIndexer == nil {
+		return nil, errors.New("internal transaction address index is disabled")
+	}
+	return rawdb.ReadInternalTxAddrEntries(b.eth.chainDb, address, number, txIndex, index, limit), nil
+}
+
 func (b *EthAPIBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types.Log, error) {
 	receipts := b.eth.blockchain.GetReceiptsByHash(hash)
 	if receipts == nil {
@@ -200,6 +224,10 @@ func (b *EthAPIBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEven
 	return b.eth.BlockChain().SubscribeRemovedLogsEvent(ch)
 }
 
+func (b *EthAPIBackend) SubscribeInternalTxsEvent(ch chan<- core.InternalTxsEvent) event.Subscription {
+	return b.eth.BlockChain().SubscribeInternalTxsEvent(ch)
+}
+
 func (b *EthAPIBackend) SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	return b.eth.miner.SubscribePendingLogs(ch)
 }
@@ -257,6 +285,10 @@ func (b *EthAPIBackend) TxPoolContent() (map[common.Address]types.Transactions,
 	return b.eth.TxPool().Content()
 }
 
+func (b *EthAPIBackend) TxPoolRejections() []*core.TxRejection {
+	return b.eth.TxPool().Rejections()
+}
+
 func (b *EthAPIBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.eth.TxPool().SubscribeNewTxsEvent(ch)
 }
@@ -293,6 +325,10 @@ func (b *EthAPIBackend) RPCGasCap() uint64 {
 	return b.eth.config.RPCGasCap
 }
 
+func (b *EthAPIBackend) RPCBundleTimeout() time.Duration {
+	return b.eth.config.RPCBundleTimeout
+}
+
 func (b *EthAPIBackend) RPCTxFeeCap() float64 {
 	return b.eth.config.RPCTxFeeCap
 }
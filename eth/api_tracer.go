@@ -62,6 +62,16 @@ type TraceConfig struct {
 	Reexec  *uint64
 }
 
+// TraceCallConfig holds extra parameters to the call trace function, on top of
+// the ones of TraceConfig.
+type TraceCallConfig struct {
+	*vm.LogConfig
+	Tracer         *string
+	Timeout        *string
+	Reexec         *uint64
+	StateOverrides *ethapi.StateOverride
+}
+
 // StdTraceConfig holds extra parameters to standard-json trace functions.
 type StdTraceConfig struct {
 	*vm.LogConfig
@@ -634,69 +644,7 @@ func containsTx(block *types.Block, hash common.Hash) bool {
 // If no state is locally available for the given block, a number of blocks are
 // attempted to be reexecuted to generate the desired state.
 func (api *PrivateDebugAPI) computeStateDB(block *types.Block, reexec uint64) (*state.StateDB, error) {
-	// If we have the state fully available, use that
-	statedb, err := api.eth.blockchain.StateAt(block.Root())
-	if err == nil {
-		return statedb, nil
-	}
-	// Otherwise try to reexec blocks until we find a state or reach our limit
-	origin := block.NumberU64()
-	database := state.NewDatabaseWithCache(api.eth.ChainDb(), 16)
-
-	for i := uint64(0); i < reexec; i++ {
-		block = api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
-		if block == nil {
-			break
-		}
-		if statedb, err = state.New(block.Root(), database, nil); err == nil {
-			break
-		}
-	}
-	if err != nil {
-		switch err.(type) {
-		case *trie.MissingNodeError:
-			return nil, fmt.Errorf("required historical state unavailable (reexec=%d)", reexec)
-		default:
-			return nil, err
-		}
-	}
-	// State was available at historical point, regenerate
-	var (
-		start  = time.Now()
-		logged time.Time
-		proot  common.Hash
-	)
-	for block.NumberU64() < origin {
-		// Print progress logs if long enough time elapsed
-		if time.Since(logged) > 8*time.Second {
-			log.Info("Regenerating historical state", "block", block.NumberU64()+1, "target", origin, "remaining", origin-block.NumberU64()-1, "elapsed", time.Since(start))
-			logged = time.Now()
-		}
-		// Retrieve the next block to regenerate and process it
-		if block = api.eth.blockchain.GetBlockByNumber(block.NumberU64() + 1); block == nil {
-			return nil, fmt.Errorf("block #%d not found", block.NumberU64()+1)
-		}
-		_, _, _, _, _, err := api.eth.blockchain.Processor().Process(block, statedb, vm.Config{})
-		if err != nil {
-			return nil, fmt.Errorf("processing block %d failed: %v", block.NumberU64(), err)
-		}
-		// Finalize the state so any modifications are written to the trie
-		root, err := statedb.Commit(api.eth.blockchain.Config().IsEIP158(block.Number()))
-		if err != nil {
-			return nil, err
-		}
-		if err := statedb.Reset(root); err != nil {
-			return nil, fmt.Errorf("state reset after block %d failed: %v", block.NumberU64(), err)
-		}
-		database.TrieDB().Reference(root, common.Hash{})
-		if proot != (common.Hash{}) {
-			database.TrieDB().Dereference(proot)
-		}
-		proot = root
-	}
-	nodes, imgs := database.TrieDB().Size()
-	log.Info("Historical state regenerated", "block", block.NumberU64(), "elapsed", time.Since(start), "nodes", nodes, "preimages", imgs)
-	return statedb, nil
+	return api.eth.stateAtBlock(block, reexec)
 }
 
 // TraceTransaction returns the structured logs created during the execution of EVM
@@ -719,11 +667,124 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 	return api.traceTx(ctx, msg, vmctx, statedb, config)
 }
 
+// TraceCall lets you trace a given eth_call. It collects the structured logs
+// created during the execution of EVM if the given transaction was added on
+// top of the provided block and returns them as a JSON object. The state can
+// be tweaked with overrides before executing the call, the same way it can be
+// for eth_call.
+func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args ethapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (interface{}, error) {
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	// Retrieve the block and the state the call should be executed on top of
+	block, statedb, err := api.callStateAt(blockNrOrHash, reexec)
+	if err != nil {
+		return nil, err
+	}
+	// Apply the customized state rules if required
+	var traceConfig *TraceConfig
+	if config != nil {
+		if err := config.StateOverrides.Apply(statedb); err != nil {
+			return nil, err
+		}
+		traceConfig = &TraceConfig{
+			LogConfig: config.LogConfig,
+			Tracer:    config.Tracer,
+			Timeout:   config.Timeout,
+			Reexec:    config.Reexec,
+		}
+	}
+	// Execute the call on top of the block and trace it
+	msg := args.ToMessage(api.eth.config.RPCGasCap)
+	vmctx := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
+
+	return api.traceTx(ctx, msg, vmctx, statedb, traceConfig)
+}
+
+// AccessProfileResult is the result of profiling the state accesses of a call.
+type AccessProfileResult struct {
+	Gas    uint64 `json:"gas"`
+	Failed bool   `json:"failed"`
+	*vm.AccessProfile
+}
+
+// AccessProfile executes the given eth_call on top of the provided block and
+// reports every account and storage slot touched by it, split into reads and
+// writes, along with the gas charged for the first access of each and the
+// number of accesses made by every contract.
+func (api *PrivateDebugAPI) AccessProfile(ctx context.Context, args ethapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash) (*AccessProfileResult, error) {
+	block, statedb, err := api.callStateAt(blockNrOrHash, defaultTraceReexec)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		msg      = args.ToMessage(api.eth.config.RPCGasCap)
+		vmctx    = core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
+		profiler = vm.NewAccessProfiler()
+		vmenv    = vm.NewEVM(vmctx, statedb, api.eth.blockchain.Config(), vm.Config{Debug: true, Tracer: profiler})
+	)
+	// Abort the execution if it takes too long or the RPC call is cancelled
+	deadlineCtx, cancel := context.WithTimeout(ctx, defaultTraceTimeout)
+	defer cancel()
+
+	go func() {
+		<-deadlineCtx.Done()
+		vmenv.Cancel()
+	}()
+	result, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas()))
+	if err != nil {
+		return nil, fmt.Errorf("profiling failed: %v", err)
+	}
+	if vmenv.Cancelled() {
+		return nil, fmt.Errorf("execution aborted (timeout = %v)", defaultTraceTimeout)
+	}
+	return &AccessProfileResult{
+		Gas:           result.UsedGas,
+		Failed:        result.Failed(),
+		AccessProfile: profiler.Profile(),
+	}, nil
+}
+
+// callStateAt retrieves the block and a mutable copy of its state, on top of
+// which a call can be executed.
+func (api *PrivateDebugAPI) callStateAt(blockNrOrHash rpc.BlockNumberOrHash, reexec uint64) (*types.Block, *state.StateDB, error) {
+	var (
+		block   *types.Block
+		statedb *state.StateDB
+		err     error
+	)
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		if block = api.eth.blockchain.GetBlockByHash(hash); block == nil {
+			return nil, nil, fmt.Errorf("block %#x not found", hash)
+		}
+	} else {
+		number, _ := blockNrOrHash.Number()
+		switch number {
+		case rpc.PendingBlockNumber:
+			block, statedb = api.eth.miner.Pending()
+		case rpc.LatestBlockNumber:
+			block = api.eth.blockchain.CurrentBlock()
+		default:
+			block = api.eth.blockchain.GetBlockByNumber(uint64(number))
+		}
+		if block == nil {
+			return nil, nil, fmt.Errorf("block #%d not found", number)
+		}
+	}
+	if statedb == nil {
+		if statedb, err = api.computeStateDB(block, reexec); err != nil {
+			return nil, nil, err
+		}
+	}
+	return block, statedb, nil
+}
+
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
 // be tracer dependent.
 func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, vmctx vm.Context, statedb *state.StateDB, config *TraceConfig) (interface{}, error) {
-	// Assemble the structured logger or the JavaScript tracer
+	// Assemble the structured logger or the JavaScript or native tracer
 	var (
 		tracer vm.Tracer
 		err    error
@@ -737,15 +798,18 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 				return nil, err
 			}
 		}
-		// Constuct the JavaScript tracer to execute with
-		if tracer, err = tracers.New(*config.Tracer); err != nil {
+		// Constuct the JavaScript or native tracer to execute with
+		txTracer, err := tracers.NewTxTracer(*config.Tracer)
+		if err != nil {
 			return nil, err
 		}
+		tracer = txTracer
+
 		// Handle timeouts and RPC cancellations
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
 			<-deadlineCtx.Done()
-			tracer.(*tracers.Tracer).Stop(errors.New("execution timeout"))
+			txTracer.Stop(errors.New("execution timeout"))
 		}()
 		defer cancel()
 
@@ -777,7 +841,7 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 			StructLogs:  ethapi.FormatLogs(tracer.StructLogs()),
 		}, nil
 
-	case *tracers.Tracer:
+	case tracers.TxTracer:
 		return tracer.GetResult()
 
 	default:
@@ -0,0 +1,187 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/common/hexutil"
+	"github.com/matthieu/go-ethereum/consensus/ethash"
+	"github.com/matthieu/go-ethereum/core"
+	"github.com/matthieu/go-ethereum/core/rawdb"
+	"github.com/matthieu/go-ethereum/core/types"
+	"github.com/matthieu/go-ethereum/core/vm"
+	"github.com/matthieu/go-ethereum/crypto"
+	"github.com/matthieu/go-ethereum/internal/ethapi"
+	"github.com/matthieu/go-ethereum/params"
+	"github.com/matthieu/go-ethereum/rpc"
+)
+
+var (
+	// traceCallBalance returns the balance of the caller.
+	traceCallBalance = common.HexToAddress("0xb1")
+	// traceCallStorage returns the sum of its storage slots 0 and 1.
+	traceCallStorage = common.HexToAddress("0xb2")
+	// traceCallCreate creates an empty contract and returns its address.
+	traceCallCreate = common.HexToAddress("0xb3")
+
+	traceCallUser = common.HexToAddress("0xb4")
+)
+
+// newTraceCallTestAPI creates a debug API on top of a chain of the given length,
+// in which every block transfers 1000 wei from the test bank to the user.
+func newTraceCallTestAPI(t *testing.T, blocks int) *PrivateDebugAPI {
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		gspec = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				testBank:         {Balance: big.NewInt(1000000)},
+				traceCallBalance: {Balance: new(big.Int), Code: hexutil.MustDecode("0x333160005260206000f3")},
+				traceCallStorage: {
+					Balance: new(big.Int),
+					Code:    hexutil.MustDecode("0x6000546001540160005260206000f3"),
+					Storage: map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(1)), {31: 1}: common.BigToHash(big.NewInt(2))},
+				},
+				traceCallCreate: {Balance: new(big.Int), Code: hexutil.MustDecode("0x60008080f060005260206000f3")},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	chain, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, blocks, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(testBank), traceCallUser, big.NewInt(1000), params.TxGas, nil, nil), signer, testBankKey)
+		b.AddTx(tx)
+	})
+	blockchain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	return NewPrivateDebugAPI(&Ethereum{config: &Config{}, chainDb: db, blockchain: blockchain})
+}
+
+// traceCallReturn traces a call with the struct logger, returning its output.
+func traceCallReturn(t *testing.T, api *PrivateDebugAPI, args ethapi.CallArgs, block rpc.BlockNumber, overrides *ethapi.StateOverride) *big.Int {
+	var config *TraceCallConfig
+	if overrides != nil {
+		config = &TraceCallConfig{StateOverrides: overrides}
+	}
+	res, err := api.TraceCall(context.Background(), args, rpc.BlockNumberOrHashWithNumber(block), config)
+	if err != nil {
+		t.Fatalf("failed to trace call: %v", err)
+	}
+	result := res.(*ethapi.ExecutionResult)
+	if result.Failed {
+		t.Fatalf("traced call failed")
+	}
+	ret, ok := new(big.Int).SetString(result.ReturnValue, 16)
+	if !ok {
+		t.Fatalf("invalid return value %q", result.ReturnValue)
+	}
+	return ret
+}
+
+// Tests that calls are traced on top of the state of the requested block.
+func TestTraceCallHistoricalBlock(t *testing.T) {
+	api := newTraceCallTestAPI(t, 3)
+	args := ethapi.CallArgs{From: &traceCallUser, To: &traceCallBalance}
+
+	for block, want := range []int64{0, 1000, 2000, 3000} {
+		if have := traceCallReturn(t, api, args, rpc.BlockNumber(block), nil); have.Int64() != want {
+			t.Errorf("block %d: balance mismatch: have %d, want %d", block, have, want)
+		}
+	}
+	if have := traceCallReturn(t, api, args, rpc.LatestBlockNumber, nil); have.Int64() != 3000 {
+		t.Errorf("latest block: balance mismatch: have %d, want %d", have, 3000)
+	}
+	if _, err := api.TraceCall(context.Background(), args, rpc.BlockNumberOrHashWithNumber(4), nil); err == nil {
+		t.Errorf("traced call on top of unknown block")
+	}
+}
+
+// Tests that the state overrides are applied before the traced call is executed.
+func TestTraceCallStateOverrides(t *testing.T) {
+	api := newTraceCallTestAPI(t, 1)
+
+	var (
+		balance = (*hexutil.Big)(big.NewInt(12345))
+		nonce   = hexutil.Uint64(5)
+		code    = hexutil.Bytes(hexutil.MustDecode("0x333160005260206000f3"))
+		slot0   = map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(5))}
+		custom  = common.HexToAddress("0xc0de")
+	)
+	tests := []struct {
+		name      string
+		to        common.Address
+		overrides ethapi.StateOverride
+		want      *big.Int
+	}{
+		{
+			name:      "balance",
+			to:        traceCallBalance,
+			overrides: ethapi.StateOverride{traceCallUser: {Balance: &balance}},
+			want:      big.NewInt(12345),
+		},
+		{
+			name:      "code",
+			to:        custom,
+			overrides: ethapi.StateOverride{custom: {Code: &code}},
+			want:      big.NewInt(1000),
+		},
+		{
+			name:      "nonce",
+			to:        traceCallCreate,
+			overrides: ethapi.StateOverride{traceCallCreate: {Nonce: &nonce}},
+			want:      new(big.Int).SetBytes(crypto.CreateAddress(traceCallCreate, 5).Bytes()),
+		},
+		{
+			name:      "state",
+			to:        traceCallStorage,
+			overrides: ethapi.StateOverride{traceCallStorage: {State: &slot0}},
+			want:      big.NewInt(5),
+		},
+		{
+			name:      "stateDiff",
+			to:        traceCallStorage,
+			overrides: ethapi.StateOverride{traceCallStorage: {StateDiff: &slot0}},
+			want:      big.NewInt(7),
+		},
+	}
+	for _, tt := range tests {
+		args := ethapi.CallArgs{From: &traceCallUser, To: &tt.to}
+		if have := traceCallReturn(t, api, args, rpc.LatestBlockNumber, &tt.overrides); have.Cmp(tt.want) != 0 {
+			t.Errorf("%s: return value mismatch: have %d, want %d", tt.name, have, tt.want)
+		}
+	}
+	// Ensure the overrides don't leak into the chain state
+	args := ethapi.CallArgs{From: &traceCallUser, To: &traceCallStorage}
+	if have := traceCallReturn(t, api, args, rpc.LatestBlockNumber, nil); have.Int64() != 3 {
+		t.Errorf("overridden state persisted: have %d, want %d", have, 3)
+	}
+	// Ensure conflicting overrides are rejected
+	overrides := &ethapi.StateOverride{traceCallStorage: {State: &slot0, StateDiff: &slot0}}
+	args = ethapi.CallArgs{From: &traceCallUser, To: &traceCallStorage}
+	if _, err := api.TraceCall(context.Background(), args, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), &TraceCallConfig{StateOverrides: overrides}); err == nil {
+		t.Errorf("conflicting state overrides accepted")
+	}
+}
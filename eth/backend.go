@@ -35,11 +35,13 @@ import (
 	"github.com/matthieu/go-ethereum/core"
 	"github.com/matthieu/go-ethereum/core/bloombits"
 	"github.com/matthieu/go-ethereum/core/rawdb"
+	"github.com/matthieu/go-ethereum/core/state/pruner"
 	"github.com/matthieu/go-ethereum/core/types"
 	"github.com/matthieu/go-ethereum/core/vm"
 	"github.com/matthieu/go-ethereum/eth/downloader"
 	"github.com/matthieu/go-ethereum/eth/filters"
 	"github.com/matthieu/go-ethereum/eth/gasprice"
+	"github.com/matthieu/go-ethereum/eth/protocols/snap"
 	"github.com/matthieu/go-ethereum/ethdb"
 	"github.com/matthieu/go-ethereum/event"
 	"github.com/matthieu/go-ethereum/internal/ethapi"
@@ -85,6 +87,8 @@ type Ethereum struct {
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
 	closeBloomHandler chan struct{}
 
+	internalTxIndexer *core.ChainIndexer // Internal transaction address indexer, nil if disabled
+
 	APIBackend *EthAPIBackend
 
 	miner     *miner.Miner
@@ -124,6 +128,10 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		log.Warn("Sanitizing invalid miner gas price", "provided", config.Miner.GasPrice, "updated", DefaultConfig.Miner.GasPrice)
 		config.Miner.GasPrice = new(big.Int).Set(DefaultConfig.Miner.GasPrice)
 	}
+	if config.SyncMode == downloader.SnapSync && config.SnapshotCache == 0 {
+		log.Warn("Snap sync requires snapshots, falling back to fast sync", "snapshot.cache", config.SnapshotCache)
+		config.SyncMode = downloader.FastSync
+	}
 	if config.NoPruning && config.TrieDirtyCache > 0 {
 		if config.SnapshotCache > 0 {
 			config.TrieCleanCache += config.TrieDirtyCache * 3 / 5
@@ -140,6 +148,11 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Finish any offline state pruning interrupted before, the database is not
+	// usable until it's done
+	if err := pruner.RecoverPruning(ctx.ResolvePath(""), chainDb); err != nil {
+		return nil, fmt.Errorf("failed to recover interrupted state pruning: %v", err)
+	}
 	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlock(chainDb, config.Genesis)
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
 		return nil, genesisErr
@@ -188,8 +201,12 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 			TrieDirtyDisabled:   config.NoPruning,
 			TrieTimeLimit:       config.TrieTimeout,
 			SnapshotLimit:       config.SnapshotCache,
+			InternalTxs:         config.InternalTxs,
 		}
 	)
+	if vmConfig.Listeners, err = core.InternalTxListenerFactories(config.InternalTxListeners); err != nil {
+		return nil, err
+	}
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, chainConfig, eth.engine, vmConfig, eth.shouldPreserve, &config.TxLookupLimit)
 	if err != nil {
 		return nil, err
@@ -202,9 +219,17 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
 
+	if config.InternalTxIndex {
+		eth.internalTxIndexer = NewInternalTxIndexer(eth, internalTxIndexSectionSize, internalTxIndexConfirms)
+		eth.internalTxIndexer.Start(eth.blockchain)
+	}
+
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.PoolJournal != "" {
+		config.TxPool.PoolJournal = ctx.ResolvePath(config.TxPool.PoolJournal)
+	}
 	eth.txPool = core.NewTxPool(config.TxPool, chainConfig, eth.blockchain)
 
 	// Permit the downloader to use the trie cache allowance during fast sync
@@ -475,7 +500,14 @@ func (s *Ethereum) StartMining(threads int) error {
 				log.Error("Etherbase account unavailable locally", "err", err)
 				return fmt.Errorf("signer missing: %v", err)
 			}
-			clique.Authorize(eb, wallet.SignData)
+			// The wallet may be an external signer (clef), in which case the
+			// block headers are sent over for sealing as typed clique data.
+			if err := clique.AuthorizeWallet(eb, wallet); err != nil {
+				log.Error("Etherbase wallet cannot seal blocks", "wallet", wallet.URL(), "err", err)
+				return fmt.Errorf("signer unsupported: %v", err)
+			}
+			clique.SetSignTimeout(s.config.Miner.SignTimeout)
+			log.Info("Authorized clique signer", "address", eb, "wallet", wallet.URL())
 		}
 		// If mining is started, we can disable the transaction rejection mechanism
 		// introduced to speed sync times.
@@ -525,6 +557,9 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 		protos[i].Attributes = []enr.Entry{s.currentEthEntry()}
 		protos[i].DialCandidates = s.dialCandidates
 	}
+	if s.config.SnapshotCache > 0 {
+		protos = append(protos, snap.MakeProtocols((*snapHandler)(s.protocolManager))...)
+	}
 	if s.lesServer != nil {
 		protos = append(protos, s.lesServer.Protocols()...)
 	}
@@ -570,6 +605,9 @@ func (s *Ethereum) Stop() error {
 	// Then stop everything else.
 	s.bloomIndexer.Close()
 	close(s.closeBloomHandler)
+	if s.internalTxIndexer != nil {
+		s.internalTxIndexer.Close()
+	}
 	s.txPool.Stop()
 	s.miner.Stop()
 	s.blockchain.Stop()
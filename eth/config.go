@@ -66,15 +66,17 @@ var DefaultConfig = Config{
 	TrieTimeout:        60 * time.Minute,
 	SnapshotCache:      256,
 	Miner: miner.Config{
-		GasFloor: 8000000,
-		GasCeil:  8000000,
-		GasPrice: big.NewInt(params.GWei),
-		Recommit: 3 * time.Second,
+		GasFloor:    8000000,
+		GasCeil:     8000000,
+		GasPrice:    big.NewInt(params.GWei),
+		Recommit:    3 * time.Second,
+		SignTimeout: 10 * time.Second,
 	},
-	TxPool:      core.DefaultTxPoolConfig,
-	RPCGasCap:   25000000,
-	GPO:         DefaultFullGPOConfig,
-	RPCTxFeeCap: 1, // 1 ether
+	TxPool:           core.DefaultTxPoolConfig,
+	RPCGasCap:        25000000,
+	RPCBundleTimeout: 5 * time.Second,
+	GPO:              DefaultFullGPOConfig,
+	RPCTxFeeCap:      1, // 1 ether
 }
 
 func init() {
@@ -118,6 +120,12 @@ type Config struct {
 
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 
+	InternalTxs     bool `toml:",omitempty"` // Whether to persist the internal transactions captured during block import
+	InternalTxIndex bool `toml:",omitempty"` // Whether to build the internal transaction address index in the background
+
+	// Names of the internal transaction listeners to attach to the EVM (nil = core.DefaultInternalTxListeners)
+	InternalTxListeners []string `toml:",omitempty"`
+
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
 
@@ -171,6 +179,9 @@ type Config struct {
 	// RPCGasCap is the global gas cap for eth-call variants.
 	RPCGasCap uint64 `toml:",omitempty"`
 
+	// RPCBundleTimeout is the global timeout for eth_callBundle simulations.
+	RPCBundleTimeout time.Duration `toml:",omitempty"`
+
 	// RPCTxFeeCap is the global transaction fee(price * gaslimit) cap for
 	// send-transction variants. The unit is ether.
 	RPCTxFeeCap float64 `toml:",omitempty"`
@@ -103,6 +103,9 @@ type Downloader struct {
 	mode uint32         // Synchronisation mode defining the strategy used (per sync cycle), use d.getMode() to get the SyncMode
 	mux  *event.TypeMux // Event multiplexer to announce sync operation events
 
+	snapSync   bool        // Whether to run the state sync over the snap protocol
+	SnapSyncer *SnapSyncer // Snapshot state syncer to use instead of the trie node based one
+
 	checkpoint uint64   // Checkpoint block number to enforce head against (e.g. fast sync)
 	genesis    uint64   // Genesis block number to limit sync to (e.g. light client CHT)
 	queue      *queue   // Scheduler for selecting the hashes to download
@@ -217,6 +220,7 @@ func New(checkpoint uint64, stateDb ethdb.Database, stateBloom *trie.SyncBloom,
 	dl := &Downloader{
 		stateDB:        stateDb,
 		stateBloom:     stateBloom,
+		SnapSyncer:     NewSnapSyncer(stateDb, stateBloom, dropPeer),
 		mux:            mux,
 		checkpoint:     checkpoint,
 		queue:          newQueue(blockCacheItems),
@@ -363,6 +367,15 @@ func (d *Downloader) synchronise(id string, hash common.Hash, td *big.Int, mode
 	if atomic.CompareAndSwapInt32(&d.notified, 0, 1) {
 		log.Info("Block synchronisation started")
 	}
+	// If snap sync was requested, enable the snap state syncer and continue as a
+	// fast sync, the two only differing in how the pivot state is retrieved.
+	if mode == SnapSync {
+		if !d.snapSync {
+			log.Info("Enabling snapshot state sync")
+			d.snapSync = true
+		}
+		mode = FastSync
+	}
 	// If we are already full syncing, but have a fast-sync bloom filter laying
 	// around, make sure it doesn't use memory any more. This is a special case
 	// when the user attempts to fast sync a new empty network.
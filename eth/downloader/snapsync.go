@@ -0,0 +1,1062 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"bytes"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/core/state"
+	"github.com/matthieu/go-ethereum/core/types"
+	"github.com/matthieu/go-ethereum/crypto"
+	"github.com/matthieu/go-ethereum/eth/protocols/snap"
+	"github.com/matthieu/go-ethereum/ethdb"
+	"github.com/matthieu/go-ethereum/ethdb/memorydb"
+	"github.com/matthieu/go-ethereum/log"
+	"github.com/matthieu/go-ethereum/rlp"
+	"github.com/matthieu/go-ethereum/trie"
+)
+
+const (
+	// snapRequestSize is the soft limit on the number of bytes requested from a
+	// remote peer in a single snap query.
+	snapRequestSize = 512 * 1024
+
+	// snapRequestTimeout is the time allowance for a peer to respond to a query.
+	snapRequestTimeout = 10 * time.Second
+
+	// snapAccountConcurrency is the number of chunks to split the account trie
+	// into to allow concurrent retrievals.
+	snapAccountConcurrency = 16
+
+	// snapStorageBatch is the maximum number of small contracts to request the
+	// storage of in a single query.
+	snapStorageBatch = 128
+
+	// snapCodeBatch is the maximum number of bytecodes to request in a single
+	// query.
+	snapCodeBatch = 64
+
+	// snapTrienodeBatch is the maximum number of trie nodes to request in a
+	// single healing query.
+	snapTrienodeBatch = 384
+
+	// snapCommitThreshold is the number of accounts or storage slots after which
+	// the account trie or a large storage trie assembled from the retrieved ranges
+	// is flushed to disk.
+	snapCommitThreshold = 16384
+)
+
+// emptyCodeHash is the known hash of the empty EVM bytecode.
+var emptyCodeHash = crypto.Keccak256Hash(nil)
+
+// SnapPeer is the subset of a `snap` protocol peer needed by the snap syncer to
+// retrieve state data.
+type SnapPeer interface {
+	ID() string
+	Log() log.Logger
+
+	RequestAccountRange(id uint64, root, origin, limit common.Hash, bytes uint64) error
+	RequestStorageRanges(id uint64, root common.Hash, accounts []common.Hash, origin, limit []byte, bytes uint64) error
+	RequestByteCodes(id uint64, hashes []common.Hash, bytes uint64) error
+	RequestTrieNodes(id uint64, root common.Hash, paths []snap.TrieNodePathSet, bytes uint64) error
+}
+
+// accountTask is a contiguous chunk of the account hash space to retrieve.
+type accountTask struct {
+	Next common.Hash // Next account to retrieve in this chunk
+	Last common.Hash // Last account belonging to this chunk
+
+	req  *snapRequest // Pending request filling this task, if any
+	done bool         // Flag whether the entire chunk was retrieved
+}
+
+// largeStorageTask is a contract whose storage could not be delivered in one
+// go, retrieved in consecutive ranges instead.
+type largeStorageTask struct {
+	account common.Hash // Hash of the account owning the storage
+	root    common.Hash // Storage root the retrieved slots must add up to
+	next    common.Hash // Next storage slot to retrieve
+	trie    *trie.Trie  // Storage trie assembled from the retrieved ranges
+
+	uncommitted int          // Number of slots inserted since the last flush
+	req         *snapRequest // Pending request filling this task, if any
+}
+
+// pendingAccount is an account retrieved from a range, waiting for its storage
+// and bytecode to be synced before being inserted into the account trie.
+type pendingAccount struct {
+	blob    []byte        // Consensus encoding of the account
+	account state.Account // Decoded account to access the storage root and code hash
+
+	needState bool // Flag whether the storage trie is still missing
+	needCode  bool // Flag whether the bytecode is still missing
+}
+
+// snapRequest tracks a pending query sent to a remote peer.
+type snapRequest struct {
+	id    uint64      // Request ID to match up responses with
+	peer  string      // Peer the request was sent to
+	kind  byte        // Message code of the query
+	root  common.Hash // State root the query was made against
+	timer *time.Timer // Timer to revert the request if the peer stalls
+
+	task   *accountTask      // Account chunk retrieved (account ranges)
+	origin common.Hash       // First account or slot requested
+	large  *largeStorageTask // Large contract retrieved (storage ranges)
+
+	accounts []common.Hash   // Accounts whose storage is retrieved (storage ranges)
+	hashes   []common.Hash   // Hashes of the bytecodes or trie nodes retrieved
+	paths    []trie.SyncPath // Paths of the trie nodes retrieved
+
+	healer *trie.Sync // Healing scheduler the request belongs to, nil for range sync
+}
+
+// snapResponse is a delivery from a remote peer to one of the pending queries.
+type snapResponse struct {
+	req *snapRequest // Request this is a response for
+
+	hashes   []common.Hash   // Account hashes (account ranges)
+	accounts [][]byte        // Account bodies in consensus format (account ranges)
+	slotKeys [][]common.Hash // Storage slot hashes per account (storage ranges)
+	slots    [][][]byte      // Storage slot values per account (storage ranges)
+	proof    [][]byte        // Edge proofs of the last range, if incomplete
+	blobs    [][]byte        // Bytecodes or trie nodes
+}
+
+// SnapSyncer synchronises a state trie using the `snap` protocol: contiguous
+// account and storage ranges are retrieved with edge proofs and assembled into
+// tries locally, after which any inconsistencies caused by the pivot moving are
+// fixed up by healing the trie node by node.
+type SnapSyncer struct {
+	db     ethdb.KeyValueStore // Database to store the synced state into
+	bloom  *trie.SyncBloom     // Bloom filter to deduplicate nodes and codes
+	triedb *trie.Database      // Intermediate database to assemble tries in
+	drop   peerDropFn          // Drops a peer for delivering invalid data
+
+	root common.Hash // Current state root being synced
+
+	accTrie      *trie.Trie                        // Account trie assembled from the synced ranges
+	uncommitted  int                               // Number of accounts inserted since the last flush
+	tasks        []*accountTask                    // Account chunks to retrieve, nil if no sync is running
+	pending      map[common.Hash]*pendingAccount   // Accounts waiting for their storage or code
+	storageQueue []common.Hash                     // Accounts with small storage tries to retrieve
+	largeTasks   map[common.Hash]*largeStorageTask // Contracts with storage retrieved in ranges
+	codeQueue    map[common.Hash]struct{}          // Bytecodes to retrieve
+	codeOwners   map[common.Hash][]common.Hash     // Accounts waiting for a specific bytecode
+	healer       *trie.Sync                        // Trie node scheduler fixing up the assembled state
+	healNodes    map[common.Hash]trie.SyncPath     // Trie nodes to retrieve for healing
+	healCodes    map[common.Hash]struct{}          // Bytecodes to retrieve for healing
+	peers        map[string]SnapPeer               // Currently active peers to download from
+	idlers       map[string]struct{}               // Peers without a pending request
+	stateless    map[string]struct{}               // Peers unable to serve the current root
+	requests     map[uint64]*snapRequest           // Pending queries to remote peers
+	responses    []*snapResponse                   // Deliveries waiting to be processed
+	nextID       uint64                            // Request ID counter
+	update       chan struct{}                     // Notification channel for new work or deliveries
+
+	accountSynced  uint64    // Number of accounts retrieved
+	slotSynced     uint64    // Number of storage slots retrieved
+	bytecodeSynced uint64    // Number of bytecodes retrieved
+	trienodeHealed uint64    // Number of trie nodes retrieved while healing
+	logTime        time.Time // Time of the last progress report
+
+	lock sync.Mutex // Protects the fields above
+}
+
+// NewSnapSyncer creates a new snap syncer to download the state into db. Peers
+// delivering invalid data are dropped using the given callback, if any.
+func NewSnapSyncer(db ethdb.KeyValueStore, bloom *trie.SyncBloom, drop peerDropFn) *SnapSyncer {
+	return &SnapSyncer{
+		db:     db,
+		bloom:  bloom,
+		triedb: trie.NewDatabase(db),
+		drop:   drop,
+		peers:  make(map[string]SnapPeer),
+		idlers: make(map[string]struct{}),
+		update: make(chan struct{}, 1),
+	}
+}
+
+// Register injects a new data source into the syncer's peerset.
+func (s *SnapSyncer) Register(peer SnapPeer) error {
+	id := peer.ID()
+
+	s.lock.Lock()
+	if _, ok := s.peers[id]; ok {
+		s.lock.Unlock()
+		return errAlreadyRegistered
+	}
+	s.peers[id] = peer
+	s.idlers[id] = struct{}{}
+	s.lock.Unlock()
+
+	s.notify()
+	return nil
+}
+
+// Unregister removes a data source from the syncer's peerset, rescheduling
+// all its pending requests.
+func (s *SnapSyncer) Unregister(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.peers[id]; !ok {
+		return errNotRegistered
+	}
+	delete(s.peers, id)
+	delete(s.idlers, id)
+	delete(s.stateless, id)
+
+	for reqid, req := range s.requests {
+		if req.peer == id {
+			req.timer.Stop()
+			delete(s.requests, reqid)
+			s.revertRequest(req)
+		}
+	}
+	s.notify()
+	return nil
+}
+
+// Sync starts (or resumes a previous) sync cycle to iterate over a state trie
+// with the given root and reconstruct the nodes based on the snapshot leaves.
+// Previously downloaded segments will not be redownloaded or fixed, rather any
+// errors will be healed after the leaves are fully accumulated.
+func (s *SnapSyncer) Sync(root common.Hash, cancel chan struct{}) error {
+	s.lock.Lock()
+	if s.tasks == nil {
+		if err := s.resetTasks(); err != nil {
+			s.lock.Unlock()
+			return err
+		}
+	}
+	if s.root != root {
+		s.root = root
+		s.healer = nil
+		s.healNodes = make(map[common.Hash]trie.SyncPath)
+		s.healCodes = make(map[common.Hash]struct{})
+	}
+	s.stateless = make(map[string]struct{})
+	s.lock.Unlock()
+
+	defer s.cleanup()
+
+	log.Debug("Starting snapshot sync cycle", "root", root)
+	for {
+		s.lock.Lock()
+		if err := s.processResponses(); err != nil {
+			s.lock.Unlock()
+			return err
+		}
+		// Once all the ranges are retrieved, fix up the assembled state
+		if s.healer == nil && s.rangesDone() {
+			if _, err := s.commitTrie(s.accTrie); err != nil {
+				s.lock.Unlock()
+				return err
+			}
+			s.uncommitted = 0
+
+			log.Debug("Snapshot ranges synced, healing state", "root", root, "assembled", s.accTrie.Hash())
+			s.healer = state.NewStateSync(root, s.db, s.bloom)
+		}
+		if s.healer != nil && s.healer.Pending() == 0 {
+			s.reportProgress(true)
+			s.tasks = nil
+			s.lock.Unlock()
+			return nil
+		}
+		s.assignTasks()
+		s.reportProgress(false)
+		s.lock.Unlock()
+
+		select {
+		case <-s.update:
+		case <-cancel:
+			return errCancelStateFetch
+		}
+	}
+}
+
+// OnAccounts is a callback method to invoke when a range of accounts are
+// received from a remote peer.
+func (s *SnapSyncer) OnAccounts(peer SnapPeer, id uint64, hashes []common.Hash, accounts [][]byte, proof [][]byte) error {
+	return s.deliver(peer, id, snap.GetAccountRangeMsg, &snapResponse{
+		hashes:   hashes,
+		accounts: accounts,
+		proof:    proof,
+	})
+}
+
+// OnStorage is a callback method to invoke when ranges of storage slots
+// are received from a remote peer.
+func (s *SnapSyncer) OnStorage(peer SnapPeer, id uint64, hashes [][]common.Hash, slots [][][]byte, proof [][]byte) error {
+	return s.deliver(peer, id, snap.GetStorageRangesMsg, &snapResponse{
+		slotKeys: hashes,
+		slots:    slots,
+		proof:    proof,
+	})
+}
+
+// OnByteCodes is a callback method to invoke when a batch of contract
+// bytes codes are received from a remote peer.
+func (s *SnapSyncer) OnByteCodes(peer SnapPeer, id uint64, bytecodes [][]byte) error {
+	return s.deliver(peer, id, snap.GetByteCodesMsg, &snapResponse{blobs: bytecodes})
+}
+
+// OnTrieNodes is a callback method to invoke when a batch of trie nodes
+// are received from a remote peer.
+func (s *SnapSyncer) OnTrieNodes(peer SnapPeer, id uint64, trienodes [][]byte) error {
+	return s.deliver(peer, id, snap.GetTrieNodesMsg, &snapResponse{blobs: trienodes})
+}
+
+// deliver matches up a response with its pending request and queues it up for
+// processing by the sync loop. Responses to unknown or already timed out
+// requests are dropped.
+func (s *SnapSyncer) deliver(peer SnapPeer, id uint64, kind byte, res *snapResponse) error {
+	s.lock.Lock()
+	req := s.requests[id]
+	if req == nil || req.peer != peer.ID() || req.kind != kind {
+		s.lock.Unlock()
+		peer.Log().Debug("Unrequested snap response", "reqid", id, "kind", kind)
+		return nil
+	}
+	req.timer.Stop()
+	delete(s.requests, id)
+	if _, ok := s.peers[req.peer]; ok {
+		s.idlers[req.peer] = struct{}{}
+	}
+	res.req = req
+	s.responses = append(s.responses, res)
+	s.lock.Unlock()
+
+	s.notify()
+	return nil
+}
+
+// notify wakes up the sync loop if it's waiting for something to happen.
+func (s *SnapSyncer) notify() {
+	select {
+	case s.update <- struct{}{}:
+	default:
+	}
+}
+
+// resetTasks drops any previous sync progress and splits the account hash
+// space into chunks to retrieve concurrently.
+func (s *SnapSyncer) resetTasks() error {
+	accTrie, err := trie.New(common.Hash{}, s.triedb)
+	if err != nil {
+		return err
+	}
+	s.accTrie, s.uncommitted = accTrie, 0
+
+	s.tasks = nil
+	step := new(big.Int).Sub(new(big.Int).Div(new(big.Int).Exp(common.Big2, common.Big256, nil), big.NewInt(snapAccountConcurrency)), common.Big1)
+	for next, i := new(big.Int), 0; i < snapAccountConcurrency; i++ {
+		last := new(big.Int).Add(next, step)
+		if i == snapAccountConcurrency-1 {
+			// Make sure we don't overflow if the step is not a proper divisor
+			last = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff").Big()
+		}
+		s.tasks = append(s.tasks, &accountTask{
+			Next: common.BigToHash(next),
+			Last: common.BigToHash(last),
+		})
+		next = new(big.Int).Add(last, common.Big1)
+	}
+	s.pending = make(map[common.Hash]*pendingAccount)
+	s.storageQueue = nil
+	s.largeTasks = make(map[common.Hash]*largeStorageTask)
+	s.codeQueue = make(map[common.Hash]struct{})
+	s.codeOwners = make(map[common.Hash][]common.Hash)
+	s.requests = make(map[uint64]*snapRequest)
+	s.responses = nil
+	s.root = common.Hash{}
+	return nil
+}
+
+// cleanup reverts all the pending requests and undelivered responses when a
+// sync cycle is terminated, making all peers available for the next cycle.
+func (s *SnapSyncer) cleanup() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for id, req := range s.requests {
+		req.timer.Stop()
+		delete(s.requests, id)
+		s.revertRequest(req)
+	}
+	for _, res := range s.responses {
+		s.revertRequest(res.req)
+	}
+	s.responses = nil
+
+	for id := range s.peers {
+		s.idlers[id] = struct{}{}
+	}
+}
+
+// rangesDone returns whether all the account ranges have been retrieved along
+// with all the storage and bytecodes they reference.
+func (s *SnapSyncer) rangesDone() bool {
+	for _, task := range s.tasks {
+		if !task.done {
+			return false
+		}
+	}
+	return len(s.pending) == 0
+}
+
+// revertRequest moves the data of a failed or timed out request back into the
+// retrieval queues.
+func (s *SnapSyncer) revertRequest(req *snapRequest) {
+	switch req.kind {
+	case snap.GetAccountRangeMsg:
+		req.task.req = nil
+
+	case snap.GetStorageRangesMsg:
+		if req.large != nil {
+			req.large.req = nil
+		} else {
+			s.storageQueue = append(s.storageQueue, req.accounts...)
+		}
+
+	case snap.GetByteCodesMsg:
+		for _, hash := range req.hashes {
+			if req.healer == nil {
+				s.codeQueue[hash] = struct{}{}
+			} else if req.healer == s.healer {
+				s.healCodes[hash] = struct{}{}
+			}
+		}
+
+	case snap.GetTrieNodesMsg:
+		if req.healer == s.healer {
+			for i, hash := range req.hashes {
+				s.healNodes[hash] = req.paths[i]
+			}
+		}
+	}
+}
+
+// timeoutRequest is invoked when a peer fails to respond to a query in time,
+// rescheduling the data it was supposed to deliver.
+func (s *SnapSyncer) timeoutRequest(req *snapRequest) {
+	s.lock.Lock()
+	if s.requests[req.id] == req {
+		log.Debug("Snap request timed out", "peer", req.peer, "reqid", req.id, "kind", req.kind)
+
+		delete(s.requests, req.id)
+		s.revertRequest(req)
+		if _, ok := s.peers[req.peer]; ok {
+			s.idlers[req.peer] = struct{}{}
+		}
+	}
+	s.lock.Unlock()
+
+	s.notify()
+}
+
+// markStateless flags a peer as unable to serve the current state root, so it
+// is not assigned any more work until the pivot moves.
+func (s *SnapSyncer) markStateless(peer string) {
+	log.Debug("Peer cannot serve requested state", "peer", peer, "root", s.root)
+	s.stateless[peer] = struct{}{}
+}
+
+// penalize flags a peer that delivered data not matching the requested state and
+// drops it. The peer isn't assigned any more work until the drop takes effect.
+func (s *SnapSyncer) penalize(peer string) {
+	log.Debug("Dropping peer delivering invalid state", "peer", peer, "root", s.root)
+	s.stateless[peer] = struct{}{}
+
+	// Dropping the peer unregisters it, which needs the lock held by the caller
+	if s.drop != nil {
+		go s.drop(peer)
+	}
+}
+
+// assignTasks hands out retrieval requests to all the idle peers.
+func (s *SnapSyncer) assignTasks() {
+	// Pull any newly scheduled healing tasks out of the trie scheduler
+	if s.healer != nil {
+		nodes, paths, codes := s.healer.MissingPaths(0)
+		for i, hash := range nodes {
+			s.healNodes[hash] = paths[i]
+		}
+		for _, hash := range codes {
+			s.healCodes[hash] = struct{}{}
+		}
+	}
+	for id := range s.idlers {
+		if _, ok := s.stateless[id]; ok {
+			continue
+		}
+		var req *snapRequest
+		if s.healer != nil {
+			req = s.nextHealRequest()
+		} else {
+			req = s.nextRangeRequest()
+		}
+		if req == nil {
+			return
+		}
+		s.dispatch(s.peers[id], req)
+	}
+}
+
+// nextRangeRequest assembles the next query to retrieve account ranges, storage
+// ranges or bytecodes, returning nil if there's nothing to request.
+func (s *SnapSyncer) nextRangeRequest() *snapRequest {
+	// Retrieve bytecodes first as they are cheap and unblock pending accounts
+	if len(s.codeQueue) > 0 {
+		req := &snapRequest{kind: snap.GetByteCodesMsg}
+		for hash := range s.codeQueue {
+			delete(s.codeQueue, hash)
+			if req.hashes = append(req.hashes, hash); len(req.hashes) >= snapCodeBatch {
+				break
+			}
+		}
+		return req
+	}
+	// Continue any large contracts next, they are the slowest to retrieve
+	for _, task := range s.largeTasks {
+		if task.req == nil {
+			task.req = &snapRequest{
+				kind:     snap.GetStorageRangesMsg,
+				large:    task,
+				origin:   task.next,
+				accounts: []common.Hash{task.account},
+			}
+			return task.req
+		}
+	}
+	// Retrieve the storage of small contracts in batches
+	if len(s.storageQueue) > 0 {
+		n := len(s.storageQueue)
+		if n > snapStorageBatch {
+			n = snapStorageBatch
+		}
+		req := &snapRequest{
+			kind:     snap.GetStorageRangesMsg,
+			accounts: append([]common.Hash{}, s.storageQueue[:n]...),
+		}
+		s.storageQueue = s.storageQueue[n:]
+		return req
+	}
+	// Finally retrieve the next account range
+	for _, task := range s.tasks {
+		if !task.done && task.req == nil {
+			task.req = &snapRequest{
+				kind:   snap.GetAccountRangeMsg,
+				task:   task,
+				origin: task.Next,
+			}
+			return task.req
+		}
+	}
+	return nil
+}
+
+// nextHealRequest assembles the next query to retrieve trie nodes or bytecodes
+// for healing, returning nil if there's nothing to request.
+func (s *SnapSyncer) nextHealRequest() *snapRequest {
+	if len(s.healCodes) > 0 {
+		req := &snapRequest{kind: snap.GetByteCodesMsg, healer: s.healer}
+		for hash := range s.healCodes {
+			delete(s.healCodes, hash)
+			if req.hashes = append(req.hashes, hash); len(req.hashes) >= snapCodeBatch {
+				break
+			}
+		}
+		return req
+	}
+	if len(s.healNodes) > 0 {
+		req := &snapRequest{kind: snap.GetTrieNodesMsg, healer: s.healer}
+		for hash, path := range s.healNodes {
+			delete(s.healNodes, hash)
+			req.hashes = append(req.hashes, hash)
+			if req.paths = append(req.paths, path); len(req.hashes) >= snapTrienodeBatch {
+				break
+			}
+		}
+		return req
+	}
+	return nil
+}
+
+// dispatch tracks a request as pending and sends it to the remote peer.
+func (s *SnapSyncer) dispatch(peer SnapPeer, req *snapRequest) {
+	s.nextID++
+	req.id, req.peer, req.root = s.nextID, peer.ID(), s.root
+
+	s.requests[req.id] = req
+	delete(s.idlers, req.peer)
+
+	req.timer = time.AfterFunc(snapRequestTimeout, func() {
+		s.timeoutRequest(req)
+	})
+	// Send the request asynchronously, a failure resolving as a timeout
+	go func() {
+		var err error
+		switch req.kind {
+		case snap.GetAccountRangeMsg:
+			err = peer.RequestAccountRange(req.id, req.root, req.origin, req.task.Last, snapRequestSize)
+
+		case snap.GetStorageRangesMsg:
+			var origin []byte
+			if req.large != nil {
+				origin = req.origin[:]
+			}
+			err = peer.RequestStorageRanges(req.id, req.root, req.accounts, origin, nil, snapRequestSize)
+
+		case snap.GetByteCodesMsg:
+			err = peer.RequestByteCodes(req.id, req.hashes, snapRequestSize)
+
+		case snap.GetTrieNodesMsg:
+			paths := make([]snap.TrieNodePathSet, len(req.paths))
+			for i, path := range req.paths {
+				paths[i] = snap.TrieNodePathSet(path)
+			}
+			err = peer.RequestTrieNodes(req.id, req.root, paths, snapRequestSize)
+		}
+		if err != nil {
+			peer.Log().Debug("Failed to send snap request", "reqid", req.id, "kind", req.kind, "err", err)
+		}
+	}()
+}
+
+// processResponses verifies and stores all the queued up deliveries.
+func (s *SnapSyncer) processResponses() error {
+	for len(s.responses) > 0 {
+		res := s.responses[0]
+		s.responses[0] = nil
+		s.responses = s.responses[1:]
+
+		var err error
+		switch res.req.kind {
+		case snap.GetAccountRangeMsg:
+			err = s.processAccountResponse(res)
+		case snap.GetStorageRangesMsg:
+			if res.req.large != nil {
+				err = s.processLargeStorageResponse(res)
+			} else {
+				err = s.processStorageResponse(res)
+			}
+		case snap.GetByteCodesMsg:
+			err = s.processBytecodeResponse(res)
+		case snap.GetTrieNodesMsg:
+			err = s.processTrienodeResponse(res)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processAccountResponse verifies a range of accounts against the edge proofs
+// and schedules their storage and bytecode retrievals.
+func (s *SnapSyncer) processAccountResponse(res *snapResponse) error {
+	req := res.req
+	task := req.task
+	task.req = nil
+
+	if len(res.hashes) == 0 {
+		if len(res.proof) == 0 {
+			s.markStateless(req.peer)
+			return nil
+		}
+		// The peer proved there are no more accounts in the chunk. Even if it's
+		// lying, the state healing will pick up the missing ones.
+		task.done = true
+		return nil
+	}
+	keys := make([][]byte, len(res.hashes))
+	for i := range res.hashes {
+		keys[i] = res.hashes[i][:]
+	}
+	proofdb := proofDatabase(res.proof)
+	err, cont := trie.VerifyRangeProof(req.root, req.origin[:], keys, res.accounts, proofdb, proofdb)
+	if err != nil {
+		log.Debug("Invalid account range", "peer", req.peer, "root", req.root, "origin", req.origin, "err", err)
+		s.penalize(req.peer)
+		return nil
+	}
+	for i, hash := range res.hashes {
+		// Accounts beyond the chunk are only needed for the proof, skip them
+		if bytes.Compare(hash[:], task.Last[:]) > 0 {
+			break
+		}
+		if err := s.scheduleAccount(hash, res.accounts[i]); err != nil {
+			return err
+		}
+	}
+	last := res.hashes[len(res.hashes)-1]
+	if !cont || bytes.Compare(last[:], task.Last[:]) >= 0 {
+		task.done = true
+	} else {
+		task.Next = incHash(last)
+	}
+	return nil
+}
+
+// processStorageResponse verifies the storage tries of a batch of small
+// contracts. If the last contract is incomplete, it's turned into a large
+// contract to be retrieved in consecutive ranges.
+func (s *SnapSyncer) processStorageResponse(res *snapResponse) error {
+	req := res.req
+	if len(res.slotKeys) == 0 && len(res.proof) == 0 {
+		s.markStateless(req.peer)
+		s.storageQueue = append(s.storageQueue, req.accounts...)
+		return nil
+	}
+	for i, account := range req.accounts {
+		// Reschedule any accounts the peer didn't have room for
+		if i >= len(res.slotKeys) {
+			s.storageQueue = append(s.storageQueue, req.accounts[i:]...)
+			break
+		}
+		root := s.pending[account].account.Root
+
+		tr, err := trie.New(common.Hash{}, s.triedb)
+		if err != nil {
+			return err
+		}
+		for j, hash := range res.slotKeys[i] {
+			if err := tr.TryUpdate(hash[:], res.slots[i][j]); err != nil {
+				return err
+			}
+		}
+		s.slotSynced += uint64(len(res.slotKeys[i]))
+
+		// If the last contract is incomplete, verify the range and continue it
+		// in a large contract task
+		if i == len(res.slotKeys)-1 && len(res.proof) > 0 && len(res.slotKeys[i]) > 0 {
+			keys := make([][]byte, len(res.slotKeys[i]))
+			for j := range res.slotKeys[i] {
+				keys[j] = res.slotKeys[i][j][:]
+			}
+			proofdb := proofDatabase(res.proof)
+			err, cont := trie.VerifyRangeProof(root, common.Hash{}.Bytes(), keys, res.slots[i], proofdb, proofdb)
+			if err != nil {
+				log.Debug("Invalid storage range", "peer", req.peer, "account", account, "err", err)
+				s.storageQueue = append(s.storageQueue, account)
+				s.penalize(req.peer)
+				break
+			}
+			if cont {
+				s.largeTasks[account] = &largeStorageTask{
+					account: account,
+					root:    root,
+					next:    incHash(res.slotKeys[i][len(res.slotKeys[i])-1]),
+					trie:    tr,
+				}
+				continue
+			}
+		}
+		if tr.Hash() != root {
+			log.Debug("Invalid storage trie", "peer", req.peer, "account", account, "root", root, "have", tr.Hash())
+			s.storageQueue = append(s.storageQueue, req.accounts[i:]...)
+			s.penalize(req.peer)
+			break
+		}
+		if _, err := s.commitTrie(tr); err != nil {
+			return err
+		}
+		s.pending[account].needState = false
+		if err := s.completeAccount(account); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processLargeStorageResponse verifies a storage range of a large contract,
+// committing the storage trie once all the slots are retrieved.
+func (s *SnapSyncer) processLargeStorageResponse(res *snapResponse) error {
+	req := res.req
+	task := req.large
+	task.req = nil
+
+	if len(res.slotKeys) == 0 || len(res.slotKeys[0]) == 0 {
+		if len(res.proof) == 0 {
+			s.markStateless(req.peer)
+			return nil
+		}
+		// The peer claims there are no more slots, the root check will tell
+	} else {
+		keys := make([][]byte, len(res.slotKeys[0]))
+		for i := range res.slotKeys[0] {
+			keys[i] = res.slotKeys[0][i][:]
+		}
+		var proofdb ethdb.KeyValueReader
+		if len(res.proof) > 0 {
+			proofdb = proofDatabase(res.proof)
+		}
+		err, cont := trie.VerifyRangeProof(task.root, req.origin[:], keys, res.slots[0], proofdb, proofdb)
+		if err != nil {
+			log.Debug("Invalid storage range", "peer", req.peer, "account", task.account, "origin", req.origin, "err", err)
+			s.penalize(req.peer)
+			return nil
+		}
+		for i, key := range keys {
+			if err := task.trie.TryUpdate(key, res.slots[0][i]); err != nil {
+				return err
+			}
+		}
+		s.slotSynced += uint64(len(keys))
+
+		// Flush the trie every now and again to avoid holding it all in memory
+		if task.uncommitted += len(keys); task.uncommitted >= snapCommitThreshold {
+			if _, err := s.commitTrie(task.trie); err != nil {
+				return err
+			}
+			task.uncommitted = 0
+		}
+
+		if cont {
+			task.next = incHash(res.slotKeys[0][len(keys)-1])
+			return nil
+		}
+	}
+	// All the slots are retrieved. Since every range was proven against the
+	// storage root, a mismatch can only be caused by a peer withholding the last
+	// slots. Rather than retrieving the entire storage again, commit what was
+	// synced and let the state healing fill in the gaps.
+	if root := task.trie.Hash(); root != task.root {
+		log.Debug("Incomplete large storage trie, leaving it to healing", "peer", req.peer, "account", task.account, "root", task.root, "have", root)
+	}
+	if _, err := s.commitTrie(task.trie); err != nil {
+		return err
+	}
+	delete(s.largeTasks, task.account)
+
+	s.pending[task.account].needState = false
+	return s.completeAccount(task.account)
+}
+
+// processBytecodeResponse verifies and stores a batch of bytecodes, either for
+// accounts pending in the range sync or for healing the state.
+func (s *SnapSyncer) processBytecodeResponse(res *snapResponse) error {
+	req := res.req
+
+	// Drop healing responses belonging to a previous pivot
+	if req.healer != nil && req.healer != s.healer {
+		return nil
+	}
+	if len(res.blobs) == 0 {
+		s.markStateless(req.peer)
+		s.revertRequest(req)
+		return nil
+	}
+	requested := make(map[common.Hash]struct{}, len(req.hashes))
+	for _, hash := range req.hashes {
+		requested[hash] = struct{}{}
+	}
+	var (
+		batch     = s.db.NewBatch()
+		delivered []common.Hash
+	)
+	for _, code := range res.blobs {
+		hash := crypto.Keccak256Hash(code)
+		if _, ok := requested[hash]; !ok {
+			continue
+		}
+		delete(requested, hash)
+
+		if req.healer != nil {
+			if _, _, err := s.healer.Process([]trie.SyncResult{{Hash: hash, Data: code}}); err != nil {
+				log.Debug("Failed to process healed bytecode", "hash", hash, "err", err)
+			}
+			continue
+		}
+		if err := batch.Put(hash[:], code); err != nil {
+			return err
+		}
+		s.bloom.Add(hash[:])
+		delivered = append(delivered, hash)
+	}
+	if req.healer != nil {
+		if err := s.healer.Commit(batch); err != nil {
+			return err
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	s.bytecodeSynced += uint64(len(req.hashes) - len(requested))
+
+	// Reschedule anything not delivered and complete the waiting accounts
+	for hash := range requested {
+		if req.healer != nil {
+			s.healCodes[hash] = struct{}{}
+		} else {
+			s.codeQueue[hash] = struct{}{}
+		}
+	}
+	for _, hash := range delivered {
+		for _, account := range s.codeOwners[hash] {
+			s.pending[account].needCode = false
+			if err := s.completeAccount(account); err != nil {
+				return err
+			}
+		}
+		delete(s.codeOwners, hash)
+	}
+	return nil
+}
+
+// processTrienodeResponse verifies and feeds a batch of trie nodes into the
+// healing scheduler.
+func (s *SnapSyncer) processTrienodeResponse(res *snapResponse) error {
+	req := res.req
+
+	// Drop responses belonging to a previous pivot
+	if req.healer != s.healer {
+		return nil
+	}
+	if len(res.blobs) == 0 {
+		s.markStateless(req.peer)
+		s.revertRequest(req)
+		return nil
+	}
+	// Nodes are delivered in request order, stopping at the first unavailable
+	var delivered int
+	for i, blob := range res.blobs {
+		if i >= len(req.hashes) || crypto.Keccak256Hash(blob) != req.hashes[i] {
+			break
+		}
+		if _, _, err := s.healer.Process([]trie.SyncResult{{Hash: req.hashes[i], Data: blob}}); err != nil {
+			log.Debug("Failed to process healed trie node", "hash", req.hashes[i], "err", err)
+		}
+		delivered++
+	}
+	for i := delivered; i < len(req.hashes); i++ {
+		s.healNodes[req.hashes[i]] = req.paths[i]
+	}
+	s.trienodeHealed += uint64(delivered)
+
+	batch := s.db.NewBatch()
+	if err := s.healer.Commit(batch); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// scheduleAccount tracks a retrieved account, scheduling its storage and code
+// if they are missing locally, or inserting it into the account trie otherwise.
+func (s *SnapSyncer) scheduleAccount(hash common.Hash, blob []byte) error {
+	acc := &pendingAccount{blob: blob}
+	if err := rlp.DecodeBytes(blob, &acc.account); err != nil {
+		return err
+	}
+	s.accountSynced++
+
+	if acc.account.Root != types.EmptyRootHash && !s.hasEntry(acc.account.Root) {
+		acc.needState = true
+		s.storageQueue = append(s.storageQueue, hash)
+	}
+	if code := common.BytesToHash(acc.account.CodeHash); code != emptyCodeHash && !s.hasEntry(code) {
+		acc.needCode = true
+		if _, ok := s.codeOwners[code]; !ok {
+			s.codeQueue[code] = struct{}{}
+		}
+		s.codeOwners[code] = append(s.codeOwners[code], hash)
+	}
+	if acc.needState || acc.needCode {
+		s.pending[hash] = acc
+		return nil
+	}
+	return s.insertAccount(hash, blob)
+}
+
+// completeAccount inserts a pending account into the account trie if both its
+// storage and bytecode are available.
+func (s *SnapSyncer) completeAccount(hash common.Hash) error {
+	acc := s.pending[hash]
+	if acc.needState || acc.needCode {
+		return nil
+	}
+	delete(s.pending, hash)
+	return s.insertAccount(hash, acc.blob)
+}
+
+// insertAccount adds a fully synced account into the account trie, flushing
+// the trie to disk every now and again.
+func (s *SnapSyncer) insertAccount(hash common.Hash, blob []byte) error {
+	if err := s.accTrie.TryUpdate(hash[:], blob); err != nil {
+		return err
+	}
+	if s.uncommitted++; s.uncommitted >= snapCommitThreshold {
+		if _, err := s.commitTrie(s.accTrie); err != nil {
+			return err
+		}
+		s.uncommitted = 0
+	}
+	return nil
+}
+
+// commitTrie flushes a trie assembled by the syncer into the database.
+func (s *SnapSyncer) commitTrie(t *trie.Trie) (common.Hash, error) {
+	root, err := t.Commit(nil)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	err = s.triedb.Commit(root, false, func(hash common.Hash) {
+		s.bloom.Add(hash[:])
+	})
+	return root, err
+}
+
+// hasEntry checks whether a trie node or bytecode is already available locally.
+func (s *SnapSyncer) hasEntry(hash common.Hash) bool {
+	if !s.bloom.Contains(hash[:]) {
+		return false
+	}
+	ok, _ := s.db.Has(hash[:])
+	return ok
+}
+
+// reportProgress logs the sync progress every now and again.
+func (s *SnapSyncer) reportProgress(force bool) {
+	if !force && time.Since(s.logTime) < 8*time.Second {
+		return
+	}
+	s.logTime = time.Now()
+
+	log.Info("State snapshot sync in progress", "accounts", s.accountSynced, "slots", s.slotSynced,
+		"codes", s.bytecodeSynced, "healed", s.trienodeHealed, "pending", len(s.pending))
+}
+
+// proofDatabase collects a list of proof nodes into a database, keyed by hash.
+func proofDatabase(proof [][]byte) *memorydb.Database {
+	db := memorydb.New()
+	for _, node := range proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	return db
+}
+
+// incHash returns the hash following h in the hash space.
+func incHash(h common.Hash) common.Hash {
+	return common.BigToHash(new(big.Int).Add(h.Big(), common.Big1))
+}
@@ -0,0 +1,287 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/core/rawdb"
+	"github.com/matthieu/go-ethereum/core/state"
+	"github.com/matthieu/go-ethereum/core/state/snapshot"
+	"github.com/matthieu/go-ethereum/core/types"
+	"github.com/matthieu/go-ethereum/crypto"
+	"github.com/matthieu/go-ethereum/eth/protocols/snap"
+	"github.com/matthieu/go-ethereum/ethdb"
+	"github.com/matthieu/go-ethereum/log"
+	"github.com/matthieu/go-ethereum/rlp"
+	"github.com/matthieu/go-ethereum/trie"
+)
+
+// snapTestPeer is a fake `snap` peer serving requests straight out of a source
+// state snapshot, delivering the responses synchronously to the syncer.
+type snapTestPeer struct {
+	id     string
+	snaps  *snapshot.Tree
+	sdb    state.Database
+	syncer *SnapSyncer
+
+	onAccounts func() // Hook invoked after delivering an account range
+	corrupt    bool   // Whether to tamper with the proven ranges delivered
+}
+
+func (p *snapTestPeer) ID() string      { return p.id }
+func (p *snapTestPeer) Log() log.Logger { return log.New("peer", p.id) }
+
+func (p *snapTestPeer) RequestAccountRange(id uint64, root, origin, limit common.Hash, bytes uint64) error {
+	accounts, proof := snap.ServiceGetAccountRangeQuery(p.snaps, p.sdb, &snap.GetAccountRangePacket{
+		ID: id, Root: root, Origin: origin, Limit: limit, Bytes: bytes,
+	})
+	hashes, blobs, err := (&snap.AccountRangePacket{ID: id, Accounts: accounts, Proof: proof}).Unpack()
+	if err != nil {
+		return err
+	}
+	if p.corrupt && len(blobs) > 0 {
+		blobs[0] = common.CopyBytes(blobs[0])
+		blobs[0][len(blobs[0])-1]++
+	}
+	err = p.syncer.OnAccounts(p, id, hashes, blobs, proof)
+	if p.onAccounts != nil {
+		p.onAccounts()
+	}
+	return err
+}
+
+func (p *snapTestPeer) RequestStorageRanges(id uint64, root common.Hash, accounts []common.Hash, origin, limit []byte, bytes uint64) error {
+	slots, proof := snap.ServiceGetStorageRangesQuery(p.snaps, p.sdb, &snap.GetStorageRangesPacket{
+		ID: id, Root: root, Accounts: accounts, Origin: origin, Limit: limit, Bytes: bytes,
+	})
+	hashes, values := (&snap.StorageRangesPacket{ID: id, Slots: slots, Proof: proof}).Unpack()
+	if p.corrupt && len(values) > 0 && len(values[0]) > 0 {
+		values[0][0] = common.CopyBytes(values[0][0])
+		values[0][0][len(values[0][0])-1]++
+	}
+	return p.syncer.OnStorage(p, id, hashes, values, proof)
+}
+
+func (p *snapTestPeer) RequestByteCodes(id uint64, hashes []common.Hash, bytes uint64) error {
+	codes := snap.ServiceGetByteCodesQuery(p.sdb, &snap.GetByteCodesPacket{ID: id, Hashes: hashes, Bytes: bytes})
+	return p.syncer.OnByteCodes(p, id, codes)
+}
+
+func (p *snapTestPeer) RequestTrieNodes(id uint64, root common.Hash, paths []snap.TrieNodePathSet, bytes uint64) error {
+	nodes, err := snap.ServiceGetTrieNodesQuery(p.sdb, &snap.GetTrieNodesPacket{ID: id, Root: root, Paths: paths, Bytes: bytes})
+	if err != nil {
+		return err
+	}
+	return p.syncer.OnTrieNodes(p, id, nodes)
+}
+
+// makeSnapSource creates a source state with plain accounts, small contracts
+// and a contract too large to be served in a single storage range, along with
+// a snapshot tree to serve it from.
+func makeSnapSource(t *testing.T) (state.Database, *snapshot.Tree, common.Hash) {
+	sdb := state.NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, _ := state.New(common.Hash{}, sdb, nil)
+
+	for i := int64(0); i < 1000; i++ {
+		addr := common.BigToAddress(big.NewInt(i + 1))
+		statedb.SetBalance(addr, big.NewInt(i+1))
+		statedb.SetNonce(addr, uint64(i))
+		if i%10 == 0 {
+			statedb.SetCode(addr, []byte{0x60, byte(i % 100), 0x60, 0x00, 0x55})
+			for j := int64(0); j < i%17+1; j++ {
+				statedb.SetState(addr, common.BigToHash(big.NewInt(j)), common.BigToHash(big.NewInt(i*j+1)))
+			}
+		}
+	}
+	large := common.HexToAddress("0x1a29e")
+	statedb.SetCode(large, []byte{0x60, 0xff})
+	for j := int64(0); j < 2*snapCommitThreshold; j++ {
+		statedb.SetState(large, common.BigToHash(big.NewInt(j)), common.BigToHash(big.NewInt(j+1)))
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit source state: %v", err)
+	}
+	if err := sdb.TrieDB().Commit(root, false, nil); err != nil {
+		t.Fatalf("failed to flush source state: %v", err)
+	}
+	return sdb, snapshot.New(sdb.TrieDB().DiskDB().(ethdb.KeyValueStore), sdb.TrieDB(), 16, root, false), root
+}
+
+// runSnapSync runs a snap sync cycle against the given peers, failing the test
+// if it doesn't finish in a reasonable time.
+func runSnapSync(t *testing.T, syncer *SnapSyncer, root common.Hash, cancel chan struct{}) error {
+	errc := make(chan error, 1)
+	go func() { errc <- syncer.Sync(root, cancel) }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-time.After(30 * time.Second):
+		t.Fatalf("snap sync of %x timed out", root)
+	}
+	return nil
+}
+
+// checkStateComplete iterates over the entire state with the given root and
+// ensures all the trie nodes and bytecodes of the wanted number of accounts are
+// present in the database.
+func checkStateComplete(t *testing.T, db ethdb.Database, root common.Hash, want int) {
+	triedb := trie.NewDatabase(db)
+	accTrie, err := trie.New(root, triedb)
+	if err != nil {
+		t.Fatalf("failed to open synced state %x: %v", root, err)
+	}
+	var accounts int
+	it := trie.NewIterator(accTrie.NodeIterator(nil))
+	for it.Next() {
+		var acc state.Account
+		if err := rlp.DecodeBytes(it.Value, &acc); err != nil {
+			t.Fatalf("failed to decode account %x: %v", it.Key, err)
+		}
+		if acc.Root != types.EmptyRootHash {
+			stTrie, err := trie.New(acc.Root, triedb)
+			if err != nil {
+				t.Fatalf("failed to open storage of %x: %v", it.Key, err)
+			}
+			sit := stTrie.NodeIterator(nil)
+			for sit.Next(true) {
+			}
+			if sit.Error() != nil {
+				t.Fatalf("incomplete storage of %x: %v", it.Key, sit.Error())
+			}
+		}
+		if hash := common.BytesToHash(acc.CodeHash); hash != emptyCodeHash {
+			if code, _ := db.Get(hash[:]); crypto.Keccak256Hash(code) != hash {
+				t.Fatalf("missing code of %x", it.Key)
+			}
+		}
+		accounts++
+	}
+	if it.Err != nil {
+		t.Fatalf("incomplete account trie: %v", it.Err)
+	}
+	if accounts != want {
+		t.Fatalf("account count mismatch: have %d, want %d", accounts, want)
+	}
+}
+
+// Tests that a state can be synced from scratch via snap ranges from multiple
+// peers, including contracts too large for a single storage range.
+func TestSnapSync(t *testing.T) {
+	sdb, snaps, root := makeSnapSource(t)
+
+	db := rawdb.NewMemoryDatabase()
+	bloom := trie.NewSyncBloom(1, db)
+	defer bloom.Close()
+
+	syncer := NewSnapSyncer(db, bloom, nil)
+	for i := 0; i < 3; i++ {
+		syncer.Register(&snapTestPeer{id: fmt.Sprintf("peer-%d", i), snaps: snaps, sdb: sdb, syncer: syncer})
+	}
+	if err := runSnapSync(t, syncer, root, make(chan struct{})); err != nil {
+		t.Fatalf("failed to snap sync: %v", err)
+	}
+	checkStateComplete(t, db, root, 1001)
+}
+
+// Tests that if the pivot moves mid-sync, the ranges already retrieved are kept
+// and the resulting inconsistencies are healed.
+func TestSnapSyncPivotMove(t *testing.T) {
+	sdb, snaps, root := makeSnapSource(t)
+
+	// Create a new pivot state modifying a batch of accounts and slots
+	statedb, _ := state.New(root, sdb, snaps)
+	for i := int64(0); i < 1000; i += 7 {
+		addr := common.BigToAddress(big.NewInt(i + 1))
+		statedb.AddBalance(addr, big.NewInt(1))
+		statedb.SetState(addr, common.Hash{}, common.BigToHash(big.NewInt(i)))
+	}
+	statedb.SetCode(common.HexToAddress("0xc0de"), []byte{0x60, 0x01})
+	pivot, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit pivot state: %v", err)
+	}
+	if err := sdb.TrieDB().Commit(pivot, false, nil); err != nil {
+		t.Fatalf("failed to flush pivot state: %v", err)
+	}
+	// Sync the original state, interrupting it after a few account ranges
+	db := rawdb.NewMemoryDatabase()
+	bloom := trie.NewSyncBloom(1, db)
+	defer bloom.Close()
+
+	var (
+		syncer = NewSnapSyncer(db, bloom, nil)
+		cancel = make(chan struct{})
+		once   sync.Once
+	)
+	syncer.Register(&snapTestPeer{id: "peer", snaps: snaps, sdb: sdb, syncer: syncer, onAccounts: func() {
+		once.Do(func() { close(cancel) })
+	}})
+	if err := runSnapSync(t, syncer, root, cancel); err != errCancelStateFetch {
+		t.Fatalf("interrupted sync error mismatch: have %v, want %v", err, errCancelStateFetch)
+	}
+	// Continue the sync with the new pivot and ensure it's complete
+	if err := runSnapSync(t, syncer, pivot, make(chan struct{})); err != nil {
+		t.Fatalf("failed to snap sync moved pivot: %v", err)
+	}
+	checkStateComplete(t, db, pivot, 1002)
+}
+
+// Tests that peers delivering ranges not matching their proofs are dropped, and
+// the sync completes from the remaining peers.
+func TestSnapSyncDropInvalidPeer(t *testing.T) {
+	sdb, snaps, root := makeSnapSource(t)
+
+	db := rawdb.NewMemoryDatabase()
+	bloom := trie.NewSyncBloom(1, db)
+	defer bloom.Close()
+
+	var (
+		syncer  *SnapSyncer
+		dropped = make(chan string, 1)
+	)
+	syncer = NewSnapSyncer(db, bloom, func(id string) {
+		syncer.Unregister(id)
+		select {
+		case dropped <- id:
+		default:
+		}
+	})
+	syncer.Register(&snapTestPeer{id: "bad", snaps: snaps, sdb: sdb, syncer: syncer, corrupt: true})
+	syncer.Register(&snapTestPeer{id: "good", snaps: snaps, sdb: sdb, syncer: syncer})
+
+	if err := runSnapSync(t, syncer, root, make(chan struct{})); err != nil {
+		t.Fatalf("failed to snap sync: %v", err)
+	}
+	checkStateComplete(t, db, root, 1001)
+
+	select {
+	case id := <-dropped:
+		if id != "bad" {
+			t.Fatalf("dropped peer mismatch: have %s, want %s", id, "bad")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("peer delivering invalid ranges not dropped")
+	}
+}
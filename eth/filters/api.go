@@ -28,9 +28,11 @@ import (
 	ethereum "github.com/matthieu/go-ethereum"
 	"github.com/matthieu/go-ethereum/common"
 	"github.com/matthieu/go-ethereum/common/hexutil"
+	"github.com/matthieu/go-ethereum/core"
 	"github.com/matthieu/go-ethereum/core/types"
 	"github.com/matthieu/go-ethereum/ethdb"
 	"github.com/matthieu/go-ethereum/event"
+	"github.com/matthieu/go-ethereum/internal/ethapi"
 	"github.com/matthieu/go-ethereum/rpc"
 )
 
@@ -135,7 +137,10 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 
 // NewPendingTransactions creates a subscription that is triggered each time a transaction
 // enters the transaction pool and was signed from one of the transactions this nodes manages.
-func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+//
+// If criteria are given, only the transactions matching them are notified, as
+// full transaction objects if requested.
+func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, crit *PendingTxCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
@@ -143,6 +148,15 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 
 	rpcSub := notifier.CreateSubscription()
 
+	if crit != nil {
+		// Install the filter before returning, so no transaction entering the pool
+		// after the subscription is confirmed is missed
+		txs := make(chan []*types.Transaction, 128)
+		pendingTxSub := api.events.SubscribeFilteredPendingTxs(*crit, txs)
+
+		go api.filteredPendingTransactions(notifier, rpcSub, *crit, pendingTxSub, txs)
+		return rpcSub, nil
+	}
 	go func() {
 		txHashes := make(chan []common.Hash, 128)
 		pendingTxSub := api.events.SubscribePendingTxs(txHashes)
@@ -168,6 +182,29 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	return rpcSub, nil
 }
 
+// filteredPendingTransactions notifies the subscriber of every transaction that
+// enters the transaction pool and matches the criteria of the installed filter.
+func (api *PublicFilterAPI) filteredPendingTransactions(notifier *rpc.Notifier, rpcSub *rpc.Subscription, crit PendingTxCriteria, pendingTxSub *Subscription, txs chan []*types.Transaction) {
+	for {
+		select {
+		case matched := <-txs:
+			for _, tx := range matched {
+				if crit.FullTx {
+					notifier.Notify(rpcSub.ID, ethapi.NewRPCPendingTransaction(tx))
+				} else {
+					notifier.Notify(rpcSub.ID, tx.Hash())
+				}
+			}
+		case <-rpcSub.Err():
+			pendingTxSub.Unsubscribe()
+			return
+		case <-notifier.Closed():
+			pendingTxSub.Unsubscribe()
+			return
+		}
+	}
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with eth_getFilterChanges.
 //
@@ -271,6 +308,121 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 	return rpcSub, nil
 }
 
+// InternalTransactions creates a subscription that fires for the internal transactions
+// of the new canonical blocks matching the given criteria. In case of a chain reorg,
+// the previously returned internal transactions are sent again with the removed
+// property set to true.
+func (api *PublicFilterAPI) InternalTransactions(ctx context.Context, crit InternalTxCriteria) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	var (
+		rpcSub      = notifier.CreateSubscription()
+		matchedItxs = make(chan core.InternalTxsEvent)
+		itxsSub     = api.events.SubscribeInternalTxs(crit, matchedItxs)
+	)
+
+	go func() {
+		for {
+			select {
+			case ev := <-matchedItxs:
+				for i, itxs := range ev.Internals {
+					for _, itx := range itxs {
+						notifier.Notify(rpcSub.ID, newInternalTxNotification(itx, ev.Block, uint64(i), ev.Removed))
+					}
+				}
+			case <-rpcSub.Err(): // client send an unsubscribe request
+				itxsSub.Unsubscribe()
+				return
+			case <-notifier.Closed(): // connection dropped
+				itxsSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// internalTxNotification is an internal transaction sent to the subscribers, in
+// the RPC representation of the eth_getInternalTransactions* methods.
+type internalTxNotification struct {
+	*ethapi.RPCInternalTransaction
+	Removed bool `json:"removed"` // Whether the block of the internal transaction was rolled back
+}
+
+func newInternalTxNotification(itx *types.InternalTransaction, block *types.Block, index uint64, removed bool) *internalTxNotification {
+	return &internalTxNotification{
+		RPCInternalTransaction: ethapi.NewRPCInternalTransaction(itx, block.Hash(), block.NumberU64(), index),
+		Removed:                removed,
+	}
+}
+
+// InternalTxCriteria represents a request to subscribe to internal transactions.
+type InternalTxCriteria struct {
+	Addresses []common.Address // Matches the internal transactions from or to any of the addresses, all if empty
+	MinValue  *big.Int         // Minimum value transferred by the internal transactions, if set
+}
+
+// UnmarshalJSON sets *args fields with given data.
+func (args *InternalTxCriteria) UnmarshalJSON(data []byte) error {
+	type input struct {
+		Addresses interface{}  `json:"address"`
+		MinValue  *hexutil.Big `json:"minValue"`
+	}
+
+	var raw input
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	addresses, err := decodeAddresses(raw.Addresses)
+	if err != nil {
+		return err
+	}
+	args.Addresses = addresses
+	args.MinValue = (*big.Int)(raw.MinValue)
+	return nil
+}
+
+// PendingTxCriteria represents a request to subscribe to pending transactions.
+type PendingTxCriteria struct {
+	FullTx    bool             // Whether to notify full transactions instead of hashes
+	From      []common.Address // Matches the transactions sent by any of the addresses, all if empty
+	To        []common.Address // Matches the transactions sent to any of the addresses, all if empty
+	Selectors [][4]byte        // Matches the contract calls invoking any of the methods, all if empty
+}
+
+// UnmarshalJSON sets *args fields with given data.
+func (args *PendingTxCriteria) UnmarshalJSON(data []byte) error {
+	type input struct {
+		FullTx    bool        `json:"fullTx"`
+		From      interface{} `json:"from"`
+		To        interface{} `json:"to"`
+		Selectors interface{} `json:"selector"`
+	}
+
+	var raw input
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	from, err := decodeAddresses(raw.From)
+	if err != nil {
+		return err
+	}
+	to, err := decodeAddresses(raw.To)
+	if err != nil {
+		return err
+	}
+	selectors, err := decodeSelectors(raw.Selectors)
+	if err != nil {
+		return err
+	}
+	args.FullTx, args.From, args.To, args.Selectors = raw.FullTx, from, to, selectors
+	return nil
+}
+
 // FilterCriteria represents a request to create a new filter.
 // Same as ethereum.FilterQuery but with UnmarshalJSON() method.
 type FilterCriteria ethereum.FilterQuery
@@ -487,33 +639,11 @@ func (args *FilterCriteria) UnmarshalJSON(data []byte) error {
 		}
 	}
 
-	args.Addresses = []common.Address{}
-
-	if raw.Addresses != nil {
-		// raw.Address can contain a single address or an array of addresses
-		switch rawAddr := raw.Addresses.(type) {
-		case []interface{}:
-			for i, addr := range rawAddr {
-				if strAddr, ok := addr.(string); ok {
-					addr, err := decodeAddress(strAddr)
-					if err != nil {
-						return fmt.Errorf("invalid address at index %d: %v", i, err)
-					}
-					args.Addresses = append(args.Addresses, addr)
-				} else {
-					return fmt.Errorf("non-string address at index %d", i)
-				}
-			}
-		case string:
-			addr, err := decodeAddress(rawAddr)
-			if err != nil {
-				return fmt.Errorf("invalid address: %v", err)
-			}
-			args.Addresses = []common.Address{addr}
-		default:
-			return errors.New("invalid addresses in query")
-		}
+	addresses, err := decodeAddresses(raw.Addresses)
+	if err != nil {
+		return err
 	}
+	args.Addresses = addresses
 
 	// topics is an array consisting of strings and/or arrays of strings.
 	// JSON null values are converted to common.Hash{} and ignored by the filter manager.
@@ -559,6 +689,38 @@ func (args *FilterCriteria) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// decodeAddresses decodes the address criteria of a filter, which can contain a
+// single address or an array of addresses.
+func decodeAddresses(raw interface{}) ([]common.Address, error) {
+	addresses := []common.Address{}
+
+	if raw != nil {
+		switch rawAddr := raw.(type) {
+		case []interface{}:
+			for i, addr := range rawAddr {
+				if strAddr, ok := addr.(string); ok {
+					addr, err := decodeAddress(strAddr)
+					if err != nil {
+						return nil, fmt.Errorf("invalid address at index %d: %v", i, err)
+					}
+					addresses = append(addresses, addr)
+				} else {
+					return nil, fmt.Errorf("non-string address at index %d", i)
+				}
+			}
+		case string:
+			addr, err := decodeAddress(rawAddr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address: %v", err)
+			}
+			addresses = []common.Address{addr}
+		default:
+			return nil, errors.New("invalid addresses in query")
+		}
+	}
+	return addresses, nil
+}
+
 func decodeAddress(s string) (common.Address, error) {
 	b, err := hexutil.Decode(s)
 	if err == nil && len(b) != common.AddressLength {
@@ -574,3 +736,37 @@ func decodeTopic(s string) (common.Hash, error) {
 	}
 	return common.BytesToHash(b), err
 }
+
+// decodeSelectors decodes a single or a list of hex encoded 4-byte method
+// selectors.
+func decodeSelectors(raw interface{}) ([][4]byte, error) {
+	var strs []string
+	switch raw := raw.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		strs = []string{raw}
+	case []interface{}:
+		for i, item := range raw {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("non-string selector at index %d", i)
+			}
+			strs = append(strs, str)
+		}
+	default:
+		return nil, errors.New("invalid selectors in query")
+	}
+	selectors := make([][4]byte, len(strs))
+	for i, str := range strs {
+		blob, err := hexutil.Decode(str)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector at index %d: %v", i, err)
+		}
+		if len(blob) != 4 {
+			return nil, fmt.Errorf("invalid selector length %d at index %d", len(blob), i)
+		}
+		copy(selectors[i][:], blob)
+	}
+	return selectors, nil
+}
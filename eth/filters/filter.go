@@ -17,6 +17,7 @@
 package filters
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"math/big"
@@ -27,10 +28,12 @@ import (
 	"github.com/matthieu/go-ethereum/core/types"
 	"github.com/matthieu/go-ethereum/ethdb"
 	"github.com/matthieu/go-ethereum/event"
+	"github.com/matthieu/go-ethereum/params"
 	"github.com/matthieu/go-ethereum/rpc"
 )
 
 type Backend interface {
+	ChainConfig() *params.ChainConfig
 	ChainDb() ethdb.Database
 	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
 	HeaderByHash(ctx context.Context, blockHash common.Hash) (*types.Header, error)
@@ -42,6 +45,7 @@ type Backend interface {
 	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
 	SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription
+	SubscribeInternalTxsEvent(ch chan<- core.InternalTxsEvent) event.Subscription
 
 	BloomStatus() (uint64, uint64)
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
@@ -282,6 +286,32 @@ func includes(addresses []common.Address, a common.Address) bool {
 	return false
 }
 
+// filterInternalTxs filters the internal transactions of a block's transactions
+// according to the given criteria, keeping the per transaction grouping. Rejected
+// internal transactions are never matched, as their effects were reverted.
+func filterInternalTxs(internals []types.InternalTransactions, crit InternalTxCriteria) ([]types.InternalTransactions, bool) {
+	var (
+		matched = make([]types.InternalTransactions, len(internals))
+		found   bool
+	)
+	for i, itxs := range internals {
+		for _, itx := range itxs {
+			if itx.Rejected {
+				continue
+			}
+			if crit.MinValue != nil && itx.Value().Cmp(crit.MinValue) < 0 {
+				continue
+			}
+			if len(crit.Addresses) > 0 && !includes(crit.Addresses, *itx.Sender) && !includes(crit.Addresses, *itx.To()) {
+				continue
+			}
+			matched[i] = append(matched[i], itx)
+			found = true
+		}
+	}
+	return matched, found
+}
+
 // filterLogs creates a slice of logs matching the given criteria.
 func filterLogs(logs []*types.Log, fromBlock, toBlock *big.Int, addresses []common.Address, topics [][]common.Hash) []*types.Log {
 	var ret []*types.Log
@@ -346,3 +376,42 @@ func bloomFilter(bloom types.Bloom, addresses []common.Address, topics [][]commo
 	}
 	return true
 }
+
+// filterPendingTxs returns the transactions matching the given criteria. The
+// criteria are combined with AND, while the options within each are ORed.
+//
+// The senders are derived with the signer of the transaction pool, reusing the
+// addresses it already cached.
+func filterPendingTxs(txs []*types.Transaction, crit PendingTxCriteria, signer types.Signer) []*types.Transaction {
+	var matched []*types.Transaction
+	for _, tx := range txs {
+		if len(crit.From) > 0 {
+			from, err := types.Sender(signer, tx)
+			if err != nil || !includes(crit.From, from) {
+				continue
+			}
+		}
+		if len(crit.To) > 0 && (tx.To() == nil || !includes(crit.To, *tx.To())) {
+			continue
+		}
+		if len(crit.Selectors) > 0 && (tx.To() == nil || !includesSelector(crit.Selectors, tx.Data())) {
+			continue
+		}
+		matched = append(matched, tx)
+	}
+	return matched
+}
+
+// includesSelector reports whether the call data invokes any of the given 4-byte
+// method selectors.
+func includesSelector(selectors [][4]byte, data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	for _, selector := range selectors {
+		if bytes.Equal(selector[:], data[:4]) {
+			return true
+		}
+	}
+	return false
+}
@@ -22,6 +22,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	ethereum "github.com/matthieu/go-ethereum"
@@ -31,6 +32,7 @@ import (
 	"github.com/matthieu/go-ethereum/core/types"
 	"github.com/matthieu/go-ethereum/event"
 	"github.com/matthieu/go-ethereum/log"
+	"github.com/matthieu/go-ethereum/metrics"
 	"github.com/matthieu/go-ethereum/rpc"
 )
 
@@ -52,6 +54,12 @@ const (
 	PendingTransactionsSubscription
 	// BlocksSubscription queries hashes for blocks that are imported
 	BlocksSubscription
+	// InternalTransactionsSubscription queries for new or removed (chain reorg)
+	// internal transactions
+	InternalTransactionsSubscription
+	// FilteredPendingTransactionsSubscription queries full transactions matching
+	// the given criteria entering the pending state
+	FilteredPendingTransactionsSubscription
 	// LastSubscription keeps track of the last index
 	LastIndexSubscription
 )
@@ -66,9 +74,19 @@ const (
 	logsChanSize = 10
 	// chainEvChanSize is the size of channel listening to ChainEvent.
 	chainEvChanSize = 10
+	// internalsChanSize is the size of channel listening to InternalTxsEvent.
+	internalsChanSize = 10
+	// pendingTxsBufferSize is the number of transaction batches buffered for each
+	// filtered pending transaction subscription before new batches are dropped.
+	pendingTxsBufferSize = 256
 )
 
+// droppedPendingTxsMeter counts the transaction batches dropped because a filtered
+// pending transaction subscription could not keep up.
+var droppedPendingTxsMeter = metrics.NewRegisteredMeter("eth/filters/pendingtxs/dropped", nil)
+
 type subscription struct {
+	dropped   uint64 // Number of transaction batches dropped due to overflow (atomic)
 	id        rpc.ID
 	typ       Type
 	created   time.Time
@@ -76,6 +94,10 @@ type subscription struct {
 	logs      chan []*types.Log
 	hashes    chan []common.Hash
 	headers   chan *types.Header
+	itxsCrit  InternalTxCriteria
+	itxs      chan core.InternalTxsEvent
+	txsCrit   PendingTxCriteria
+	txs       chan []*types.Transaction
 	installed chan struct{} // closed when the filter is installed
 	err       chan error    // closed when the filter is uninstalled
 }
@@ -84,6 +106,7 @@ type subscription struct {
 // subscription which match the subscription criteria.
 type EventSystem struct {
 	backend   Backend
+	signer    types.Signer // Signer deriving the senders of pending transactions
 	lightMode bool
 	lastHead  *types.Header
 
@@ -93,6 +116,7 @@ type EventSystem struct {
 	rmLogsSub      event.Subscription // Subscription for removed log event
 	pendingLogsSub event.Subscription // Subscription for pending log event
 	chainSub       event.Subscription // Subscription for new chain event
+	itxsSub        event.Subscription // Subscription for new or removed internal transactions event
 
 	// Channels
 	install       chan *subscription         // install filter for event notification
@@ -102,6 +126,7 @@ type EventSystem struct {
 	pendingLogsCh chan []*types.Log          // Channel to receive new log event
 	rmLogsCh      chan core.RemovedLogsEvent // Channel to receive removed log event
 	chainCh       chan core.ChainEvent       // Channel to receive new chain event
+	itxsCh        chan core.InternalTxsEvent // Channel to receive new or removed internal transactions event
 }
 
 // NewEventSystem creates a new manager that listens for event on the given mux,
@@ -113,6 +138,7 @@ type EventSystem struct {
 func NewEventSystem(backend Backend, lightMode bool) *EventSystem {
 	m := &EventSystem{
 		backend:       backend,
+		signer:        types.LatestSignerForChainID(backend.ChainConfig().ChainID),
 		lightMode:     lightMode,
 		install:       make(chan *subscription),
 		uninstall:     make(chan *subscription),
@@ -121,6 +147,7 @@ func NewEventSystem(backend Backend, lightMode bool) *EventSystem {
 		rmLogsCh:      make(chan core.RemovedLogsEvent, rmLogsChanSize),
 		pendingLogsCh: make(chan []*types.Log, logsChanSize),
 		chainCh:       make(chan core.ChainEvent, chainEvChanSize),
+		itxsCh:        make(chan core.InternalTxsEvent, internalsChanSize),
 	}
 
 	// Subscribe events
@@ -129,9 +156,10 @@ func NewEventSystem(backend Backend, lightMode bool) *EventSystem {
 	m.rmLogsSub = m.backend.SubscribeRemovedLogsEvent(m.rmLogsCh)
 	m.chainSub = m.backend.SubscribeChainEvent(m.chainCh)
 	m.pendingLogsSub = m.backend.SubscribePendingLogsEvent(m.pendingLogsCh)
+	m.itxsSub = m.backend.SubscribeInternalTxsEvent(m.itxsCh)
 
 	// Make sure none of the subscriptions are empty
-	if m.txsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil || m.pendingLogsSub == nil {
+	if m.txsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil || m.pendingLogsSub == nil || m.itxsSub == nil {
 		log.Crit("Subscribe for event system failed")
 	}
 
@@ -167,6 +195,8 @@ func (sub *Subscription) Unsubscribe() {
 			case <-sub.f.logs:
 			case <-sub.f.hashes:
 			case <-sub.f.headers:
+			case <-sub.f.itxs:
+			case <-sub.f.txs:
 			}
 		}
 
@@ -234,6 +264,8 @@ func (es *EventSystem) subscribeMinedPendingLogs(crit ethereum.FilterQuery, logs
 		logs:      logs,
 		hashes:    make(chan []common.Hash),
 		headers:   make(chan *types.Header),
+		itxs:      make(chan core.InternalTxsEvent),
+		txs:       make(chan []*types.Transaction),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -251,6 +283,8 @@ func (es *EventSystem) subscribeLogs(crit ethereum.FilterQuery, logs chan []*typ
 		logs:      logs,
 		hashes:    make(chan []common.Hash),
 		headers:   make(chan *types.Header),
+		itxs:      make(chan core.InternalTxsEvent),
+		txs:       make(chan []*types.Transaction),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -268,6 +302,8 @@ func (es *EventSystem) subscribePendingLogs(crit ethereum.FilterQuery, logs chan
 		logs:      logs,
 		hashes:    make(chan []common.Hash),
 		headers:   make(chan *types.Header),
+		itxs:      make(chan core.InternalTxsEvent),
+		txs:       make(chan []*types.Transaction),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -284,6 +320,8 @@ func (es *EventSystem) SubscribeNewHeads(headers chan *types.Header) *Subscripti
 		logs:      make(chan []*types.Log),
 		hashes:    make(chan []common.Hash),
 		headers:   headers,
+		itxs:      make(chan core.InternalTxsEvent),
+		txs:       make(chan []*types.Transaction),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -300,12 +338,76 @@ func (es *EventSystem) SubscribePendingTxs(hashes chan []common.Hash) *Subscript
 		logs:      make(chan []*types.Log),
 		hashes:    hashes,
 		headers:   make(chan *types.Header),
+		itxs:      make(chan core.InternalTxsEvent),
+		txs:       make(chan []*types.Transaction),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+// SubscribeInternalTxs creates a subscription that writes the internal transactions
+// matching the given criteria of the blocks entering or leaving the canonical chain.
+func (es *EventSystem) SubscribeInternalTxs(crit InternalTxCriteria, itxs chan core.InternalTxsEvent) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       InternalTransactionsSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan []common.Hash),
+		headers:   make(chan *types.Header),
+		itxsCrit:  crit,
+		itxs:      itxs,
+		txs:       make(chan []*types.Transaction),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+// SubscribeFilteredPendingTxs creates a subscription that writes the transactions
+// matching the given criteria that enter the transaction pool.
+//
+// The new transactions are buffered and matched outside of the event loop, so a
+// slow subscriber doesn't hold up the others. If the buffer is full, new batches
+// are dropped and counted instead.
+func (es *EventSystem) SubscribeFilteredPendingTxs(crit PendingTxCriteria, txs chan []*types.Transaction) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       FilteredPendingTransactionsSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan []common.Hash),
+		headers:   make(chan *types.Header),
+		itxs:      make(chan core.InternalTxsEvent),
+		txsCrit:   crit,
+		txs:       make(chan []*types.Transaction, pendingTxsBufferSize),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
+	go sub.matchPendingTxs(txs, es.signer)
 	return es.subscribe(sub)
 }
 
+// matchPendingTxs filters the buffered transaction batches of the subscription,
+// forwarding the matching transactions until the subscription is uninstalled.
+func (sub *subscription) matchPendingTxs(txs chan []*types.Transaction, signer types.Signer) {
+	for {
+		select {
+		case batch := <-sub.txs:
+			if matched := filterPendingTxs(batch, sub.txsCrit, signer); len(matched) > 0 {
+				select {
+				case txs <- matched:
+				case <-sub.err:
+					return
+				}
+			}
+		case <-sub.err:
+			return
+		}
+	}
+}
+
 type filterIndex map[Type]map[rpc.ID]*subscription
 
 func (es *EventSystem) handleLogs(filters filterIndex, ev []*types.Log) {
@@ -349,6 +451,16 @@ func (es *EventSystem) handleTxsEvent(filters filterIndex, ev core.NewTxsEvent)
 	for _, f := range filters[PendingTransactionsSubscription] {
 		f.hashes <- hashes
 	}
+	for _, f := range filters[FilteredPendingTransactionsSubscription] {
+		select {
+		case f.txs <- ev.Txs:
+		default:
+			droppedPendingTxsMeter.Mark(1)
+			if atomic.AddUint64(&f.dropped, 1) == 1 {
+				log.Warn("Pending transaction subscriber overflowing, dropping transactions", "id", f.id)
+			}
+		}
+	}
 }
 
 func (es *EventSystem) handleChainEvent(filters filterIndex, ev core.ChainEvent) {
@@ -366,6 +478,14 @@ func (es *EventSystem) handleChainEvent(filters filterIndex, ev core.ChainEvent)
 	}
 }
 
+func (es *EventSystem) handleInternalTxsEvent(filters filterIndex, ev core.InternalTxsEvent) {
+	for _, f := range filters[InternalTransactionsSubscription] {
+		if matched, ok := filterInternalTxs(ev.Internals, f.itxsCrit); ok {
+			f.itxs <- core.InternalTxsEvent{Block: ev.Block, Internals: matched, Removed: ev.Removed}
+		}
+	}
+}
+
 func (es *EventSystem) lightFilterNewHead(newHeader *types.Header, callBack func(*types.Header, bool)) {
 	oldh := es.lastHead
 	es.lastHead = newHeader
@@ -448,6 +568,7 @@ func (es *EventSystem) eventLoop() {
 		es.rmLogsSub.Unsubscribe()
 		es.pendingLogsSub.Unsubscribe()
 		es.chainSub.Unsubscribe()
+		es.itxsSub.Unsubscribe()
 	}()
 
 	index := make(filterIndex)
@@ -467,6 +588,8 @@ func (es *EventSystem) eventLoop() {
 			es.handlePendingLogs(index, ev)
 		case ev := <-es.chainCh:
 			es.handleChainEvent(index, ev)
+		case ev := <-es.itxsCh:
+			es.handleInternalTxsEvent(index, ev)
 
 		case f := <-es.install:
 			if f.typ == MinedAndPendingLogsSubscription {
@@ -497,6 +620,8 @@ func (es *EventSystem) eventLoop() {
 			return
 		case <-es.chainSub.Err():
 			return
+		case <-es.itxsSub.Err():
+			return
 		}
 	}
 }
@@ -22,6 +22,7 @@ import (
 	"math/big"
 	"math/rand"
 	"reflect"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -32,6 +33,7 @@ import (
 	"github.com/matthieu/go-ethereum/core/bloombits"
 	"github.com/matthieu/go-ethereum/core/rawdb"
 	"github.com/matthieu/go-ethereum/core/types"
+	"github.com/matthieu/go-ethereum/crypto"
 	"github.com/matthieu/go-ethereum/ethdb"
 	"github.com/matthieu/go-ethereum/event"
 	"github.com/matthieu/go-ethereum/params"
@@ -47,6 +49,11 @@ type testBackend struct {
 	rmLogsFeed      event.Feed
 	pendingLogsFeed event.Feed
 	chainFeed       event.Feed
+	itxsFeed        event.Feed
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig {
+	return params.TestChainConfig
 }
 
 func (b *testBackend) ChainDb() ethdb.Database {
@@ -121,6 +128,10 @@ func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subsc
 	return b.chainFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeInternalTxsEvent(ch chan<- core.InternalTxsEvent) event.Subscription {
+	return b.itxsFeed.Subscribe(ch)
+}
+
 func (b *testBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, b.sections
 }
@@ -208,6 +219,159 @@ func TestBlockSubscription(t *testing.T) {
 	<-sub1.Err()
 }
 
+// TestInternalTxsSubscription tests that internal transaction subscriptions only
+// receive the executed internal transactions matching their criteria.
+func TestInternalTxsSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		api     = NewPublicFilterAPI(backend, false)
+		genesis = new(core.Genesis).MustCommit(db)
+
+		wallet   = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		contract = common.HexToAddress("0x2222222222222222222222222222222222222222")
+		other    = common.HexToAddress("0x3333333333333333333333333333333333333333")
+
+		paid      = types.NewInternalTransaction(0, new(big.Int), 0, contract, wallet, big.NewInt(100), nil, 1, 0, "call")
+		dust      = types.NewInternalTransaction(0, new(big.Int), 0, contract, wallet, big.NewInt(1), nil, 1, 1, "call")
+		reverted  = types.NewInternalTransaction(0, new(big.Int), 0, contract, wallet, big.NewInt(100), nil, 1, 0, "call")
+		unrelated = types.NewInternalTransaction(0, new(big.Int), 0, contract, other, big.NewInt(100), nil, 1, 0, "call")
+	)
+	reverted.Reject()
+
+	events := []core.InternalTxsEvent{
+		{Block: genesis, Internals: []types.InternalTransactions{{paid, dust}, {reverted}, {unrelated}}},
+		{Block: genesis, Internals: []types.InternalTransactions{{unrelated}}},
+		{Block: genesis, Internals: []types.InternalTransactions{{paid}}, Removed: true},
+	}
+	crit := InternalTxCriteria{Addresses: []common.Address{wallet}, MinValue: big.NewInt(10)}
+
+	matched := make(chan core.InternalTxsEvent)
+	sub := api.events.SubscribeInternalTxs(crit, matched)
+
+	go func() {
+		time.Sleep(1 * time.Second)
+		for _, ev := range events {
+			backend.itxsFeed.Send(ev)
+		}
+	}()
+	expected := []struct {
+		internals []types.InternalTransactions
+		removed   bool
+	}{
+		{[]types.InternalTransactions{{paid}, nil, nil}, false},
+		{[]types.InternalTransactions{{paid}}, true},
+	}
+	for i, tt := range expected {
+		select {
+		case ev := <-matched:
+			if ev.Removed != tt.removed {
+				t.Errorf("event %d: removed mismatch: have %v, want %v", i, ev.Removed, tt.removed)
+			}
+			if !reflect.DeepEqual(ev.Internals, tt.internals) {
+				t.Errorf("event %d: internal transactions mismatch: have %v, want %v", i, ev.Internals, tt.internals)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("event %d: timeout", i)
+		}
+	}
+	sub.Unsubscribe()
+}
+
+// TestFilteredPendingTxsSubscription tests that full pending transactions are
+// delivered to subscribers, filtered by sender, recipient and method selector.
+func TestFilteredPendingTxsSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		api     = NewPublicFilterAPI(backend, false)
+
+		key, _   = crypto.GenerateKey()
+		signer   = types.NewEIP155Signer(big.NewInt(1))
+		contract = common.HexToAddress("0x2222222222222222222222222222222222222222")
+		other    = common.HexToAddress("0x3333333333333333333333333333333333333333")
+		transfer = common.FromHex("0xa9059cbb0000")
+		approve  = common.FromHex("0x095ea7b30000")
+	)
+	sign := func(nonce uint64, to common.Address, data []byte, signer types.Signer) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, to, new(big.Int), 0, new(big.Int), data), signer, key)
+		return tx
+	}
+	var (
+		matching     = sign(0, contract, transfer, signer)
+		wrongTo      = sign(1, other, transfer, signer)
+		wrongCall    = sign(2, contract, approve, signer)
+		unsigned     = types.NewTransaction(3, contract, new(big.Int), 0, new(big.Int), transfer)
+		unprotected  = sign(4, contract, transfer, types.HomesteadSigner{})
+		transactions = []*types.Transaction{matching, wrongTo, wrongCall, unsigned, unprotected}
+	)
+	crit := PendingTxCriteria{
+		From:      []common.Address{crypto.PubkeyToAddress(key.PublicKey)},
+		To:        []common.Address{contract},
+		Selectors: [][4]byte{{0xa9, 0x05, 0x9c, 0xbb}},
+	}
+	matched := make(chan []*types.Transaction)
+	sub := api.events.SubscribeFilteredPendingTxs(crit, matched)
+
+	go func() {
+		time.Sleep(1 * time.Second)
+		backend.txFeed.Send(core.NewTxsEvent{Txs: transactions})
+	}()
+	select {
+	case txs := <-matched:
+		if len(txs) != 2 || txs[0].Hash() != matching.Hash() || txs[1].Hash() != unprotected.Hash() {
+			t.Errorf("matched transactions mismatch: have %v, want %x, %x", txs, matching.Hash(), unprotected.Hash())
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("timeout")
+	}
+	sub.Unsubscribe()
+}
+
+// TestFilteredPendingTxsOverflow tests that a filtered pending transaction
+// subscriber not reading its notifications has new batches dropped, instead of
+// blocking the event loop.
+func TestFilteredPendingTxsOverflow(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		api     = NewPublicFilterAPI(backend, false)
+
+		tx = types.NewTransaction(0, common.Address{1}, new(big.Int), 0, new(big.Int), nil)
+
+		// One batch is held by the matcher, one more than the buffer overflows
+		// and the last one ensures the overflow was handled by the event loop
+		batches = pendingTxsBufferSize + 3
+	)
+	stalled := api.events.SubscribeFilteredPendingTxs(PendingTxCriteria{}, make(chan []*types.Transaction))
+	defer stalled.Unsubscribe()
+
+	hashes := make(chan []common.Hash, batches)
+	sub := api.events.SubscribePendingTxs(hashes)
+	defer sub.Unsubscribe()
+
+	for i := 0; i < batches; i++ {
+		backend.txFeed.Send(core.NewTxsEvent{Txs: []*types.Transaction{tx}})
+	}
+	// Ensure the other subscribers are still served, and the overflow accounted
+	for i := 0; i < batches; i++ {
+		select {
+		case <-hashes:
+		case <-time.After(3 * time.Second):
+			t.Fatalf("event loop blocked after %d batches", i)
+		}
+	}
+	if dropped := atomic.LoadUint64(&stalled.f.dropped); dropped == 0 {
+		t.Errorf("no dropped batches accounted")
+	}
+}
+
 // TestPendingTxFilter tests whether pending tx filters retrieve all pending transactions that are posted to the event mux.
 func TestPendingTxFilter(t *testing.T) {
 	t.Parallel()
@@ -30,6 +30,8 @@ import (
 	"github.com/matthieu/go-ethereum/consensus"
 	"github.com/matthieu/go-ethereum/core"
 	"github.com/matthieu/go-ethereum/core/forkid"
+	"github.com/matthieu/go-ethereum/core/state"
+	"github.com/matthieu/go-ethereum/core/state/snapshot"
 	"github.com/matthieu/go-ethereum/core/types"
 	"github.com/matthieu/go-ethereum/eth/downloader"
 	"github.com/matthieu/go-ethereum/eth/fetcher"
@@ -71,6 +73,8 @@ type pmBlockChain interface {
 	Genesis() *types.Block
 	GetAncestor(hash common.Hash, number, ancestor uint64, maxNonCanonical *uint64) (common.Hash, uint64)
 	TrieNode(hash common.Hash) ([]byte, error)
+	Snapshot() *snapshot.Tree
+	StateCache() state.Database
 	GetReceiptsByHash(hash common.Hash) types.Receipts
 	GetTdByHash(hash common.Hash) *big.Int
 	StopInsert()
@@ -83,6 +87,7 @@ type ProtocolManager struct {
 	forkFilter forkid.Filter // Fork ID filter, constant across the lifetime of the node
 
 	fastSync  uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
+	snapSync  uint32 // Flag whether fast sync should retrieve the state via the snap protocol
 	acceptTxs uint32 // Flag whether we're considered synchronised (enables transaction processing)
 
 	checkpointNumber uint64      // Block number for the sync progress validator to cross reference
@@ -155,6 +160,9 @@ func NewProtocolManager(config *params.ChainConfig, checkpoint *params.TrustedCh
 		} else {
 			// If fast sync was requested and our database is empty, grant it
 			manager.fastSync = uint32(1)
+			if mode == downloader.SnapSync {
+				manager.snapSync = uint32(1)
+			}
 		}
 	}
 
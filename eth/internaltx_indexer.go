@@ -0,0 +1,110 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+package eth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/core"
+	"github.com/matthieu/go-ethereum/core/rawdb"
+	"github.com/matthieu/go-ethereum/core/types"
+	"github.com/matthieu/go-ethereum/ethdb"
+)
+
+const (
+	// internalTxIndexSectionSize is the number of blocks in a single internal
+	// transaction index section.
+	internalTxIndexSectionSize = 4096
+
+	// internalTxIndexConfirms is the number of confirmation blocks before an
+	// internal transaction index section is considered final.
+	internalTxIndexConfirms = 256
+
+	// internalTxIndexThrottling is the time to wait between processing two
+	// consecutive index sections, preventing disk overload during backfilling.
+	internalTxIndexThrottling = 100 * time.Millisecond
+)
+
+// InternalTxIndexer implements a core.ChainIndexer, building up an address index
+// for the internal transactions of the canonical chain. The internal transactions
+// are read from the database if they were persisted during import, otherwise
+// the blocks are reexecuted.
+type InternalTxIndexer struct {
+	eth    *Ethereum      // Ethereum instance to reexecute blocks with
+	db     ethdb.Database // Database instance to write index data into
+	batch  ethdb.Batch    // Batch accumulating the index entries of the current section
+	reexec uint64         // Number of blocks to reexecute for missing historical state
+}
+
+// NewInternalTxIndexer returns a chain indexer that generates the address index
+// of the internal transactions for the canonical chain.
+func NewInternalTxIndexer(eth *Ethereum, size, confirms uint64) *core.ChainIndexer {
+	backend := &InternalTxIndexer{
+		eth:    eth,
+		db:     eth.chainDb,
+		reexec: defaultTraceReexec,
+	}
+	table := rawdb.NewTable(eth.chainDb, string(rawdb.InternalTxIndexPrefix))
+
+	return core.NewChainIndexer(eth.chainDb, table, backend, size, confirms, internalTxIndexThrottling, "internaltxs")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new internal transaction
+// index section.
+func (b *InternalTxIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	b.batch = b.db.NewBatch()
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the internal transactions
+// of a new header into the index.
+func (b *InternalTxIndexer) Process(ctx context.Context, header *types.Header) error {
+	hash, number := header.Hash(), header.Number.Uint64()
+
+	internals, _ := rawdb.ReadInternalTxs(b.db, hash, number)
+	if internals == nil {
+		block := b.eth.blockchain.GetBlock(hash, number)
+		if block == nil {
+			return fmt.Errorf("block #%d [%x…] not found", number, hash[:4])
+		}
+		var err error
+		if internals, _, err = b.eth.internalTxsAtBlock(block, b.reexec); err != nil {
+			return err
+		}
+	}
+	rawdb.WriteInternalTxAddrEntries(b.batch, hash, number, internals)
+	if b.batch.ValueSize() >= ethdb.IdealBatchSize {
+		if err := b.batch.Write(); err != nil {
+			return err
+		}
+		b.batch.Reset()
+	}
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, writing out the index entries of
+// the section into the database.
+func (b *InternalTxIndexer) Commit() error {
+	return b.batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (b *InternalTxIndexer) Prune(threshold uint64) error {
+	return nil
+}
@@ -0,0 +1,298 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/common/hexutil"
+	"github.com/matthieu/go-ethereum/core/vm"
+)
+
+// callFrame is a single call reported by the call tracer. The field order is
+// the one of the JavaScript callTracer's output.
+type callFrame struct {
+	Type    string          `json:"type"`
+	From    *common.Address `json:"from,omitempty"`
+	To      *common.Address `json:"to,omitempty"`
+	Value   *hexutil.Big    `json:"value,omitempty"`
+	Gas     *hexutil.Uint64 `json:"gas,omitempty"`
+	GasUsed *hexutil.Uint64 `json:"gasUsed,omitempty"`
+	Input   *hexutil.Bytes  `json:"input,omitempty"`
+	Output  *hexutil.Bytes  `json:"output,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Time    string          `json:"time,omitempty"`
+	Calls   []*callFrame    `json:"calls,omitempty"`
+
+	gasIn   uint64 // Gas available when the call was made
+	gasCost uint64 // Cost of the call opcode, including the gas passed along
+	outOff  int64  // Memory offset of the call output in the caller
+	outLen  int64  // Length of the call output in the caller
+}
+
+// callTracer is a native implementation of the JavaScript callTracer, which
+// extracts and reports all the internal calls made by a transaction.
+type callTracer struct {
+	callstack []*callFrame // Current recursive call stack of the EVM execution
+	descended bool         // Whether we've just descended into an inner call
+
+	typ     string         // Type of the outermost call (CALL or CREATE)
+	from    common.Address // Sender of the outermost call
+	to      common.Address // Recipient of the outermost call
+	input   []byte         // Input of the outermost call
+	gas     uint64         // Gas available to the outermost call
+	value   *big.Int       // Value transferred by the outermost call
+	output  []byte         // Output of the outermost call
+	gasUsed uint64         // Gas used by the outermost call
+	time    string         // Execution time of the outermost call
+	failure string         // Error of the outermost call, if any
+
+	err       error  // Error, if one has occurred
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// newCallTracer creates a native call tracer.
+func newCallTracer() *callTracer {
+	return &callTracer{callstack: []*callFrame{{}}}
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *callTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *callTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.typ = "CALL"
+	if create {
+		t.typ = "CREATE"
+	}
+	t.from, t.to, t.input, t.gas, t.value = from, to, input, gas, value
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rData []byte, contract *vm.Contract, depth int, err error) error {
+	if t.err != nil {
+		return nil
+	}
+	// If tracing was interrupted, set the error and stop
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		t.err = t.reason
+		return nil
+	}
+	// Capture any errors immediately
+	if err != nil {
+		t.fault(err)
+		return nil
+	}
+	switch op {
+	case vm.CREATE, vm.CREATE2:
+		// If a new contract is being created, add to the call stack
+		inOff := stackOffset(stack, 1)
+		inEnd := inOff + stackOffset(stack, 2)
+
+		from := contract.Address()
+		input := hexutil.Bytes(memorySlice(memory, inOff, inEnd))
+		t.callstack = append(t.callstack, &callFrame{
+			Type:    op.String(),
+			From:    &from,
+			Input:   &input,
+			gasIn:   gas,
+			gasCost: cost,
+			Value:   (*hexutil.Big)(stackPeek(stack, 0)),
+		})
+		t.descended = true
+		return nil
+
+	case vm.SELFDESTRUCT:
+		// If a contract is being self destructed, gather that as a subcall too
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, &callFrame{Type: op.String()})
+		return nil
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		// Skip any pre-compile invocations, those are just fancy opcodes
+		to := common.BigToAddress(stackPeek(stack, 1))
+		if _, ok := vm.PrecompiledContractsIstanbul[to]; ok {
+			return nil
+		}
+		off := 1
+		if op == vm.DELEGATECALL || op == vm.STATICCALL {
+			off = 0
+		}
+		inOff := stackOffset(stack, 2+off)
+		inEnd := inOff + stackOffset(stack, 3+off)
+
+		from := contract.Address()
+		input := hexutil.Bytes(memorySlice(memory, inOff, inEnd))
+		call := &callFrame{
+			Type:    op.String(),
+			From:    &from,
+			To:      &to,
+			Input:   &input,
+			gasIn:   gas,
+			gasCost: cost,
+			outOff:  stackOffset(stack, 4+off),
+			outLen:  stackOffset(stack, 5+off),
+		}
+		if off == 1 {
+			call.Value = (*hexutil.Big)(stackPeek(stack, 2))
+		}
+		t.callstack = append(t.callstack, call)
+		t.descended = true
+		return nil
+	}
+	// If we've just descended into an inner call, retrieve it's true allowance. We
+	// need to extract if from within the call as there may be funky gas dynamics
+	// with regard to requested and actually given gas (2300 stipend, 63/64 rule).
+	// Calls made to plain accounts have no code to retrieve it from, so their gas
+	// is not reported.
+	if t.descended {
+		if depth >= len(t.callstack) {
+			allowance := hexutil.Uint64(gas)
+			t.callstack[len(t.callstack)-1].Gas = &allowance
+		}
+		t.descended = false
+	}
+	// If an existing call is returning, pop off the call stack
+	if op == vm.REVERT {
+		t.callstack[len(t.callstack)-1].Error = "execution reverted"
+		return nil
+	}
+	if depth == len(t.callstack)-1 {
+		// Pop off the last call and get the execution results
+		call := t.callstack[len(t.callstack)-1]
+		t.callstack = t.callstack[:len(t.callstack)-1]
+
+		if call.Type == vm.CREATE.String() || call.Type == vm.CREATE2.String() {
+			// If the call was a CREATE, retrieve the contract address and output code
+			gasUsed := hexutil.Uint64(call.gasIn - call.gasCost - gas)
+			call.GasUsed = &gasUsed
+
+			if ret := stackPeek(stack, 0); ret.Sign() != 0 {
+				to := common.BigToAddress(ret)
+				output := hexutil.Bytes(env.StateDB.GetCode(to))
+				call.To, call.Output = &to, &output
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		} else if call.Gas != nil {
+			// If the call was a contract call, retrieve the gas usage and output
+			gasUsed := hexutil.Uint64(call.gasIn - call.gasCost + uint64(*call.Gas) - gas)
+			call.GasUsed = &gasUsed
+
+			if ret := stackPeek(stack, 0); ret.Sign() != 0 {
+				output := hexutil.Bytes(memorySlice(memory, call.outOff, call.outOff+call.outLen))
+				call.Output = &output
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		}
+		// Inject the call into the previous one
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, call)
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, contract *vm.Contract, depth int, err error) error {
+	if t.err == nil {
+		t.fault(err)
+	}
+	return nil
+}
+
+// fault flattens the call failed by an opcode into its parent.
+func (t *callTracer) fault(err error) {
+	// If the topmost call already reverted, don't handle the additional fault again
+	if t.callstack[len(t.callstack)-1].Error != "" {
+		return
+	}
+	// Pop off the just failed call, consuming all its available gas
+	call := t.callstack[len(t.callstack)-1]
+	t.callstack = t.callstack[:len(t.callstack)-1]
+
+	call.Error = err.Error()
+	if call.Gas != nil {
+		gasUsed := *call.Gas
+		call.GasUsed = &gasUsed
+	}
+	// Flatten the failed call into its parent, unless it was the last one
+	if len(t.callstack) > 0 {
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, call)
+		return
+	}
+	t.callstack = append(t.callstack, call)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64, elapsed time.Duration, err error) error {
+	t.output, t.gasUsed, t.time = output, gasUsed, elapsed.String()
+	if err != nil {
+		t.failure = err.Error()
+	}
+	return nil
+}
+
+// GetResult returns the outermost call along with all its internal calls, or
+// any error that occurred while tracing.
+func (t *callTracer) GetResult() (json.RawMessage, error) {
+	value := t.value
+	if value == nil {
+		value = new(big.Int)
+	}
+	var (
+		gas     = hexutil.Uint64(t.gas)
+		gasUsed = hexutil.Uint64(t.gasUsed)
+		input   = hexutil.Bytes(t.input)
+		output  = hexutil.Bytes(t.output)
+	)
+	result := &callFrame{
+		Type:    t.typ,
+		From:    &t.from,
+		To:      &t.to,
+		Value:   (*hexutil.Big)(value),
+		Gas:     &gas,
+		GasUsed: &gasUsed,
+		Input:   &input,
+		Output:  &output,
+		Error:   t.callstack[0].Error,
+		Time:    t.time,
+		Calls:   t.callstack[0].Calls,
+	}
+	if result.Error == "" {
+		result.Error = t.failure
+	}
+	if result.Error != "" {
+		result.Output = nil
+	}
+	blob, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return blob, t.err
+}
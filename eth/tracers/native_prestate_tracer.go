@@ -0,0 +1,245 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/common/hexutil"
+	"github.com/matthieu/go-ethereum/core/vm"
+	"github.com/matthieu/go-ethereum/crypto"
+)
+
+// prestateAccount is the prestate of a single account accessed by a transaction.
+type prestateAccount struct {
+	Balance *hexutil.Big     `json:"balance"`
+	Nonce   uint64           `json:"nonce"`
+	Code    hexutil.Bytes    `json:"code"`
+	Storage *prestateStorage `json:"storage"`
+}
+
+// prestateStorage is the set of storage slots accessed in an account, encoded
+// in the order they were first accessed.
+type prestateStorage struct {
+	keys  []common.Hash
+	slots map[common.Hash]common.Hash
+}
+
+// MarshalJSON implements json.Marshaler, retaining the access order.
+func (s *prestateStorage) MarshalJSON() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	for i, key := range s.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`"` + key.Hex() + `":"` + s.slots[key].Hex() + `"`)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// prestateAlloc is the set of accounts accessed by a transaction, encoded in
+// the order they were first accessed.
+type prestateAlloc struct {
+	addrs    []common.Address
+	accounts map[common.Address]*prestateAccount
+}
+
+// MarshalJSON implements json.Marshaler, retaining the access order.
+func (a *prestateAlloc) MarshalJSON() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	for i, addr := range a.addrs {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		blob, err := json.Marshal(a.accounts[addr])
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(`"` + hexutil.Encode(addr[:]) + `":`)
+		buf.Write(blob)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// prestateTracer is a native implementation of the JavaScript prestateTracer,
+// which outputs sufficient information to create a local execution of the
+// transaction from a custom assembled genesis block.
+type prestateTracer struct {
+	prestate *prestateAlloc // Genesis allocation being built, nil until the first step
+	db       vm.StateDB     // State database accessed by the transaction
+
+	create bool           // Whether the outermost call is a contract creation
+	from   common.Address // Sender of the outermost call
+	to     common.Address // Recipient of the outermost call
+	value  *big.Int       // Value transferred by the outermost call
+
+	err       error  // Error, if one has occurred
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// newPrestateTracer creates a native prestate tracer.
+func newPrestateTracer() *prestateTracer {
+	return new(prestateTracer)
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *prestateTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// lookupAccount injects the specified account into the prestate.
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.prestate.accounts[addr]; ok {
+		return
+	}
+	t.prestate.addrs = append(t.prestate.addrs, addr)
+	t.prestate.accounts[addr] = &prestateAccount{
+		Balance: (*hexutil.Big)(new(big.Int).Set(t.db.GetBalance(addr))),
+		Nonce:   t.db.GetNonce(addr),
+		Code:    t.db.GetCode(addr),
+		Storage: &prestateStorage{slots: make(map[common.Hash]common.Hash)},
+	}
+}
+
+// lookupStorage injects the specified storage entry of the given account into
+// the prestate.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	t.lookupAccount(addr)
+
+	storage := t.prestate.accounts[addr].Storage
+	if _, ok := storage.slots[key]; ok {
+		return
+	}
+	storage.keys = append(storage.keys, key)
+	storage.slots[key] = t.db.GetState(addr, key)
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *prestateTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.create, t.from, t.to, t.value = create, from, to, value
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rData []byte, contract *vm.Contract, depth int, err error) error {
+	if t.err != nil {
+		return nil
+	}
+	// If tracing was interrupted, set the error and stop
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		t.err = t.reason
+		return nil
+	}
+	// Add the current account if we just started tracing. Balance will potentially
+	// be wrong here, since this will include the value sent along with the message.
+	// We fix that in GetResult.
+	t.db = env.StateDB
+	if t.prestate == nil {
+		t.prestate = &prestateAlloc{accounts: make(map[common.Address]*prestateAccount)}
+		t.lookupAccount(contract.Address())
+	}
+	// Whenever new state is accessed, add it to the prestate
+	switch op {
+	case vm.EXTCODECOPY, vm.EXTCODESIZE, vm.BALANCE:
+		t.lookupAccount(common.BigToAddress(stackPeek(stack, 0)))
+
+	case vm.CREATE:
+		from := contract.Address()
+		t.lookupAccount(crypto.CreateAddress(from, t.db.GetNonce(from)))
+
+	case vm.CREATE2:
+		// stack: salt, size, offset, endowment
+		offset := stackOffset(stack, 1)
+		code := memorySlice(memory, offset, offset+stackOffset(stack, 2))
+		salt := common.BigToHash(stackPeek(stack, 3))
+		t.lookupAccount(crypto.CreateAddress2(contract.Address(), salt, crypto.Keccak256(code)))
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.lookupAccount(common.BigToAddress(stackPeek(stack, 1)))
+
+	case vm.SSTORE, vm.SLOAD:
+		t.lookupStorage(contract.Address(), common.BigToHash(stackPeek(stack, 0)))
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *prestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, elapsed time.Duration, err error) error {
+	return nil
+}
+
+// GetResult returns the assembled allocations, or any error that occurred while
+// tracing.
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	if t.prestate == nil {
+		return nil, errors.New("no code executed, prestate unavailable")
+	}
+	value := t.value
+	if value == nil {
+		value = new(big.Int)
+	}
+	// At this point, we need to deduct the 'value' from the outer transaction,
+	// and move it back to the origin
+	t.lookupAccount(t.from)
+	t.lookupAccount(t.to)
+
+	var (
+		from    = t.prestate.accounts[t.from]
+		to      = t.prestate.accounts[t.to]
+		fromBal = new(big.Int).Add(from.Balance.ToInt(), value)
+		toBal   = new(big.Int).Sub(to.Balance.ToInt(), value)
+	)
+	to.Balance = (*hexutil.Big)(toBal)
+	from.Balance = (*hexutil.Big)(fromBal)
+
+	// Decrement the caller's nonce, and remove empty create targets. We can blindly
+	// delete the contract prestate, as any existing state would have caused the
+	// transaction to be rejected as invalid in the first place.
+	from.Nonce--
+	if t.create {
+		delete(t.prestate.accounts, t.to)
+		for i, addr := range t.prestate.addrs {
+			if addr == t.to {
+				t.prestate.addrs = append(t.prestate.addrs[:i], t.prestate.addrs[i+1:]...)
+				break
+			}
+		}
+	}
+	blob, err := json.Marshal(t.prestate)
+	if err != nil {
+		return nil, err
+	}
+	return blob, t.err
+}
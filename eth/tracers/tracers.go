@@ -14,19 +14,44 @@
 // You should have received a copy of the GNU Lesser General Public License
 // along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
 
-// Package tracers is a collection of JavaScript transaction tracers.
+// Package tracers is a collection of JavaScript and native transaction tracers.
 package tracers
 
 import (
+	"encoding/json"
+	"math"
+	"math/big"
 	"strings"
 	"unicode"
 
+	"github.com/matthieu/go-ethereum/core/vm"
 	"github.com/matthieu/go-ethereum/eth/tracers/internal/tracers"
 )
 
+// TxTracer is a transaction tracer assembling its own result, which can be
+// interrupted if it runs for too long. It is implemented by both the JavaScript
+// and the native tracers.
+type TxTracer interface {
+	vm.Tracer
+
+	// GetResult returns the JSON encoded result of the trace, or any error that
+	// occurred while tracing.
+	GetResult() (json.RawMessage, error)
+
+	// Stop terminates execution of the tracer at the first opportune moment.
+	Stop(err error)
+}
+
 // all contains all the built in JavaScript tracers by name.
 var all = make(map[string]string)
 
+// native contains the built in tracers implemented in Go, shadowing the same
+// named JavaScript ones.
+var native = map[string]func() TxTracer{
+	"callTracer":     func() TxTracer { return newCallTracer() },
+	"prestateTracer": func() TxTracer { return newPrestateTracer() },
+}
+
 // camel converts a snake cased input string into a camel cased output.
 func camel(str string) string {
 	pieces := strings.Split(str, "_")
@@ -51,3 +76,40 @@ func tracer(name string) (string, bool) {
 	}
 	return "", false
 }
+
+// NewTxTracer creates a tracer from the given name or JavaScript snippet. Built
+// in tracers with a native implementation are preferred over their JavaScript
+// counterparts, everything else is handed to the JavaScript tracer.
+func NewTxTracer(code string) (TxTracer, error) {
+	if constructor, ok := native[code]; ok {
+		return constructor(), nil
+	}
+	return New(code)
+}
+
+// stackPeek returns the nth-from-the-top element of the stack, or zero if the
+// stack is not deep enough.
+func stackPeek(stack *vm.Stack, n int) *big.Int {
+	if len(stack.Data()) <= n {
+		return new(big.Int)
+	}
+	return stack.Back(n).ToBig()
+}
+
+// stackOffset returns the nth-from-the-top element of the stack as a memory
+// offset, saturating values which do not fit.
+func stackOffset(stack *vm.Stack, n int) int64 {
+	if v := stackPeek(stack, n); v.IsInt64() {
+		return v.Int64()
+	}
+	return math.MaxInt64
+}
+
+// memorySlice returns a copy of the requested range of memory, or an empty one
+// if the range is invalid or not yet allocated.
+func memorySlice(memory *vm.Memory, begin, end int64) []byte {
+	if end <= begin || begin < 0 || int64(memory.Len()) < end {
+		return []byte{}
+	}
+	return memory.GetCopy(begin, end-begin)
+}
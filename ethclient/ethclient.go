@@ -28,7 +28,6 @@ import (
 	"github.com/matthieu/go-ethereum/common"
 	"github.com/matthieu/go-ethereum/common/hexutil"
 	"github.com/matthieu/go-ethereum/core/types"
-	"github.com/matthieu/go-ethereum/rlp"
 	"github.com/matthieu/go-ethereum/rpc"
 )
 
@@ -278,6 +277,16 @@ func (ec *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*
 	return r, err
 }
 
+// BlockReceipts returns the receipts of all the transactions in the given block.
+func (ec *Client) BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	var r []*types.Receipt
+	err := ec.c.CallContext(ctx, &r, "eth_getBlockReceipts", blockNrOrHash)
+	if err == nil && r == nil {
+		return nil, ethereum.NotFound
+	}
+	return r, err
+}
+
 func toBlockNumArg(number *big.Int) string {
 	if number == nil {
 		return "latest"
@@ -456,7 +465,31 @@ func (ec *Client) PendingTransactionCount(ctx context.Context) (uint, error) {
 	return uint(num), err
 }
 
-// TODO: SubscribePendingTransactions (needs server side)
+// SubscribePendingTransactions subscribes to notifications about the transactions
+// matching the given query that enter the transaction pool.
+func (ec *Client) SubscribePendingTransactions(ctx context.Context, q ethereum.PendingTxQuery, ch chan<- *types.Transaction) (ethereum.Subscription, error) {
+	return ec.c.EthSubscribe(ctx, ch, "newPendingTransactions", toPendingTxArg(q))
+}
+
+func toPendingTxArg(q ethereum.PendingTxQuery) interface{} {
+	arg := map[string]interface{}{
+		"fullTx": true,
+	}
+	if len(q.From) > 0 {
+		arg["from"] = q.From
+	}
+	if len(q.To) > 0 {
+		arg["to"] = q.To
+	}
+	if len(q.Selectors) > 0 {
+		selectors := make([]hexutil.Bytes, len(q.Selectors))
+		for i := range q.Selectors {
+			selectors[i] = q.Selectors[i][:]
+		}
+		arg["selector"] = selectors
+	}
+	return arg
+}
 
 // Contract Calling
 
@@ -514,7 +547,7 @@ func (ec *Client) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64
 // If the transaction was a contract creation use the TransactionReceipt method to get the
 // contract address after the transaction has been mined.
 func (ec *Client) SendTransaction(ctx context.Context, tx *types.Transaction) error {
-	data, err := rlp.EncodeToBytes(tx)
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return err
 	}
@@ -538,5 +571,8 @@ func toCallArg(msg ethereum.CallMsg) interface{} {
 	if msg.GasPrice != nil {
 		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
 	}
+	if msg.AccessList != nil {
+		arg["accessList"] = msg.AccessList
+	}
 	return arg
 }
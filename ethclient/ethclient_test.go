@@ -35,6 +35,7 @@ import (
 	"github.com/matthieu/go-ethereum/eth"
 	"github.com/matthieu/go-ethereum/node"
 	"github.com/matthieu/go-ethereum/params"
+	"github.com/matthieu/go-ethereum/rpc"
 )
 
 // Verify that Client implements the ethereum interfaces.
@@ -351,3 +352,91 @@ func TestChainID(t *testing.T) {
 		t.Fatalf("ChainID returned wrong number: %+v", id)
 	}
 }
+
+func TestBlockReceipts(t *testing.T) {
+	backend, chain := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Stop()
+	defer client.Close()
+	ec := NewClient(client)
+
+	tests := map[string]struct {
+		block   rpc.BlockNumberOrHash
+		wantErr error
+	}{
+		"latest": {
+			block: rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber),
+		},
+		"first_block_hash": {
+			block: rpc.BlockNumberOrHashWithHash(chain[1].Hash(), false),
+		},
+		"first_block_canonical_hash": {
+			block: rpc.BlockNumberOrHashWithHash(chain[1].Hash(), true),
+		},
+		"first_block_number": {
+			block: rpc.BlockNumberOrHashWithNumber(1),
+		},
+		"future_block": {
+			block:   rpc.BlockNumberOrHashWithNumber(1000000000),
+			wantErr: ethereum.NotFound,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			receipts, err := ec.BlockReceipts(ctx, tt.block)
+			if err != tt.wantErr {
+				t.Fatalf("BlockReceipts(%v) error = %v, want %v", tt.block.String(), err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (receipts == nil || len(receipts) != len(chain[1].Transactions())) {
+				t.Fatalf("BlockReceipts(%v) returned %d receipts, want %d", tt.block.String(), len(receipts), len(chain[1].Transactions()))
+			}
+		})
+	}
+}
+
+func TestSubscribePendingTransactions(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Stop()
+	defer client.Close()
+	ec := NewClient(client)
+
+	var (
+		contract = common.Address{0xc0}
+		selector = [4]byte{0xa9, 0x05, 0x9c, 0xbb}
+		signer   = types.NewEIP155Signer(params.AllEthashProtocolChanges.ChainID)
+	)
+	ch := make(chan *types.Transaction)
+	sub, err := ec.SubscribePendingTransactions(context.Background(), ethereum.PendingTxQuery{
+		From:      []common.Address{testAddr},
+		To:        []common.Address{contract},
+		Selectors: [][4]byte{selector},
+	}, ch)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	// Send a transaction not matching the query, followed by a matching one
+	other, _ := types.SignTx(types.NewTransaction(0, common.Address{0xc1}, new(big.Int), 100000, big.NewInt(1), selector[:]), signer, testKey)
+	match, _ := types.SignTx(types.NewTransaction(1, contract, new(big.Int), 100000, big.NewInt(1), append(selector[:], 0x01)), signer, testKey)
+
+	for _, tx := range []*types.Transaction{other, match} {
+		if err := ec.SendTransaction(context.Background(), tx); err != nil {
+			t.Fatalf("failed to send transaction: %v", err)
+		}
+	}
+	select {
+	case tx := <-ch:
+		if tx.Hash() != match.Hash() {
+			t.Fatalf("notified transaction mismatch: have %x, want %x", tx.Hash(), match.Hash())
+		}
+	case err := <-sub.Err():
+		t.Fatalf("subscription failed: %v", err)
+	case <-time.After(3 * time.Second):
+		t.Fatalf("timeout waiting for pending transaction")
+	}
+}
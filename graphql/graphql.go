@@ -41,7 +41,7 @@ var (
 
 // Account represents an Ethereum account at a particular block.
 type Account struct {
-	backend       ethapi.Backend
+	backend       Backend
 	address       common.Address
 	blockNrOrHash rpc.BlockNumberOrHash
 }
@@ -90,7 +90,7 @@ func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash })
 
 // Log represents an individual log message. All arguments are mandatory.
 type Log struct {
-	backend     ethapi.Backend
+	backend     Backend
 	transaction *Transaction
 	log         *types.Log
 }
@@ -119,10 +119,135 @@ func (l *Log) Data(ctx context.Context) hexutil.Bytes {
 	return hexutil.Bytes(l.log.Data)
 }
 
+// InternalTransaction represents a transaction nested in the execution of another
+// one. All arguments are mandatory.
+type InternalTransaction struct {
+	backend     Backend
+	transaction *Transaction
+	itx         *types.InternalTransaction
+}
+
+func (i *InternalTransaction) Transaction(ctx context.Context) *Transaction {
+	return i.transaction
+}
+
+func (i *InternalTransaction) Index(ctx context.Context) int32 {
+	return int32(i.itx.Index)
+}
+
+func (i *InternalTransaction) Depth(ctx context.Context) int32 {
+	return int32(i.itx.Depth)
+}
+
+func (i *InternalTransaction) Type(ctx context.Context) string {
+	return i.itx.Note
+}
+
+func (i *InternalTransaction) From(ctx context.Context, args BlockNumberArgs) *Account {
+	return &Account{
+		backend:       i.backend,
+		address:       *i.itx.Sender,
+		blockNrOrHash: args.NumberOrLatest(),
+	}
+}
+
+func (i *InternalTransaction) To(ctx context.Context, args BlockNumberArgs) *Account {
+	return &Account{
+		backend:       i.backend,
+		address:       *i.itx.To(),
+		blockNrOrHash: args.NumberOrLatest(),
+	}
+}
+
+func (i *InternalTransaction) Value(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*i.itx.Value())
+}
+
+func (i *InternalTransaction) Gas(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(i.itx.Gas())
+}
+
+func (i *InternalTransaction) GasUsed(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(i.itx.GasUsed)
+}
+
+func (i *InternalTransaction) Rejected(ctx context.Context) bool {
+	return i.itx.Rejected
+}
+
+func (i *InternalTransaction) Error(ctx context.Context) *string {
+	if i.itx.Error == "" {
+		return nil
+	}
+	return &i.itx.Error
+}
+
+// Receipt represents the outcome of a mined transaction. All arguments are
+// mandatory.
+type Receipt struct {
+	backend     Backend
+	transaction *Transaction
+	receipt     *types.Receipt
+}
+
+func (r *Receipt) Transaction(ctx context.Context) *Transaction {
+	return r.transaction
+}
+
+func (r *Receipt) Status(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(r.receipt.Status)
+}
+
+func (r *Receipt) GasUsed(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(r.receipt.GasUsed)
+}
+
+func (r *Receipt) CumulativeGasUsed(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(r.receipt.CumulativeGasUsed)
+}
+
+func (r *Receipt) CreatedContract(ctx context.Context, args BlockNumberArgs) *Account {
+	if r.receipt.ContractAddress == (common.Address{}) {
+		return nil
+	}
+	return &Account{
+		backend:       r.backend,
+		address:       r.receipt.ContractAddress,
+		blockNrOrHash: args.NumberOrLatest(),
+	}
+}
+
+func (r *Receipt) Logs(ctx context.Context) []*Log {
+	ret := make([]*Log, 0, len(r.receipt.Logs))
+	for _, log := range r.receipt.Logs {
+		ret = append(ret, &Log{
+			backend:     r.backend,
+			transaction: r.transaction,
+			log:         log,
+		})
+	}
+	return ret
+}
+
+func (r *Receipt) LogsBloom(ctx context.Context) hexutil.Bytes {
+	return hexutil.Bytes(r.receipt.Bloom.Bytes())
+}
+
+// InternalTransactionFilterArgs are the arguments of the internalTransactions
+// accessors, restricting the results to the ones involving an address.
+type InternalTransactionFilterArgs struct {
+	Address *common.Address
+}
+
+// match returns whether an internal transaction satisfies the filter.
+func (args InternalTransactionFilterArgs) match(itx *types.InternalTransaction) bool {
+	return args.Address == nil || *itx.Sender == *args.Address || *itx.To() == *args.Address
+}
+
 // Transaction represents an Ethereum transaction.
 // backend and hash are mandatory; all others will be fetched when required.
 type Transaction struct {
-	backend ethapi.Backend
+	backend Backend
 	hash    common.Hash
 	tx      *types.Transaction
 	block   *Block
@@ -314,6 +439,33 @@ func (t *Transaction) Logs(ctx context.Context) (*[]*Log, error) {
 	return &ret, nil
 }
 
+func (t *Transaction) InternalTransactions(ctx context.Context, args InternalTransactionFilterArgs) (*[]*InternalTransaction, error) {
+	if _, err := t.resolve(ctx); err != nil {
+		return nil, err
+	}
+	if t.block == nil {
+		return nil, nil
+	}
+	internals, err := t.block.resolveInternals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*InternalTransaction, 0)
+	if int(t.index) >= len(internals) {
+		return &ret, nil
+	}
+	for _, itx := range internals[t.index] {
+		if args.match(itx) {
+			ret = append(ret, &InternalTransaction{
+				backend:     t.backend,
+				transaction: t,
+				itx:         itx,
+			})
+		}
+	}
+	return &ret, nil
+}
+
 func (t *Transaction) R(ctx context.Context) (hexutil.Big, error) {
 	tx, err := t.resolve(ctx)
 	if err != nil || tx == nil {
@@ -347,12 +499,13 @@ type BlockType int
 // backend, and numberOrHash are mandatory. All other fields are lazily fetched
 // when required.
 type Block struct {
-	backend      ethapi.Backend
+	backend      Backend
 	numberOrHash *rpc.BlockNumberOrHash
 	hash         common.Hash
 	header       *types.Header
 	block        *types.Block
 	receipts     []*types.Receipt
+	internals    []types.InternalTransactions
 }
 
 // resolve returns the internal Block object representing this block, fetching
@@ -415,6 +568,27 @@ func (b *Block) resolveReceipts(ctx context.Context) ([]*types.Receipt, error) {
 	return b.receipts, nil
 }
 
+// resolveInternals returns the internal transactions of the transactions in this
+// block, fetching or recomputing them if necessary.
+func (b *Block) resolveInternals(ctx context.Context) ([]types.InternalTransactions, error) {
+	if b.internals == nil {
+		hash := b.hash
+		if hash == (common.Hash{}) {
+			header, err := b.resolveHeader(ctx)
+			if err != nil {
+				return nil, err
+			}
+			hash = header.Hash()
+		}
+		internals, err := b.backend.GetInternalTxs(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		b.internals = internals
+	}
+	return b.internals, nil
+}
+
 func (b *Block) Number(ctx context.Context) (hexutil.Uint64, error) {
 	header, err := b.resolveHeader(ctx)
 	if err != nil {
@@ -650,6 +824,74 @@ func (b *Block) Transactions(ctx context.Context) (*[]*Transaction, error) {
 	return &ret, nil
 }
 
+func (b *Block) Receipts(ctx context.Context) ([]*Receipt, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	receipts, err := b.resolveReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*Receipt, 0, len(receipts))
+	for i, receipt := range receipts {
+		if i >= len(block.Transactions()) {
+			break
+		}
+		tx := block.Transactions()[i]
+		ret = append(ret, &Receipt{
+			backend: b.backend,
+			transaction: &Transaction{
+				backend: b.backend,
+				hash:    tx.Hash(),
+				tx:      tx,
+				block:   b,
+				index:   uint64(i),
+			},
+			receipt: receipt,
+		})
+	}
+	return ret, nil
+}
+
+func (b *Block) InternalTransactions(ctx context.Context, args InternalTransactionFilterArgs) ([]*InternalTransaction, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	internals, err := b.resolveInternals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*InternalTransaction, 0)
+	for i, itxs := range internals {
+		var (
+			tx          = block.Transactions()[i]
+			transaction *Transaction
+		)
+		for _, itx := range itxs {
+			if !args.match(itx) {
+				continue
+			}
+			if transaction == nil {
+				transaction = &Transaction{
+					backend: b.backend,
+					hash:    tx.Hash(),
+					tx:      tx,
+					block:   b,
+					index:   uint64(i),
+				}
+			}
+			ret = append(ret, &InternalTransaction{
+				backend:     b.backend,
+				transaction: transaction,
+				itx:         itx,
+			})
+		}
+	}
+	return ret, nil
+}
+
 func (b *Block) TransactionAt(ctx context.Context, args struct{ Index int32 }) (*Transaction, error) {
 	block, err := b.resolve(ctx)
 	if err != nil || block == nil {
@@ -708,7 +950,7 @@ type BlockFilterCriteria struct {
 
 // runFilter accepts a filter and executes it, returning all its results as
 // `Log` objects.
-func runFilter(ctx context.Context, be ethapi.Backend, filter *filters.Filter) ([]*Log, error) {
+func runFilter(ctx context.Context, be Backend, filter *filters.Filter) ([]*Log, error) {
 	logs, err := filter.Logs(ctx)
 	if err != nil || logs == nil {
 		return nil, err
@@ -833,7 +1075,7 @@ func (b *Block) EstimateGas(ctx context.Context, args struct {
 }
 
 type Pending struct {
-	backend ethapi.Backend
+	backend Backend
 }
 
 func (p *Pending) TransactionCount(ctx context.Context) (int32, error) {
@@ -898,7 +1140,7 @@ func (p *Pending) EstimateGas(ctx context.Context, args struct {
 
 // Resolver is the top-level object in the GraphQL hierarchy.
 type Resolver struct {
-	backend ethapi.Backend
+	backend Backend
 }
 
 func (r *Resolver) Block(ctx context.Context, args struct {
@@ -17,7 +17,18 @@
 package graphql
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
 	"testing"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/core/rawdb"
+	"github.com/matthieu/go-ethereum/core/types"
+	"github.com/matthieu/go-ethereum/ethdb"
+	"github.com/matthieu/go-ethereum/rpc"
 )
 
 func TestBuildSchema(t *testing.T) {
@@ -26,3 +37,150 @@ func TestBuildSchema(t *testing.T) {
 		t.Errorf("Could not construct GraphQL handler: %v", err)
 	}
 }
+
+// internalTxBackend is a backend serving the blocks and internal transactions
+// of a chain database. Methods not needed by the internal transaction queries
+// are left unimplemented.
+type internalTxBackend struct {
+	Backend
+	db        ethdb.Database
+	internals map[common.Hash][]types.InternalTransactions
+}
+
+func (b *internalTxBackend) ChainDb() ethdb.Database { return b.db }
+
+func (b *internalTxBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	number := rawdb.ReadHeaderNumber(b.db, hash)
+	if number == nil {
+		return nil, nil
+	}
+	return rawdb.ReadHeader(b.db, hash, *number), nil
+}
+
+func (b *internalTxBackend) HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		return b.HeaderByHash(ctx, hash)
+	}
+	number, _ := blockNrOrHash.Number()
+	return rawdb.ReadHeader(b.db, rawdb.ReadCanonicalHash(b.db, uint64(number)), uint64(number)), nil
+}
+
+func (b *internalTxBackend) BlockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	header, _ := b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if header == nil {
+		return nil, nil
+	}
+	return rawdb.ReadBlock(b.db, header.Hash(), header.Number.Uint64()), nil
+}
+
+func (b *internalTxBackend) GetInternalTxs(ctx context.Context, blockHash common.Hash) ([]types.InternalTransactions, error) {
+	return b.internals[blockHash], nil
+}
+
+// Tests that the internal transactions of blocks and transactions are resolved,
+// optionally restricted to the ones involving an address.
+func TestInternalTransactions(t *testing.T) {
+	var (
+		db       = rawdb.NewMemoryDatabase()
+		contract = common.HexToAddress("0x1000")
+		wallet   = common.HexToAddress("0x2000")
+		other    = common.HexToAddress("0x3000")
+
+		paid     = types.NewInternalTransaction(0, new(big.Int), 0, contract, wallet, big.NewInt(100), nil, 1, 0, "call")
+		rejected = types.NewInternalTransaction(0, new(big.Int), 0, contract, other, big.NewInt(200), nil, 1, 1, "call")
+		nested   = types.NewInternalTransaction(0, new(big.Int), 0, wallet, other, big.NewInt(0), nil, 1, 0, "call")
+
+		txs = []*types.Transaction{
+			types.NewTransaction(0, contract, new(big.Int), 100000, new(big.Int), nil),
+			types.NewTransaction(1, contract, new(big.Int), 100000, new(big.Int), nil),
+		}
+		genesis = types.NewBlock(&types.Header{Number: big.NewInt(0)}, nil, nil, nil)
+		full    = types.NewBlock(&types.Header{Number: big.NewInt(1), ParentHash: genesis.Hash()}, txs, nil, nil)
+		empty   = types.NewBlock(&types.Header{Number: big.NewInt(2), ParentHash: full.Hash()},
+			[]*types.Transaction{types.NewTransaction(2, contract, new(big.Int), 100000, new(big.Int), nil)}, nil, nil)
+	)
+	rejected.Reject()
+	rejected.Error = "out of gas"
+
+	for _, block := range []*types.Block{genesis, full, empty} {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteTxLookupEntries(db, block)
+	}
+	backend := &internalTxBackend{
+		db: db,
+		internals: map[common.Hash][]types.InternalTransactions{
+			full.Hash(): {{paid, rejected}, {nested}},
+		},
+	}
+	s, err := graphql.ParseSchema(schema, &Resolver{backend})
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+	fields := "{ index to { address } rejected error transaction { hash } }"
+	tests := []struct {
+		query string
+		want  string
+	}{
+		// Block without an address filter, including the rejected one
+		{
+			query: `{ block(number: 1) { internalTransactions ` + fields + ` } }`,
+			want: fmt.Sprintf(`{"block":{"internalTransactions":[`+
+				`{"index":0,"to":{"address":"%s"},"rejected":false,"error":null,"transaction":{"hash":"%s"}},`+
+				`{"index":1,"to":{"address":"%s"},"rejected":true,"error":"out of gas","transaction":{"hash":"%s"}},`+
+				`{"index":0,"to":{"address":"%s"},"rejected":false,"error":null,"transaction":{"hash":"%s"}}]}}`,
+				hexAddr(wallet), txs[0].Hash().Hex(), hexAddr(other), txs[0].Hash().Hex(), hexAddr(other), txs[1].Hash().Hex()),
+		},
+		// Block filtered by the sender of the nested call
+		{
+			query: fmt.Sprintf(`{ block(number: 1) { internalTransactions(address: "%s") { index rejected transaction { hash } } } }`, wallet.Hex()),
+			want: fmt.Sprintf(`{"block":{"internalTransactions":[`+
+				`{"index":0,"rejected":false,"transaction":{"hash":"%s"}},`+
+				`{"index":0,"rejected":false,"transaction":{"hash":"%s"}}]}}`,
+				txs[0].Hash().Hex(), txs[1].Hash().Hex()),
+		},
+		// Block filtered by an address not involved in any internal transaction
+		{
+			query: fmt.Sprintf(`{ block(number: 1) { internalTransactions(address: "%s") { index } } }`, common.HexToAddress("0x4000").Hex()),
+			want:  `{"block":{"internalTransactions":[]}}`,
+		},
+		// Block without internal transactions
+		{
+			query: `{ block(number: 2) { internalTransactions { index } } }`,
+			want:  `{"block":{"internalTransactions":[]}}`,
+		},
+		// Transaction without an address filter, including the rejected one
+		{
+			query: fmt.Sprintf(`{ transaction(hash: "%s") { internalTransactions { index rejected error } } }`, txs[0].Hash().Hex()),
+			want: `{"transaction":{"internalTransactions":[` +
+				`{"index":0,"rejected":false,"error":null},` +
+				`{"index":1,"rejected":true,"error":"out of gas"}]}}`,
+		},
+		// Transaction filtered by the recipient of the rejected call
+		{
+			query: fmt.Sprintf(`{ transaction(hash: "%s") { internalTransactions(address: "%s") { index rejected } } }`, txs[0].Hash().Hex(), other.Hex()),
+			want:  `{"transaction":{"internalTransactions":[{"index":1,"rejected":true}]}}`,
+		},
+		// Transaction in a block without internal transactions
+		{
+			query: fmt.Sprintf(`{ transaction(hash: "%s") { internalTransactions { index } } }`, empty.Transactions()[0].Hash().Hex()),
+			want:  `{"transaction":{"internalTransactions":[]}}`,
+		},
+	}
+	for i, tt := range tests {
+		res := s.Exec(context.Background(), tt.query, "", nil)
+		if len(res.Errors) > 0 {
+			t.Errorf("test %d: query failed: %v", i, res.Errors)
+			continue
+		}
+		if have := string(res.Data); have != tt.want {
+			t.Errorf("test %d: result mismatch:\nhave %s\nwant %s", i, have, tt.want)
+		}
+	}
+}
+
+// hexAddr returns the GraphQL representation of an address.
+func hexAddr(addr common.Address) string {
+	blob, _ := json.Marshal(addr)
+	return string(blob[1 : len(blob)-1])
+}
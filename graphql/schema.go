@@ -69,6 +69,61 @@ const schema string = `
         transaction: Transaction!
     }
 
+    # InternalTransaction is a value transfer, message call, contract creation
+    # or self-destruct nested in the execution of a transaction.
+    type InternalTransaction {
+        # Index is the index of this internal transaction in its transaction.
+        index: Int!
+        # Depth is the call depth the internal transaction was made at, starting
+        # at 1 for the calls made by the transaction itself.
+        depth: Int!
+        # Type is the kind of internal transaction, such as call, create or suicide.
+        type: String!
+        # From is the account that sent this internal transaction.
+        from(block: Long): Account!
+        # To is the account this internal transaction was sent to.
+        to(block: Long): Account!
+        # Value is the value, in wei, sent along with this internal transaction.
+        value: BigInt!
+        # Gas is the amount of gas made available to this internal transaction.
+        gas: Long!
+        # GasUsed is the amount of gas used by the call frame of this internal
+        # transaction.
+        gasUsed: Long!
+        # Rejected is true if the effects of this internal transaction were
+        # reverted, either by its own call frame failing or by an enclosing one.
+        rejected: Boolean!
+        # Error is the error the call frame of this internal transaction failed
+        # with, if any.
+        error: String
+        # Transaction is the transaction that generated this internal transaction.
+        transaction: Transaction!
+    }
+
+    # Receipt is the outcome of the execution of a mined transaction.
+    type Receipt {
+        # Transaction is the transaction this receipt belongs to.
+        transaction: Transaction!
+        # Status is the return status of the transaction. This will be 1 if the
+        # transaction succeeded, or 0 if it failed (due to a revert, or due to
+        # running out of gas).
+        status: Long!
+        # GasUsed is the amount of gas that was used processing the transaction.
+        gasUsed: Long!
+        # CumulativeGasUsed is the total gas used in the block up to and including
+        # the transaction.
+        cumulativeGasUsed: Long!
+        # CreatedContract is the account that was created by a contract creation
+        # transaction. If the transaction was not a contract creation transaction,
+        # this field will be null.
+        createdContract(block: Long): Account
+        # Logs is a list of log entries emitted by the transaction.
+        logs: [Log!]!
+        # LogsBloom is a bloom filter that can be used to check if the transaction
+        # emitted a log of interest.
+        logsBloom: Bytes!
+    }
+
     # Transaction is an Ethereum transaction.
     type Transaction {
         # Hash is the hash of this transaction.
@@ -115,6 +170,11 @@ const schema string = `
         # Logs is a list of log entries emitted by this transaction. If the
         # transaction has not yet been mined, this field will be null.
         logs: [Log!]
+        # InternalTransactions is a list of the internal transactions nested in
+        # the execution of this transaction, optionally restricted to the ones
+        # sent from or to an address. If the transaction has not yet been mined,
+        # this field will be null.
+        internalTransactions(address: Address): [InternalTransaction!]
         r: BigInt!
         s: BigInt!
         v: BigInt!
@@ -202,6 +262,12 @@ const schema string = `
         transactionAt(index: Int!): Transaction
         # Logs returns a filtered set of logs from this block.
         logs(filter: BlockFilterCriteria!): [Log!]!
+        # InternalTransactions returns the internal transactions of all the
+        # transactions in this block, optionally restricted to the ones sent from
+        # or to an address.
+        internalTransactions(address: Address): [InternalTransaction!]!
+        # Receipts returns the receipts of all the transactions in this block.
+        receipts: [Receipt!]!
         # Account fetches an Ethereum account at the current block's state.
         account(address: Address!): Account!
         # Call executes a local call operation at the current block's state.
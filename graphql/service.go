@@ -21,6 +21,8 @@ import (
 	"net"
 	"net/http"
 
+	"github.com/matthieu/go-ethereum/core"
+	"github.com/matthieu/go-ethereum/event"
 	"github.com/matthieu/go-ethereum/internal/ethapi"
 	"github.com/matthieu/go-ethereum/log"
 	"github.com/matthieu/go-ethereum/node"
@@ -30,19 +32,27 @@ import (
 	"github.com/graph-gophers/graphql-go/relay"
 )
 
+// Backend is the backend GraphQL queries operate on. Besides the common API
+// services, it must be able to subscribe to the internal transactions of newly
+// imported blocks, which the filter API requires.
+type Backend interface {
+	ethapi.Backend
+	SubscribeInternalTxsEvent(ch chan<- core.InternalTxsEvent) event.Subscription
+}
+
 // Service encapsulates a GraphQL service.
 type Service struct {
 	endpoint string           // The host:port endpoint for this service.
 	cors     []string         // Allowed CORS domains
 	vhosts   []string         // Recognised vhosts
 	timeouts rpc.HTTPTimeouts // Timeout settings for HTTP requests.
-	backend  ethapi.Backend   // The backend that queries will operate on.
+	backend  Backend          // The backend that queries will operate on.
 	handler  http.Handler     // The `http.Handler` used to answer queries.
 	listener net.Listener     // The listening socket.
 }
 
 // New constructs a new GraphQL service instance.
-func New(backend ethapi.Backend, endpoint string, cors, vhosts []string, timeouts rpc.HTTPTimeouts) (*Service, error) {
+func New(backend Backend, endpoint string, cors, vhosts []string, timeouts rpc.HTTPTimeouts) (*Service, error) {
 	return &Service{
 		endpoint: endpoint,
 		cors:     cors,
@@ -87,7 +97,7 @@ func (s *Service) Start(server *p2p.Server) error {
 
 // newHandler returns a new `http.Handler` that will answer GraphQL queries.
 // It additionally exports an interactive query browser on the / endpoint.
-func newHandler(backend ethapi.Backend) (http.Handler, error) {
+func newHandler(backend Backend) (http.Handler, error) {
 	q := Resolver{backend}
 
 	s, err := graphql.ParseSchema(schema, &q)
@@ -119,6 +119,8 @@ type CallMsg struct {
 	GasPrice *big.Int        // wei <-> gas exchange ratio
 	Value    *big.Int        // amount of wei sent along with the call
 	Data     []byte          // input data, usually an ABI-encoded contract method invocation
+
+	AccessList types.AccessList // EIP-2930 access list.
 }
 
 // A ContractCaller provides contract calls, essentially transactions that are executed by
@@ -150,6 +152,15 @@ type FilterQuery struct {
 	Topics [][]common.Hash
 }
 
+// PendingTxQuery contains options for filtering the transactions entering the
+// transaction pool. The options are combined with AND, while the values within
+// each option are ORed.
+type PendingTxQuery struct {
+	From      []common.Address // restricts matches to transactions sent by specific accounts
+	To        []common.Address // restricts matches to transactions sent to specific accounts
+	Selectors [][4]byte        // restricts matches to contract calls invoking specific methods
+}
+
 // LogFilterer provides access to contract log events using a one-off query or continuous
 // event subscription.
 //
@@ -36,6 +36,7 @@ import (
 	"github.com/matthieu/go-ethereum/consensus/clique"
 	"github.com/matthieu/go-ethereum/consensus/ethash"
 	"github.com/matthieu/go-ethereum/core"
+	"github.com/matthieu/go-ethereum/core/state"
 	"github.com/matthieu/go-ethereum/core/types"
 	"github.com/matthieu/go-ethereum/core/vm"
 	"github.com/matthieu/go-ethereum/crypto"
@@ -115,7 +116,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range pending {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
 		}
 		content["pending"][account.Hex()] = dump
 	}
@@ -123,13 +124,23 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range queue {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
 		}
 		content["queued"][account.Hex()] = dump
 	}
 	return content
 }
 
+// Rejections returns the most recent transactions refused or evicted by the
+// transaction pool policies.
+func (s *PublicTxPoolAPI) Rejections() []*core.TxRejection {
+	rejections := s.b.TxPoolRejections()
+	if rejections == nil {
+		rejections = []*core.TxRejection{}
+	}
+	return rejections
+}
+
 // Status returns the number of pending and queued transaction in the pool.
 func (s *PublicTxPoolAPI) Status() map[string]hexutil.Uint {
 	pending, queue := s.b.Stats()
@@ -415,7 +426,7 @@ func (s *PrivateAccountAPI) SignTransaction(ctx context.Context, args SendTxArgs
 		log.Warn("Failed transaction sign attempt", "from", args.From, "to", args.To, "value", args.Value.ToInt(), "err", err)
 		return nil, err
 	}
-	data, err := rlp.EncodeToBytes(signed)
+	data, err := signed.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
@@ -759,6 +770,8 @@ type CallArgs struct {
 	GasPrice *hexutil.Big    `json:"gasPrice"`
 	Value    *hexutil.Big    `json:"value"`
 	Data     *hexutil.Bytes  `json:"data"`
+
+	AccessList *types.AccessList `json:"accessList,omitempty"`
 }
 
 // ToMessage converts CallArgs to the Message type used by the core evm
@@ -796,17 +809,21 @@ func (args *CallArgs) ToMessage(globalGasCap uint64) types.Message {
 		data = []byte(*args.Data)
 	}
 
-	msg := types.NewMessage(addr, args.To, 0, value, gas, gasPrice, data, false)
+	var accessList types.AccessList
+	if args.AccessList != nil {
+		accessList = *args.AccessList
+	}
+	msg := types.NewMessage(addr, args.To, 0, value, gas, gasPrice, data, accessList, false)
 	return msg
 }
 
-// account indicates the overriding fields of account during the execution of
-// a message call.
+// OverrideAccount indicates the overriding fields of account during the execution
+// of a message call.
 // Note, state and stateDiff can't be specified at the same time. If state is
 // set, message execution will only use the data in the given state. Otherwise
 // if statDiff is set, all diff will be applied first and then execute the call
 // message.
-type account struct {
+type OverrideAccount struct {
 	Nonce     *hexutil.Uint64              `json:"nonce"`
 	Code      *hexutil.Bytes               `json:"code"`
 	Balance   **hexutil.Big                `json:"balance"`
@@ -814,15 +831,15 @@ type account struct {
 	StateDiff *map[common.Hash]common.Hash `json:"stateDiff"`
 }
 
-func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides map[common.Address]account, vmCfg vm.Config, timeout time.Duration, globalGasCap uint64) (*core.ExecutionResult, error) {
-	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+// StateOverride is the collection of overridden accounts.
+type StateOverride map[common.Address]OverrideAccount
 
-	state, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
-	if state == nil || err != nil {
-		return nil, err
+// Apply overrides the fields of specified accounts into the given state.
+func (diff *StateOverride) Apply(state *state.StateDB) error {
+	if diff == nil {
+		return nil
 	}
-	// Override the fields of specified contracts before execution.
-	for addr, account := range overrides {
+	for addr, account := range *diff {
 		// Override account nonce.
 		if account.Nonce != nil {
 			state.SetNonce(addr, uint64(*account.Nonce))
@@ -836,7 +853,7 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.Blo
 			state.SetBalance(addr, (*big.Int)(*account.Balance))
 		}
 		if account.State != nil && account.StateDiff != nil {
-			return nil, fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
+			return fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
 		}
 		// Replace entire state if caller requires.
 		if account.State != nil {
@@ -849,6 +866,19 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.Blo
 			}
 		}
 	}
+	return nil
+}
+
+func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, vmCfg vm.Config, timeout time.Duration, globalGasCap uint64) (*core.ExecutionResult, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+
+	state, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if err := overrides.Apply(state); err != nil {
+		return nil, err
+	}
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
 	var cancel context.CancelFunc
@@ -927,12 +957,8 @@ func (e *revertError) ErrorData() interface{} {
 //
 // Note, this function doesn't make and changes in the state/blockchain and is
 // useful to execute and retrieve values.
-func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *map[common.Address]account) (hexutil.Bytes, error) {
-	var accounts map[common.Address]account
-	if overrides != nil {
-		accounts = *overrides
-	}
-	result, err := DoCall(ctx, s.b, args, blockNrOrHash, accounts, vm.Config{}, 5*time.Second, s.b.RPCGasCap())
+func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride) (hexutil.Bytes, error) {
+	result, err := DoCall(ctx, s.b, args, blockNrOrHash, overrides, vm.Config{}, 5*time.Second, s.b.RPCGasCap())
 	if err != nil {
 		return nil, err
 	}
@@ -1199,20 +1225,23 @@ func (s *PublicBlockChainAPI) rpcMarshalBlock(ctx context.Context, b *types.Bloc
 
 // RPCTransaction represents a transaction that will serialize to the RPC representation of a transaction
 type RPCTransaction struct {
-	BlockHash        *common.Hash    `json:"blockHash"`
-	BlockNumber      *hexutil.Big    `json:"blockNumber"`
-	From             common.Address  `json:"from"`
-	Gas              hexutil.Uint64  `json:"gas"`
-	GasPrice         *hexutil.Big    `json:"gasPrice"`
-	Hash             common.Hash     `json:"hash"`
-	Input            hexutil.Bytes   `json:"input"`
-	Nonce            hexutil.Uint64  `json:"nonce"`
-	To               *common.Address `json:"to"`
-	TransactionIndex *hexutil.Uint64 `json:"transactionIndex"`
-	Value            *hexutil.Big    `json:"value"`
-	V                *hexutil.Big    `json:"v"`
-	R                *hexutil.Big    `json:"r"`
-	S                *hexutil.Big    `json:"s"`
+	BlockHash        *common.Hash      `json:"blockHash"`
+	BlockNumber      *hexutil.Big      `json:"blockNumber"`
+	From             common.Address    `json:"from"`
+	Gas              hexutil.Uint64    `json:"gas"`
+	GasPrice         *hexutil.Big      `json:"gasPrice"`
+	Hash             common.Hash       `json:"hash"`
+	Input            hexutil.Bytes     `json:"input"`
+	Nonce            hexutil.Uint64    `json:"nonce"`
+	To               *common.Address   `json:"to"`
+	TransactionIndex *hexutil.Uint64   `json:"transactionIndex"`
+	Value            *hexutil.Big      `json:"value"`
+	Type             hexutil.Uint64    `json:"type"`
+	Accesses         *types.AccessList `json:"accessList,omitempty"`
+	ChainID          *hexutil.Big      `json:"chainId,omitempty"`
+	V                *hexutil.Big      `json:"v"`
+	R                *hexutil.Big      `json:"r"`
+	S                *hexutil.Big      `json:"s"`
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
@@ -1220,7 +1249,7 @@ type RPCTransaction struct {
 func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber uint64, index uint64) *RPCTransaction {
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
+		signer = types.NewEIP2930Signer(tx.ChainId())
 	}
 	from, _ := types.Sender(signer, tx)
 	v, r, s := tx.RawSignatureValues()
@@ -1234,10 +1263,16 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		Nonce:    hexutil.Uint64(tx.Nonce()),
 		To:       tx.To(),
 		Value:    (*hexutil.Big)(tx.Value()),
+		Type:     hexutil.Uint64(tx.Type()),
 		V:        (*hexutil.Big)(v),
 		R:        (*hexutil.Big)(r),
 		S:        (*hexutil.Big)(s),
 	}
+	if tx.Type() == types.AccessListTxType {
+		al := tx.AccessList()
+		result.Accesses = &al
+		result.ChainID = (*hexutil.Big)(tx.ChainId())
+	}
 	if blockHash != (common.Hash{}) {
 		result.BlockHash = &blockHash
 		result.BlockNumber = (*hexutil.Big)(new(big.Int).SetUint64(blockNumber))
@@ -1246,8 +1281,8 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 	return result
 }
 
-// newRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
-func newRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
+// NewRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
+func NewRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
 	return newRPCTransaction(tx, common.Hash{}, 0, 0)
 }
 
@@ -1266,7 +1301,7 @@ func newRPCRawTransactionFromBlockIndex(b *types.Block, index uint64) hexutil.By
 	if index >= uint64(len(txs)) {
 		return nil
 	}
-	blob, _ := rlp.EncodeToBytes(txs[index])
+	blob, _ := txs[index].MarshalBinary()
 	return blob
 }
 
@@ -1372,7 +1407,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, has
 	}
 	// No finalized transaction, try to retrieve it from the pool
 	if tx := s.b.GetPoolTransaction(hash); tx != nil {
-		return newRPCPendingTransaction(tx), nil
+		return NewRPCPendingTransaction(tx), nil
 	}
 
 	// Transaction unknown, return as such
@@ -1392,8 +1427,8 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 			return nil, nil
 		}
 	}
-	// Serialize to RLP and return
-	return rlp.EncodeToBytes(tx)
+	// Serialize to binary and return
+	return tx.MarshalBinary()
 }
 
 // GetTransactionReceipt returns the transaction receipt for the given transaction hash.
@@ -1409,18 +1444,45 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if len(receipts) <= int(index) {
 		return nil, nil
 	}
-	receipt := receipts[index]
+	return marshalReceipt(receipts[index], blockHash, blockNumber, tx, index), nil
+}
+
+// GetBlockReceipts returns the receipts of all the transactions in the given
+// block, retrieving them in one go instead of one transaction at a time.
+func (s *PublicTransactionPoolAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	block, err := s.b.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	receipts, err := s.b.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if len(receipts) != len(txs) {
+		return nil, nil
+	}
+	fields := make([]map[string]interface{}, len(receipts))
+	for i, receipt := range receipts {
+		fields[i] = marshalReceipt(receipt, block.Hash(), block.NumberU64(), txs[i], uint64(i))
+	}
+	return fields, nil
+}
 
+// marshalReceipt converts a receipt into the RPC representation, filling in the
+// fields derived from the transaction and its inclusion.
+func marshalReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, tx *types.Transaction, index uint64) map[string]interface{} {
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
+		signer = types.NewEIP2930Signer(tx.ChainId())
 	}
 	from, _ := types.Sender(signer, tx)
 
 	fields := map[string]interface{}{
+		"type":              hexutil.Uint(tx.Type()),
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
+		"transactionHash":   tx.Hash(),
 		"transactionIndex":  hexutil.Uint64(index),
 		"from":              from,
 		"to":                tx.To(),
@@ -1444,7 +1506,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
-	return fields, nil
+	return fields
 }
 
 // sign is a helper function that signs a transaction with the private key of the given address.
@@ -1472,6 +1534,10 @@ type SendTxArgs struct {
 	// newer name and should be preferred by clients.
 	Data  *hexutil.Bytes `json:"data"`
 	Input *hexutil.Bytes `json:"input"`
+
+	// For non-legacy transactions
+	AccessList *types.AccessList `json:"accessList,omitempty"`
+	ChainID    *hexutil.Big      `json:"chainId,omitempty"`
 }
 
 // setDefaults is a helper function that fills in default values for unspecified tx fields.
@@ -1493,6 +1559,15 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 		}
 		args.Nonce = (*hexutil.Uint64)(&nonce)
 	}
+	if args.AccessList != nil {
+		// Access list transactions are replay protected by an explicit chain id
+		want := b.ChainConfig().ChainID
+		if args.ChainID == nil {
+			args.ChainID = (*hexutil.Big)(want)
+		} else if args.ChainID.ToInt().Cmp(want) != 0 {
+			return fmt.Errorf("chainId does not match node's (have=%v, want=%v)", args.ChainID.ToInt(), want)
+		}
+	}
 	if args.Data != nil && args.Input != nil && !bytes.Equal(*args.Data, *args.Input) {
 		return errors.New(`both "data" and "input" are set and not equal. Please use "input" to pass transaction call data`)
 	}
@@ -1517,11 +1592,12 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 			input = args.Data
 		}
 		callArgs := CallArgs{
-			From:     &args.From, // From shouldn't be nil
-			To:       args.To,
-			GasPrice: args.GasPrice,
-			Value:    args.Value,
-			Data:     input,
+			From:       &args.From, // From shouldn't be nil
+			To:         args.To,
+			GasPrice:   args.GasPrice,
+			Value:      args.Value,
+			Data:       input,
+			AccessList: args.AccessList,
 		}
 		pendingBlockNr := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
 		estimated, err := DoEstimateGas(ctx, b, callArgs, pendingBlockNr, b.RPCGasCap())
@@ -1534,6 +1610,8 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 	return nil
 }
 
+// toTransaction converts the arguments to a transaction. An access list
+// transaction is assembled if an access list was given, otherwise a legacy one.
 func (args *SendTxArgs) toTransaction() *types.Transaction {
 	var input []byte
 	if args.Input != nil {
@@ -1541,6 +1619,9 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 	} else if args.Data != nil {
 		input = *args.Data
 	}
+	if args.AccessList != nil {
+		return types.NewAccessListTransaction((*big.Int)(args.ChainID), uint64(*args.Nonce), args.To, (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input, *args.AccessList)
+	}
 	if args.To == nil {
 		return types.NewContractCreation(uint64(*args.Nonce), (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input)
 	}
@@ -1555,6 +1636,10 @@ func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 		return common.Hash{}, err
 	}
 	if err := b.SendTx(ctx, tx); err != nil {
+		var perr *core.TxPolicyError
+		if errors.As(err, &perr) {
+			return common.Hash{}, &txRejectedError{TxPolicyError: perr, hash: tx.Hash()}
+		}
 		return common.Hash{}, err
 	}
 	if tx.To() == nil {
@@ -1571,6 +1656,28 @@ func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 	return tx.Hash(), nil
 }
 
+// txRejectedError is an API error that encompasses a transaction refused by one
+// of the transaction pool policies, with the reason as structured error data.
+type txRejectedError struct {
+	*core.TxPolicyError
+	hash common.Hash
+}
+
+// ErrorCode returns the JSON error code for a transaction rejection.
+func (e *txRejectedError) ErrorCode() int {
+	return -32003
+}
+
+// ErrorData returns the hash of the rejected transaction, the refusing policy
+// and its reason.
+func (e *txRejectedError) ErrorData() interface{} {
+	return map[string]interface{}{
+		"hash":   e.hash,
+		"policy": e.Policy,
+		"reason": e.Reason,
+	}
+}
+
 // SendTransaction creates a transaction for the given argument, sign it and submit it to the
 // transaction pool.
 func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args SendTxArgs) (common.Hash, error) {
@@ -1612,7 +1719,7 @@ func (s *PublicTransactionPoolAPI) FillTransaction(ctx context.Context, args Sen
 	}
 	// Assemble the transaction and obtain rlp
 	tx := args.toTransaction()
-	data, err := rlp.EncodeToBytes(tx)
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
@@ -1623,7 +1730,7 @@ func (s *PublicTransactionPoolAPI) FillTransaction(ctx context.Context, args Sen
 // The sender is responsible for signing the transaction and using the correct nonce.
 func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
 	tx := new(types.Transaction)
-	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+	if err := tx.UnmarshalBinary(encodedTx); err != nil {
 		return common.Hash{}, err
 	}
 	return SubmitTransaction(ctx, s.b, tx)
@@ -1684,7 +1791,7 @@ func (s *PublicTransactionPoolAPI) SignTransaction(ctx context.Context, args Sen
 	if err != nil {
 		return nil, err
 	}
-	data, err := rlp.EncodeToBytes(tx)
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
@@ -1708,11 +1815,11 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 	for _, tx := range pending {
 		var signer types.Signer = types.HomesteadSigner{}
 		if tx.Protected() {
-			signer = types.NewEIP155Signer(tx.ChainId())
+			signer = types.NewEIP2930Signer(tx.ChainId())
 		}
 		from, _ := types.Sender(signer, tx)
 		if _, exists := accounts[from]; exists {
-			transactions = append(transactions, newRPCPendingTransaction(tx))
+			transactions = append(transactions, NewRPCPendingTransaction(tx))
 		}
 	}
 	return transactions, nil
@@ -1749,7 +1856,7 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 	for _, p := range pending {
 		var signer types.Signer = types.HomesteadSigner{}
 		if p.Protected() {
-			signer = types.NewEIP155Signer(p.ChainId())
+			signer = types.NewEIP2930Signer(p.ChainId())
 		}
 		wantSigHash := signer.Hash(matchTx)
 
@@ -1775,6 +1882,166 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 	return common.Hash{}, fmt.Errorf("transaction %#x not found", matchTx.Hash())
 }
 
+// maxInternalTxPageSize is the maximum number of internal transactions returned
+// by a single paged query.
+const maxInternalTxPageSize = 1000
+
+// RPCInternalTransaction represents an internal transaction that will serialize
+// to the RPC representation of an internal transaction.
+type RPCInternalTransaction struct {
+	BlockHash        common.Hash    `json:"blockHash"`
+	BlockNumber      *hexutil.Big   `json:"blockNumber"`
+	ParentHash       common.Hash    `json:"parentHash"`
+	TransactionIndex hexutil.Uint64 `json:"transactionIndex"`
+	From             common.Address `json:"from"`
+	To               common.Address `json:"to"`
+	Value            *hexutil.Big   `json:"value"`
+	Gas              hexutil.Uint64 `json:"gas"`
+	Input            hexutil.Bytes  `json:"input"`
+	Depth            hexutil.Uint64 `json:"depth"`
+	Index            hexutil.Uint64 `json:"index"`
+	Note             string         `json:"note"`
+	Rejected         bool           `json:"rejected"`
+	GasUsed          hexutil.Uint64 `json:"gasUsed"`
+	Error            string         `json:"error,omitempty"`
+}
+
+// NewRPCInternalTransaction returns an internal transaction that will serialize
+// to the RPC representation, with the given location metadata set.
+func NewRPCInternalTransaction(itx *types.InternalTransaction, blockHash common.Hash, blockNumber uint64, index uint64) *RPCInternalTransaction {
+	return &RPCInternalTransaction{
+		BlockHash:        blockHash,
+		BlockNumber:      (*hexutil.Big)(new(big.Int).SetUint64(blockNumber)),
+		ParentHash:       itx.ParentHash,
+		TransactionIndex: hexutil.Uint64(index),
+		From:             *itx.Sender,
+		To:               *itx.To(),
+		Value:            (*hexutil.Big)(itx.Value()),
+		Gas:              hexutil.Uint64(itx.Gas()),
+		Input:            hexutil.Bytes(itx.Data()),
+		Depth:            hexutil.Uint64(itx.Depth),
+		Index:            hexutil.Uint64(itx.Index),
+		Note:             itx.Note,
+		Rejected:         itx.Rejected,
+		GasUsed:          hexutil.Uint64(itx.GasUsed),
+		Error:            itx.Error,
+	}
+}
+
+// PublicInternalTransactionAPI exposes the internal transactions (value transfers,
+// calls, creations and self-destructs nested in contract executions) of the
+// canonical chain.
+type PublicInternalTransactionAPI struct {
+	b Backend
+}
+
+// NewPublicInternalTransactionAPI creates a new RPC service with methods for
+// retrieving internal transactions.
+func NewPublicInternalTransactionAPI(b Backend) *PublicInternalTransactionAPI {
+	return &PublicInternalTransactionAPI{b}
+}
+
+// GetInternalTransactionsByHash returns the internal transactions of the transaction
+// with the given hash.
+func (s *PublicInternalTransactionAPI) GetInternalTransactionsByHash(ctx context.Context, hash common.Hash) ([]*RPCInternalTransaction, error) {
+	tx, blockHash, blockNumber, index, err := s.b.GetTransaction(ctx, hash)
+	if tx == nil || err != nil {
+		return nil, err
+	}
+	internals, err := s.b.GetInternalTxs(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if len(internals) <= int(index) {
+		return nil, nil
+	}
+	result := make([]*RPCInternalTransaction, len(internals[index]))
+	for i, itx := range internals[index] {
+		result[i] = NewRPCInternalTransaction(itx, blockHash, blockNumber, index)
+	}
+	return result, nil
+}
+
+// GetInternalTransactionsByBlockNumber returns the internal transactions of all
+// the transactions in the block with the given number.
+func (s *PublicInternalTransactionAPI) GetInternalTransactionsByBlockNumber(ctx context.Context, blockNr rpc.BlockNumber) ([]*RPCInternalTransaction, error) {
+	block, err := s.b.BlockByNumber(ctx, blockNr)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	internals, err := s.b.GetInternalTxs(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*RPCInternalTransaction, 0)
+	for i, txInternals := range internals {
+		for _, itx := range txInternals {
+			result = append(result, NewRPCInternalTransaction(itx, block.Hash(), block.NumberU64(), uint64(i)))
+		}
+	}
+	return result, nil
+}
+
+// InternalTxPosition identifies an internal transaction by its position in the
+// chain, used for paging through the internal transactions of an address.
+type InternalTxPosition struct {
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	TransactionIndex hexutil.Uint64 `json:"transactionIndex"`
+	Index            hexutil.Uint64 `json:"index"`
+}
+
+// GetInternalTransactionsByAddress returns a page of the internal transactions
+// sent or received by the given address, in chain order. The page starts after
+// the given position, usually that of the last internal transaction of the
+// previous page, or at the genesis block if no position is given. It requires
+// the node to maintain the internal transaction address index, and only covers
+// the blocks indexed so far.
+func (s *PublicInternalTransactionAPI) GetInternalTransactionsByAddress(ctx context.Context, address common.Address, limit hexutil.Uint, after *InternalTxPosition) ([]*RPCInternalTransaction, error) {
+	if limit == 0 || limit > maxInternalTxPageSize {
+		return nil, fmt.Errorf("invalid page size %d, must be between 1 and %d", limit, maxInternalTxPageSize)
+	}
+	var (
+		number         uint64
+		txIndex, index uint32
+	)
+	if after != nil {
+		if after.TransactionIndex > math.MaxUint32 || after.Index > math.MaxUint32 {
+			return nil, errors.New("invalid internal transaction position")
+		}
+		// Start at the position directly following the given one
+		number, txIndex, index = uint64(after.BlockNumber), uint32(after.TransactionIndex), uint32(after.Index)+1
+		if index == 0 {
+			if txIndex++; txIndex == 0 {
+				number++
+			}
+		}
+	}
+	entries, err := s.b.GetInternalTxAddrEntries(ctx, address, number, txIndex, index, int(limit))
+	if err != nil {
+		return nil, err
+	}
+	var (
+		blocks = make(map[common.Hash][]types.InternalTransactions)
+		result = make([]*RPCInternalTransaction, 0, len(entries))
+	)
+	for _, entry := range entries {
+		internals, ok := blocks[entry.BlockHash]
+		if !ok {
+			var err error
+			if internals, err = s.b.GetInternalTxs(ctx, entry.BlockHash); err != nil {
+				return nil, err
+			}
+			blocks[entry.BlockHash] = internals
+		}
+		if int(entry.TxIndex) >= len(internals) || int(entry.Index) >= len(internals[entry.TxIndex]) {
+			return nil, fmt.Errorf("internal transaction %d/%d of block %#x not found", entry.TxIndex, entry.Index, entry.BlockHash)
+		}
+		itx := internals[entry.TxIndex][entry.Index]
+		result = append(result, NewRPCInternalTransaction(itx, entry.BlockHash, entry.BlockNumber, uint64(entry.TxIndex)))
+	}
+	return result, nil
+}
+
 // PublicDebugAPI is the collection of Ethereum APIs exposed over the public
 // debugging endpoint.
 type PublicDebugAPI struct {
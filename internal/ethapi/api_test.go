@@ -0,0 +1,118 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/common/hexutil"
+	"github.com/matthieu/go-ethereum/core"
+	"github.com/matthieu/go-ethereum/core/rawdb"
+	"github.com/matthieu/go-ethereum/core/types"
+	"github.com/matthieu/go-ethereum/ethdb"
+)
+
+// testBackend implements the parts of Backend the tested APIs rely on. Calling
+// any other method panics.
+type testBackend struct {
+	Backend
+
+	db        ethdb.Database
+	chain     *core.BlockChain
+	internals map[common.Hash][]types.InternalTransactions
+}
+
+func (b *testBackend) GetInternalTxs(ctx context.Context, hash common.Hash) ([]types.InternalTransactions, error) {
+	return b.internals[hash], nil
+}
+
+func (b *testBackend) GetInternalTxAddrEntries(ctx context.Context, address common.Address, number uint64, txIndex, index uint32, limit int) ([]rawdb.InternalTxAddrEntry, error) {
+	return rawdb.ReadInternalTxAddrEntries(b.db, address, number, txIndex, index, limit), nil
+}
+
+// Tests that the internal transactions of an address can be paged through using
+// the position of the last internal transaction of the previous page.
+func TestGetInternalTransactionsByAddress(t *testing.T) {
+	var (
+		alice = common.HexToAddress("0x1")
+		bob   = common.HexToAddress("0x2")
+		carol = common.HexToAddress("0x3")
+
+		backend = &testBackend{
+			db:        rawdb.NewMemoryDatabase(),
+			internals: make(map[common.Hash][]types.InternalTransactions),
+		}
+		api = NewPublicInternalTransactionAPI(backend)
+	)
+	// Create three blocks with two internal transactions sent by alice each
+	for i := uint64(0); i < 3; i++ {
+		hash := common.Hash{byte(i + 1)}
+		internals := []types.InternalTransactions{
+			{
+				types.NewInternalTransaction(0, nil, 0, alice, bob, big.NewInt(int64(2*i)), nil, 1, 0, "call"),
+				types.NewInternalTransaction(0, nil, 0, bob, carol, big.NewInt(1), nil, 2, 1, "call"),
+			},
+			{
+				types.NewInternalTransaction(0, nil, 0, alice, carol, big.NewInt(int64(2*i+1)), nil, 1, 0, "call"),
+			},
+		}
+		rawdb.WriteCanonicalHash(backend.db, hash, i)
+		rawdb.WriteInternalTxAddrEntries(backend.db, hash, i, internals)
+		backend.internals[hash] = internals
+	}
+	// Page through the internal transactions, ensuring they are all returned in order
+	var (
+		after *InternalTxPosition
+		seen  int64
+	)
+	for page := 0; ; page++ {
+		result, err := api.GetInternalTransactionsByAddress(context.Background(), alice, 4, after)
+		if err != nil {
+			t.Fatalf("page %d: failed to retrieve internal transactions: %v", page, err)
+		}
+		if len(result) == 0 {
+			break
+		}
+		for _, itx := range result {
+			if itx.From != alice {
+				t.Fatalf("page %d: internal transaction from wrong sender: have %x, want %x", page, itx.From, alice)
+			}
+			if itx.Value.ToInt().Int64() != seen {
+				t.Fatalf("page %d: internal transaction out of order: have value %d, want %d", page, itx.Value.ToInt(), seen)
+			}
+			seen++
+		}
+		last := result[len(result)-1]
+		after = &InternalTxPosition{
+			BlockNumber:      hexutil.Uint64(last.BlockNumber.ToInt().Uint64()),
+			TransactionIndex: last.TransactionIndex,
+			Index:            last.Index,
+		}
+	}
+	if seen != 6 {
+		t.Fatalf("internal transaction count mismatch: have %d, want %d", seen, 6)
+	}
+	// Ensure invalid page sizes are rejected
+	for _, limit := range []hexutil.Uint{0, maxInternalTxPageSize + 1} {
+		if _, err := api.GetInternalTransactionsByAddress(context.Background(), alice, limit, nil); err == nil {
+			t.Errorf("page size %d: expected error", limit)
+		}
+	}
+}
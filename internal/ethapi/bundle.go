@@ -0,0 +1,387 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/matthieu/go-ethereum/accounts/abi"
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/common/hexutil"
+	"github.com/matthieu/go-ethereum/consensus"
+	"github.com/matthieu/go-ethereum/core"
+	"github.com/matthieu/go-ethereum/core/state"
+	"github.com/matthieu/go-ethereum/core/types"
+	"github.com/matthieu/go-ethereum/core/vm"
+	"github.com/matthieu/go-ethereum/crypto"
+	"github.com/matthieu/go-ethereum/log"
+	"github.com/matthieu/go-ethereum/rpc"
+)
+
+// emptyCodeHash is the code hash of accounts without code.
+var emptyCodeHash = crypto.Keccak256Hash(nil)
+
+// CallBundleArgs represents the arguments for simulating a transaction bundle.
+type CallBundleArgs struct {
+	Txs              []hexutil.Bytes        `json:"txs"`              // Signed raw transactions, in execution order
+	StateBlockNumber *rpc.BlockNumberOrHash `json:"stateBlockNumber"` // Block to simulate on top of, latest if omitted
+	Coinbase         *common.Address        `json:"coinbase"`         // Fee recipient, the parent's if omitted
+	Timestamp        *hexutil.Uint64        `json:"timestamp"`        // Block timestamp, the parent's plus one if omitted
+}
+
+// BundleStateChange is the modification of a single account made by a simulated
+// transaction. Only the fields which changed are set, each holding the values
+// before and after the transaction.
+type BundleStateChange struct {
+	Address  common.Address                `json:"address"`
+	Balance  []*hexutil.Big                `json:"balance,omitempty"`
+	Nonce    []hexutil.Uint64              `json:"nonce,omitempty"`
+	CodeHash []common.Hash                 `json:"codeHash,omitempty"`
+	Storage  map[common.Hash][]common.Hash `json:"storage,omitempty"`
+}
+
+// BundleTxResult is the outcome of a single transaction of a simulated bundle.
+type BundleTxResult struct {
+	TxHash       common.Hash               `json:"txHash"`
+	From         common.Address            `json:"from"`
+	To           *common.Address           `json:"to"`
+	GasUsed      hexutil.Uint64            `json:"gasUsed"`
+	ReturnData   hexutil.Bytes             `json:"returnData"`
+	Error        string                    `json:"error,omitempty"`
+	RevertReason string                    `json:"revertReason,omitempty"`
+	Logs         []*types.Log              `json:"logs"`
+	CoinbaseDiff *hexutil.Big              `json:"coinbaseDiff"`
+	StateChanges []*BundleStateChange      `json:"stateChanges"`
+	InternalTxs  []*RPCInternalTransaction `json:"internalTransactions"`
+}
+
+// CallBundleResult is the outcome of a simulated transaction bundle.
+type CallBundleResult struct {
+	StateBlockHash   common.Hash       `json:"stateBlockHash"`
+	StateBlockNumber hexutil.Uint64    `json:"stateBlockNumber"`
+	GasUsed          hexutil.Uint64    `json:"gasUsed"`
+	CoinbaseDiff     *hexutil.Big      `json:"coinbaseDiff"`
+	Results          []*BundleTxResult `json:"results"`
+}
+
+// CallBundle simulates an ordered list of signed transactions in a new block on
+// top of the given one, returning the outcome of each of them.
+//
+// Note, this function doesn't make any changes in the state/blockchain and is
+// useful to check the effects of transactions before submitting them.
+func (s *PublicBlockChainAPI) CallBundle(ctx context.Context, args CallBundleArgs) (*CallBundleResult, error) {
+	return DoCallBundle(ctx, s.b, args, s.b.RPCBundleTimeout(), s.b.RPCGasCap())
+}
+
+// DoCallBundle simulates a transaction bundle on top of the requested block. The
+// cumulative gas limit of the transactions is capped by globalGasCap, unless it
+// is zero.
+func DoCallBundle(ctx context.Context, b Backend, args CallBundleArgs, timeout time.Duration, globalGasCap uint64) (*CallBundleResult, error) {
+	defer func(start time.Time) { log.Debug("Executing bundle call finished", "runtime", time.Since(start)) }(time.Now())
+
+	if len(args.Txs) == 0 {
+		return nil, errors.New("bundle missing transactions")
+	}
+	var (
+		txs = make(types.Transactions, len(args.Txs))
+		gas uint64
+	)
+	for i, encoded := range args.Txs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(encoded); err != nil {
+			return nil, fmt.Errorf("invalid transaction %d: %v", i, err)
+		}
+		txs[i], gas = tx, gas+tx.Gas()
+	}
+	if globalGasCap != 0 && gas > globalGasCap {
+		return nil, fmt.Errorf("bundle gas %d exceeds cap %d", gas, globalGasCap)
+	}
+	blockNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if args.StateBlockNumber != nil {
+		blockNrOrHash = *args.StateBlockNumber
+	}
+	state, parent, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	// Setup context so it may be cancelled the call has completed
+	// or, in case of unmetered gas, setup a context with a timeout.
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	// Make sure the context is cancelled when the call has completed
+	// this makes sure resources are cleaned up.
+	defer cancel()
+
+	// Assemble the header of the block the bundle is simulated in
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Coinbase:   parent.Coinbase,
+		Difficulty: parent.Difficulty,
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + 1,
+	}
+	if args.Coinbase != nil {
+		header.Coinbase = *args.Coinbase
+	}
+	if args.Timestamp != nil {
+		header.Time = uint64(*args.Timestamp)
+	}
+	var (
+		config  = b.ChainConfig()
+		signer  = types.MakeSigner(config, header.Number)
+		chain   = &backendChainContext{ctx: ctx, b: b}
+		gp      = new(core.GasPool).AddGas(header.GasLimit)
+		usedGas uint64
+
+		coinbase = state.GetBalance(header.Coinbase)
+		results  = make([]*BundleTxResult, 0, len(txs))
+	)
+	for i, tx := range txs {
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d (%x) invalid: %v", i, tx.Hash(), err)
+		}
+		// Execute the transaction on top of a recorder of the modified state, so
+		// the changes can be reported without copying the whole state
+		var (
+			balance  = state.GetBalance(header.Coinbase)
+			recorder = newBundleStateRecorder(state)
+		)
+		evm := vm.NewEVM(core.NewEVMContext(msg, header, chain, &header.Coinbase), recorder, config, vm.Config{})
+		evm.AddListener(core.NewInternalTxWatcher())
+
+		// Wait for the context to be done and cancel the evm, unless the
+		// transaction finishes first
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-done:
+			}
+		}()
+		state.Prepare(tx.Hash(), common.Hash{}, i)
+		receipt, result, internals, err := core.ApplyTransactionWithEVM(msg, config, gp, state, header, tx, &usedGas, evm)
+		close(done)
+
+		// If the timer caused an abort, return an appropriate error message
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d (%x) failed: %w", i, tx.Hash(), err)
+		}
+		res := &BundleTxResult{
+			TxHash:       tx.Hash(),
+			From:         msg.From(),
+			To:           tx.To(),
+			GasUsed:      hexutil.Uint64(receipt.GasUsed),
+			ReturnData:   result.Return(),
+			Logs:         receipt.Logs,
+			CoinbaseDiff: (*hexutil.Big)(new(big.Int).Sub(state.GetBalance(header.Coinbase), balance)),
+			InternalTxs:  make([]*RPCInternalTransaction, len(internals)),
+		}
+		if result.Err != nil {
+			res.Error = result.Err.Error()
+		}
+		if revert := result.Revert(); len(revert) > 0 {
+			res.ReturnData = revert
+			if reason, err := abi.UnpackRevert(revert); err == nil {
+				res.RevertReason = reason
+			}
+		}
+		if res.Logs == nil {
+			res.Logs = []*types.Log{}
+		}
+		for j, itx := range internals {
+			res.InternalTxs[j] = NewRPCInternalTransaction(itx, common.Hash{}, header.Number.Uint64(), uint64(i))
+		}
+		res.StateChanges = recorder.changes()
+
+		results = append(results, res)
+	}
+	return &CallBundleResult{
+		StateBlockHash:   parent.Hash(),
+		StateBlockNumber: hexutil.Uint64(parent.Number.Uint64()),
+		GasUsed:          hexutil.Uint64(usedGas),
+		CoinbaseDiff:     (*hexutil.Big)(new(big.Int).Sub(state.GetBalance(header.Coinbase), coinbase)),
+		Results:          results,
+	}, nil
+}
+
+// bundleStateRecorder wraps the state of a bundle simulation, recording the
+// values of the accounts and storage slots before they are first modified by the
+// transaction being executed.
+type bundleStateRecorder struct {
+	*state.StateDB
+
+	accounts []common.Address                        // Modified accounts in order of first modification
+	origins  map[common.Address]*bundleAccountOrigin // Values of the modified accounts before the transaction
+}
+
+// bundleAccountOrigin is the state of an account before a transaction modified it.
+type bundleAccountOrigin struct {
+	balance  *big.Int
+	nonce    uint64
+	codeHash common.Hash
+	storage  map[common.Hash]common.Hash
+}
+
+// newBundleStateRecorder creates a recorder of the modifications made on top of
+// the given state.
+func newBundleStateRecorder(db *state.StateDB) *bundleStateRecorder {
+	return &bundleStateRecorder{
+		StateDB: db,
+		origins: make(map[common.Address]*bundleAccountOrigin),
+	}
+}
+
+// origin returns the original values of an account, recording them if the
+// account is modified for the first time.
+func (r *bundleStateRecorder) origin(addr common.Address) *bundleAccountOrigin {
+	if origin, ok := r.origins[addr]; ok {
+		return origin
+	}
+	origin := &bundleAccountOrigin{
+		balance:  new(big.Int).Set(r.StateDB.GetBalance(addr)),
+		nonce:    r.StateDB.GetNonce(addr),
+		codeHash: bundleCodeHash(r.StateDB, addr),
+		storage:  make(map[common.Hash]common.Hash),
+	}
+	r.accounts = append(r.accounts, addr)
+	r.origins[addr] = origin
+	return origin
+}
+
+// CreateAccount implements vm.StateDB, recording the account before creation.
+func (r *bundleStateRecorder) CreateAccount(addr common.Address) {
+	r.origin(addr)
+	r.StateDB.CreateAccount(addr)
+}
+
+// SubBalance implements vm.StateDB, recording the account before the change.
+func (r *bundleStateRecorder) SubBalance(addr common.Address, amount *big.Int) {
+	r.origin(addr)
+	r.StateDB.SubBalance(addr, amount)
+}
+
+// AddBalance implements vm.StateDB, recording the account before the change.
+func (r *bundleStateRecorder) AddBalance(addr common.Address, amount *big.Int) {
+	r.origin(addr)
+	r.StateDB.AddBalance(addr, amount)
+}
+
+// SetNonce implements vm.StateDB, recording the account before the change.
+func (r *bundleStateRecorder) SetNonce(addr common.Address, nonce uint64) {
+	r.origin(addr)
+	r.StateDB.SetNonce(addr, nonce)
+}
+
+// SetCode implements vm.StateDB, recording the account before the change.
+func (r *bundleStateRecorder) SetCode(addr common.Address, code []byte) {
+	r.origin(addr)
+	r.StateDB.SetCode(addr, code)
+}
+
+// SetState implements vm.StateDB, recording the storage slot before the change.
+func (r *bundleStateRecorder) SetState(addr common.Address, key, value common.Hash) {
+	origin := r.origin(addr)
+	if _, ok := origin.storage[key]; !ok {
+		origin.storage[key] = r.StateDB.GetState(addr, key)
+	}
+	r.StateDB.SetState(addr, key, value)
+}
+
+// Suicide implements vm.StateDB, recording the account before its destruction.
+func (r *bundleStateRecorder) Suicide(addr common.Address) bool {
+	r.origin(addr)
+	return r.StateDB.Suicide(addr)
+}
+
+// changes compares the recorded accounts and storage slots with their current
+// values, returning the modified ones in order of first modification. Values
+// modified within reverted calls are recorded too, but left out as unchanged.
+func (r *bundleStateRecorder) changes() []*BundleStateChange {
+	changes := []*BundleStateChange{}
+	for _, addr := range r.accounts {
+		var (
+			origin = r.origins[addr]
+			change = &BundleStateChange{Address: addr}
+		)
+		if after := r.StateDB.GetBalance(addr); origin.balance.Cmp(after) != 0 {
+			change.Balance = []*hexutil.Big{(*hexutil.Big)(origin.balance), (*hexutil.Big)(new(big.Int).Set(after))}
+		}
+		if after := r.StateDB.GetNonce(addr); origin.nonce != after {
+			change.Nonce = []hexutil.Uint64{hexutil.Uint64(origin.nonce), hexutil.Uint64(after)}
+		}
+		if after := bundleCodeHash(r.StateDB, addr); origin.codeHash != after {
+			change.CodeHash = []common.Hash{origin.codeHash, after}
+		}
+		for key, before := range origin.storage {
+			if after := r.StateDB.GetState(addr, key); before != after {
+				if change.Storage == nil {
+					change.Storage = make(map[common.Hash][]common.Hash)
+				}
+				change.Storage[key] = []common.Hash{before, after}
+			}
+		}
+		if change.Balance != nil || change.Nonce != nil || change.CodeHash != nil || change.Storage != nil {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// bundleCodeHash returns the code hash of an account, treating non-existent ones
+// as accounts without code.
+func bundleCodeHash(db *state.StateDB, addr common.Address) common.Hash {
+	if hash := db.GetCodeHash(addr); hash != (common.Hash{}) {
+		return hash
+	}
+	return emptyCodeHash
+}
+
+// backendChainContext implements core.ChainContext on top of an API backend, so
+// that historical block hashes can be resolved during simulations. There is no
+// consensus engine, the block author always being set explicitly.
+type backendChainContext struct {
+	ctx context.Context
+	b   Backend
+}
+
+// Engine implements core.ChainContext, returning no consensus engine.
+func (c *backendChainContext) Engine() consensus.Engine {
+	return nil
+}
+
+// GetHeader implements core.ChainContext, retrieving a header from the backend.
+func (c *backendChainContext) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header, err := c.b.HeaderByHash(c.ctx, hash)
+	if err != nil || header == nil || header.Number.Uint64() != number {
+		return nil
+	}
+	return header
+}
@@ -0,0 +1,191 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/common/hexutil"
+	"github.com/matthieu/go-ethereum/consensus/ethash"
+	"github.com/matthieu/go-ethereum/core"
+	"github.com/matthieu/go-ethereum/core/rawdb"
+	"github.com/matthieu/go-ethereum/core/state"
+	"github.com/matthieu/go-ethereum/core/types"
+	"github.com/matthieu/go-ethereum/core/vm"
+	"github.com/matthieu/go-ethereum/crypto"
+	"github.com/matthieu/go-ethereum/params"
+	"github.com/matthieu/go-ethereum/rpc"
+)
+
+func (b *testBackend) ChainConfig() *params.ChainConfig {
+	return b.chain.Config()
+}
+
+func (b *testBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return b.chain.GetHeaderByHash(hash), nil
+}
+
+func (b *testBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	header := b.chain.CurrentHeader()
+	statedb, err := b.chain.StateAt(header.Root)
+	return statedb, header, err
+}
+
+var (
+	bundleTestKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	bundleTestAddress = crypto.PubkeyToAddress(bundleTestKey.PublicKey)
+
+	// bundleTestReverter reverts with the reason "nope", copied from its own code.
+	bundleTestReverter = common.HexToAddress("0xbb01")
+	// bundleTestLooper loops forever.
+	bundleTestLooper = common.HexToAddress("0xbb02")
+
+	bundleTestCoinbase = common.HexToAddress("0xbbcb")
+)
+
+// newBundleTestBackend creates a backend on top of a genesis block funding the
+// test account and deploying the test contracts.
+func newBundleTestBackend(t *testing.T) *testBackend {
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		gspec = &core.Genesis{
+			Config:   params.TestChainConfig,
+			GasLimit: 30000000,
+			Alloc: core.GenesisAlloc{
+				bundleTestAddress: {Balance: big.NewInt(params.Ether)},
+				bundleTestReverter: {
+					Balance: new(big.Int),
+					Code: hexutil.MustDecode("0x6064600c60003960646000fd" +
+						"08c379a0" +
+						"0000000000000000000000000000000000000000000000000000000000000020" +
+						"0000000000000000000000000000000000000000000000000000000000000004" +
+						"6e6f706500000000000000000000000000000000000000000000000000000000"),
+				},
+				bundleTestLooper: {Balance: new(big.Int), Code: hexutil.MustDecode("0x5b600056")},
+			},
+		}
+	)
+	gspec.MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	return &testBackend{db: db, chain: chain}
+}
+
+// newBundleTestTx creates a signed and encoded transaction of the test account.
+func newBundleTestTx(t *testing.T, nonce uint64, to common.Address, value int64, gas uint64) []byte {
+	signer := types.MakeSigner(params.TestChainConfig, common.Big1)
+	tx, err := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(value), gas, big.NewInt(params.GWei), nil), signer, bundleTestKey)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	blob, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to encode transaction: %v", err)
+	}
+	return blob
+}
+
+// Tests that the outcome of every transaction of a bundle is reported, with the
+// bundle executed in order on top of the requested state.
+func TestCallBundle(t *testing.T) {
+	var (
+		backend = newBundleTestBackend(t)
+		payment = newBundleTestTx(t, 0, bundleTestCoinbase, 1000, params.TxGas)
+		revert  = newBundleTestTx(t, 1, bundleTestReverter, 0, 100000)
+		args    = CallBundleArgs{Txs: []hexutil.Bytes{payment, revert}, Coinbase: &bundleTestCoinbase}
+	)
+	defer backend.chain.Stop()
+
+	result, err := DoCallBundle(context.Background(), backend, args, time.Second, 0)
+	if err != nil {
+		t.Fatalf("failed to call bundle: %v", err)
+	}
+	if len(result.Results) != 2 {
+		t.Fatalf("result count mismatch: have %d, want %d", len(result.Results), 2)
+	}
+	// The payment should use the plain transfer gas and pay the coinbase directly
+	paid := result.Results[0]
+	if paid.GasUsed != hexutil.Uint64(params.TxGas) {
+		t.Errorf("payment gas mismatch: have %d, want %d", paid.GasUsed, params.TxGas)
+	}
+	if paid.Error != "" {
+		t.Errorf("payment failed: %v", paid.Error)
+	}
+	if want := new(big.Int).Add(big.NewInt(1000), new(big.Int).Mul(big.NewInt(int64(params.TxGas)), big.NewInt(params.GWei))); paid.CoinbaseDiff.ToInt().Cmp(want) != 0 {
+		t.Errorf("payment coinbase diff mismatch: have %v, want %v", paid.CoinbaseDiff, want)
+	}
+	var nonce []hexutil.Uint64
+	for _, change := range paid.StateChanges {
+		if change.Address == bundleTestAddress {
+			nonce = change.Nonce
+		}
+	}
+	if len(nonce) != 2 || nonce[0] != 0 || nonce[1] != 1 {
+		t.Errorf("payment sender nonce change mismatch: have %v, want [0 1]", nonce)
+	}
+	// The reverted transaction should be reported along with its reason
+	reverted := result.Results[1]
+	if reverted.GasUsed <= hexutil.Uint64(params.TxGas) || reverted.GasUsed >= 100000 {
+		t.Errorf("reverted gas out of range: have %d", reverted.GasUsed)
+	}
+	if reverted.Error != vm.ErrExecutionReverted.Error() {
+		t.Errorf("reverted error mismatch: have %q, want %q", reverted.Error, vm.ErrExecutionReverted)
+	}
+	if reverted.RevertReason != "nope" {
+		t.Errorf("revert reason mismatch: have %q, want %q", reverted.RevertReason, "nope")
+	}
+	if want := new(big.Int).Mul(big.NewInt(int64(reverted.GasUsed)), big.NewInt(params.GWei)); reverted.CoinbaseDiff.ToInt().Cmp(want) != 0 {
+		t.Errorf("reverted coinbase diff mismatch: have %v, want %v", reverted.CoinbaseDiff, want)
+	}
+	// The totals should cover the whole bundle
+	if result.GasUsed != paid.GasUsed+reverted.GasUsed {
+		t.Errorf("bundle gas mismatch: have %d, want %d", result.GasUsed, paid.GasUsed+reverted.GasUsed)
+	}
+	if want := new(big.Int).Add(paid.CoinbaseDiff.ToInt(), reverted.CoinbaseDiff.ToInt()); result.CoinbaseDiff.ToInt().Cmp(want) != 0 {
+		t.Errorf("bundle coinbase diff mismatch: have %v, want %v", result.CoinbaseDiff, want)
+	}
+}
+
+// Tests that bundles exceeding the gas cap or the time limit are refused.
+func TestCallBundleLimits(t *testing.T) {
+	backend := newBundleTestBackend(t)
+	defer backend.chain.Stop()
+
+	// Bundles exceeding the gas cap should be rejected without execution
+	args := CallBundleArgs{Txs: []hexutil.Bytes{
+		newBundleTestTx(t, 0, bundleTestCoinbase, 0, params.TxGas),
+		newBundleTestTx(t, 1, bundleTestCoinbase, 0, params.TxGas),
+	}}
+	if _, err := DoCallBundle(context.Background(), backend, args, time.Second, 2*params.TxGas-1); err == nil || !strings.Contains(err.Error(), "exceeds cap") {
+		t.Errorf("gas cap error mismatch: have %v", err)
+	}
+	if _, err := DoCallBundle(context.Background(), backend, args, time.Second, 2*params.TxGas); err != nil {
+		t.Errorf("failed to call bundle within gas cap: %v", err)
+	}
+	// Bundles running for too long should be aborted
+	args = CallBundleArgs{Txs: []hexutil.Bytes{newBundleTestTx(t, 0, bundleTestLooper, 0, 25000000)}}
+	if _, err := DoCallBundle(context.Background(), backend, args, time.Millisecond, 0); err == nil || !strings.Contains(err.Error(), "execution aborted") {
+		t.Errorf("timeout error mismatch: have %v", err)
+	}
+}
@@ -108,6 +108,18 @@ web3._extend({
 			call: 'clique_status',
 			params: 0
 		}),
+		new web3._extend.Method({
+			name: 'getVoteHistory',
+			call: 'clique_getVoteHistory',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getSignerStats',
+			call: 'clique_getSignerStats',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -429,6 +441,18 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null, null]
 		}),
+		new web3._extend.Method({
+			name: 'traceCall',
+			call: 'debug_traceCall',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'accessProfile',
+			call: 'debug_accessProfile',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
 		new web3._extend.Method({
 			name: 'preimage',
 			call: 'debug_preimage',
@@ -545,6 +569,34 @@ web3._extend({
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'getBlockReceipts',
+			call: 'eth_getBlockReceipts',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'callBundle',
+			call: 'eth_callBundle',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getInternalTransactionsByHash',
+			call: 'eth_getInternalTransactionsByHash',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getInternalTransactionsByBlockNumber',
+			call: 'eth_getInternalTransactionsByBlockNumber',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getInternalTransactionsByAddress',
+			call: 'eth_getInternalTransactionsByAddress',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.utils.toHex, null]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -686,6 +738,10 @@ web3._extend({
 			name: 'modules',
 			getter: 'rpc_modules'
 		}),
+		new web3._extend.Property({
+			name: 'discover',
+			getter: 'rpc_discover'
+		}),
 	]
 });
 `
@@ -748,6 +804,10 @@ web3._extend({
 			name: 'inspect',
 			getter: 'txpool_inspect'
 		}),
+		new web3._extend.Property({
+			name: 'rejections',
+			getter: 'txpool_rejections'
+		}),
 		new web3._extend.Property({
 			name: 'status',
 			getter: 'txpool_status',
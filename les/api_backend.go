@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"math/big"
+	"time"
 
 	"github.com/matthieu/go-ethereum/accounts"
 	"github.com/matthieu/go-ethereum/common"
@@ -155,6 +156,14 @@ func (b *LesApiBackend) GetReceipts(ctx context.Context, hash common.Hash) (type
 	return nil, nil
 }
 
+func (b *LesApiBackend) GetInternalTxs(ctx context.Context, hash common.Hash) ([]types.InternalTransactions, error) {
+	return nil, errors.New("internal transactions are not available in light mode")
+}
+
+func (b *LesApiBackend) GetInternalTxAddrEntries(ctx context.Context, address common.Address, number uint64, txIndex, index uint32, limit int) ([]rawdb.InternalTxAddrEntry, error) {
+	return nil, errors.New("internal transaction address index is not available in light mode")
+}
+
 func (b *LesApiBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types.Log, error) {
 	if number := rawdb.ReadHeaderNumber(b.eth.chainDb, hash); number != nil {
 		return light.GetBlockLogs(ctx, b.eth.odr, hash, *number)
@@ -206,6 +215,11 @@ func (b *LesApiBackend) TxPoolContent() (map[common.Address]types.Transactions,
 	return b.eth.txPool.Content()
 }
 
+// TxPoolRejections returns nil, light clients don't run any pool policies.
+func (b *LesApiBackend) TxPoolRejections() []*core.TxRejection {
+	return nil
+}
+
 func (b *LesApiBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.eth.txPool.SubscribeNewTxsEvent(ch)
 }
@@ -237,6 +251,10 @@ func (b *LesApiBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEven
 	return b.eth.blockchain.SubscribeRemovedLogsEvent(ch)
 }
 
+func (b *LesApiBackend) SubscribeInternalTxsEvent(ch chan<- core.InternalTxsEvent) event.Subscription {
+	return b.eth.blockchain.SubscribeInternalTxsEvent(ch)
+}
+
 func (b *LesApiBackend) Downloader() *downloader.Downloader {
 	return b.eth.Downloader()
 }
@@ -265,6 +283,10 @@ func (b *LesApiBackend) RPCGasCap() uint64 {
 	return b.eth.config.RPCGasCap
 }
 
+func (b *LesApiBackend) RPCBundleTimeout() time.Duration {
+	return b.eth.config.RPCBundleTimeout
+}
+
 func (b *LesApiBackend) RPCTxFeeCap() float64 {
 	return b.eth.config.RPCTxFeeCap
 }
@@ -20,6 +20,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/matthieu/go-ethereum/common/mclock"
 	"github.com/matthieu/go-ethereum/core"
 	"github.com/matthieu/go-ethereum/ethdb"
 	"github.com/matthieu/go-ethereum/light"
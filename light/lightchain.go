@@ -571,6 +571,12 @@ func (lc *LightChain) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent)
 	return lc.scope.Track(new(event.Feed).Subscribe(ch))
 }
 
+// SubscribeInternalTxsEvent implements the interface of filters.Backend
+// LightChain does not send core.InternalTxsEvent, so return an empty subscription.
+func (lc *LightChain) SubscribeInternalTxsEvent(ch chan<- core.InternalTxsEvent) event.Subscription {
+	return lc.scope.Track(new(event.Feed).Subscribe(ch))
+}
+
 // DisableCheckFreq disables header validation. This is used for ultralight mode.
 func (lc *LightChain) DisableCheckFreq() {
 	atomic.StoreInt32(&lc.disableCheckFreq, 1)
@@ -0,0 +1,374 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"bytes"
+	"container/heap"
+	"math"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/core"
+	"github.com/matthieu/go-ethereum/core/types"
+	"github.com/matthieu/go-ethereum/log"
+)
+
+// Block building strategies ordering the pending transactions.
+const (
+	PriceNonceStrategy = "price" // Locals first, then by gas price and nonce
+	FIFOStrategy       = "fifo"  // By arrival in the transaction pool and nonce
+)
+
+// newBlockBuilder creates the block building strategy configured for the miner,
+// falling back to price and nonce ordering if the strategy is unknown.
+func newBlockBuilder(config *Config, pool *core.TxPool) BlockBuilder {
+	var builder BlockBuilder
+	switch config.Builder {
+	case FIFOStrategy:
+		builder = NewFIFOBuilder(pool.Arrival)
+	case PriceNonceStrategy, "":
+		builder = NewPriceNonceBuilder()
+	default:
+		log.Warn("Unknown block building strategy, using price ordering", "strategy", config.Builder)
+		builder = NewPriceNonceBuilder()
+	}
+	if len(config.ReservedSenders) > 0 && config.ReservedGas > 0 {
+		builder = NewReservedBuilder(config.ReservedSenders, config.ReservedGas, builder)
+	}
+	if config.Bundles != nil {
+		builder = NewBundleBuilder(config.Bundles, builder)
+	}
+	return builder
+}
+
+// TxOrderer is an iterator over the candidate transactions of a block, returning
+// them in the order they should be included. It is implemented by the price and
+// nonce sorted types.TransactionsByPriceAndNonce.
+type TxOrderer interface {
+	// Peek returns the next transaction to include, or nil if none are left.
+	Peek() *types.Transaction
+
+	// Shift replaces the current transaction with the next one from the same
+	// account, called after the current one was processed.
+	Shift()
+
+	// Pop removes the current transaction without replacing it with the next one
+	// from the same account, called if the account cannot execute any more.
+	Pop()
+}
+
+// BuildStage is a batch of transactions committed into the block being built.
+type BuildStage struct {
+	Txs     TxOrderer // Transactions to commit, in inclusion order
+	Reserve uint64    // Block gas withheld from this stage for later ones
+	Atomic  bool      // Whether to include all transactions of the stage or none
+}
+
+// BlockBuilder is a block building strategy, deciding which of the pending
+// transactions get included in a block and in what order.
+type BlockBuilder interface {
+	// Stages splits the pending transactions into stages which are committed
+	// in order on top of the given header. The pending map is owned by the
+	// builder, locals lists the accounts considered local by the pool.
+	Stages(signer types.Signer, header *types.Header, pending map[common.Address]types.Transactions, locals []common.Address) []*BuildStage
+
+	// Incremental orders the transactions arriving after a block was built, to
+	// be committed on top of it. Only the base ordering applies, the bundles and
+	// reservations are settled when the block is built. The pending map is owned
+	// by the builder.
+	Incremental(signer types.Signer, header *types.Header, pending map[common.Address]types.Transactions) *BuildStage
+}
+
+// priceNonceBuilder is the default block building strategy, including local
+// transactions first and ordering both locals and remotes by price and nonce.
+type priceNonceBuilder struct{}
+
+// NewPriceNonceBuilder creates the default block builder, including the local
+// transactions first, each batch sorted by gas price while honouring nonces.
+func NewPriceNonceBuilder() BlockBuilder {
+	return priceNonceBuilder{}
+}
+
+// Stages implements BlockBuilder, splitting the pending transactions into the
+// locals and remotes.
+func (priceNonceBuilder) Stages(signer types.Signer, header *types.Header, pending map[common.Address]types.Transactions, locals []common.Address) []*BuildStage {
+	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
+	for _, account := range locals {
+		if txs := remoteTxs[account]; len(txs) > 0 {
+			delete(remoteTxs, account)
+			localTxs[account] = txs
+		}
+	}
+	var stages []*BuildStage
+	if len(localTxs) > 0 {
+		stages = append(stages, &BuildStage{Txs: types.NewTransactionsByPriceAndNonce(signer, localTxs)})
+	}
+	if len(remoteTxs) > 0 {
+		stages = append(stages, &BuildStage{Txs: types.NewTransactionsByPriceAndNonce(signer, remoteTxs)})
+	}
+	return stages
+}
+
+// Incremental implements BlockBuilder, ordering the new transactions by price
+// and nonce.
+func (priceNonceBuilder) Incremental(signer types.Signer, header *types.Header, pending map[common.Address]types.Transactions) *BuildStage {
+	if len(pending) == 0 {
+		return nil
+	}
+	return &BuildStage{Txs: types.NewTransactionsByPriceAndNonce(signer, pending)}
+}
+
+// fifoBuilder is a block building strategy including transactions in the order
+// they entered the transaction pool, disregarding gas prices.
+type fifoBuilder struct {
+	arrival func(hash common.Hash) (uint64, bool)
+}
+
+// NewFIFOBuilder creates a block builder including the pending transactions in
+// their arrival order while honouring nonces. Locals are not prioritized. The
+// arrival function returns the sequence number of a transaction in the order
+// the transactions entered the pool, as done by core.TxPool.Arrival.
+func NewFIFOBuilder(arrival func(hash common.Hash) (uint64, bool)) BlockBuilder {
+	return fifoBuilder{arrival: arrival}
+}
+
+// Stages implements BlockBuilder, ordering all pending transactions by arrival.
+func (b fifoBuilder) Stages(signer types.Signer, header *types.Header, pending map[common.Address]types.Transactions, locals []common.Address) []*BuildStage {
+	if stage := b.Incremental(signer, header, pending); stage != nil {
+		return []*BuildStage{stage}
+	}
+	return nil
+}
+
+// Incremental implements BlockBuilder, ordering the new transactions by arrival.
+func (b fifoBuilder) Incremental(signer types.Signer, header *types.Header, pending map[common.Address]types.Transactions) *BuildStage {
+	if len(pending) == 0 {
+		return nil
+	}
+	return &BuildStage{Txs: newTxsByArrivalAndNonce(signer, pending, b.arrival)}
+}
+
+// reservedBuilder is a block building strategy reserving a portion of the block
+// gas for a set of system senders.
+type reservedBuilder struct {
+	senders map[common.Address]struct{}
+	reserve uint64
+	inner   BlockBuilder
+}
+
+// NewReservedBuilder creates a block builder which withholds reserve gas from
+// all the transactions ordered by the inner builder, making it available only
+// to the given system senders. Transactions of the system senders are included
+// last, sorted by price and nonce, and may use any gas left in the block.
+func NewReservedBuilder(senders []common.Address, reserve uint64, inner BlockBuilder) BlockBuilder {
+	builder := &reservedBuilder{
+		senders: make(map[common.Address]struct{}),
+		reserve: reserve,
+		inner:   inner,
+	}
+	for _, addr := range senders {
+		builder.senders[addr] = struct{}{}
+	}
+	return builder
+}
+
+// Stages implements BlockBuilder, splitting the system senders from the rest of
+// the pending transactions.
+func (b *reservedBuilder) Stages(signer types.Signer, header *types.Header, pending map[common.Address]types.Transactions, locals []common.Address) []*BuildStage {
+	system := make(map[common.Address]types.Transactions)
+	for addr := range b.senders {
+		if txs := pending[addr]; len(txs) > 0 {
+			delete(pending, addr)
+			system[addr] = txs
+		}
+	}
+	stages := b.inner.Stages(signer, header, pending, locals)
+	for _, stage := range stages {
+		if stage.Reserve < b.reserve {
+			stage.Reserve = b.reserve
+		}
+	}
+	if len(system) > 0 {
+		stages = append(stages, &BuildStage{Txs: types.NewTransactionsByPriceAndNonce(signer, system)})
+	}
+	return stages
+}
+
+// Incremental implements BlockBuilder, ordering the new transactions with the
+// inner builder.
+func (b *reservedBuilder) Incremental(signer types.Signer, header *types.Header, pending map[common.Address]types.Transactions) *BuildStage {
+	return b.inner.Incremental(signer, header, pending)
+}
+
+// bundleBuilder is a block building strategy including transaction bundles as a
+// whole or not at all, ahead of the other pending transactions.
+type bundleBuilder struct {
+	bundles func(header *types.Header) []types.Transactions
+	inner   BlockBuilder
+}
+
+// NewBundleBuilder creates a block builder which includes the bundles returned
+// by the given source at the top of the block. Each bundle is committed in its
+// given order and atomically: if any of its transactions fails or reverts, none
+// of them is included. The remaining block space is filled by the inner builder.
+func NewBundleBuilder(bundles func(header *types.Header) []types.Transactions, inner BlockBuilder) BlockBuilder {
+	return &bundleBuilder{
+		bundles: bundles,
+		inner:   inner,
+	}
+}
+
+// Stages implements BlockBuilder, adding an atomic stage for every bundle.
+func (b *bundleBuilder) Stages(signer types.Signer, header *types.Header, pending map[common.Address]types.Transactions, locals []common.Address) []*BuildStage {
+	var stages []*BuildStage
+	for _, bundle := range b.bundles(header) {
+		if len(bundle) > 0 {
+			stages = append(stages, &BuildStage{Txs: newTxsInOrder(bundle), Atomic: true})
+		}
+	}
+	return append(stages, b.inner.Stages(signer, header, pending, locals)...)
+}
+
+// Incremental implements BlockBuilder, ordering the new transactions with the
+// inner builder. Bundles are only included when the block is built.
+func (b *bundleBuilder) Incremental(signer types.Signer, header *types.Header, pending map[common.Address]types.Transactions) *BuildStage {
+	return b.inner.Incremental(signer, header, pending)
+}
+
+// txsInOrder is a transaction orderer returning a list of transactions in their
+// original order.
+type txsInOrder struct {
+	txs types.Transactions
+}
+
+// newTxsInOrder creates an orderer iterating over the given transactions.
+func newTxsInOrder(txs types.Transactions) *txsInOrder {
+	return &txsInOrder{txs: txs}
+}
+
+// Peek implements TxOrderer, returning the next transaction in the list.
+func (t *txsInOrder) Peek() *types.Transaction {
+	if len(t.txs) == 0 {
+		return nil
+	}
+	return t.txs[0]
+}
+
+// Shift implements TxOrderer, moving on to the next transaction in the list.
+func (t *txsInOrder) Shift() { t.txs = t.txs[1:] }
+
+// Pop implements TxOrderer, moving on to the next transaction in the list.
+func (t *txsInOrder) Pop() { t.txs = t.txs[1:] }
+
+// arrivalTx is a transaction along with its arrival sequence number in the pool.
+type arrivalTx struct {
+	tx  *types.Transaction
+	seq uint64
+}
+
+// txByArrival implements the heap interface, ordering transactions by the order
+// they entered the pool and falling back to the hash for unknown transactions.
+type txByArrival []*arrivalTx
+
+func (s txByArrival) Len() int { return len(s) }
+func (s txByArrival) Less(i, j int) bool {
+	if s[i].seq != s[j].seq {
+		return s[i].seq < s[j].seq
+	}
+	hi, hj := s[i].tx.Hash(), s[j].tx.Hash()
+	return bytes.Compare(hi[:], hj[:]) < 0
+}
+func (s txByArrival) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s *txByArrival) Push(x interface{}) {
+	*s = append(*s, x.(*arrivalTx))
+}
+
+func (s *txByArrival) Pop() interface{} {
+	old := *s
+	n := len(old)
+	x := old[n-1]
+	*s = old[0 : n-1]
+	return x
+}
+
+// txsByArrivalAndNonce is a transaction orderer returning transactions in their
+// arrival order, while never reordering the transactions of a single account.
+type txsByArrivalAndNonce struct {
+	txs     map[common.Address]types.Transactions // Per account nonce-sorted list of transactions
+	heads   txByArrival                           // Next transaction for each unique account (arrival heap)
+	signer  types.Signer                          // Signer for the set of transactions
+	arrival func(hash common.Hash) (uint64, bool) // Arrival sequence number lookup
+}
+
+// newTxsByArrivalAndNonce creates a transaction set that can retrieve arrival
+// sorted transactions in a nonce-honouring way. The input map is reowned.
+func newTxsByArrivalAndNonce(signer types.Signer, txs map[common.Address]types.Transactions, arrival func(hash common.Hash) (uint64, bool)) *txsByArrivalAndNonce {
+	t := &txsByArrivalAndNonce{
+		txs:     txs,
+		heads:   make(txByArrival, 0, len(txs)),
+		signer:  signer,
+		arrival: arrival,
+	}
+	for from, accTxs := range txs {
+		t.heads = append(t.heads, t.wrap(accTxs[0]))
+		// Ensure the sender address is from the signer
+		acc, _ := types.Sender(signer, accTxs[0])
+		txs[acc] = accTxs[1:]
+		if from != acc {
+			delete(txs, from)
+		}
+	}
+	heap.Init(&t.heads)
+	return t
+}
+
+// wrap looks up the arrival sequence number of a transaction. Transactions which
+// already left the pool are ordered last.
+func (t *txsByArrivalAndNonce) wrap(tx *types.Transaction) *arrivalTx {
+	seq, ok := t.arrival(tx.Hash())
+	if !ok {
+		seq = math.MaxUint64
+	}
+	return &arrivalTx{tx: tx, seq: seq}
+}
+
+// Peek implements TxOrderer, returning the earliest arrived executable transaction.
+func (t *txsByArrivalAndNonce) Peek() *types.Transaction {
+	if len(t.heads) == 0 {
+		return nil
+	}
+	return t.heads[0].tx
+}
+
+// Shift implements TxOrderer, replacing the current head with the next one from
+// the same account.
+func (t *txsByArrivalAndNonce) Shift() {
+	acc, _ := types.Sender(t.signer, t.heads[0].tx)
+	if txs, ok := t.txs[acc]; ok && len(txs) > 0 {
+		t.heads[0], t.txs[acc] = t.wrap(txs[0]), txs[1:]
+		heap.Fix(&t.heads, 0)
+	} else {
+		heap.Pop(&t.heads)
+	}
+}
+
+// Pop implements TxOrderer, removing the current head without replacing it with
+// the next one from the same account.
+func (t *txsByArrivalAndNonce) Pop() {
+	heap.Pop(&t.heads)
+}
@@ -51,6 +51,14 @@ type Config struct {
 	GasPrice  *big.Int       // Minimum gas price for mining a transaction
 	Recommit  time.Duration  // The time interval for miner to re-create mining work.
 	Noverify  bool           // Disable remote mining solution verification(only useful in ethash).
+
+	SignTimeout time.Duration `toml:",omitempty"` // Maximum time to wait for the signer to seal a block (only useful in clique).
+
+	Builder         string           `toml:",omitempty"` // Block building strategy, PriceNonceStrategy if empty
+	ReservedSenders []common.Address `toml:",omitempty"` // System senders allowed to use the reserved block gas
+	ReservedGas     uint64           `toml:",omitempty"` // Block gas withheld from all but the system senders
+
+	Bundles func(header *types.Header) []types.Transactions `toml:"-"` // Source of the bundles included atomically at the top of blocks
 }
 
 // Miner creates blocks and searches for proof-of-work values.
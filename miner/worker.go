@@ -50,6 +50,9 @@ const (
 	// chainSideChanSize is the size of channel listening to ChainSideEvent.
 	chainSideChanSize = 10
 
+	// sealFailureChanSize is the size of channel listening to SealFailure.
+	sealFailureChanSize = 10
+
 	// resubmitAdjustChanSize is the size of resubmitting interval adjustment channel.
 	resubmitAdjustChanSize = 10
 
@@ -127,6 +130,7 @@ type worker struct {
 	engine      consensus.Engine
 	eth         Backend
 	chain       *core.BlockChain
+	builder     BlockBuilder // Block building strategy ordering the pending transactions
 
 	// Feeds
 	pendingLogsFeed event.Feed
@@ -140,6 +144,9 @@ type worker struct {
 	chainSideCh  chan core.ChainSideEvent
 	chainSideSub event.Subscription
 
+	sealFailureCh  chan consensus.SealFailure
+	sealFailureSub event.Subscription // Nil if the engine doesn't seal in the background
+
 	// Channels
 	newWorkCh          chan *newWorkReq
 	taskCh             chan *task
@@ -148,6 +155,7 @@ type worker struct {
 	exitCh             chan struct{}
 	resubmitIntervalCh chan time.Duration
 	resubmitAdjustCh   chan *intervalAdjust
+	resealCh           chan struct{}
 
 	current      *environment                 // An environment for current running cycle.
 	localUncles  map[common.Hash]*types.Block // A set of side blocks generated locally as the possible uncle blocks.
@@ -184,6 +192,7 @@ type worker struct {
 	skipSealHook func(*task) bool                   // Method to decide whether skipping the sealing.
 	fullTaskHook func()                             // Method to call before pushing the full sealing task.
 	resubmitHook func(time.Duration, time.Duration) // Method to call upon updating resubmitting interval.
+	sealFailHook func(consensus.SealFailure)        // Method to call upon a sealing failure reported by the engine.
 }
 
 func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus.Engine, eth Backend, mux *event.TypeMux, isLocalBlock func(*types.Block) bool, init bool) *worker {
@@ -194,6 +203,7 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		eth:                eth,
 		mux:                mux,
 		chain:              eth.BlockChain(),
+		builder:            newBlockBuilder(config, eth.TxPool()),
 		isLocalBlock:       isLocalBlock,
 		localUncles:        make(map[common.Hash]*types.Block),
 		remoteUncles:       make(map[common.Hash]*types.Block),
@@ -209,6 +219,7 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		startCh:            make(chan struct{}, 1),
 		resubmitIntervalCh: make(chan time.Duration),
 		resubmitAdjustCh:   make(chan *intervalAdjust, resubmitAdjustChanSize),
+		resealCh:           make(chan struct{}, 1),
 	}
 	// Subscribe NewTxsEvent for tx pool
 	worker.txsSub = eth.TxPool().SubscribeNewTxsEvent(worker.txsCh)
@@ -216,6 +227,12 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 	worker.chainHeadSub = eth.BlockChain().SubscribeChainHeadEvent(worker.chainHeadCh)
 	worker.chainSideSub = eth.BlockChain().SubscribeChainSideEvent(worker.chainSideCh)
 
+	// Subscribe the sealing failures reported after the engine accepted a task
+	if reporter, ok := engine.(consensus.SealFailureReporter); ok {
+		worker.sealFailureCh = make(chan consensus.SealFailure, sealFailureChanSize)
+		worker.sealFailureSub = reporter.SubscribeSealFailures(worker.sealFailureCh)
+	}
+
 	// Sanitize recommit interval if the user-specified one is too short.
 	recommit := worker.config.Recommit
 	if recommit < minRecommitInterval {
@@ -311,6 +328,7 @@ func (w *worker) newWorkLoop(recommit time.Duration) {
 		interrupt   *int32
 		minRecommit = recommit // minimal resubmit interval specified by user.
 		timestamp   int64      // timestamp for each round of mining.
+		reseal      bool       // whether the current work failed to be sealed.
 	)
 
 	timer := time.NewTimer(0)
@@ -326,6 +344,7 @@ func (w *worker) newWorkLoop(recommit time.Duration) {
 		w.newWorkCh <- &newWorkReq{interrupt: interrupt, noempty: noempty, timestamp: timestamp}
 		timer.Reset(recommit)
 		atomic.StoreInt32(&w.newTxs, 0)
+		reseal = false
 	}
 	// recalcRecommit recalculates the resubmitting interval upon feedback.
 	recalcRecommit := func(target float64, inc bool) {
@@ -375,6 +394,11 @@ func (w *worker) newWorkLoop(recommit time.Duration) {
 			// If mining is running resubmit a new work cycle periodically to pull in
 			// higher priced transactions. Disable this overhead for pending blocks.
 			if w.isRunning() && (w.chainConfig.Clique == nil || w.chainConfig.Clique.Period > 0) {
+				// Resubmit the work failed to be sealed, including the empty block.
+				if reseal {
+					commit(false, commitInterruptResubmit)
+					continue
+				}
 				// Short circuit if no new transaction arrives.
 				if atomic.LoadInt32(&w.newTxs) == 0 {
 					timer.Reset(recommit)
@@ -383,6 +407,11 @@ func (w *worker) newWorkLoop(recommit time.Duration) {
 				commit(true, commitInterruptResubmit)
 			}
 
+		case <-w.resealCh:
+			// The engine failed to seal the current work, resubmit it on the next
+			// recommit instead of waiting for new transactions.
+			reseal = true
+
 		case interval := <-w.resubmitIntervalCh:
 			// Adjust resubmit interval explicitly by user.
 			if interval < minRecommitInterval {
@@ -488,9 +517,12 @@ func (w *worker) mainLoop() {
 					acc, _ := types.Sender(w.current.signer, tx)
 					txs[acc] = append(txs[acc], tx)
 				}
-				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs)
+				// Only order the new transactions, the bundles and reservations
+				// were settled when the pending block was built
 				tcount := w.current.tcount
-				w.commitTransactions(txset, coinbase, nil)
+				if stage := w.builder.Incremental(w.current.signer, w.current.header, txs); stage != nil {
+					w.commitStage(stage, coinbase, nil)
+				}
 				// Only update the snapshot if any new transactons were added
 				// to the pending block
 				if tcount != w.current.tcount {
@@ -534,6 +566,9 @@ func (w *worker) taskLoop() {
 			stopCh = nil
 		}
 	}
+	if w.sealFailureSub != nil {
+		defer w.sealFailureSub.Unsubscribe()
+	}
 	for {
 		select {
 		case task := <-w.taskCh:
@@ -559,6 +594,24 @@ func (w *worker) taskLoop() {
 			if err := w.engine.Seal(w.chain, task.block, w.resultCh, stopCh); err != nil {
 				log.Warn("Block sealing failed", "err", err)
 			}
+		case failure := <-w.sealFailureCh:
+			// The engine gave up on a task in the background, drop it and request
+			// the current work to be resubmitted and sealed again.
+			log.Warn("Block sealing failed", "number", failure.Number, "sealhash", failure.SealHash, "err", failure.Err)
+			if failure.SealHash == prev {
+				prev = common.Hash{}
+				select {
+				case w.resealCh <- struct{}{}:
+				default:
+				}
+			}
+			w.pendingMu.Lock()
+			delete(w.pendingTasks, failure.SealHash)
+			w.pendingMu.Unlock()
+
+			if w.sealFailHook != nil {
+				w.sealFailHook(failure)
+			}
 		case <-w.exitCh:
 			interrupt()
 			return
@@ -728,7 +781,7 @@ func (w *worker) commitTransaction(tx *types.Transaction, coinbase common.Addres
 	return receipt.Logs, nil
 }
 
-func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coinbase common.Address, interrupt *int32) bool {
+func (w *worker) commitTransactions(txs TxOrderer, coinbase common.Address, interrupt *int32) bool {
 	// Short circuit if current is nil
 	if w.current == nil {
 		return true
@@ -818,21 +871,8 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 		}
 	}
 
-	if !w.isRunning() && len(coalescedLogs) > 0 {
-		// We don't push the pendingLogsEvent while we are mining. The reason is that
-		// when we are mining, the worker will regenerate a mining block every 3 seconds.
-		// In order to avoid pushing the repeated pendingLog, we disable the pending log pushing.
+	w.postPendingLogs(coalescedLogs)
 
-		// make a copy, the state caches the logs and these logs get "upgraded" from pending to mined
-		// logs by filling in the block hash when the block was mined by the local miner. This can
-		// cause a race condition if a log was "upgraded" before the PendingLogsEvent is processed.
-		cpy := make([]*types.Log, len(coalescedLogs))
-		for i, l := range coalescedLogs {
-			cpy[i] = new(types.Log)
-			*cpy[i] = *l
-		}
-		w.pendingLogsFeed.Send(cpy)
-	}
 	// Notify resubmit loop to decrease resubmitting interval if current interval is larger
 	// than the user-specified one.
 	if interrupt != nil {
@@ -841,6 +881,107 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 	return false
 }
 
+// commitStage commits a single stage of the block building strategy, withholding
+// the gas reserved for later stages. The return value is the same as the one of
+// commitTransactions.
+func (w *worker) commitStage(stage *BuildStage, coinbase common.Address, interrupt *int32) bool {
+	// Short circuit if current is nil
+	if w.current == nil {
+		return true
+	}
+	if w.current.gasPool == nil {
+		w.current.gasPool = new(core.GasPool).AddGas(w.current.header.GasLimit)
+	}
+	// Withhold the reserved gas for the duration of the stage
+	reserved := stage.Reserve
+	if gas := w.current.gasPool.Gas(); reserved > gas {
+		reserved = gas
+	}
+	w.current.gasPool.SubGas(reserved)
+	defer w.current.gasPool.AddGas(reserved)
+
+	if stage.Atomic {
+		return w.commitBundle(stage.Txs, coinbase, interrupt)
+	}
+	return w.commitTransactions(stage.Txs, coinbase, interrupt)
+}
+
+// commitBundle commits a batch of transactions in the given order, reverting all
+// of them if any fails or the commit is interrupted. The return value is the same
+// as the one of commitTransactions.
+//
+// State snapshots don't survive transaction finalisation, so the bundle is rolled
+// back to a copy of the state taken before the first transaction.
+func (w *worker) commitBundle(txs TxOrderer, coinbase common.Address, interrupt *int32) bool {
+	var (
+		env    = w.current
+		state  = env.state.Copy()
+		gas    = env.gasPool.Gas()
+		used   = env.header.GasUsed
+		tcount = env.tcount
+		count  = len(env.txs)
+
+		coalescedLogs []*types.Log
+	)
+	revert := func() {
+		env.state = state
+		*env.gasPool = core.GasPool(gas)
+		env.header.GasUsed = used
+		env.tcount = tcount
+		env.txs, env.receipts = env.txs[:count], env.receipts[:count]
+	}
+	for tx := txs.Peek(); tx != nil; tx = txs.Peek() {
+		if interrupt != nil && atomic.LoadInt32(interrupt) != commitInterruptNone {
+			revert()
+			return atomic.LoadInt32(interrupt) == commitInterruptNewHead
+		}
+		if tx.Protected() && !w.chainConfig.IsEIP155(env.header.Number) {
+			log.Trace("Dropping bundle with reply protected transaction", "hash", tx.Hash(), "eip155", w.chainConfig.EIP155Block)
+			revert()
+			return false
+		}
+		env.state.Prepare(tx.Hash(), common.Hash{}, env.tcount)
+
+		logs, err := w.commitTransaction(tx, coinbase)
+		if err != nil {
+			log.Debug("Bundle transaction failed, bundle dropped", "hash", tx.Hash(), "err", err)
+			revert()
+			return false
+		}
+		// A reverted transaction is valid in the block, but breaks the bundle
+		if env.receipts[len(env.receipts)-1].Status == types.ReceiptStatusFailed {
+			log.Debug("Bundle transaction reverted, bundle dropped", "hash", tx.Hash())
+			revert()
+			return false
+		}
+		coalescedLogs = append(coalescedLogs, logs...)
+		env.tcount++
+		txs.Shift()
+	}
+	w.postPendingLogs(coalescedLogs)
+	return false
+}
+
+// postPendingLogs sends the logs of the transactions committed into the pending
+// block to the subscribers, unless the worker is mining.
+func (w *worker) postPendingLogs(logs []*types.Log) {
+	if w.isRunning() || len(logs) == 0 {
+		// We don't push the pendingLogsEvent while we are mining. The reason is that
+		// when we are mining, the worker will regenerate a mining block every 3 seconds.
+		// In order to avoid pushing the repeated pendingLog, we disable the pending log pushing.
+		return
+	}
+	// make a copy, the state caches the logs and these logs get "upgraded" from pending to mined
+	// logs by filling in the block hash when the block was mined by the local miner. This can
+	// cause a race condition if a log was "upgraded" before the PendingLogsEvent is processed.
+	cpy := make([]*types.Log, len(logs))
+	for i, l := range logs {
+		cpy[i] = new(types.Log)
+		*cpy[i] = *l
+	}
+	w.pendingLogsFeed.Send(cpy)
+}
+
 // commitNewWork generates several new sealing tasks based on the parent block.
 func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64) {
 	w.mu.RLock()
@@ -940,30 +1081,18 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		log.Error("Failed to fetch pending transactions", "err", err)
 		return
 	}
+	// Order the pending transactions with the configured building strategy
+	stages := w.builder.Stages(w.current.signer, header, pending, w.eth.TxPool().Locals())
+
 	// Short circuit if there is no available pending transactions.
 	// But if we disable empty precommit already, ignore it. Since
 	// empty block is necessary to keep the liveness of the network.
-	if len(pending) == 0 && atomic.LoadUint32(&w.noempty) == 0 {
+	if len(stages) == 0 && atomic.LoadUint32(&w.noempty) == 0 {
 		w.updateSnapshot()
 		return
 	}
-	// Split the pending transactions into locals and remotes
-	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
-	for _, account := range w.eth.TxPool().Locals() {
-		if txs := remoteTxs[account]; len(txs) > 0 {
-			delete(remoteTxs, account)
-			localTxs[account] = txs
-		}
-	}
-	if len(localTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, localTxs)
-		if w.commitTransactions(txs, w.coinbase, interrupt) {
-			return
-		}
-	}
-	if len(remoteTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, remoteTxs)
-		if w.commitTransactions(txs, w.coinbase, interrupt) {
+	for _, stage := range stages {
+		if w.commitStage(stage, w.coinbase, interrupt) {
 			return
 		}
 	}
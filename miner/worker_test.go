@@ -17,6 +17,8 @@
 package miner
 
 import (
+	"crypto/ecdsa"
+	"errors"
 	"math/big"
 	"math/rand"
 	"sync/atomic"
@@ -61,6 +63,9 @@ var (
 	testUserKey, _  = crypto.GenerateKey()
 	testUserAddress = crypto.PubkeyToAddress(testUserKey.PublicKey)
 
+	testSystemKey, _  = crypto.GenerateKey()
+	testSystemAddress = crypto.PubkeyToAddress(testSystemKey.PublicKey)
+
 	// Test transactions
 	pendingTxs []*types.Transaction
 	newTxs     []*types.Transaction
@@ -101,7 +106,10 @@ type testWorkerBackend struct {
 func newTestWorkerBackend(t *testing.T, chainConfig *params.ChainConfig, engine consensus.Engine, db ethdb.Database, n int) *testWorkerBackend {
 	var gspec = core.Genesis{
 		Config: chainConfig,
-		Alloc:  core.GenesisAlloc{testBankAddress: {Balance: testBankFunds}},
+		Alloc: core.GenesisAlloc{
+			testBankAddress:   {Balance: testBankFunds},
+			testSystemAddress: {Balance: testBankFunds},
+		},
 	}
 
 	switch e := engine.(type) {
@@ -501,3 +509,222 @@ func testAdjustInterval(t *testing.T, chainConfig *params.ChainConfig, engine co
 		t.Error("interval reset timeout")
 	}
 }
+
+// Tests that blocks the engine failed to seal in the background are resubmitted
+// and sealed again, without waiting for new transactions.
+func TestResealAfterSealFailure(t *testing.T) {
+	var (
+		db          = rawdb.NewMemoryDatabase()
+		chainConfig = params.AllCliqueProtocolChanges
+	)
+	chainConfig.Clique = &params.CliqueConfig{Period: 1, Epoch: 30000}
+	engine := clique.New(chainConfig.Clique, db)
+
+	w, b := newTestWorker(t, chainConfig, engine, db, 0)
+	defer w.close()
+
+	// Reject the first signing request, accept the following ones
+	var signed int32
+	engine.Authorize(testBankAddress, func(account accounts.Account, s string, data []byte) ([]byte, error) {
+		if atomic.AddInt32(&signed, 1) == 1 {
+			return nil, errors.New("rejected")
+		}
+		return crypto.Sign(crypto.Keccak256(data), testBankKey)
+	})
+	failures := make(chan consensus.SealFailure, 1)
+	w.sealFailHook = func(failure consensus.SealFailure) {
+		failures <- failure
+	}
+	w.skipSealHook = func(task *task) bool {
+		return len(task.receipts) == 0
+	}
+	sub := w.mux.Subscribe(core.NewMinedBlockEvent{})
+	defer sub.Unsubscribe()
+
+	b.txPool.AddLocal(b.newRandomTx(true))
+	w.start()
+
+	select {
+	case failure := <-failures:
+		if failure.Number != 1 {
+			t.Errorf("failed block number mismatch: have %d, want %d", failure.Number, 1)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("sealing failure not reported")
+	}
+	select {
+	case ev := <-sub.Chan():
+		if block := ev.Data.(core.NewMinedBlockEvent).Block; block.NumberU64() != 1 {
+			t.Errorf("mined block number mismatch: have %d, want %d", block.NumberU64(), 1)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("block not resealed")
+	}
+}
+
+func TestPriceNonceBuilder(t *testing.T) {
+	var (
+		bank0 = newTestTransfer(testBankKey, 0, 1)
+		bank1 = newTestTransfer(testBankKey, 1, 1)
+		sys0  = newTestTransfer(testSystemKey, 0, 5)
+	)
+	config := *testConfig
+	config.Builder = PriceNonceStrategy
+
+	txs := testBuildBlock(t, &config, bank0, bank1, sys0)
+	checkBlockTxs(t, txs, sys0, bank0, bank1)
+}
+
+func TestFIFOBuilder(t *testing.T) {
+	var (
+		bank0 = newTestTransfer(testBankKey, 0, 1)
+		bank1 = newTestTransfer(testBankKey, 1, 10)
+		sys0  = newTestTransfer(testSystemKey, 0, 5)
+	)
+	config := *testConfig
+	config.Builder = FIFOStrategy
+
+	// The pool sees sys0 first, so it goes ahead of the nonce-ordered bank ones
+	txs := testBuildBlock(t, &config, sys0, bank1, bank0)
+	checkBlockTxs(t, txs, sys0, bank0, bank1)
+
+	// Reversing the arrival order puts the bank transactions first
+	txs = testBuildBlock(t, &config, bank1, bank0, sys0)
+	checkBlockTxs(t, txs, bank0, bank1, sys0)
+}
+
+func TestReservedBuilder(t *testing.T) {
+	var (
+		bank0 = newTestTransfer(testBankKey, 0, 5)
+		bank1 = newTestTransfer(testBankKey, 1, 5)
+		sys0  = newTestTransfer(testSystemKey, 0, 1)
+	)
+	// Reserve all but a single transfer for the system sender
+	config := *testConfig
+	config.ReservedSenders = []common.Address{testSystemAddress}
+	config.ReservedGas = params.GenesisGasLimit - params.TxGas
+
+	txs := testBuildBlock(t, &config, bank0, bank1, sys0)
+	checkBlockTxs(t, txs, bank0, sys0)
+}
+
+func TestBundleBuilder(t *testing.T) {
+	var (
+		bank0 = newTestTransfer(testBankKey, 0, 1)
+		bank1 = newTestTransfer(testBankKey, 1, 1)
+		sys0  = newTestTransfer(testSystemKey, 0, 1)
+		sys1  = newTestTransfer(testSystemKey, 1, 1)
+		sys2  = newTestTransfer(testSystemKey, 2, 1)
+		sys4  = newTestTransfer(testSystemKey, 4, 1)
+	)
+	// The second bundle contains a nonce gap, so it must be dropped as a whole
+	config := *testConfig
+	config.Bundles = func(header *types.Header) []types.Transactions {
+		return []types.Transactions{{sys0, sys1}, {sys2, sys4}}
+	}
+	txs := testBuildBlock(t, &config, bank0, bank1)
+	checkBlockTxs(t, txs, sys0, sys1, bank0, bank1)
+}
+
+func TestBundleBuilderRevert(t *testing.T) {
+	var (
+		bank0 = newTestTransfer(testBankKey, 0, 1)
+		sys0  = newTestTransfer(testSystemKey, 0, 1)
+		sys2  = newTestTransfer(testSystemKey, 2, 1)
+	)
+	// Deploying a contract with an invalid init code is valid, but reverts
+	sys1, _ := types.SignTx(types.NewContractCreation(1, new(big.Int), 100000, big.NewInt(1), []byte{0xfe}), types.HomesteadSigner{}, testSystemKey)
+
+	// The bundle contains a reverting transaction, so it must be dropped as a whole
+	config := *testConfig
+	config.Bundles = func(header *types.Header) []types.Transactions {
+		return []types.Transactions{{sys0, sys1, sys2}}
+	}
+	txs := testBuildBlock(t, &config, bank0)
+	checkBlockTxs(t, txs, bank0)
+}
+
+// Tests that transactions arriving after the pending block was built are added
+// on top of it, without including the bundles again.
+func TestBundleBuilderIncremental(t *testing.T) {
+	var (
+		bank0 = newTestTransfer(testBankKey, 0, 1)
+		bank1 = newTestTransfer(testBankKey, 1, 1)
+		sys0  = newTestTransfer(testSystemKey, 0, 1)
+
+		requests int32
+	)
+	config := *testConfig
+	config.Bundles = func(header *types.Header) []types.Transactions {
+		atomic.AddInt32(&requests, 1)
+		return []types.Transactions{{sys0}}
+	}
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	b := newTestWorkerBackend(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	if err := b.txPool.AddRemotesSync([]*types.Transaction{bank0})[0]; err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	w := newWorker(&config, ethashChainConfig, engine, b, new(event.TypeMux), nil, false)
+	defer w.close()
+
+	w.commitNewWork(nil, true, time.Now().Unix())
+	checkBlockTxs(t, w.pendingBlock().Transactions(), sys0, bank0)
+
+	if err := b.txPool.AddRemotesSync([]*types.Transaction{bank1})[0]; err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	for i := 0; len(w.pendingBlock().Transactions()) < 3; i++ {
+		if i == 100 {
+			t.Fatalf("new transaction not added to the pending block")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	checkBlockTxs(t, w.pendingBlock().Transactions(), sys0, bank0, bank1)
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("bundle request count mismatch: have %d, want %d", n, 1)
+	}
+}
+
+// newTestTransfer creates a signed value transfer to the test user.
+func newTestTransfer(key *ecdsa.PrivateKey, nonce uint64, price int64) *types.Transaction {
+	tx, _ := types.SignTx(types.NewTransaction(nonce, testUserAddress, big.NewInt(1000), params.TxGas, big.NewInt(price), nil), types.HomesteadSigner{}, key)
+	return tx
+}
+
+// testBuildBlock adds the given transactions to the pool of a new test worker,
+// creates a pending block with the given mining configuration and returns the
+// transactions included.
+func testBuildBlock(t *testing.T, config *Config, txs ...*types.Transaction) types.Transactions {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	b := newTestWorkerBackend(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	for _, err := range b.txPool.AddRemotesSync(txs) {
+		if err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	w := newWorker(config, ethashChainConfig, engine, b, new(event.TypeMux), nil, false)
+	defer w.close()
+
+	w.commitNewWork(nil, true, time.Now().Unix())
+	return w.pendingBlock().Transactions()
+}
+
+// checkBlockTxs checks that a block contains exactly the wanted transactions in
+// the given order.
+func checkBlockTxs(t *testing.T, have types.Transactions, want ...*types.Transaction) {
+	t.Helper()
+
+	if len(have) != len(want) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i := range want {
+		if have[i].Hash() != want[i].Hash() {
+			t.Errorf("transaction %d mismatch: have %x, want %x", i, have[i].Hash(), want[i].Hash())
+		}
+	}
+}
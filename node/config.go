@@ -18,6 +18,9 @@ package node
 
 import (
 	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -45,6 +48,7 @@ const (
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
+	datadirJWTSecret       = "jwtsecret"          // Path within the datadir to the RPC authentication secret
 )
 
 // Config represents a small collection of configuration values to fine tune the
@@ -136,6 +140,16 @@ type Config struct {
 	// interface.
 	HTTPTimeouts rpc.HTTPTimeouts
 
+	// RPCLimits configures the resource quotas enforced on the clients of the HTTP,
+	// WebSocket and authenticated RPC interfaces, such as the batch and response
+	// sizes, method concurrency and per client request rates.
+	RPCLimits rpc.Limits `toml:",omitempty"`
+
+	// RPCRequestLog configures the structured log of the calls served by the HTTP,
+	// WebSocket and authenticated RPC interfaces. The log is enabled by setting its
+	// path, which is resolved relative to the instance directory.
+	RPCRequestLog rpc.RequestLogConfig `toml:",omitempty"`
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string
@@ -162,6 +176,38 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// AuthHost is the host interface on which to start the authenticated RPC
+	// server, serving both HTTP and websocket requests carrying a JWT bearer
+	// token. If this field is empty, no authenticated API endpoint will be started.
+	AuthHost string `toml:",omitempty"`
+
+	// AuthPort is the TCP port number on which to start the authenticated RPC
+	// server. The default zero value is valid and will pick a port number randomly.
+	AuthPort int `toml:",omitempty"`
+
+	// AuthVirtualHosts is the list of virtual hostnames which are allowed on incoming
+	// requests to the authenticated RPC server. This is by default {'localhost'}.
+	AuthVirtualHosts []string `toml:",omitempty"`
+
+	// AuthModules is a list of API modules to expose via the authenticated RPC
+	// interface to clients whose token carries no role claim. If the module list
+	// is empty, all RPC API endpoints designated public will be exposed.
+	AuthModules []string `toml:",omitempty"`
+
+	// AuthRoles maps the role claims of the JWT bearer tokens to the API modules
+	// exposed to the clients presenting them. Tokens with a role not listed here
+	// are rejected.
+	AuthRoles map[string][]string `toml:",omitempty"`
+
+	// JWTSecret is the path to the file holding the hex encoded 32 byte secret
+	// used to verify the HS256 bearer tokens of the authenticated RPC server. If
+	// empty, the secret is stored in the data directory. A missing secret file
+	// is created with a newly generated secret.
+	//
+	// Tokens must carry their issuance time, and are valid for at most a day past
+	// it, regardless of any later expiry time they claim.
+	JWTSecret string `toml:",omitempty"`
+
 	// GraphQLHost is the host interface on which to start the GraphQL server. If this
 	// field is empty, no GraphQL API endpoint will be started.
 	GraphQLHost string
@@ -271,6 +317,15 @@ func (c *Config) WSEndpoint() string {
 	return fmt.Sprintf("%s:%d", c.WSHost, c.WSPort)
 }
 
+// AuthEndpoint resolves the authenticated RPC endpoint based on the configured
+// host interface and port parameters.
+func (c *Config) AuthEndpoint() string {
+	if c.AuthHost == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", c.AuthHost, c.AuthPort)
+}
+
 // DefaultWSEndpoint returns the websocket endpoint used by default.
 func DefaultWSEndpoint() string {
 	config := &Config{WSHost: DefaultWSHost, WSPort: DefaultWSPort}
@@ -391,6 +446,44 @@ func (c *Config) NodeKey() *ecdsa.PrivateKey {
 	return key
 }
 
+// JWTSecretKey retrieves the secret used to authenticate RPC clients, loading it
+// from the configured file or the data directory. If the file doesn't exist yet,
+// a new secret is generated and stored.
+func (c *Config) JWTSecretKey() ([]byte, error) {
+	path := c.JWTSecret
+	if path == "" {
+		if c.DataDir == "" {
+			return nil, errors.New("no JWT secret file configured for ephemeral node")
+		}
+		path = c.ResolvePath(datadirJWTSecret)
+	}
+	if blob, err := ioutil.ReadFile(path); err == nil {
+		secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(blob)), "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT secret in %s: %v", path, err)
+		}
+		if len(secret) != 32 {
+			return nil, fmt.Errorf("invalid JWT secret in %s: have %d bytes, want 32", path, len(secret))
+		}
+		return secret, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	// No persistent secret found, generate and store a new one.
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path, []byte(hex.EncodeToString(secret)), 0600); err != nil {
+		return nil, err
+	}
+	log.Info("Generated JWT secret", "path", path)
+	return secret, nil
+}
+
 // StaticNodes returns a list of node enode URLs configured as static nodes.
 func (c *Config) StaticNodes() []*enode.Node {
 	return c.parsePersistentNodes(&c.staticNodesWarning, c.ResolvePath(datadirStaticNodes))
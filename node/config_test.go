@@ -160,3 +160,46 @@ func TestNodeKeyPersistency(t *testing.T) {
 		t.Fatalf("ephemeral node key persisted to disk")
 	}
 }
+
+// Tests that the JWT secret is generated if missing and loaded back afterwards.
+func TestJWTSecretPersistency(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node-test")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Configure a node with no secret and ensure one is generated and persisted
+	config := &Config{Name: "unit-test", DataDir: dir}
+	secret1, err := config.JWTSecretKey()
+	if err != nil {
+		t.Fatalf("failed to generate JWT secret: %v", err)
+	}
+	if len(secret1) != 32 {
+		t.Fatalf("JWT secret length mismatch: have %d, want 32", len(secret1))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "unit-test", datadirJWTSecret)); err != nil {
+		t.Fatalf("JWT secret not persisted to data directory: %v", err)
+	}
+	// Configure a new node and ensure the previously persisted secret is loaded
+	secret2, err := config.JWTSecretKey()
+	if err != nil {
+		t.Fatalf("failed to load JWT secret: %v", err)
+	}
+	if !bytes.Equal(secret1, secret2) {
+		t.Fatalf("persisted JWT secret mismatch: have %x, want %x", secret2, secret1)
+	}
+	// Configure an explicit secret file and ensure invalid secrets are rejected
+	config.JWTSecret = filepath.Join(dir, "secret")
+	if err := ioutil.WriteFile(config.JWTSecret, []byte("0x1234"), 0600); err != nil {
+		t.Fatalf("failed to write JWT secret: %v", err)
+	}
+	if _, err := config.JWTSecretKey(); err == nil {
+		t.Fatalf("short JWT secret accepted")
+	}
+	// Configure an ephemeral node and ensure no secret is generated
+	config = &Config{Name: "unit-test"}
+	if _, err := config.JWTSecretKey(); err == nil {
+		t.Fatalf("ephemeral node generated JWT secret")
+	}
+}
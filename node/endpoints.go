@@ -37,12 +37,13 @@ func StartHTTPEndpoint(endpoint string, timeouts rpc.HTTPTimeouts, handler http.
 	}
 	// make sure timeout values are meaningful
 	CheckTimeouts(&timeouts)
-	// Bundle and start the HTTP server
+	// Bundle and start the HTTP server, letting event streams outlive the write timeout
 	httpSrv := &http.Server{
 		Handler:      handler,
 		ReadTimeout:  timeouts.ReadTimeout,
 		WriteTimeout: timeouts.WriteTimeout,
 		IdleTimeout:  timeouts.IdleTimeout,
+		ConnContext:  rpc.WithHTTPConn,
 	}
 	go httpSrv.Serve(listener)
 	return httpSrv, listener.Addr(), err
@@ -72,6 +72,14 @@ type Node struct {
 	wsHTTPServer   *http.Server // WebSocket RPC HTTP server
 	wsHandler      *rpc.Server  // WebSocket RPC request handler to process the API requests
 
+	authEndpoint     string        // Authenticated RPC endpoint (interface + port) to listen at (empty = disabled)
+	authListenerAddr net.Addr      // Address of authenticated RPC listener socket serving API requests
+	authHTTPServer   *http.Server  // Authenticated RPC HTTP server
+	authHandlers     []*rpc.Server // Authenticated RPC request handlers, one for each role
+
+	rpcLimiter *rpc.Limiter    // Resource limits shared by the remote RPC endpoints
+	requestLog *rpc.RequestLog // Log of the calls served to remote RPC clients (nil = disabled)
+
 	stop chan struct{} // Channel to wait for termination notifications
 	lock sync.RWMutex
 
@@ -121,6 +129,7 @@ func New(conf *Config) (*Node, error) {
 		ipcEndpoint:       conf.IPCEndpoint(),
 		httpEndpoint:      conf.HTTPEndpoint(),
 		wsEndpoint:        conf.WSEndpoint(),
+		authEndpoint:      conf.AuthEndpoint(),
 		eventmux:          new(event.TypeMux),
 		log:               conf.Logger,
 	}, nil
@@ -295,7 +304,16 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 		n.stopInProc()
 		return err
 	}
+	// Charge the clients of all remote endpoints against the same quotas
+	n.rpcLimiter = rpc.NewLimiter(n.config.RPCLimits)
+
+	if err := n.startRequestLog(); err != nil {
+		n.stopIPC()
+		n.stopInProc()
+		return err
+	}
 	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors, n.config.HTTPVirtualHosts, n.config.HTTPTimeouts, n.config.WSOrigins); err != nil {
+		n.stopRequestLog()
 		n.stopIPC()
 		n.stopInProc()
 		return err
@@ -304,11 +322,20 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 	if n.httpEndpoint != n.wsEndpoint {
 		if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins, n.config.WSExposeAll); err != nil {
 			n.stopHTTP()
+			n.stopRequestLog()
 			n.stopIPC()
 			n.stopInProc()
 			return err
 		}
 	}
+	if err := n.startAuth(n.authEndpoint, apis); err != nil {
+		n.stopWS()
+		n.stopHTTP()
+		n.stopRequestLog()
+		n.stopIPC()
+		n.stopInProc()
+		return err
+	}
 
 	// All API endpoints started successfully
 	n.rpcAPIs = apis
@@ -366,6 +393,33 @@ func (n *Node) stopIPC() {
 	}
 }
 
+// startRequestLog opens the log of the calls served to remote RPC clients, if
+// one is configured.
+func (n *Node) startRequestLog() error {
+	config := n.config.RPCRequestLog
+	if config.Path == "" {
+		return nil // Request log disabled.
+	}
+	if config.Path = n.config.ResolvePath(config.Path); config.Path == "" {
+		return errors.New("relative RPC request log path on ephemeral node")
+	}
+	reqlog, err := rpc.NewRequestLog(config)
+	if err != nil {
+		return err
+	}
+	n.requestLog = reqlog
+	n.log.Info("RPC request log opened", "path", config.Path, "sample", config.SampleRate, "slow", config.SlowThreshold)
+	return nil
+}
+
+// stopRequestLog closes the log of the calls served to remote RPC clients.
+func (n *Node) stopRequestLog() {
+	if n.requestLog != nil {
+		n.requestLog.Close()
+		n.requestLog = nil
+	}
+}
+
 // startHTTP initializes and starts the HTTP RPC endpoint.
 func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string, vhosts []string, timeouts rpc.HTTPTimeouts, wsOrigins []string) error {
 	// Short circuit if the HTTP endpoint isn't being exposed
@@ -374,6 +428,8 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	}
 	// register apis and create handler stack
 	srv := rpc.NewServer()
+	srv.SetLimiter(n.rpcLimiter)
+	srv.SetRequestLog(n.requestLog)
 	err := RegisterApisFromWhitelist(apis, modules, srv, false)
 	if err != nil {
 		return err
@@ -423,6 +479,8 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	}
 
 	srv := rpc.NewServer()
+	srv.SetLimiter(n.rpcLimiter)
+	srv.SetRequestLog(n.requestLog)
 	handler := srv.WebsocketHandler(wsOrigins)
 	err := RegisterApisFromWhitelist(apis, modules, srv, exposeAll)
 	if err != nil {
@@ -455,6 +513,74 @@ func (n *Node) stopWS() {
 	}
 }
 
+// startAuth initializes and starts the authenticated RPC endpoint, serving both
+// HTTP and WebSocket requests. Every role configured gets its own set of modules.
+func (n *Node) startAuth(endpoint string, apis []rpc.API) error {
+	// Short circuit if the authenticated endpoint isn't being exposed
+	if endpoint == "" {
+		return nil
+	}
+	secret, err := n.config.JWTSecretKey()
+	if err != nil {
+		return err
+	}
+	roles := map[string][]string{"": n.config.AuthModules}
+	for role, modules := range n.config.AuthRoles {
+		if role == "" {
+			return errors.New("empty authenticated RPC role name")
+		}
+		roles[role] = modules
+	}
+	var (
+		srvs     []*rpc.Server
+		handlers = make(map[string]http.Handler)
+	)
+	for role, modules := range roles {
+		srv := rpc.NewServer()
+		srv.SetLimiter(n.rpcLimiter)
+		srv.SetRequestLog(n.requestLog)
+		if err := RegisterApisFromWhitelist(apis, modules, srv, false); err != nil {
+			for _, srv := range srvs {
+				srv.Stop()
+			}
+			return err
+		}
+		srvs = append(srvs, srv)
+		handlers[role] = NewWebsocketUpgradeHandler(NewHTTPHandlerStack(srv, nil, n.config.AuthVirtualHosts), srv.WebsocketHandler(n.config.WSOrigins))
+	}
+	httpServer, addr, err := StartHTTPEndpoint(endpoint, n.config.HTTPTimeouts, NewJWTHandler(secret, handlers))
+	if err != nil {
+		for _, srv := range srvs {
+			srv.Stop()
+		}
+		return err
+	}
+	n.log.Info("Authenticated RPC endpoint opened", "url", fmt.Sprintf("http://%v/", addr),
+		"vhosts", strings.Join(n.config.AuthVirtualHosts, ","), "roles", len(roles)-1)
+
+	// All listeners booted successfully
+	n.authEndpoint = endpoint
+	n.authListenerAddr = addr
+	n.authHTTPServer = httpServer
+	n.authHandlers = srvs
+
+	return nil
+}
+
+// stopAuth terminates the authenticated RPC endpoint.
+func (n *Node) stopAuth() {
+	if n.authHTTPServer != nil {
+		// Don't bother imposing a timeout here.
+		n.authHTTPServer.Shutdown(context.Background())
+		n.log.Info("Authenticated RPC endpoint closed", "url", fmt.Sprintf("http://%v/", n.authListenerAddr))
+		n.authHTTPServer = nil
+	}
+	for _, srv := range n.authHandlers {
+		srv.Stop()
+	}
+	n.authHandlers = nil
+}
+
 // Stop terminates a running node along with all it's services. In the node was
 // not started, an error is returned.
 func (n *Node) Stop() error {
@@ -467,8 +593,10 @@ func (n *Node) Stop() error {
 	}
 
 	// Terminate the API, services and the p2p server.
+	n.stopAuth()
 	n.stopWS()
 	n.stopHTTP()
+	n.stopRequestLog()
 	n.stopIPC()
 	n.rpcAPIs = nil
 	failure := &StopError{
@@ -617,6 +745,18 @@ func (n *Node) HTTPEndpoint() string {
 	return n.httpEndpoint
 }
 
+// AuthEndpoint retrieves the current authenticated RPC endpoint used by the
+// protocol stack.
+func (n *Node) AuthEndpoint() string {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.authListenerAddr != nil {
+		return n.authListenerAddr.String()
+	}
+	return n.authEndpoint
+}
+
 // WSEndpoint retrieves the current WS endpoint used by the protocol stack.
 func (n *Node) WSEndpoint() string {
 	n.lock.Lock()
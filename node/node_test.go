@@ -17,11 +17,14 @@
 package node
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -632,6 +635,119 @@ func TestWebsocketHTTPOnSamePort_HTTPRequest(t *testing.T) {
 	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
 }
 
+// Tests that the HTTP and WebSocket endpoints charge the requests of a client
+// against the same rate limiting quota.
+func TestRPCLimitsSharedAcrossEndpoints(t *testing.T) {
+	node, err := New(&Config{RPCLimits: rpc.Limits{RequestRate: 0.001, RequestBurst: 2}})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	node.rpcLimiter = rpc.NewLimiter(node.config.RPCLimits)
+	if err := node.startHTTP("127.0.0.1:0", nil, nil, nil, nil, rpc.HTTPTimeouts{}, nil); err != nil {
+		t.Fatalf("failed to start HTTP endpoint: %v", err)
+	}
+	defer node.stopHTTP()
+	if err := node.startWS("127.0.0.1:0", nil, nil, nil, false); err != nil {
+		t.Fatalf("failed to start WebSocket endpoint: %v", err)
+	}
+	defer node.stopWS()
+
+	httpClient, err := rpc.DialHTTP("http://" + node.httpListenerAddr.String())
+	if err != nil {
+		t.Fatalf("failed to dial HTTP endpoint: %v", err)
+	}
+	defer httpClient.Close()
+	wsClient, err := rpc.DialWebsocket(context.Background(), "ws://"+node.wsListenerAddr.String(), "")
+	if err != nil {
+		t.Fatalf("failed to dial WebSocket endpoint: %v", err)
+	}
+	defer wsClient.Close()
+
+	var modules map[string]string
+	for i, client := range []*rpc.Client{httpClient, wsClient} {
+		if err := client.Call(&modules, "rpc_modules"); err != nil {
+			t.Fatalf("call %d: failed within the burst: %v", i, err)
+		}
+	}
+	for i, client := range []*rpc.Client{httpClient, wsClient} {
+		if err := client.Call(&modules, "rpc_modules"); err == nil {
+			t.Errorf("call %d: rate limit not shared across endpoints", i)
+		}
+	}
+}
+
+// tickService is an RPC service notifying its subscribers of the ticks fed to it.
+type tickService struct {
+	ticks chan int
+}
+
+func (s *tickService) Ticks(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+	go func() {
+		for {
+			select {
+			case tick := <-s.ticks:
+				notifier.Notify(sub.ID, tick)
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// Tests that subscriptions served over HTTP event streams outlive the write
+// timeout of the HTTP endpoint.
+func TestEventStreamWriteTimeout(t *testing.T) {
+	node, err := New(&Config{})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	service := &tickService{ticks: make(chan int)}
+	apis := []rpc.API{{Namespace: "test", Version: "1.0", Service: service, Public: true}}
+	timeouts := rpc.HTTPTimeouts{ReadTimeout: time.Second, WriteTimeout: time.Second, IdleTimeout: time.Second}
+	if err := node.startHTTP("127.0.0.1:0", apis, []string{"test"}, nil, nil, timeouts, nil); err != nil {
+		t.Fatalf("failed to start HTTP endpoint: %v", err)
+	}
+	defer node.stopHTTP()
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"test_subscribe","params":["ticks"]}`
+	req, _ := http.NewRequest(http.MethodPost, "http://"+node.httpListenerAddr.String(), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer resp.Body.Close()
+
+	events := bufio.NewReader(resp.Body)
+	nextData := func() string {
+		for {
+			line, err := events.ReadString('\n')
+			if err != nil {
+				t.Fatalf("failed to read event: %v", err)
+			}
+			if strings.HasPrefix(line, "data: ") {
+				return strings.TrimSpace(strings.TrimPrefix(line, "data: "))
+			}
+		}
+	}
+	if data := nextData(); !strings.Contains(data, `"result"`) {
+		t.Fatalf("wrong subscription response: %s", data)
+	}
+	time.Sleep(timeouts.WriteTimeout + 500*time.Millisecond)
+
+	service.ticks <- 42
+	if data := nextData(); !strings.Contains(data, `"result":42`) {
+		t.Fatalf("wrong notification: %s", data)
+	}
+}
+
 func startHTTP(t *testing.T) *Node {
 	conf := &Config{HTTPPort: 7453, WSPort: 7453}
 	node, err := New(conf)
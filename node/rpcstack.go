@@ -18,14 +18,22 @@ package node
 
 import (
 	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/matthieu/go-ethereum/log"
+	"github.com/matthieu/go-ethereum/rpc"
 	"github.com/rs/cors"
 )
 
@@ -119,6 +127,16 @@ func (w *gzipResponseWriter) Write(b []byte) (int, error) {
 	return w.Writer.Write(b)
 }
 
+// Flush implements http.Flusher, allowing event streams to be compressed.
+func (w *gzipResponseWriter) Flush() {
+	if gz, ok := w.Writer.(*gzip.Writer); ok {
+		gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func newGzipHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
@@ -157,3 +175,119 @@ func isWebsocket(r *http.Request) bool {
 	return strings.ToLower(r.Header.Get("Upgrade")) == "websocket" &&
 		strings.ToLower(r.Header.Get("Connection")) == "upgrade"
 }
+
+// jwtClockSkew is the maximum allowed difference between the local time and the
+// issuance or expiry time of a JWT bearer token.
+const jwtClockSkew = 60 * time.Second
+
+// jwtMaxLifetime is the maximum validity period of a JWT bearer token, counted
+// from its issuance time. Tokens claiming a later expiry time are rejected, so a
+// leaked token can't be used indefinitely.
+const jwtMaxLifetime = 24 * time.Hour
+
+// jwtClaims are the claims of a JWT bearer token checked by the jwtHandler.
+type jwtClaims struct {
+	IssuedAt  *int64 `json:"iat"`  // Issuance time, mandatory
+	ExpiresAt *int64 `json:"exp"`  // Expiry time, the issuance time plus the clock skew if omitted, at most jwtMaxLifetime after it
+	Subject   string `json:"sub"`  // Client identity to rate limit by, the remote address if omitted
+	Role      string `json:"role"` // Role deciding the exposed API modules
+}
+
+// jwtHandler is a handler which authenticates incoming requests using HS256 signed
+// JWT bearer tokens, serving each of them by the handler of the token's role.
+type jwtHandler struct {
+	secret   []byte
+	handlers map[string]http.Handler // Handlers by role claim, "" for tokens without one
+}
+
+// NewJWTHandler returns a handler authenticating requests with the given secret,
+// forwarding them to the handler of the token's role claim. Requests without a
+// valid token or with an unknown role are rejected.
+func NewJWTHandler(secret []byte, handlers map[string]http.Handler) http.Handler {
+	return &jwtHandler{secret: secret, handlers: handlers}
+}
+
+// ServeHTTP serves authenticated JSON-RPC requests over HTTP, implements http.Handler
+func (h *jwtHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "missing bearer token", http.StatusUnauthorized)
+		return
+	}
+	claims, err := verifyJWT(h.secret, strings.TrimPrefix(auth, "Bearer "), time.Now())
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	next, ok := h.handlers[claims.Role]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown role %q", claims.Role), http.StatusForbidden)
+		return
+	}
+	if claims.Subject != "" {
+		r = r.WithContext(rpc.WithClientIdentity(r.Context(), claims.Subject))
+	}
+	next.ServeHTTP(w, r)
+}
+
+// verifyJWT checks the signature and the validity period of a HS256 signed JWT
+// token, returning its claims. Tokens must be issued no later than the clock
+// skew from now, and are valid until their expiry time or, lacking one, for the
+// clock skew after their issuance.
+func verifyJWT(secret []byte, token string, now time.Time) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	// Verify the signature before looking into the contents
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, errors.New("invalid token signature")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %v", err)
+	}
+	if header.Alg != "HS256" {
+		return nil, fmt.Errorf("unsupported token algorithm %q", header.Alg)
+	}
+	claims := new(jwtClaims)
+	if err := decodeJWTSegment(parts[1], claims); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %v", err)
+	}
+	// Check the validity period of the token
+	if claims.IssuedAt == nil {
+		return nil, errors.New("missing token issuance time")
+	}
+	issued := time.Unix(*claims.IssuedAt, 0)
+	if issued.Sub(now) > jwtClockSkew {
+		return nil, errors.New("token issued in the future")
+	}
+	expiry := issued
+	if claims.ExpiresAt != nil {
+		expiry = time.Unix(*claims.ExpiresAt, 0)
+		if expiry.Sub(issued) > jwtMaxLifetime {
+			return nil, fmt.Errorf("token lifetime exceeds %v", jwtMaxLifetime)
+		}
+	}
+	if now.Sub(expiry) > jwtClockSkew {
+		return nil, errors.New("token expired")
+	}
+	return claims, nil
+}
+
+// decodeJWTSegment decodes a base64url encoded JSON segment of a JWT token.
+func decodeJWTSegment(segment string, v interface{}) error {
+	blob, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(blob, v)
+}
@@ -1,9 +1,15 @@
 package node
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/matthieu/go-ethereum/rpc"
 	"github.com/stretchr/testify/assert"
@@ -36,3 +42,79 @@ func TestNewWebsocketUpgradeHandler_websocket(t *testing.T) {
 	response := <-responses
 	assert.Equal(t, "websocket", response.Header.Get("Upgrade"))
 }
+
+// Tests that the JWT handler only lets through requests with valid tokens and
+// routes them to the handler of the token's role.
+func TestJWTHandler(t *testing.T) {
+	var (
+		secret = []byte("0123456789abcdef0123456789abcdef")
+		now    = time.Now().Unix()
+	)
+	newToken := func(secret []byte, alg string, claims map[string]interface{}) string {
+		header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+		payload, _ := json.Marshal(claims)
+
+		unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(unsigned))
+		return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	}
+	handlers := make(map[string]http.Handler)
+	for _, role := range []string{"", "admin"} {
+		role := role
+		handlers[role] = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(role))
+		})
+	}
+	ts := httptest.NewServer(NewJWTHandler(secret, handlers))
+	defer ts.Close()
+
+	tests := []struct {
+		auth   string
+		status int
+		role   string
+	}{
+		// Valid tokens, routed by role
+		{auth: "Bearer " + newToken(secret, "HS256", map[string]interface{}{"iat": now}), status: http.StatusOK},
+		{auth: "Bearer " + newToken(secret, "HS256", map[string]interface{}{"iat": now, "role": "admin"}), status: http.StatusOK, role: "admin"},
+		{auth: "Bearer " + newToken(secret, "HS256", map[string]interface{}{"iat": now - 30}), status: http.StatusOK},
+		{auth: "Bearer " + newToken(secret, "HS256", map[string]interface{}{"iat": now - 3600, "exp": now + 3600}), status: http.StatusOK},
+
+		// Missing or invalid tokens
+		{status: http.StatusUnauthorized},
+		{auth: "Basic dXNlcjpwYXNz", status: http.StatusUnauthorized},
+		{auth: "Bearer invalid", status: http.StatusUnauthorized},
+		{auth: "Bearer " + newToken([]byte("wrong secret"), "HS256", map[string]interface{}{"iat": now}), status: http.StatusUnauthorized},
+		{auth: "Bearer " + newToken(secret, "none", map[string]interface{}{"iat": now}), status: http.StatusUnauthorized},
+
+		// Tokens outside their validity period
+		{auth: "Bearer " + newToken(secret, "HS256", map[string]interface{}{}), status: http.StatusUnauthorized},
+		{auth: "Bearer " + newToken(secret, "HS256", map[string]interface{}{"iat": now - 120}), status: http.StatusUnauthorized},
+		{auth: "Bearer " + newToken(secret, "HS256", map[string]interface{}{"iat": now + 120}), status: http.StatusUnauthorized},
+		{auth: "Bearer " + newToken(secret, "HS256", map[string]interface{}{"iat": now - 3600, "exp": now - 120}), status: http.StatusUnauthorized},
+		{auth: "Bearer " + newToken(secret, "HS256", map[string]interface{}{"iat": now, "exp": now + 2*24*3600}), status: http.StatusUnauthorized},
+
+		// Tokens with unknown roles
+		{auth: "Bearer " + newToken(secret, "HS256", map[string]interface{}{"iat": now, "role": "debug"}), status: http.StatusForbidden},
+	}
+	for i, tt := range tests {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL, nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("test %d: request failed: %v", i, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d (%s)", i, resp.StatusCode, tt.status, body)
+			continue
+		}
+		if tt.status == http.StatusOK && string(body) != tt.role {
+			t.Errorf("test %d: role mismatch: have %q, want %q", i, body, tt.role)
+		}
+	}
+}
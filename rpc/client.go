@@ -77,6 +77,9 @@ type Client struct {
 	idgen    func() ID // for subscriptions
 	isHTTP   bool
 	services *serviceRegistry
+	quota    *quota          // limits of the remote side when serving it, nil if unlimited
+	reqlog   *RequestLog     // log of the calls served to the remote side, nil if disabled
+	ctx      context.Context // base context of the calls served to the remote side
 
 	idCounter uint32
 
@@ -110,8 +113,8 @@ type clientConn struct {
 }
 
 func (c *Client) newClientConn(conn ServerCodec) *clientConn {
-	ctx := context.WithValue(context.Background(), clientContextKey{}, c)
-	handler := newHandler(ctx, conn, c.idgen, c.services)
+	ctx := context.WithValue(c.ctx, clientContextKey{}, c)
+	handler := newHandler(ctx, conn, c.idgen, c.services, c.quota, c.reqlog)
 	return &clientConn{conn, handler}
 }
 
@@ -197,17 +200,20 @@ func newClient(initctx context.Context, connect reconnectFunc) (*Client, error)
 	if err != nil {
 		return nil, err
 	}
-	c := initClient(conn, randomIDGenerator(), new(serviceRegistry))
+	c := initClient(context.Background(), conn, randomIDGenerator(), new(serviceRegistry), nil, nil)
 	c.reconnectFunc = connect
 	return c, nil
 }
 
-func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry) *Client {
+func initClient(ctx context.Context, conn ServerCodec, idgen func() ID, services *serviceRegistry, quota *quota, reqlog *RequestLog) *Client {
 	_, isHTTP := conn.(*httpConn)
 	c := &Client{
 		idgen:       idgen,
 		isHTTP:      isHTTP,
 		services:    services,
+		quota:       quota,
+		reqlog:      reqlog,
+		ctx:         ctx,
 		writeConn:   conn,
 		close:       make(chan struct{}),
 		closing:     make(chan struct{}),
@@ -251,6 +257,8 @@ func (c *Client) SupportedModules() (map[string]string, error) {
 // Close closes the client, aborting any in-flight requests.
 func (c *Client) Close() {
 	if c.isHTTP {
+		// Only the subscriptions of HTTP clients need to be ended
+		c.writeConn.(*httpConn).close()
 		return
 	}
 	select {
@@ -418,6 +426,9 @@ func (c *Client) ShhSubscribe(ctx context.Context, channel interface{}, args ...
 // before considering the subscriber dead. The subscription Err channel will receive
 // ErrSubscriptionQueueOverflow. Use a sufficiently large buffer on the channel or ensure
 // that the channel usually has at least one reader to prevent this issue.
+//
+// Over HTTP, subscriptions are served as server-sent event streams, which are resumed
+// transparently if interrupted.
 func (c *Client) Subscribe(ctx context.Context, namespace string, channel interface{}, args ...interface{}) (*ClientSubscription, error) {
 	// Check type of channel first.
 	chanVal := reflect.ValueOf(channel)
@@ -427,14 +438,13 @@ func (c *Client) Subscribe(ctx context.Context, namespace string, channel interf
 	if chanVal.IsNil() {
 		panic("channel given to Subscribe must not be nil")
 	}
-	if c.isHTTP {
-		return nil, ErrNotificationsUnsupported
-	}
-
 	msg, err := c.newMessage(namespace+subscribeMethodSuffix, args...)
 	if err != nil {
 		return nil, err
 	}
+	if c.isHTTP {
+		return c.subscribeHTTP(ctx, namespace, chanVal, msg)
+	}
 	op := &requestOp{
 		ids:  []json.RawMessage{msg.ID},
 		resp: make(chan *jsonrpcMessage),
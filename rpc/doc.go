@@ -99,6 +99,10 @@ Subscriptions are deleted when the user sends an unsubscribe request or when the
 connection which was used to create the subscription is closed. This can be initiated by
 the client and server. The server will close the connection for any write error.
 
+Over HTTP, subscriptions are served as server-sent event streams. A client requests one by
+posting the subscribe call with a text/event-stream Accept header. Interrupted streams can
+be resumed within a short time using the Last-Event-ID header, replaying missed events.
+
 For more information about subscriptions, see https://github.com/matthieu/go-ethereum/wiki/RPC-PUB-SUB.
 
 Reverse Calls
@@ -61,6 +61,8 @@ type handler struct {
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
 	allowSubscribe bool
+	quota          *quota      // resource limits of the remote client, nil if unlimited
+	reqlog         *RequestLog // log of the served calls, nil if disabled
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
@@ -71,7 +73,7 @@ type callProc struct {
 	notifiers []*Notifier
 }
 
-func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry) *handler {
+func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, quota *quota, reqlog *RequestLog) *handler {
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	h := &handler{
 		reg:            reg,
@@ -84,6 +86,8 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 		allowSubscribe: true,
 		serverSubs:     make(map[ID]*Subscription),
 		log:            log.Root(),
+		quota:          quota,
+		reqlog:         reqlog,
 	}
 	if conn.remoteAddr() != "" {
 		h.log = h.log.New("conn", conn.remoteAddr())
@@ -101,6 +105,22 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		})
 		return
 	}
+	// Reject batches exceeding the size limit without executing any call:
+	if err := h.quota.checkBatch(len(msgs)); err != nil {
+		h.startCallProc(func(cp *callProc) {
+			answers := make([]*jsonrpcMessage, 0, len(msgs))
+			for _, msg := range msgs {
+				if msg.isCall() {
+					answers = append(answers, msg.errorResponse(err))
+				}
+			}
+			if len(answers) == 0 {
+				answers = append(answers, errorMessage(err))
+			}
+			h.conn.writeJSON(cp.ctx, answers)
+		})
+		return
+	}
 
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
@@ -115,8 +135,12 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 	// Process calls on a goroutine because they may block indefinitely:
 	h.startCallProc(func(cp *callProc) {
 		answers := make([]*jsonrpcMessage, 0, len(msgs))
-		for _, msg := range calls {
-			if answer := h.handleCallMsg(cp, msg); answer != nil {
+		size := 0
+		for i, msg := range calls {
+			start := time.Now()
+			answer := h.quota.checkResponse(msg, h.handleCallMsg(cp, msg), &size)
+			h.reqlog.record(cp.ctx, h.conn.remoteAddr(), msg, answer, start, len(calls), i)
+			if answer != nil {
 				answers = append(answers, answer)
 			}
 		}
@@ -136,7 +160,9 @@ func (h *handler) handleMsg(msg *jsonrpcMessage) {
 		return
 	}
 	h.startCallProc(func(cp *callProc) {
-		answer := h.handleCallMsg(cp, msg)
+		size, start := 0, time.Now()
+		answer := h.quota.checkResponse(msg, h.handleCallMsg(cp, msg), &size)
+		h.reqlog.record(cp.ctx, h.conn.remoteAddr(), msg, answer, start, 0, 0)
 		h.addSubscriptions(cp.notifiers)
 		if answer != nil {
 			h.conn.writeJSON(cp.ctx, answer)
@@ -317,6 +343,12 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 
 // handleCall processes method calls.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+	// Always allow unsubscribing, so that clients can release server resources
+	if !msg.isUnsubscribe() {
+		if err := h.quota.admit(); err != nil {
+			return msg.errorResponse(err)
+		}
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg)
 	}
@@ -333,8 +365,13 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 	if err != nil {
 		return msg.errorResponse(&invalidParamsError{err.Error()})
 	}
+	release, err := h.quota.acquire(msg.Method)
+	if err != nil {
+		return msg.errorResponse(err)
+	}
 	start := time.Now()
 	answer := h.runMethod(cp.ctx, msg, callb, args)
+	release()
 
 	// Collect the statistics for RPC calls if metrics is enabled.
 	// We only care about pure rpc call. Filter out subscription.
@@ -347,6 +384,7 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 		}
 		rpcServingTimer.UpdateSince(start)
 		newRPCServingTimer(msg.Method, answer.Error == nil).UpdateSince(start)
+		newRPCLatencyHistogram(msg.Method).Update(int64(time.Since(start) / time.Microsecond))
 	}
 	return answer
 }
@@ -0,0 +1,236 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// bucketSweepInterval is the interval at which the rate limiting state of idle
+// clients is dropped.
+const bucketSweepInterval = time.Minute
+
+// Limits configures the resource quotas a server enforces on its clients. Zero
+// values disable the respective limits.
+type Limits struct {
+	BatchItems        int            `toml:",omitempty"` // Maximum number of requests in a batch
+	ResponseBytes     int            `toml:",omitempty"` // Maximum size of the response to a request or batch
+	MethodConcurrency map[string]int `toml:",omitempty"` // Maximum number of concurrent executions per method
+	RequestRate       float64        `toml:",omitempty"` // Number of requests per second allowed per client
+	RequestBurst      int            `toml:",omitempty"` // Number of requests a client may issue at once, defaults to the rate
+	EventStreams      int            `toml:",omitempty"` // Maximum number of concurrent event stream subscriptions per client
+}
+
+type clientIdentityKey struct{}
+
+// WithClientIdentity returns a copy of the context carrying the identity of an
+// authenticated client. Requests served with such a context are rate limited
+// by the identity instead of the remote address.
+func WithClientIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, clientIdentityKey{}, identity)
+}
+
+// Limiter enforces resource limits on the clients of one or more servers, tracking
+// the request rates of all clients. Servers sharing a limiter charge a client's
+// requests against the same quota, regardless of the transport used.
+type Limiter struct {
+	limits  Limits
+	burst   float64                  // Capacity of the client token buckets
+	methods map[string]chan struct{} // Semaphores limiting the concurrency of methods
+
+	buckets map[string]*tokenBucket // Request rate state of the recently active clients
+	swept   time.Time               // Last time idle client buckets were dropped
+	streams map[string]int          // Number of open event streams per client
+	lock    sync.Mutex              // Protects the buckets and stream counts
+}
+
+// tokenBucket is the request rate limiting state of a single client.
+type tokenBucket struct {
+	tokens float64   // Number of requests the client may still issue
+	last   time.Time // Last time the tokens were refilled
+}
+
+// NewLimiter creates a limiter enforcing the given limits.
+func NewLimiter(limits Limits) *Limiter {
+	l := &Limiter{
+		limits:  limits,
+		burst:   float64(limits.RequestBurst),
+		methods: make(map[string]chan struct{}),
+		buckets: make(map[string]*tokenBucket),
+		swept:   time.Now(),
+		streams: make(map[string]int),
+	}
+	if l.burst <= 0 {
+		l.burst = limits.RequestRate
+	}
+	if l.burst < 1 {
+		l.burst = 1
+	}
+	for method, limit := range limits.MethodConcurrency {
+		if limit > 0 {
+			l.methods[method] = make(chan struct{}, limit)
+		}
+	}
+	return l
+}
+
+// quota creates the view of the limits applying to a single client, identified
+// by the identity in the context if authenticated or its remote address otherwise.
+func (l *Limiter) quota(ctx context.Context, remote string) *quota {
+	if l == nil {
+		return nil
+	}
+	return &quota{Limiter: l, client: clientKey(ctx, remote)}
+}
+
+// clientKey returns the key identifying a client, its identity if authenticated
+// or its remote address otherwise.
+func clientKey(ctx context.Context, remote string) string {
+	if identity, ok := ctx.Value(clientIdentityKey{}).(string); ok && identity != "" {
+		return "id:" + identity
+	}
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	return "addr:" + remote
+}
+
+// allow consumes a token of the client's bucket, returning whether the client
+// is allowed to issue a request.
+func (l *Limiter) allow(client string) bool {
+	rate := l.limits.RequestRate
+	if rate <= 0 {
+		return true
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	// Refill the client's bucket, creating a full one for new clients
+	now := time.Now()
+	bucket := l.buckets[client]
+	if bucket == nil {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[client] = bucket
+	}
+	bucket.tokens += now.Sub(bucket.last).Seconds() * rate
+	if bucket.tokens > l.burst {
+		bucket.tokens = l.burst
+	}
+	bucket.last = now
+
+	// Periodically drop the buckets of clients idle long enough to be full
+	if now.Sub(l.swept) > bucketSweepInterval {
+		for id, b := range l.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*rate >= l.burst {
+				delete(l.buckets, id)
+			}
+		}
+		l.swept = now
+	}
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// quota is the view of the server limits applying to a single client. A nil
+// quota imposes no limits.
+type quota struct {
+	*Limiter
+	client string // Key identifying the client for rate limiting
+}
+
+// admit checks whether the client may issue another request.
+func (q *quota) admit() error {
+	if q == nil || q.allow(q.client) {
+		return nil
+	}
+	rpcLimitRateMeter.Mark(1)
+	return &limitExceededError{"request rate limit exceeded"}
+}
+
+// acquire reserves an execution slot for the given method, returning a function
+// to release it once done.
+func (q *quota) acquire(method string) (func(), error) {
+	if q == nil || q.methods[method] == nil {
+		return func() {}, nil
+	}
+	sem := q.methods[method]
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	default:
+		rpcLimitConcurrencyMeter.Mark(1)
+		return nil, &limitExceededError{fmt.Sprintf("too many concurrent %s requests (limit %d)", method, cap(sem))}
+	}
+}
+
+// acquireStream reserves an event stream subscription for the client, returning
+// a function to release it once the subscription ends.
+func (q *quota) acquireStream() (func(), error) {
+	if q == nil || q.limits.EventStreams <= 0 {
+		return func() {}, nil
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.streams[q.client] >= q.limits.EventStreams {
+		rpcLimitStreamMeter.Mark(1)
+		return nil, &limitExceededError{fmt.Sprintf("too many event streams (limit %d)", q.limits.EventStreams)}
+	}
+	q.streams[q.client]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.lock.Lock()
+			defer q.lock.Unlock()
+
+			if q.streams[q.client]--; q.streams[q.client] == 0 {
+				delete(q.streams, q.client)
+			}
+		})
+	}, nil
+}
+
+// checkBatch checks whether the client may issue a batch of the given size.
+func (q *quota) checkBatch(items int) error {
+	if q == nil || q.limits.BatchItems <= 0 || items <= q.limits.BatchItems {
+		return nil
+	}
+	rpcLimitBatchMeter.Mark(1)
+	return &limitExceededError{fmt.Sprintf("batch too large (%d>%d)", items, q.limits.BatchItems)}
+}
+
+// checkResponse adds the size of an answer to the total response size, replacing
+// it with an error if the limit is exceeded.
+func (q *quota) checkResponse(msg, answer *jsonrpcMessage, total *int) *jsonrpcMessage {
+	if q == nil || q.limits.ResponseBytes <= 0 || answer == nil {
+		return answer
+	}
+	*total += len(answer.Result)
+	if *total <= q.limits.ResponseBytes {
+		return answer
+	}
+	rpcLimitResponseMeter.Mark(1)
+	return msg.errorResponse(&limitExceededError{fmt.Sprintf("response too large (limit %d bytes)", q.limits.ResponseBytes)})
+}
@@ -0,0 +1,161 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// checkLimitError checks that the error is a limit exceeded RPC error.
+func checkLimitError(t *testing.T, err error) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatal("expected limit exceeded error")
+	}
+	if e, ok := err.(Error); !ok || e.ErrorCode() != (&limitExceededError{}).ErrorCode() {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
+
+func TestLimitBatchItems(t *testing.T) {
+	server := newTestServer()
+	server.SetLimits(Limits{BatchItems: 2})
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	batch := []BatchElem{
+		{Method: "test_rets", Result: new(string)},
+		{Method: "test_rets", Result: new(string)},
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	for i, elem := range batch {
+		if elem.Error != nil {
+			t.Fatalf("batch element %d failed: %v", i, elem.Error)
+		}
+	}
+	batch = append(batch, BatchElem{Method: "test_rets", Result: new(string)})
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	for _, elem := range batch {
+		checkLimitError(t, elem.Error)
+	}
+}
+
+func TestLimitResponseBytes(t *testing.T) {
+	server := newTestServer()
+	server.SetLimits(Limits{ResponseBytes: 64})
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	// Single responses within and above the limit
+	var resp echoResult
+	if err := client.Call(&resp, "test_echo", "hello", 10, &echoArgs{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	checkLimitError(t, client.Call(&resp, "test_echo", string(make([]byte, 64)), 10, &echoArgs{"world"}))
+
+	// Batch responses exceeding the limit together
+	batch := []BatchElem{
+		{Method: "test_echo", Args: []interface{}{"hello", 10, &echoArgs{"world"}}, Result: new(echoResult)},
+		{Method: "test_echo", Args: []interface{}{"hello", 10, &echoArgs{"world"}}, Result: new(echoResult)},
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	if batch[0].Error != nil {
+		t.Fatalf("first batch element failed: %v", batch[0].Error)
+	}
+	checkLimitError(t, batch[1].Error)
+}
+
+func TestLimitMethodConcurrency(t *testing.T) {
+	server := newTestServer()
+	server.SetLimits(Limits{MethodConcurrency: map[string]int{"test_sleep": 1}})
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	errc := make(chan error)
+	go func() {
+		errc <- client.Call(nil, "test_sleep", 300*time.Millisecond)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	checkLimitError(t, client.Call(nil, "test_sleep", 0))
+	if err := client.Call(nil, "test_rets"); err != nil {
+		t.Fatalf("unlimited method failed: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	if err := client.Call(nil, "test_sleep", 0); err != nil {
+		t.Fatalf("call after release failed: %v", err)
+	}
+}
+
+func TestLimitRequestRate(t *testing.T) {
+	server := newTestServer()
+	server.SetLimits(Limits{RequestRate: 10, RequestBurst: 2})
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := client.Call(nil, "test_rets"); err != nil {
+			t.Fatalf("call %d failed: %v", i, err)
+		}
+	}
+	checkLimitError(t, client.Call(nil, "test_rets"))
+
+	// Wait for a token to be refilled
+	time.Sleep(150 * time.Millisecond)
+	if err := client.Call(nil, "test_rets"); err != nil {
+		t.Fatalf("call after refill failed: %v", err)
+	}
+}
+
+func TestLimitClientKeys(t *testing.T) {
+	l := NewLimiter(Limits{})
+
+	tests := []struct {
+		ctx    context.Context
+		remote string
+		client string
+	}{
+		{ctx: context.Background(), remote: "1.2.3.4:5678", client: "addr:1.2.3.4"},
+		{ctx: context.Background(), remote: "[::1]:5678", client: "addr:::1"},
+		{ctx: context.Background(), remote: "/tmp/geth.ipc", client: "addr:/tmp/geth.ipc"},
+		{ctx: WithClientIdentity(context.Background(), "tooling"), remote: "1.2.3.4:5678", client: "id:tooling"},
+		{ctx: WithClientIdentity(context.Background(), ""), remote: "1.2.3.4:5678", client: "addr:1.2.3.4"},
+	}
+	for i, tt := range tests {
+		if client := l.quota(tt.ctx, tt.remote).client; client != tt.client {
+			t.Errorf("test %d: client key mismatch: have %q, want %q", i, client, tt.client)
+		}
+	}
+	if q := (*Limiter)(nil).quota(context.Background(), "1.2.3.4:5678"); q != nil {
+		t.Errorf("unlimited server returned quota %v", q)
+	}
+}
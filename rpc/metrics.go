@@ -27,6 +27,14 @@ var (
 	successfulRequestGauge = metrics.NewRegisteredGauge("rpc/success", nil)
 	failedReqeustGauge     = metrics.NewRegisteredGauge("rpc/failure", nil)
 	rpcServingTimer        = metrics.NewRegisteredTimer("rpc/duration/all", nil)
+
+	rpcLimitBatchMeter       = metrics.NewRegisteredMeter("rpc/limits/batch", nil)
+	rpcLimitResponseMeter    = metrics.NewRegisteredMeter("rpc/limits/response", nil)
+	rpcLimitConcurrencyMeter = metrics.NewRegisteredMeter("rpc/limits/concurrency", nil)
+	rpcLimitRateMeter        = metrics.NewRegisteredMeter("rpc/limits/rate", nil)
+	rpcLimitStreamMeter      = metrics.NewRegisteredMeter("rpc/limits/streams", nil)
+
+	rpcRequestLogDropMeter = metrics.NewRegisteredMeter("rpc/reqlog/dropped", nil)
 )
 
 func newRPCServingTimer(method string, valid bool) metrics.Timer {
@@ -37,3 +45,10 @@ func newRPCServingTimer(method string, valid bool) metrics.Timer {
 	m := fmt.Sprintf("rpc/duration/%s/%s", method, flag)
 	return metrics.GetOrRegisterTimer(m, nil)
 }
+
+// newRPCLatencyHistogram returns the histogram of the serving latencies of a method
+// in microseconds, regardless of the outcome of the calls.
+func newRPCLatencyHistogram(method string) metrics.Histogram {
+	m := fmt.Sprintf("rpc/latency/%s", method)
+	return metrics.GetOrRegisterHistogram(m, nil, metrics.NewExpDecaySample(1028, 0.015))
+}
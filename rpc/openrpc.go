@@ -0,0 +1,359 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/common/hexutil"
+)
+
+// openRPCVersion is the version of the OpenRPC specification the service
+// discovery documents conform to.
+const openRPCVersion = "1.2.6"
+
+// OpenRPCDocument is an OpenRPC service description, listing the methods of a
+// server along with the schemas of their parameters and results.
+//
+// As OpenRPC has no notion of subscriptions, the *_subscribe methods accept the
+// name of any available subscription, whereas the parameters of the individual
+// subscriptions are described by the x-subscriptions extension.
+type OpenRPCDocument struct {
+	OpenRPC       string                 `json:"openrpc"`
+	Info          OpenRPCInfo            `json:"info"`
+	Methods       []*OpenRPCMethod       `json:"methods"`
+	Components    OpenRPCComponents      `json:"components"`
+	Subscriptions []*OpenRPCSubscription `json:"x-subscriptions,omitempty"`
+}
+
+// OpenRPCInfo is the metadata of an OpenRPC document.
+type OpenRPCInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenRPCComponents holds the reusable schemas referenced within a document.
+type OpenRPCComponents struct {
+	Schemas map[string]*JSONSchema `json:"schemas"`
+}
+
+// OpenRPCMethod describes a single RPC method.
+type OpenRPCMethod struct {
+	Name   string                      `json:"name"`
+	Params []*OpenRPCContentDescriptor `json:"params"`
+	Result *OpenRPCContentDescriptor   `json:"result"`
+}
+
+// OpenRPCSubscription describes a subscription available through the subscribe
+// method of its namespace.
+type OpenRPCSubscription struct {
+	Namespace string                      `json:"namespace"`
+	Name      string                      `json:"name"`
+	Params    []*OpenRPCContentDescriptor `json:"params"`
+}
+
+// OpenRPCContentDescriptor describes a parameter or the result of a method.
+type OpenRPCContentDescriptor struct {
+	Name     string      `json:"name"`
+	Required bool        `json:"required,omitempty"`
+	Schema   *JSONSchema `json:"schema"`
+}
+
+// JSONSchema is the subset of JSON schema used to describe RPC values. The empty
+// schema accepts any value.
+type JSONSchema struct {
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+	OneOf                []*JSONSchema          `json:"oneOf,omitempty"`
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	hexQuantitySchema = &JSONSchema{Title: "hex encoded unsigned integer", Type: "string", Pattern: "^0x(0|[1-9a-f][0-9a-f]*)$"}
+	hexBytesSchema    = &JSONSchema{Title: "hex encoded bytes", Type: "string", Pattern: "^0x([0-9a-fA-F]{2})*$"}
+	addressSchema     = &JSONSchema{Title: "address", Type: "string", Pattern: "^0x[0-9a-fA-F]{40}$"}
+	hashSchema        = &JSONSchema{Title: "hash", Type: "string", Pattern: "^0x[0-9a-fA-F]{64}$"}
+	blockTagSchema    = &JSONSchema{Title: "block tag", Type: "string", Enum: []string{"earliest", "latest", "pending"}}
+	blockNumberSchema = &JSONSchema{Title: "block number", OneOf: []*JSONSchema{hexQuantitySchema, blockTagSchema}}
+
+	// knownSchemas are the schemas of the types whose JSON encoding cannot be
+	// derived from their Go definition.
+	knownSchemas = map[reflect.Type]*JSONSchema{
+		reflect.TypeOf(hexutil.Big{}):     hexQuantitySchema,
+		reflect.TypeOf(hexutil.Uint(0)):   hexQuantitySchema,
+		reflect.TypeOf(hexutil.Uint64(0)): hexQuantitySchema,
+		reflect.TypeOf(hexutil.Bytes{}):   hexBytesSchema,
+		reflect.TypeOf(common.Address{}):  addressSchema,
+		reflect.TypeOf(common.Hash{}):     hashSchema,
+		reflect.TypeOf(big.Int{}):         {Title: "integer", Type: "integer"},
+		reflect.TypeOf(BlockNumber(0)):    blockNumberSchema,
+		reflect.TypeOf(BlockNumberOrHash{}): {Title: "block number or hash", OneOf: []*JSONSchema{
+			hexQuantitySchema,
+			blockTagSchema,
+			hashSchema,
+			{Type: "object", Properties: map[string]*JSONSchema{
+				"blockNumber":      blockNumberSchema,
+				"blockHash":        hashSchema,
+				"requireCanonical": {Type: "boolean"},
+			}},
+		}},
+		reflect.TypeOf(ID("")):               {Title: "subscription id", Type: "string"},
+		reflect.TypeOf(json.RawMessage{}):    {},
+		reflect.TypeOf((*error)(nil)).Elem(): {},
+	}
+)
+
+// Discover returns an OpenRPC document describing all the methods and
+// subscriptions of the server.
+func (s *RPCService) Discover() *OpenRPCDocument {
+	s.server.services.mu.Lock()
+	defer s.server.services.mu.Unlock()
+
+	doc := &OpenRPCDocument{
+		OpenRPC: openRPCVersion,
+		Info:    OpenRPCInfo{Title: "JSON-RPC API", Version: "1.0"},
+		Methods: []*OpenRPCMethod{},
+	}
+	gen := newSchemaGenerator()
+
+	namespaces := make([]string, 0, len(s.server.services.services))
+	for name := range s.server.services.services {
+		namespaces = append(namespaces, name)
+	}
+	sort.Strings(namespaces)
+
+	for _, namespace := range namespaces {
+		svc := s.server.services.services[namespace]
+		for _, name := range sortedCallbacks(svc.callbacks) {
+			cb := svc.callbacks[name]
+			doc.Methods = append(doc.Methods, &OpenRPCMethod{
+				Name:   namespace + serviceMethodSeparator + name,
+				Params: gen.params(cb),
+				Result: &OpenRPCContentDescriptor{Name: "result", Schema: gen.result(cb)},
+			})
+		}
+		if len(svc.subscriptions) == 0 {
+			continue
+		}
+		// Describe the subscriptions and the generic methods to manage them
+		names := sortedCallbacks(svc.subscriptions)
+		for _, name := range names {
+			doc.Subscriptions = append(doc.Subscriptions, &OpenRPCSubscription{
+				Namespace: namespace,
+				Name:      name,
+				Params:    gen.params(svc.subscriptions[name]),
+			})
+		}
+		id := gen.schema(reflect.TypeOf(ID("")))
+		doc.Methods = append(doc.Methods, &OpenRPCMethod{
+			Name: namespace + subscribeMethodSuffix,
+			Params: []*OpenRPCContentDescriptor{
+				{Name: "subscription", Required: true, Schema: &JSONSchema{Type: "string", Enum: names}},
+			},
+			Result: &OpenRPCContentDescriptor{Name: "id", Schema: id},
+		}, &OpenRPCMethod{
+			Name:   namespace + unsubscribeMethodSuffix,
+			Params: []*OpenRPCContentDescriptor{{Name: "id", Required: true, Schema: id}},
+			Result: &OpenRPCContentDescriptor{Name: "result", Schema: &JSONSchema{Type: "boolean"}},
+		})
+	}
+	doc.Components.Schemas = gen.schemas
+	return doc
+}
+
+// sortedCallbacks returns the names of the given callbacks in alphabetical order.
+func sortedCallbacks(callbacks map[string]*callback) []string {
+	names := make([]string, 0, len(callbacks))
+	for name := range callbacks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// schemaGenerator derives JSON schemas from Go types, collecting the schemas of
+// named struct types as reusable components.
+type schemaGenerator struct {
+	schemas map[string]*JSONSchema  // Component schemas by name
+	names   map[reflect.Type]string // Component names of the already described types
+}
+
+// newSchemaGenerator creates a schema generator with no components.
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		schemas: make(map[string]*JSONSchema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// params describes the parameters of a callback. Go doesn't retain parameter
+// names, so they are derived from the parameter types where possible.
+func (g *schemaGenerator) params(cb *callback) []*OpenRPCContentDescriptor {
+	var (
+		params = make([]*OpenRPCContentDescriptor, len(cb.argTypes))
+		seen   = make(map[string]bool)
+	)
+	for i, typ := range cb.argTypes {
+		elem := typ
+		for elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		name := fmt.Sprintf("arg%d", i)
+		if elem.Name() != "" && elem.PkgPath() != "" {
+			name = formatName(elem.Name())
+		}
+		if seen[name] {
+			name = fmt.Sprintf("%s%d", name, i)
+		}
+		seen[name] = true
+
+		params[i] = &OpenRPCContentDescriptor{
+			Name:     name,
+			Required: typ.Kind() != reflect.Ptr, // Trailing pointers may be omitted
+			Schema:   g.schema(typ),
+		}
+	}
+	return params
+}
+
+// result describes the result of a callback.
+func (g *schemaGenerator) result(cb *callback) *JSONSchema {
+	fntype := cb.fn.Type()
+	if fntype.NumOut() == 0 || cb.errPos == 0 {
+		return &JSONSchema{Type: "null"}
+	}
+	return g.schema(fntype.Out(0))
+}
+
+// schema returns the JSON schema of the given type.
+func (g *schemaGenerator) schema(typ reflect.Type) *JSONSchema {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if schema, ok := knownSchemas[typ]; ok {
+		return schema
+	}
+	// Types with custom encodings can't be described reliably
+	ptr := reflect.PtrTo(typ)
+	if typ.Implements(jsonMarshalerType) || ptr.Implements(jsonMarshalerType) {
+		return &JSONSchema{Title: typ.String()}
+	}
+	if typ.Implements(textMarshalerType) || ptr.Implements(textMarshalerType) {
+		return &JSONSchema{Title: typ.String(), Type: "string"}
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return &JSONSchema{Title: "base64 encoded bytes", Type: "string"}
+		}
+		return &JSONSchema{Type: "array", Items: g.schema(typ.Elem())}
+	case reflect.Array:
+		return &JSONSchema{Type: "array", Items: g.schema(typ.Elem())}
+	case reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: g.schema(typ.Elem())}
+	case reflect.Struct:
+		if typ.Name() == "" {
+			return g.structSchema(typ)
+		}
+		// Describe named structs as components, referencing them by name
+		name, ok := g.names[typ]
+		if !ok {
+			name = path.Base(typ.PkgPath()) + "." + typ.Name()
+			for i := 2; g.schemas[name] != nil; i++ {
+				name = fmt.Sprintf("%s.%s%d", path.Base(typ.PkgPath()), typ.Name(), i)
+			}
+			g.names[typ] = name
+			g.schemas[name] = new(JSONSchema) // Placeholder for recursive types
+			*g.schemas[name] = *g.structSchema(typ)
+		}
+		return &JSONSchema{Ref: "#/components/schemas/" + name}
+	default:
+		// Interfaces may hold anything, channels and functions are not encodable
+		return &JSONSchema{}
+	}
+}
+
+// structSchema describes the JSON object encoding of a struct type.
+func (g *schemaGenerator) structSchema(typ reflect.Type) *JSONSchema {
+	schema := &JSONSchema{Type: "object", Properties: make(map[string]*JSONSchema)}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if idx := strings.Index(tag, ","); idx >= 0 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+		// Embedded structs without a name get their fields promoted
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				inner := g.structSchema(embedded)
+				for prop, s := range inner.Properties {
+					if _, ok := schema.Properties[prop]; !ok {
+						schema.Properties[prop] = s
+					}
+				}
+				schema.Required = append(schema.Required, inner.Required...)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue // field not exported
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = g.schema(field.Type)
+		if field.Type.Kind() != reflect.Ptr && !strings.Contains(opts, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/matthieu/go-ethereum/common"
+	"github.com/matthieu/go-ethereum/common/hexutil"
+)
+
+type discoverArgs struct {
+	Address common.Address  `json:"address"`
+	Value   *hexutil.Big    `json:"value"`
+	Block   BlockNumber     `json:"block,omitempty"`
+	Data    hexutil.Bytes   `json:"-"`
+	Next    *discoverArgs   `json:"next"`
+	Tags    map[string]bool `json:"tags"`
+}
+
+type discoverService struct{}
+
+func (s *discoverService) Query(addr common.Address, block BlockNumberOrHash, args *discoverArgs) (*hexutil.Big, error) {
+	return nil, nil
+}
+
+func TestDiscover(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	if err := server.RegisterName("discover", new(discoverService)); err != nil {
+		t.Fatal(err)
+	}
+	client := DialInProc(server)
+	defer client.Close()
+
+	var doc OpenRPCDocument
+	if err := client.Call(&doc, "rpc_discover"); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenRPC != openRPCVersion {
+		t.Errorf("wrong openrpc version: %q", doc.OpenRPC)
+	}
+	methods := make(map[string]*OpenRPCMethod)
+	for _, method := range doc.Methods {
+		methods[method.Name] = method
+	}
+	for _, name := range []string{"rpc_modules", "rpc_discover", "test_echo", "nftest_subscribe", "nftest_unsubscribe"} {
+		if methods[name] == nil {
+			t.Errorf("method %s missing", name)
+		}
+	}
+	// Check the parameters and result derived from the method signature
+	query := methods["discover_query"]
+	if query == nil {
+		t.Fatal("method discover_query missing")
+	}
+	wantParams := []*OpenRPCContentDescriptor{
+		{Name: "address", Required: true, Schema: addressSchema},
+		{Name: "blockNumberOrHash", Required: true, Schema: knownSchemas[reflect.TypeOf(BlockNumberOrHash{})]},
+		{Name: "discoverArgs", Schema: &JSONSchema{Ref: "#/components/schemas/rpc.discoverArgs"}},
+	}
+	if !reflect.DeepEqual(query.Params, wantParams) {
+		t.Errorf("discover_query params mismatch:\nhave %+v\nwant %+v", query.Params, wantParams)
+	}
+	if !reflect.DeepEqual(query.Result.Schema, hexQuantitySchema) {
+		t.Errorf("discover_query result mismatch: have %+v", query.Result.Schema)
+	}
+	// Check the component schema of the struct argument
+	wantArgs := &JSONSchema{
+		Type: "object",
+		Properties: map[string]*JSONSchema{
+			"address": addressSchema,
+			"value":   hexQuantitySchema,
+			"block":   blockNumberSchema,
+			"next":    {Ref: "#/components/schemas/rpc.discoverArgs"},
+			"tags":    {Type: "object", AdditionalProperties: &JSONSchema{Type: "boolean"}},
+		},
+		Required: []string{"address", "tags"},
+	}
+	if have := doc.Components.Schemas["rpc.discoverArgs"]; !reflect.DeepEqual(have, wantArgs) {
+		t.Errorf("discoverArgs schema mismatch:\nhave %+v\nwant %+v", have, wantArgs)
+	}
+	// Check the subscriptions
+	subscribe := methods["nftest_subscribe"]
+	if subscribe == nil {
+		t.Fatal("method nftest_subscribe missing")
+	}
+	if enum := subscribe.Params[0].Schema.Enum; !reflect.DeepEqual(enum, []string{"hangSubscription", "someSubscription"}) {
+		t.Errorf("wrong subscription names: %v", enum)
+	}
+	var found bool
+	for _, sub := range doc.Subscriptions {
+		if sub.Namespace == "nftest" && sub.Name == "someSubscription" {
+			found = true
+			if len(sub.Params) != 2 || sub.Params[0].Schema.Type != "integer" || !sub.Params[0].Required {
+				t.Errorf("wrong someSubscription params: %+v", sub.Params)
+			}
+		}
+	}
+	if !found {
+		t.Error("subscription nftest_someSubscription missing")
+	}
+}
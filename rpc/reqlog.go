@@ -0,0 +1,262 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/matthieu/go-ethereum/log"
+)
+
+// requestLogQueue is the number of entries buffered for writing, beyond which
+// entries are dropped instead of delaying the served calls.
+const requestLogQueue = 1024
+
+// RequestLogConfig configures the structured log of the calls served by RPC
+// servers. Calls are logged if they are sampled or slower than the threshold.
+type RequestLogConfig struct {
+	Path          string        // File to write the JSON encoded entries to
+	MaxSize       int64         // Size in bytes at which the log file is rotated, 0 disables rotation
+	MaxFiles      int           // Number of rotated files to retain besides the active one
+	SampleRate    float64       // Fraction of the calls to log regardless of their duration (0-1)
+	SlowThreshold time.Duration // Duration beyond which calls are always logged, 0 disables
+}
+
+// DefaultRequestLogConfig represents the default request log settings used if
+// further configuration is not provided. The log itself is disabled by default.
+var DefaultRequestLogConfig = RequestLogConfig{
+	MaxSize:    100 * 1024 * 1024,
+	MaxFiles:   5,
+	SampleRate: 1,
+}
+
+// RequestLog writes an entry for the served RPC calls to a rotating log file, one
+// JSON object per line. A single request log may be shared by multiple servers.
+type RequestLog struct {
+	config RequestLogConfig
+	file   *rotatingFile
+
+	entries chan *requestLogEntry
+	quit    chan struct{}
+	done    chan struct{}
+	closed  sync.Once
+}
+
+// requestLogEntry is the record of a single served call.
+type requestLogEntry struct {
+	Time         time.Time        `json:"time"`
+	Method       string           `json:"method"`
+	ID           json.RawMessage  `json:"id,omitempty"`
+	Remote       string           `json:"remote,omitempty"`
+	Client       string           `json:"client,omitempty"`
+	ParamsSize   int              `json:"paramsSize"`
+	ResponseSize int              `json:"responseSize"`
+	Duration     float64          `json:"duration"` // seconds
+	ErrorCode    int              `json:"errorCode,omitempty"`
+	Batch        *requestLogBatch `json:"batch,omitempty"`
+	Slow         bool             `json:"slow,omitempty"`
+}
+
+// requestLogBatch is the position of a logged call within its batch.
+type requestLogBatch struct {
+	Size  int `json:"size"`
+	Index int `json:"index"`
+}
+
+// NewRequestLog opens the configured log file and starts writing entries to it.
+func NewRequestLog(config RequestLogConfig) (*RequestLog, error) {
+	if config.Path == "" {
+		return nil, errors.New("no request log path configured")
+	}
+	file, err := openRotatingFile(config.Path, config.MaxSize, config.MaxFiles)
+	if err != nil {
+		return nil, err
+	}
+	l := &RequestLog{
+		config:  config,
+		file:    file,
+		entries: make(chan *requestLogEntry, requestLogQueue),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go l.loop()
+	return l, nil
+}
+
+// Close stops the request log, writing out all pending entries.
+func (l *RequestLog) Close() error {
+	l.closed.Do(func() { close(l.quit) })
+	<-l.done
+	return l.file.Close()
+}
+
+// loop writes the queued entries to the log file until the log is closed.
+func (l *RequestLog) loop() {
+	defer close(l.done)
+
+	// Every entry is encoded into a single write, so rotation never splits lines
+	enc := json.NewEncoder(l.file)
+	write := func(entry *requestLogEntry) {
+		if err := enc.Encode(entry); err != nil {
+			log.Warn("Failed to write RPC request log", "err", err)
+		}
+	}
+	for {
+		select {
+		case entry := <-l.entries:
+			write(entry)
+		case <-l.quit:
+			for {
+				select {
+				case entry := <-l.entries:
+					write(entry)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// record logs a served call if it is sampled or slow. Batch is the size of the
+// batch the call was part of, zero if it was sent on its own.
+func (l *RequestLog) record(ctx context.Context, remote string, msg, answer *jsonrpcMessage, start time.Time, batch, index int) {
+	if l == nil {
+		return
+	}
+	elapsed := time.Since(start)
+	slow := l.config.SlowThreshold > 0 && elapsed >= l.config.SlowThreshold
+	if !slow && (l.config.SampleRate <= 0 || (l.config.SampleRate < 1 && rand.Float64() >= l.config.SampleRate)) {
+		return
+	}
+	entry := &requestLogEntry{
+		Time:       start,
+		Method:     msg.Method,
+		ID:         msg.ID,
+		Remote:     remote,
+		ParamsSize: len(msg.Params),
+		Duration:   elapsed.Seconds(),
+		Slow:       slow,
+	}
+	if identity, ok := ctx.Value(clientIdentityKey{}).(string); ok {
+		entry.Client = identity
+	}
+	if answer != nil {
+		entry.ResponseSize = len(answer.Result)
+		if answer.Error != nil {
+			entry.ErrorCode = answer.Error.Code
+		}
+	}
+	if batch > 0 {
+		entry.Batch = &requestLogBatch{Size: batch, Index: index}
+	}
+	select {
+	case l.entries <- entry:
+	case <-l.quit:
+	default:
+		rpcRequestLogDropMeter.Mark(1)
+	}
+}
+
+// rotatingFile is a log file which is moved aside once it grows beyond a size
+// limit, retaining a number of the previous files with a numeric suffix.
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	file *os.File
+	size int64
+}
+
+// openRotatingFile opens a rotating log file, appending to the existing one.
+func openRotatingFile(path string, maxSize int64, maxFiles int) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens the active log file.
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// Write implements io.Writer, rotating the file first if the data would exceed
+// the size limit.
+func (f *rotatingFile) Write(data []byte) (int, error) {
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(data)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			log.Warn("Failed to rotate RPC request log", "path", f.path, "err", err)
+		}
+	}
+	n, err := f.file.Write(data)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate shifts the retained files by one, dropping the oldest, and starts a new
+// active log file. The active file is reopened even if closing or shifting it
+// fails, so that logging can continue.
+func (f *rotatingFile) rotate() error {
+	err := f.file.Close()
+	for i := f.maxFiles - 1; i > 0 && err == nil; i-- {
+		err = os.Rename(f.rotated(i), f.rotated(i+1))
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	}
+	if err == nil {
+		if f.maxFiles > 0 {
+			err = os.Rename(f.path, f.rotated(1))
+		} else {
+			err = os.Remove(f.path)
+		}
+	}
+	if openErr := f.open(); openErr != nil {
+		return openErr
+	}
+	return err
+}
+
+// rotated returns the path of the n-th most recent rotated file.
+func (f *rotatingFile) rotated(n int) string {
+	return fmt.Sprintf("%s.%d", f.path, n)
+}
+
+// Close closes the active log file.
+func (f *rotatingFile) Close() error {
+	return f.file.Close()
+}
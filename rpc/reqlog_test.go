@@ -0,0 +1,248 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readRequestLog parses the entries of a request log file.
+func readRequestLog(t *testing.T, path string) []*requestLogEntry {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var entries []*requestLogEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry := new(requestLogEntry)
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// serveWithRequestLog runs the given calls against a test server with a request
+// log, returning the logged entries.
+func serveWithRequestLog(t *testing.T, config RequestLogConfig, calls func(*Client)) []*requestLogEntry {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "rpc-reqlog-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config.Path = filepath.Join(dir, "requests.log")
+	reqlog, err := NewRequestLog(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer()
+	server.SetRequestLog(reqlog)
+	client := DialInProc(server)
+
+	calls(client)
+
+	client.Close()
+	server.Stop()
+	if err := reqlog.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return readRequestLog(t, config.Path)
+}
+
+func TestRequestLog(t *testing.T) {
+	entries := serveWithRequestLog(t, RequestLogConfig{SampleRate: 1}, func(client *Client) {
+		var resp echoResult
+		if err := client.Call(&resp, "test_echo", "hello", 10, &echoArgs{"world"}); err != nil {
+			t.Fatal(err)
+		}
+		client.Call(nil, "test_returnError")
+		client.BatchCall([]BatchElem{
+			{Method: "test_rets", Result: new(string)},
+			{Method: "no_such_method", Result: new(string)},
+		})
+	})
+	if len(entries) != 4 {
+		t.Fatalf("wrong number of log entries: have %d, want 4", len(entries))
+	}
+	echo := entries[0]
+	if echo.Method != "test_echo" || echo.ParamsSize != len(`["hello",10,{"S":"world"}]`) || echo.ResponseSize == 0 || echo.ErrorCode != 0 || echo.Batch != nil {
+		t.Errorf("wrong test_echo entry: %+v", echo)
+	}
+	if entries[1].Method != "test_returnError" || entries[1].ErrorCode != (testError{}).ErrorCode() {
+		t.Errorf("wrong test_returnError entry: %+v", entries[1])
+	}
+	for i, entry := range entries[2:] {
+		if entry.Batch == nil || entry.Batch.Size != 2 || entry.Batch.Index != i {
+			t.Errorf("wrong batch membership of entry %d: %+v", i, entry.Batch)
+		}
+	}
+	if entries[3].ErrorCode != (&methodNotFoundError{}).ErrorCode() {
+		t.Errorf("wrong error code of unknown method: %d", entries[3].ErrorCode)
+	}
+}
+
+func TestRequestLogSlowCalls(t *testing.T) {
+	entries := serveWithRequestLog(t, RequestLogConfig{SlowThreshold: 50 * time.Millisecond}, func(client *Client) {
+		client.Call(nil, "test_sleep", 0)
+		client.Call(nil, "test_sleep", 100*time.Millisecond)
+		client.Call(nil, "test_rets")
+	})
+	if len(entries) != 1 {
+		t.Fatalf("wrong number of log entries: have %d, want 1", len(entries))
+	}
+	if !entries[0].Slow || entries[0].Duration < 0.1 {
+		t.Errorf("wrong slow call entry: %+v", entries[0])
+	}
+}
+
+// Tests that calls served over WebSocket are logged with the client identity set
+// on the upgrade request.
+func TestRequestLogWebsocketIdentity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpc-reqlog-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "requests.log")
+	reqlog, err := NewRequestLog(RequestLogConfig{Path: path, SampleRate: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer()
+	server.SetRequestLog(reqlog)
+	defer server.Stop()
+
+	handler := server.WebsocketHandler([]string{"*"})
+	httpsrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(WithClientIdentity(r.Context(), "alice")))
+	}))
+	defer httpsrv.Close()
+
+	client, err := DialWebsocket(context.Background(), "ws:"+strings.TrimPrefix(httpsrv.URL, "http:"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(nil, "test_rets"); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+	if err := reqlog.Close(); err != nil {
+		t.Fatal(err)
+	}
+	entries := readRequestLog(t, path)
+	if len(entries) != 1 {
+		t.Fatalf("wrong number of log entries: have %d, want 1", len(entries))
+	}
+	if entries[0].Client != "alice" {
+		t.Errorf("client identity mismatch: have %q, want %q", entries[0].Client, "alice")
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpc-rotate-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "log")
+	file, err := openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n", "eeeeeeeeeeee\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file.Close()
+
+	want := map[string]string{
+		path:        "eeeeeeeeeeee\n",
+		path + ".1": "cccc\ndddd\n",
+		path + ".2": "aaaa\nbbbb\n",
+	}
+	for name, content := range want {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("%s: content mismatch: have %q, want %q", filepath.Base(name), data, content)
+		}
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != len(want) {
+		var names []string
+		for _, f := range files {
+			names = append(names, f.Name())
+		}
+		t.Errorf("wrong files retained: %s", strings.Join(names, ", "))
+	}
+}
+
+// Tests that the log file is reopened if closing it fails during rotation.
+func TestRotatingFileCloseFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpc-rotate-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "log")
+	file, err := openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Write([]byte("aaaa\n")); err != nil {
+		t.Fatal(err)
+	}
+	file.file.Close() // make the close during rotation fail
+
+	if _, err := file.Write([]byte("bbbbbbbb\n")); err != nil {
+		t.Fatalf("failed to write after rotation failure: %v", err)
+	}
+	file.Close()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "aaaa\nbbbbbbbb\n"; string(data) != want {
+		t.Errorf("content mismatch: have %q, want %q", data, want)
+	}
+}
@@ -46,6 +46,9 @@ type Server struct {
 	idgen    func() ID
 	run      int32
 	codecs   mapset.Set
+	limiter  *Limiter    // resource limits enforced on clients, nil if unlimited
+	reqlog   *RequestLog // log of the served calls, nil if disabled
+	streams  sseRegistry // subscriptions served over HTTP event streams
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -66,12 +69,40 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 	return s.services.registerName(name, receiver)
 }
 
+// SetLimits configures the resource limits the server enforces on its clients. It
+// must be called before the server starts serving requests.
+func (s *Server) SetLimits(limits Limits) {
+	s.SetLimiter(NewLimiter(limits))
+}
+
+// SetLimiter configures the limiter enforcing resource limits on the clients of
+// the server. The limiter may be shared between servers to make them charge the
+// same quotas. It must be called before the server starts serving requests.
+func (s *Server) SetLimiter(limiter *Limiter) {
+	s.limiter = limiter
+}
+
+// SetRequestLog configures the log recording the calls served by the server. It
+// must be called before the server starts serving requests. The log is not closed
+// when the server is stopped, as it may be shared.
+func (s *Server) SetRequestLog(reqlog *RequestLog) {
+	s.reqlog = reqlog
+}
+
 // ServeCodec reads incoming requests from codec, calls the appropriate callback and writes
 // the response back using the given codec. It will block until the codec is closed or the
 // server is stopped. In either case the codec is closed.
 //
 // Note that codec options are no longer supported.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	ctx := context.Background()
+	s.serveCodec(ctx, codec, s.limiter.quota(ctx, codec.remoteAddr()))
+}
+
+// serveCodec serves the requests of a codec, enforcing the given client limits.
+// The calls are served with contexts derived from ctx, which must remain valid
+// until the codec is closed.
+func (s *Server) serveCodec(ctx context.Context, codec ServerCodec, quota *quota) {
 	defer codec.close()
 
 	// Don't serve if server is stopped.
@@ -83,7 +114,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(ctx, codec, s.idgen, &s.services, quota, s.reqlog)
 	<-codec.closed()
 	c.Close()
 }
@@ -97,7 +128,7 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 		return
 	}
 
-	h := newHandler(ctx, codec, s.idgen, &s.services)
+	h := newHandler(ctx, codec, s.idgen, &s.services, s.limiter.quota(ctx, codec.remoteAddr()), s.reqlog)
 	h.allowSubscribe = false
 	defer h.close(io.EOF, nil)
 
@@ -125,6 +156,7 @@ func (s *Server) Stop() {
 			c.(ServerCodec).close()
 			return true
 		})
+		s.streams.closeAll()
 	}
 }
 
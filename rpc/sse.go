@@ -0,0 +1,802 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Subscriptions are served over HTTP as server-sent event streams. A subscription
+// is requested by posting a *_subscribe call that accepts text/event-stream. The
+// response to the call is sent as the first event of the stream, followed by the
+// notifications of the subscription, each event carrying the JSON-RPC message as
+// its data.
+//
+// Event IDs consist of a stream token and a sequence number. An interrupted stream
+// is resumed by sending a request with the Last-Event-ID header, which replays the
+// events the client missed. A DELETE request with the header ends the subscription.
+//
+// If the HTTP server sets WithHTTPConn as its ConnContext, streams replace its
+// write timeout with a deadline for each write. Otherwise streams are cut off by
+// the write timeout and have to be resumed by the client.
+const (
+	eventStreamContentType = "text/event-stream"
+
+	// sseBufferSize is the number of events retained per subscription. Events are
+	// replayed from this buffer when a stream is resumed, and subscriptions whose
+	// client falls further behind are dropped.
+	sseBufferSize = 1024
+
+	// sseResumeTimeout is the time a subscription is kept alive after its stream
+	// was interrupted, waiting for the client to resume it.
+	sseResumeTimeout = 30 * time.Second
+
+	// sseKeepAliveInterval is the interval at which comments are sent on idle
+	// streams, preventing proxies from closing them.
+	sseKeepAliveInterval = 15 * time.Second
+
+	// sseMaxRetries is the number of consecutive attempts a client makes to resume
+	// an interrupted stream before failing the subscription.
+	sseMaxRetries = 5
+
+	// sseRetryInterval is the delay between the attempts to resume a stream.
+	sseRetryInterval = time.Second
+
+	// sseWriteTimeout is the time allowed for writing events to a stream before
+	// it is considered interrupted.
+	sseWriteTimeout = 10 * time.Second
+
+	// sseMaxSessions is the maximum number of subscriptions a server serves over
+	// event streams, and sseMaxClientSessions the maximum number per client.
+	sseMaxSessions       = 1024
+	sseMaxClientSessions = 32
+)
+
+var (
+	errEventStreamOverflow = errors.New("event stream overflow")
+	errEventStreamClosed   = errors.New("event stream closed")
+	errEventStreamLost     = errors.New("event stream events no longer available")
+)
+
+type httpConnKey struct{}
+
+// WithHTTPConn returns a copy of the context carrying the network connection of
+// an HTTP server. It is meant to be used as the ConnContext of HTTP servers, and
+// lets event streams lift the write timeout of the server.
+func WithHTTPConn(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, httpConnKey{}, conn)
+}
+
+// isEventStream checks whether the HTTP request accepts an event stream response.
+func isEventStream(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mt, _, err := mime.ParseMediaType(accept); err == nil && mt == eventStreamContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// serveEventStream serves a subscription over an HTTP event stream, either
+// creating a new subscription or resuming an interrupted one. Requests which are
+// not subscription calls are left to be served as plain calls, in which case it
+// returns false.
+func (s *Server) serveEventStream(w http.ResponseWriter, r *http.Request) bool {
+	// Don't serve if server is stopped.
+	if atomic.LoadInt32(&s.run) == 0 {
+		return true
+	}
+	if _, ok := w.(http.Flusher); !ok {
+		http.Error(w, "event streams not supported", http.StatusInternalServerError)
+		return true
+	}
+	conn, _ := r.Context().Value(httpConnKey{}).(net.Conn)
+
+	// Resume or end existing subscriptions identified by the last event
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		token, cursor, err := parseEventID(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return true
+		}
+		session := s.streams.get(token)
+		if session == nil {
+			http.Error(w, ErrSubscriptionNotFound.Error(), http.StatusNotFound)
+			return true
+		}
+		if r.Method == http.MethodDelete {
+			session.close()
+			w.WriteHeader(http.StatusNoContent)
+			return true
+		}
+		replaced, err := session.attach(cursor)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusGone)
+			return true
+		}
+		session.serve(w, conn, replaced, cursor, r.Context().Done())
+		return true
+	}
+	// Otherwise only subscription calls are streamed, the body is restored for
+	// serving other requests as plain calls
+	if code, err := validateRequest(r); err != nil {
+		http.Error(w, err.Error(), code)
+		return true
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxRequestContentLength))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return true
+	}
+	var msg jsonrpcMessage
+	if err := json.Unmarshal(body, &msg); err != nil || !msg.isSubscribe() || !msg.hasValidID() {
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		return false
+	}
+	token, err := newEventStreamToken()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return true
+	}
+	// The subscription outlives the request, but keeps the values of its context
+	ctx := detachedContext{r.Context()}
+	quota := s.limiter.quota(ctx, r.RemoteAddr)
+	release, err := quota.acquireStream()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return true
+	}
+	session := newSSESession(token, r.RemoteAddr, &s.streams)
+	session.client, session.release = clientKey(ctx, r.RemoteAddr), release
+	session.h = newHandler(ctx, session, s.idgen, &s.services, quota, s.reqlog)
+	if err := s.streams.add(session); err != nil {
+		session.h.close(err, nil)
+		release()
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return true
+	}
+	replaced, _ := session.attach(0)
+	session.h.handleMsg(&msg)
+	session.serve(w, conn, replaced, 0, r.Context().Done())
+	return true
+}
+
+// newEventStreamToken generates the random token identifying an event stream.
+// Since the token authorizes resuming and ending the subscription, it is not
+// derived from the subscription ID.
+func newEventStreamToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := crand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// parseEventID splits an event ID into the stream token and sequence number.
+func parseEventID(id string) (string, uint64, error) {
+	idx := strings.LastIndexByte(id, ':')
+	if idx < 0 {
+		return "", 0, fmt.Errorf("invalid event id %q", id)
+	}
+	seq, err := strconv.ParseUint(id[idx+1:], 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid event id %q", id)
+	}
+	return id[:idx], seq, nil
+}
+
+// detachedContext carries the values of a context without its cancellation.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// sseRegistry tracks the subscriptions served over event streams.
+type sseRegistry struct {
+	mu       sync.Mutex
+	sessions map[string]*sseSession
+	clients  map[string]int // Number of sessions per client
+}
+
+// add registers a session, failing if the server or the client of the session
+// already has too many.
+func (r *sseRegistry) add(s *sseSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.sessions) >= sseMaxSessions {
+		rpcLimitStreamMeter.Mark(1)
+		return &limitExceededError{fmt.Sprintf("too many event streams on server (limit %d)", sseMaxSessions)}
+	}
+	if r.clients[s.client] >= sseMaxClientSessions {
+		rpcLimitStreamMeter.Mark(1)
+		return &limitExceededError{fmt.Sprintf("too many event streams (limit %d)", sseMaxClientSessions)}
+	}
+	if r.sessions == nil {
+		r.sessions = make(map[string]*sseSession)
+		r.clients = make(map[string]int)
+	}
+	r.sessions[s.token] = s
+	r.clients[s.client]++
+	return nil
+}
+
+func (r *sseRegistry) get(token string) *sseSession {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.sessions[token]
+}
+
+func (r *sseRegistry) remove(token string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s, ok := r.sessions[token]; ok {
+		delete(r.sessions, token)
+		if r.clients[s.client]--; r.clients[s.client] == 0 {
+			delete(r.clients, s.client)
+		}
+	}
+}
+
+// closeAll ends all subscriptions.
+func (r *sseRegistry) closeAll() {
+	r.mu.Lock()
+	sessions := make([]*sseSession, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		sessions = append(sessions, s)
+	}
+	r.mu.Unlock()
+
+	for _, s := range sessions {
+		s.close()
+	}
+}
+
+// sseSession is a subscription served over an event stream. It acts as the
+// connection of the handler serving the subscription, buffering the messages
+// written to it until they are streamed to the client.
+//
+// Writing never blocks the notifier. If the client doesn't keep up with the
+// notifications, the buffer overflows and the subscription is dropped.
+type sseSession struct {
+	token    string
+	remote   string
+	client   string // Key identifying the client, for limiting its sessions
+	registry *sseRegistry
+	h        *handler
+	release  func() // Releases the event stream quota of the client, if any
+
+	mu       sync.Mutex
+	events   [][]byte      // Retained encoded messages, events[i] has sequence number first+i
+	first    uint64        // Sequence number of the oldest retained event
+	sent     uint64        // Sequence number of the last event written to the current stream
+	done     bool          // Whether the stream ended, no more events are accepted
+	err      error         // Error ending the stream, if any
+	replaced chan struct{} // Closed when the current stream is superseded by a resumed one
+	expire   *time.Timer   // Closes the session if the interrupted stream isn't resumed
+
+	wake      chan struct{}    // Signals new events to the current stream
+	closeCh   chan interface{} // Closed when the session ends
+	closeOnce sync.Once
+}
+
+func newSSESession(token, remote string, registry *sseRegistry) *sseSession {
+	return &sseSession{
+		token:    token,
+		remote:   remote,
+		registry: registry,
+		first:    1,
+		wake:     make(chan struct{}, 1),
+		closeCh:  make(chan interface{}),
+	}
+}
+
+// writeJSON implements jsonWriter, queueing the message as an event.
+func (s *sseSession) writeJSON(ctx context.Context, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done {
+		return errEventStreamClosed
+	}
+	if len(s.events) == sseBufferSize {
+		// Drop the client if the oldest event wasn't even streamed yet
+		if s.first > s.sent {
+			s.err, s.done = errEventStreamOverflow, true
+			s.signal()
+			return errEventStreamOverflow
+		}
+		s.events = s.events[1:]
+		s.first++
+	}
+	s.events = append(s.events, data)
+
+	// End the stream after delivering a failed subscription response
+	if msg, ok := v.(*jsonrpcMessage); ok && msg.isResponse() && msg.Error != nil {
+		s.done = true
+	}
+	s.signal()
+	return nil
+}
+
+// signal wakes up the current stream. It must be called with the lock held.
+func (s *sseSession) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// closed implements jsonWriter.
+func (s *sseSession) closed() <-chan interface{} {
+	return s.closeCh
+}
+
+// remoteAddr implements jsonWriter.
+func (s *sseSession) remoteAddr() string {
+	return s.remote
+}
+
+// attach starts a new stream of the events following the given sequence number,
+// superseding the current one. The returned channel is closed when the new
+// stream is superseded in turn.
+func (s *sseSession) attach(cursor uint64) (chan struct{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cursor+1 < s.first || cursor >= s.first+uint64(len(s.events)) {
+		return nil, errEventStreamLost
+	}
+	if s.replaced != nil {
+		close(s.replaced)
+	}
+	if s.expire != nil {
+		s.expire.Stop()
+		s.expire = nil
+	}
+	s.replaced = make(chan struct{})
+	s.sent = cursor
+	return s.replaced, nil
+}
+
+// detach marks the stream as interrupted, ending the subscription unless it is
+// resumed in time.
+func (s *sseSession) detach(replaced chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.replaced == replaced && s.expire == nil {
+		s.expire = time.AfterFunc(sseResumeTimeout, s.close)
+	}
+}
+
+// serve streams the events following the cursor until the stream ends, the client
+// disconnects or the stream is superseded. If the connection of the stream is
+// known, the write timeout of the server is replaced by a deadline for each write.
+func (s *sseSession) serve(w http.ResponseWriter, conn net.Conn, replaced chan struct{}, cursor uint64, disconnected <-chan struct{}) {
+	flusher := w.(http.Flusher)
+	extend := func() {
+		if conn != nil {
+			conn.SetWriteDeadline(time.Now().Add(sseWriteTimeout))
+		}
+	}
+	if conn != nil {
+		// Don't leak the deadline into later requests on the connection
+		defer conn.SetWriteDeadline(time.Time{})
+	}
+	extend()
+
+	w.Header().Set("Content-Type", eventStreamContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable buffering in nginx
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(sseKeepAliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		// Retrieve the events the stream didn't deliver yet
+		s.mu.Lock()
+		if s.replaced != replaced {
+			s.mu.Unlock()
+			return
+		}
+		pending := append([][]byte(nil), s.events[cursor+1-s.first:]...)
+		done, err := s.done, s.err
+		s.mu.Unlock()
+
+		extend()
+		for _, data := range pending {
+			cursor++
+			if _, err := fmt.Fprintf(w, "id: %s:%d\ndata: %s\n\n", s.token, cursor, data); err != nil {
+				s.detach(replaced)
+				return
+			}
+		}
+		s.mu.Lock()
+		if s.replaced == replaced {
+			s.sent = cursor
+		}
+		s.mu.Unlock()
+
+		// Terminate the stream if all events were delivered
+		if done {
+			if err != nil {
+				data, _ := json.Marshal(&jsonError{Code: defaultErrorCode, Message: err.Error()})
+				fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+			}
+			flusher.Flush()
+			s.close()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-s.wake:
+		case <-keepalive.C:
+			extend()
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				s.detach(replaced)
+				return
+			}
+			flusher.Flush()
+		case <-disconnected:
+			s.detach(replaced)
+			return
+		case <-replaced:
+			return
+		case <-s.closeCh:
+			return
+		}
+	}
+}
+
+// close ends the subscription.
+func (s *sseSession) close() {
+	s.closeOnce.Do(func() {
+		s.registry.remove(s.token)
+
+		s.mu.Lock()
+		s.done = true
+		if s.expire != nil {
+			s.expire.Stop()
+		}
+		s.mu.Unlock()
+
+		close(s.closeCh)
+		s.h.close(errEventStreamClosed, nil)
+		if s.release != nil {
+			s.release()
+		}
+	})
+}
+
+// subscribeHTTP establishes a subscription over an HTTP event stream.
+func (c *Client) subscribeHTTP(ctx context.Context, namespace string, channel reflect.Value, msg *jsonrpcMessage) (*ClientSubscription, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	sub := newClientSubscription(c, namespace, channel)
+	stream := &sseClientStream{conn: c.writeConn.(*httpConn), sub: sub}
+
+	// The context only applies until the subscription is established
+	established := make(chan struct{})
+	defer close(established)
+	go func() {
+		select {
+		case <-ctx.Done():
+			stream.abort()
+		case <-established:
+		}
+	}()
+	var resp []byte
+	err = stream.connect(http.MethodPost, body)
+	if err == nil {
+		resp, _, err = stream.next()
+	}
+	if err != nil {
+		stream.abort()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	// The first event is the response to the subscription request
+	var answer jsonrpcMessage
+	if err := json.Unmarshal(resp, &answer); err != nil {
+		stream.abort()
+		return nil, err
+	}
+	if answer.Error != nil {
+		stream.abort()
+		return nil, answer.Error
+	}
+	if err := json.Unmarshal(answer.Result, &sub.subid); err != nil {
+		stream.abort()
+		return nil, err
+	}
+	sub.stream = stream
+	go sub.start()
+	go stream.loop()
+	return sub, nil
+}
+
+// sseClientStream is the client side of a subscription served over an HTTP
+// event stream. Interrupted streams are resumed transparently.
+//
+// Notifications are read from the stream only as fast as the subscription
+// consumes them, letting the server drop subscriptions falling behind.
+type sseClientStream struct {
+	conn *httpConn
+	sub  *ClientSubscription
+
+	mu     sync.Mutex
+	events *bufio.Reader      // Body of the current stream
+	cancel context.CancelFunc // Aborts the current stream
+	lastID string             // ID of the last received event, for resuming
+}
+
+// request sends a request for the event stream to the server.
+func (s *sseClientStream) request(ctx context.Context, method string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, s.conn.req.URL.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header = s.conn.req.Header.Clone()
+	req.Header.Set("Accept", eventStreamContentType)
+
+	s.mu.Lock()
+	if s.lastID != "" {
+		req.Header.Set("Last-Event-ID", s.lastID)
+	}
+	s.mu.Unlock()
+
+	return s.conn.client.Do(req)
+}
+
+// connect opens a new event stream, sending the given request body.
+func (s *sseClientStream) connect(method string, body []byte) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.cancel = cancel
+	s.mu.Unlock()
+
+	resp, err := s.request(ctx, method, body)
+	if err != nil {
+		return err
+	}
+	if err := checkEventStream(resp); err != nil {
+		resp.Body.Close()
+		return err
+	}
+	s.mu.Lock()
+	s.events = bufio.NewReader(resp.Body)
+	s.mu.Unlock()
+	return nil
+}
+
+// checkEventStream checks whether the server responded with an event stream.
+func checkEventStream(resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrSubscriptionNotFound
+	case resp.StatusCode == http.StatusGone:
+		return errEventStreamLost
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return errors.New(resp.Status)
+	}
+	if mt, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && mt == eventStreamContentType {
+		return nil
+	}
+	// Servers without event stream support answer the subscription request as a
+	// plain call, failing it.
+	var answer jsonrpcMessage
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err == nil && answer.Error != nil {
+		return answer.Error
+	}
+	return ErrNotificationsUnsupported
+}
+
+// next reads the next message event from the stream, returning its data. Error
+// events sent by the server end the stream and are reported as final.
+func (s *sseClientStream) next() (data []byte, final bool, err error) {
+	s.mu.Lock()
+	events := s.events
+	s.mu.Unlock()
+
+	var (
+		id, event string
+		hasData   bool
+	)
+	for {
+		line, err := events.ReadBytes('\n')
+		if err != nil {
+			return nil, false, err
+		}
+		line = bytes.TrimRight(line, "\r\n")
+
+		// Dispatch the event on blank lines
+		if len(line) == 0 {
+			if !hasData {
+				id, event = "", ""
+				continue
+			}
+			if id != "" {
+				s.mu.Lock()
+				s.lastID = id
+				s.mu.Unlock()
+			}
+			if event == "error" {
+				jerr := new(jsonError)
+				if err := json.Unmarshal(data, jerr); err != nil {
+					return nil, true, err
+				}
+				return nil, true, jerr
+			}
+			return data, false, nil
+		}
+		if line[0] == ':' {
+			continue // Comment
+		}
+		field, value := line, []byte(nil)
+		if idx := bytes.IndexByte(line, ':'); idx >= 0 {
+			field, value = line[:idx], bytes.TrimPrefix(line[idx+1:], []byte(" "))
+		}
+		switch string(field) {
+		case "id":
+			id = string(value)
+		case "event":
+			event = string(value)
+		case "data":
+			if hasData {
+				data = append(data, '\n')
+			}
+			data, hasData = append(data, value...), true
+		}
+	}
+}
+
+// loop forwards the notifications to the subscription, resuming the stream when
+// it is interrupted, until the subscription ends.
+func (s *sseClientStream) loop() {
+	// Unsubscribe when the client is closed
+	go func() {
+		select {
+		case <-s.conn.closeCh:
+			s.sub.quitWithError(true, ErrClientQuit)
+		case <-s.sub.quit:
+		}
+	}()
+	for {
+		final, err := s.forward()
+		select {
+		case <-s.sub.quit:
+			return
+		default:
+		}
+		if !final {
+			err = s.resume()
+		}
+		if err != nil {
+			s.sub.quitWithError(false, err)
+			return
+		}
+	}
+}
+
+// forward delivers the notifications of the stream to the subscription until
+// the stream ends.
+func (s *sseClientStream) forward() (final bool, err error) {
+	for {
+		data, final, err := s.next()
+		if err != nil {
+			return final, err
+		}
+		var msg jsonrpcMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return true, err
+		}
+		if !msg.isNotification() {
+			continue
+		}
+		var result subscriptionResult
+		if err := json.Unmarshal(msg.Params, &result); err != nil {
+			return true, err
+		}
+		if result.ID != s.sub.subid {
+			continue
+		}
+		if !s.sub.deliver(result.Result) {
+			return true, nil
+		}
+	}
+}
+
+// resume reconnects an interrupted stream, replaying the missed events.
+func (s *sseClientStream) resume() (err error) {
+	for i := 0; i < sseMaxRetries; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(sseRetryInterval):
+			case <-s.sub.quit:
+				return nil
+			}
+		}
+		if err = s.connect(http.MethodGet, nil); err == nil {
+			return nil
+		}
+		if err == ErrSubscriptionNotFound || err == errEventStreamLost {
+			return err
+		}
+	}
+	return err
+}
+
+// abort closes the current stream.
+func (s *sseClientStream) abort() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+// close ends the subscription on the server.
+func (s *sseClientStream) close() error {
+	s.abort()
+
+	resp, err := s.request(context.Background(), http.MethodDelete, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,276 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// feedService is a subscription service sending the values fed to it.
+type feedService struct {
+	values chan int
+}
+
+func (s *feedService) Feed(ctx context.Context) (*Subscription, error) {
+	notifier, supported := NotifierFromContext(ctx)
+	if !supported {
+		return nil, ErrNotificationsUnsupported
+	}
+	subscription := notifier.CreateSubscription()
+	go func() {
+		for {
+			select {
+			case val := <-s.values:
+				if err := notifier.Notify(subscription.ID, val); err != nil {
+					return
+				}
+			case <-subscription.Err():
+				return
+			}
+		}
+	}()
+	return subscription, nil
+}
+
+// streamCount returns the number of subscriptions served over event streams.
+func streamCount(server *Server) int {
+	server.streams.mu.Lock()
+	defer server.streams.mu.Unlock()
+	return len(server.streams.sessions)
+}
+
+func TestClientSubscribeHTTP(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	client, hs := httpTestClient(server, "http", nil)
+	defer hs.Close()
+	defer client.Close()
+
+	nc := make(chan int)
+	count := 10
+	sub, err := client.Subscribe(context.Background(), "nftest", nc, "someSubscription", count, 0)
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	for i := 0; i < count; i++ {
+		if val := <-nc; val != i {
+			t.Fatalf("value mismatch: got %d, want %d", val, i)
+		}
+	}
+	if n := streamCount(server); n != 1 {
+		t.Fatalf("wrong number of event streams: have %d, want 1", n)
+	}
+	sub.Unsubscribe()
+	select {
+	case v := <-nc:
+		t.Fatal("received value after unsubscribe:", v)
+	case err := <-sub.Err():
+		if err != nil {
+			t.Fatalf("Err returned a non-nil error after explicit unsubscribe: %q", err)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatalf("subscription not closed within 1s after unsubscribe")
+	}
+	if n := streamCount(server); n != 0 {
+		t.Fatalf("event stream not closed after unsubscribe")
+	}
+	// Check that failing subscription requests are reported
+	if _, err := client.Subscribe(context.Background(), "nftest", nc, "noSuchSubscription"); err == nil {
+		t.Fatal("subscribing to unknown subscription succeeded")
+	}
+}
+
+func TestClientSubscribeHTTPResume(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	service := &feedService{values: make(chan int)}
+	if err := server.RegisterName("feed", service); err != nil {
+		t.Fatal(err)
+	}
+	client, hs := httpTestClient(server, "http", nil)
+	defer hs.Close()
+	defer client.Close()
+
+	nc := make(chan int)
+	sub, err := client.Subscribe(context.Background(), "feed", nc, "feed")
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	defer sub.Unsubscribe()
+
+	service.values <- 1
+	if val := <-nc; val != 1 {
+		t.Fatalf("value mismatch: got %d, want 1", val)
+	}
+	// Interrupt the stream and check that the missed values are replayed
+	hs.CloseClientConnections()
+	service.values <- 2
+	service.values <- 3
+	for want := 2; want <= 3; want++ {
+		select {
+		case val := <-nc:
+			if val != want {
+				t.Fatalf("value mismatch: got %d, want %d", val, want)
+			}
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("value %d not received after resume", want)
+		}
+	}
+}
+
+func TestClientSubscribeHTTPUnsupported(t *testing.T) {
+	// Servers without event stream support fail the subscription call
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"notifications not supported"}}`))
+	}))
+	defer hs.Close()
+
+	client, err := DialHTTP(hs.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	_, err = client.Subscribe(context.Background(), "nftest", make(chan int), "someSubscription", 1, 0)
+	if err == nil || err.Error() != ErrNotificationsUnsupported.Error() {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
+
+// Tests that plain calls accepting event streams are answered as usual.
+func TestEventStreamPlainCall(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	hs := httptest.NewServer(server)
+	defer hs.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, hs.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"rpc_modules"}`))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", eventStreamContentType+", "+contentType)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	confirmStatusCode(t, resp.StatusCode, http.StatusOK)
+	var answer jsonrpcMessage
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if answer.Error != nil || len(answer.Result) == 0 {
+		t.Fatalf("wrong response: %+v", answer)
+	}
+}
+
+// Tests that the number of event streams of a client is limited by its quota.
+func TestEventStreamQuota(t *testing.T) {
+	server := newTestServer()
+	server.SetLimits(Limits{EventStreams: 1})
+	defer server.Stop()
+	client, hs := httpTestClient(server, "http", nil)
+	defer hs.Close()
+	defer client.Close()
+
+	sub, err := client.Subscribe(context.Background(), "nftest", make(chan int), "someSubscription", 1, 0)
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	if _, err := client.Subscribe(context.Background(), "nftest", make(chan int), "someSubscription", 1, 0); err == nil {
+		t.Fatal("subscribed beyond the event stream quota")
+	}
+	sub.Unsubscribe()
+	for i := 0; streamCount(server) > 0; i++ {
+		if i == 100 {
+			t.Fatal("event stream not closed after unsubscribe")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	sub, err = client.Subscribe(context.Background(), "nftest", make(chan int), "someSubscription", 1, 0)
+	if err != nil {
+		t.Fatal("can't subscribe after releasing the quota:", err)
+	}
+	sub.Unsubscribe()
+}
+
+// Tests that the number of event streams is limited per client and per server.
+func TestEventStreamRegistryLimits(t *testing.T) {
+	registry := new(sseRegistry)
+	add := func(id int, client string) error {
+		session := newSSESession(fmt.Sprint(id), "", registry)
+		session.client = client
+		return registry.add(session)
+	}
+	for i := 0; i < sseMaxClientSessions; i++ {
+		if err := add(i, "a"); err != nil {
+			t.Fatalf("session %d: %v", i, err)
+		}
+	}
+	if err := add(sseMaxClientSessions, "a"); err == nil {
+		t.Fatal("added session beyond the client limit")
+	}
+	registry.remove("0")
+	if err := add(sseMaxClientSessions, "a"); err != nil {
+		t.Fatalf("failed to add session after removal: %v", err)
+	}
+	for i := sseMaxClientSessions + 1; i <= sseMaxSessions; i++ {
+		if err := add(i, fmt.Sprint(i)); err != nil {
+			t.Fatalf("session %d: %v", i, err)
+		}
+	}
+	if err := add(sseMaxSessions+1, "b"); err == nil {
+		t.Fatal("added session beyond the server limit")
+	}
+}
+
+func TestEventStreamBuffer(t *testing.T) {
+	session := newSSESession("token", "", new(sseRegistry))
+	msg := &jsonrpcMessage{Version: vsn, Method: "test_subscription"}
+
+	// Events not streamed to the client may not be dropped
+	for i := 0; i < sseBufferSize; i++ {
+		if err := session.writeJSON(context.Background(), msg); err != nil {
+			t.Fatalf("event %d: %v", i, err)
+		}
+	}
+	session.sent = 1
+	if err := session.writeJSON(context.Background(), msg); err != nil {
+		t.Fatalf("failed to evict streamed event: %v", err)
+	}
+	if _, err := session.attach(0); err != errEventStreamLost {
+		t.Fatalf("resumed from evicted event: %v", err)
+	}
+	if _, err := session.attach(1); err != nil {
+		t.Fatalf("failed to resume from retained event: %v", err)
+	}
+	if err := session.writeJSON(context.Background(), msg); err != errEventStreamOverflow {
+		t.Fatalf("wrong error on overflow: %v", err)
+	}
+	if err := session.writeJSON(context.Background(), msg); err != errEventStreamClosed {
+		t.Fatalf("wrong error after overflow: %v", err)
+	}
+}
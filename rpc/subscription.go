@@ -206,6 +206,7 @@ type ClientSubscription struct {
 	namespace string
 	subid     string
 	in        chan json.RawMessage
+	stream    *sseClientStream // event stream delivering the notifications over HTTP
 
 	quitOnce sync.Once     // ensures quit is closed once
 	quit     chan struct{} // quit is closed when the subscription exits
@@ -322,6 +323,9 @@ func (sub *ClientSubscription) unmarshal(result json.RawMessage) (interface{}, e
 }
 
 func (sub *ClientSubscription) requestUnsubscribe() error {
+	if sub.stream != nil {
+		return sub.stream.close()
+	}
 	var result interface{}
 	return sub.client.Call(&result, sub.namespace+unsubscribeMethodSuffix, sub.subid)
 }
@@ -109,6 +109,21 @@ func (bn *BlockNumber) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// argument returns the block number in the form accepted as an RPC argument:
+// "latest", "earliest" or "pending" for the tags, hex for the other numbers.
+func (bn BlockNumber) argument() string {
+	switch bn {
+	case EarliestBlockNumber:
+		return "earliest"
+	case LatestBlockNumber:
+		return "latest"
+	case PendingBlockNumber:
+		return "pending"
+	default:
+		return hexutil.EncodeUint64(uint64(bn))
+	}
+}
+
 func (bn BlockNumber) Int64() int64 {
 	return (int64)(bn)
 }
@@ -174,6 +189,21 @@ func (bnh *BlockNumberOrHash) UnmarshalJSON(data []byte) error {
 	}
 }
 
+// MarshalJSON implements json.Marshaler, encoding the block number or hash as
+// an object accepted by UnmarshalJSON, retaining the canonical requirement.
+func (bnh BlockNumberOrHash) MarshalJSON() ([]byte, error) {
+	type output struct {
+		BlockNumber      string       `json:"blockNumber,omitempty"`
+		BlockHash        *common.Hash `json:"blockHash,omitempty"`
+		RequireCanonical bool         `json:"requireCanonical,omitempty"`
+	}
+	out := output{BlockHash: bnh.BlockHash, RequireCanonical: bnh.RequireCanonical}
+	if bnh.BlockNumber != nil {
+		out.BlockNumber = bnh.BlockNumber.argument()
+	}
+	return json.Marshal(out)
+}
+
 func (bnh *BlockNumberOrHash) Number() (BlockNumber, bool) {
 	if bnh.BlockNumber != nil {
 		return *bnh.BlockNumber, true
@@ -188,6 +218,18 @@ func (bnh *BlockNumberOrHash) Hash() (common.Hash, bool) {
 	return common.Hash{}, false
 }
 
+// String returns the block number or hash in the form accepted as an RPC
+// argument.
+func (bnh *BlockNumberOrHash) String() string {
+	if bnh.BlockNumber != nil {
+		return bnh.BlockNumber.argument()
+	}
+	if bnh.BlockHash != nil {
+		return bnh.BlockHash.Hex()
+	}
+	return "nil"
+}
+
 func BlockNumberOrHashWithNumber(blockNr BlockNumber) BlockNumberOrHash {
 	return BlockNumberOrHash{
 		BlockNumber:      &blockNr,
@@ -122,3 +122,36 @@ func TestBlockNumberOrHash_UnmarshalJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestBlockNumberOrHash_MarshalJSON(t *testing.T) {
+	tests := []BlockNumberOrHash{
+		BlockNumberOrHashWithNumber(0),
+		BlockNumberOrHashWithNumber(18),
+		BlockNumberOrHashWithNumber(math.MaxInt64),
+		BlockNumberOrHashWithNumber(PendingBlockNumber),
+		BlockNumberOrHashWithNumber(LatestBlockNumber),
+		BlockNumberOrHashWithHash(common.HexToHash("0x01"), false),
+		BlockNumberOrHashWithHash(common.HexToHash("0x01"), true),
+	}
+	for i, test := range tests {
+		blob, err := json.Marshal(test)
+		if err != nil {
+			t.Errorf("Test %d failed to marshal: %v", i, err)
+			continue
+		}
+		var bnh BlockNumberOrHash
+		if err := json.Unmarshal(blob, &bnh); err != nil {
+			t.Errorf("Test %d failed to unmarshal %s: %v", i, blob, err)
+			continue
+		}
+		hash, hashOk := bnh.Hash()
+		expectedHash, expectedHashOk := test.Hash()
+		num, numOk := bnh.Number()
+		expectedNum, expectedNumOk := test.Number()
+		if bnh.RequireCanonical != test.RequireCanonical ||
+			hash != expectedHash || hashOk != expectedHashOk ||
+			num != expectedNum || numOk != expectedNumOk {
+			t.Errorf("Test %d got unexpected value from %s, want %v, got %v", i, blob, test, bnh)
+		}
+	}
+}
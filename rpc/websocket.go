@@ -59,7 +59,9 @@ func (s *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			return
 		}
 		codec := newWebsocketCodec(conn)
-		s.ServeCodec(codec, 0)
+		// Serve the calls in the context of the upgrade request, so they see the
+		// values set by the HTTP middleware, like the client identity.
+		s.serveCodec(r.Context(), codec, s.limiter.quota(r.Context(), r.RemoteAddr))
 	})
 }
 
@@ -39,13 +39,40 @@ type request struct {
 	data []byte      // Data content of the node, cached until all subtrees complete
 	raw  bool        // Whether this is a raw entry (code) or a trie node
 
+	path    []byte     // Merkle path leading to this node, also used to prioritise DFS
 	parents []*request // Parent state nodes referencing this entry (notify all upon completion)
-	depth   int        // Depth level within the trie the node is located to prioritise DFS
 	deps    int        // Number of dependencies before allowed to commit this node
 
 	callback LeafCallback // Callback to invoke if a leaf node it reached on this branch
 }
 
+// SyncPath is a path tuple identifying a particular trie node either in a single
+// trie (account) or a layered trie (account -> storage).
+//
+// Content wise the tuple either has 1 element if it addresses a node in a single
+// trie or 2 elements if it addresses a node in a stacked trie.
+//
+// To support aiming arbitrary trie nodes, the path needs to support odd nibble
+// lengths. To avoid transferring expanded hex form over the network, the last
+// part of the tuple (which needs to index into the middle of a trie) is compact
+// encoded. In case of a 2-tuple, the first item is always 32 bytes so that is
+// simple binary encoded.
+type SyncPath [][]byte
+
+// newSyncPath converts an expanded trie path from nibble form into a compact
+// version that can be sent over the network.
+func newSyncPath(path []byte) SyncPath {
+	// If the hash is from the account trie, append a single item, if it
+	// is from the a storage trie, append a tuple. Note, the length 64 is
+	// clashing between account leaf and storage root. It's fine though
+	// because having a trie node at 64 depth means a hash collision was
+	// found and we're long dead.
+	if len(path) < 64 {
+		return SyncPath{hexToCompact(path)}
+	}
+	return SyncPath{hexToKeybytes(path[:64]), hexToCompact(path[64:])}
+}
+
 // SyncResult is a simple list to return missing nodes along with their request
 // hashes.
 type SyncResult struct {
@@ -86,12 +113,14 @@ func NewSync(root common.Hash, database ethdb.KeyValueReader, callback LeafCallb
 		queue:    prque.New(nil),
 		bloom:    bloom,
 	}
-	ts.AddSubTrie(root, 0, common.Hash{}, callback)
+	ts.AddSubTrie(root, nil, common.Hash{}, callback)
 	return ts
 }
 
-// AddSubTrie registers a new trie to the sync code, rooted at the designated parent.
-func (s *Sync) AddSubTrie(root common.Hash, depth int, parent common.Hash, callback LeafCallback) {
+// AddSubTrie registers a new trie to the sync code, rooted at the designated
+// parent. The path is the hex encoded location of the sub-trie's root, which is
+// the key of the referencing leaf for tries nested into other ones.
+func (s *Sync) AddSubTrie(root common.Hash, path []byte, parent common.Hash, callback LeafCallback) {
 	// Short circuit if the trie is empty or already known
 	if root == emptyRoot {
 		return
@@ -111,7 +140,7 @@ func (s *Sync) AddSubTrie(root common.Hash, depth int, parent common.Hash, callb
 	// Assemble the new sub-trie sync request
 	req := &request{
 		hash:     root,
-		depth:    depth,
+		path:     path,
 		callback: callback,
 	}
 	// If this sub-trie has a designated parent, link them together
@@ -130,7 +159,7 @@ func (s *Sync) AddSubTrie(root common.Hash, depth int, parent common.Hash, callb
 // interpreted as a trie node, but rather accepted and stored into the database
 // as is. This method's goal is to support misc state metadata retrievals (e.g.
 // contract code).
-func (s *Sync) AddRawEntry(hash common.Hash, depth int, parent common.Hash) {
+func (s *Sync) AddRawEntry(hash common.Hash, path []byte, parent common.Hash) {
 	// Short circuit if the entry is empty or already known
 	if hash == emptyState {
 		return
@@ -148,9 +177,9 @@ func (s *Sync) AddRawEntry(hash common.Hash, depth int, parent common.Hash) {
 	}
 	// Assemble the new sub-trie sync request
 	req := &request{
-		hash:  hash,
-		raw:   true,
-		depth: depth,
+		hash: hash,
+		raw:  true,
+		path: path,
 	}
 	// If this sub-trie has a designated parent, link them together
 	if parent != (common.Hash{}) {
@@ -173,6 +202,22 @@ func (s *Sync) Missing(max int) []common.Hash {
 	return requests
 }
 
+// MissingPaths retrieves the known missing entries from the trie for retrieval,
+// same as Missing. To aid path based retrievals, the trie nodes are returned
+// along with their paths, separately from the raw entries (contract codes).
+func (s *Sync) MissingPaths(max int) (nodes []common.Hash, paths []SyncPath, codes []common.Hash) {
+	for !s.queue.Empty() && (max == 0 || len(nodes)+len(codes) < max) {
+		hash := s.queue.PopItem().(common.Hash)
+		if req := s.requests[hash]; req.raw {
+			codes = append(codes, hash)
+		} else {
+			nodes = append(nodes, hash)
+			paths = append(paths, newSyncPath(req.path))
+		}
+	}
+	return nodes, paths, codes
+}
+
 // Process injects a batch of retrieved trie nodes data, returning if something
 // was committed to the database and also the index of an entry if its processing
 // failed.
@@ -250,7 +295,7 @@ func (s *Sync) schedule(req *request) {
 		return
 	}
 	// Schedule the request for future retrieval
-	s.queue.Push(req.hash, int64(req.depth))
+	s.queue.Push(req.hash, int64(len(req.path)))
 	s.requests[req.hash] = req
 }
 
@@ -259,23 +304,31 @@ func (s *Sync) schedule(req *request) {
 func (s *Sync) children(req *request, object node) ([]*request, error) {
 	// Gather all the children of the node, irrelevant whether known or not
 	type child struct {
-		node  node
-		depth int
+		path []byte
+		node node
 	}
 	var children []child
 
 	switch node := (object).(type) {
 	case *shortNode:
+		key := node.Key
+		if hasTerm(key) {
+			key = key[:len(key)-1]
+		}
 		children = []child{{
-			node:  node.Val,
-			depth: req.depth + len(node.Key),
+			node: node.Val,
+			path: append(append([]byte(nil), req.path...), key...),
 		}}
 	case *fullNode:
 		for i := 0; i < 17; i++ {
 			if node.Children[i] != nil {
+				path := append([]byte(nil), req.path...)
+				if i < 16 {
+					path = append(path, byte(i))
+				}
 				children = append(children, child{
-					node:  node.Children[i],
-					depth: req.depth + 1,
+					node: node.Children[i],
+					path: path,
 				})
 			}
 		}
@@ -288,7 +341,7 @@ func (s *Sync) children(req *request, object node) ([]*request, error) {
 		// Notify any external watcher of a new key/value node
 		if req.callback != nil {
 			if node, ok := (child.node).(valueNode); ok {
-				if err := req.callback(node, req.hash); err != nil {
+				if err := req.callback(child.path, node, req.hash); err != nil {
 					return nil, err
 				}
 			}
@@ -311,8 +364,8 @@ func (s *Sync) children(req *request, object node) ([]*request, error) {
 			// Locally unknown node, schedule for retrieval
 			requests = append(requests, &request{
 				hash:     hash,
+				path:     child.path,
 				parents:  []*request{req},
-				depth:    child.depth,
 				callback: req.callback,
 			})
 		}
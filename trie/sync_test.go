@@ -138,6 +138,47 @@ func testIterativeSync(t *testing.T, count int) {
 	checkTrieContents(t, triedb, srcTrie.Hash().Bytes(), srcData)
 }
 
+// Tests that the trie scheduler can correctly reconstruct the state when the
+// nodes are retrieved by their paths instead of their hashes.
+func TestIterativeSyncByPath(t *testing.T) {
+	// Create a random trie to copy
+	_, srcTrie, srcData := makeTestTrie()
+
+	// Create a destination trie and sync with the scheduler
+	diskdb := memorydb.New()
+	triedb := NewDatabase(diskdb)
+	sched := NewSync(srcTrie.Hash(), diskdb, nil, NewSyncBloom(1, diskdb))
+
+	nodes, paths, codes := sched.MissingPaths(100)
+	for len(nodes) > 0 {
+		if len(codes) != 0 {
+			t.Fatalf("unexpected raw entries scheduled: %d", len(codes))
+		}
+		results := make([]SyncResult, len(nodes))
+		for i, path := range paths {
+			if len(path) != 1 {
+				t.Fatalf("path #%d: tuple length mismatch: have %d, want 1", i, len(path))
+			}
+			data, _, err := srcTrie.TryGetNode(path[0])
+			if err != nil {
+				t.Fatalf("failed to retrieve node data for path %x: %v", path, err)
+			}
+			results[i] = SyncResult{nodes[i], data}
+		}
+		if _, index, err := sched.Process(results); err != nil {
+			t.Fatalf("failed to process result #%d: %v", index, err)
+		}
+		batch := diskdb.NewBatch()
+		if err := sched.Commit(batch); err != nil {
+			t.Fatalf("failed to commit data: %v", err)
+		}
+		batch.Write()
+		nodes, paths, codes = sched.MissingPaths(100)
+	}
+	// Cross check that the two tries are in sync
+	checkTrieContents(t, triedb, srcTrie.Hash().Bytes(), srcData)
+}
+
 // Tests that the trie scheduler can correctly reconstruct the state even if only
 // partial results are returned, and the others sent only later.
 func TestIterativeDelayedSync(t *testing.T) {